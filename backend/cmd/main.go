@@ -12,6 +12,7 @@ import (
 
 	"backend/config"
 	"backend/database"
+	"backend/models"
 	"backend/routes"
 	"backend/services"
 )
@@ -22,6 +23,29 @@ func main() {
 	database.Connect(cfg.MongoURI, cfg.DBName)
 	database.CreateIndexes()
 
+	models.SetCameraClockPolicy(models.CameraClockPolicy{
+		MaxFutureSkew: cfg.CameraClockMaxFuture,
+		MaxPastAge:    cfg.CameraClockMaxPast,
+		OnViolation:   cfg.CameraClockPolicy,
+	})
+	models.SetCameraSignatureMaxSkew(cfg.CameraSignatureMaxSkew)
+	models.SetDataQualityConfig(models.DataQualityConfig{
+		MaxSpeed:               cfg.QualityMaxSpeed,
+		MinConfidence:          cfg.QualityMinConfidence,
+		ZeroCountOccupancy:     cfg.QualityZeroCountOccupancy,
+		MinScore:               cfg.QualityMinScore,
+		ExcludeFlaggedAnalysis: cfg.QualityExcludeFlagged,
+	})
+	models.SetDefaultGapFillMethod(cfg.GapFillMethod)
+	models.SetSpeedDeviationThreshold(cfg.SpeedDeviationThreshold)
+	models.SetDefaultHambatanSampingSource(cfg.SideFrictionSource)
+	models.SetLHRTConfig(models.LHRTConfig{
+		MinKelengkapanHari: cfg.LHRTMinKelengkapanHari,
+		MinHariPermanen:    cfg.LHRTMinHariPermanen,
+	})
+	models.SetProjectionConfig(cfg.ProjectionGrowthRate, cfg.ProjectionHorizonYears)
+	models.SetForecastConfig(cfg.ForecastHistoryWeeks, cfg.ForecastHorizonHours)
+
 	app := fiber.New(fiber.Config{
 		BodyLimit: 50 * 1024 * 1024, // 50MB limit dari base64 images
 	})
@@ -29,7 +53,7 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Signature, X-Signature-Timestamp, X-Signature-Nonce",
 	}))
 
 	routes.Setup(app)
@@ -37,6 +61,38 @@ func main() {
 	trafficCollector := services.NewTrafficCollectorService()
 	trafficCollector.Start()
 
+	sideFriction := services.NewSideFrictionService(cfg.SideFrictionInterval, cfg.SideFrictionWindowDays)
+	sideFriction.Start()
+
+	forecastService := services.NewForecastService(cfg.ForecastInterval)
+	forecastService.Start()
+
+	var mqttListener *services.MQTTListenerService
+	if cfg.MQTTBrokerURL != "" {
+		mqttClient := services.NewPahoMQTTClient(services.MQTTClientOptions{
+			BrokerURL: cfg.MQTTBrokerURL,
+			ClientID:  cfg.MQTTClientID,
+			Username:  cfg.MQTTUsername,
+			Password:  cfg.MQTTPassword,
+		})
+		mqttListener = services.NewMQTTListenerService(mqttClient, cfg.MQTTTopicPrefix)
+		if err := mqttListener.Start(); err != nil {
+			log.Printf("MQTT listener tidak berjalan: %v", err)
+			mqttListener = nil
+		}
+	}
+
+	reprocessService := services.NewReprocessService()
+	services.SetDefaultReprocessService(reprocessService)
+	reprocessService.Start()
+
+	var ingestQueue *services.IngestionQueueService
+	if cfg.IngestAsync {
+		ingestQueue = services.NewIngestionQueueService(cfg.IngestWorkers, cfg.IngestQueueSize)
+		ingestQueue.Start()
+		services.SetDefaultIngestionQueue(ingestQueue)
+	}
+
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour)
 		defer ticker.Stop()
@@ -46,17 +102,31 @@ func main() {
 		}
 	}()
 
+	shutdownDone := make(chan bool)
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 
 		log.Println("Shutting down gracefully...")
-		trafficCollector.Stop()
 		app.Shutdown()
+		if mqttListener != nil {
+			mqttListener.Stop()
+		}
+		if ingestQueue != nil {
+			ingestQueue.Stop()
+		}
+		reprocessService.Stop()
+		trafficCollector.Stop()
+		sideFriction.Stop()
+		forecastService.Stop()
+		close(shutdownDone)
 	}()
 
 	log.Println("Server running on http://localhost:" + cfg.AppPort)
 	log.Println("Traffic Collector Service is running with per-location intervals")
-	log.Fatal(app.Listen(":" + cfg.AppPort))
+	if err := app.Listen(":" + cfg.AppPort); err != nil {
+		log.Fatal(err)
+	}
+	<-shutdownDone
 }
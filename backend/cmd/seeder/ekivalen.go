@@ -0,0 +1,15 @@
+package main
+
+import (
+	"backend/models"
+	"log"
+)
+
+func SeedEkivalen() {
+	err := models.InitEkivalenTables()
+	if err != nil {
+		log.Printf("Error seeding tabel ekivalen: %v\n", err)
+	} else {
+		log.Printf("Tabel ekivalen seeded successfully")
+	}
+}
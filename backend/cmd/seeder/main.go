@@ -10,7 +10,7 @@ import (
 )
 
 func main() {
-	seedType := flag.String("type", "all", "Type of seed to run: 'superadmin', 'klasifikasi', 'balai', or 'all'")
+	seedType := flag.String("type", "all", "Type of seed to run: 'superadmin', 'klasifikasi', 'balai', 'ekivalen', or 'all'")
 	flag.Parse()
 	cfg := config.Load()
 	database.Connect(cfg.MongoURI, cfg.DBName)
@@ -22,14 +22,17 @@ func main() {
 		SeedKlasifikasi()
 	case "balai":
 		SeedBalai()
+	case "ekivalen":
+		SeedEkivalen()
 	case "all":
 		fmt.Println("------------------------------------------------")
 		SeedSuperAdmin()
 		SeedKlasifikasi()
 		SeedBalai()
+		SeedEkivalen()
 		fmt.Println("------------------------------------------------")
 	default:
-		fmt.Println("Invalid seed type. Use 'superadmin', 'klasifikasi', 'balai', or 'all'")
+		fmt.Println("Invalid seed type. Use 'superadmin', 'klasifikasi', 'balai', 'ekivalen', or 'all'")
 		os.Exit(1)
 	}
 }
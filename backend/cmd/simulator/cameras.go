@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"backend/config"
+	"backend/database"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Kamera yang disimulasikan beserta konteks lokasinya
+type SimCamera struct {
+	ID            string  `json:"id"`
+	APIKey        string  `json:"api_key"`
+	TipeKamera    string  `json:"tipe_kamera"`
+	TipeLokasi    string  `json:"tipe_lokasi"`
+	Interval      int     `json:"interval"` // detik
+	ZoneIDs       []int   `json:"zone_ids"`
+	SigningSecret string  `json:"signing_secret"`
+	ZonaWaktu     float64 `json:"zona_waktu"`
+}
+
+// loadCamerasFromFile membaca daftar kamera dari file JSON (array SimCamera)
+func loadCamerasFromFile(path string) ([]SimCamera, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cameras []SimCamera
+	if err := json.Unmarshal(content, &cameras); err != nil {
+		return nil, fmt.Errorf("format file kamera tidak valid: %v", err)
+	}
+	for i := range cameras {
+		if cameras[i].APIKey == "" {
+			return nil, fmt.Errorf("kamera[%d].api_key tidak boleh kosong", i)
+		}
+		if cameras[i].ID == "" {
+			cameras[i].ID = fmt.Sprintf("SIM-%03d", i+1)
+		}
+		if cameras[i].TipeKamera == "" {
+			cameras[i].TipeKamera = "trafficam"
+		}
+		if cameras[i].TipeLokasi == "" {
+			cameras[i].TipeLokasi = "perkotaan"
+		}
+		if len(cameras[i].ZoneIDs) == 0 {
+			cameras[i].ZoneIDs = []int{1, 2}
+		}
+	}
+	return cameras, nil
+}
+
+// loadCamerasFromDB membaca kamera terdaftar beserta tipe lokasi dan interval dari database
+func loadCamerasFromDB(ids string) ([]SimCamera, error) {
+	cfg := config.Load()
+	database.Connect(cfg.MongoURI, cfg.DBName)
+
+	filter := bson.M{}
+	if ids != "" {
+		filter["_id"] = bson.M{"$in": strings.Split(ids, ",")}
+	}
+	cameras, err := models.GetCameras(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var simCameras []SimCamera
+	for _, camera := range cameras {
+		location, err := models.GetLocationByID(camera.LokasiID)
+		if err != nil {
+			fmt.Printf("Kamera %s dilewati: lokasi %s tidak ditemukan\n", camera.ID, camera.LokasiID)
+			continue
+		}
+
+		zoneIDs := make([]int, 0, len(camera.ZonaArah))
+		for i, za := range camera.ZonaArah {
+			zoneIDs = append(zoneIDs, za.ZoneNumber(i))
+		}
+		simCameras = append(simCameras, SimCamera{
+			ID:            camera.ID,
+			APIKey:        camera.APIKey,
+			TipeKamera:    camera.TipeKamera,
+			TipeLokasi:    location.Tipe_lokasi,
+			Interval:      location.Interval,
+			ZoneIDs:       zoneIDs,
+			SigningSecret: camera.SigningSecret,
+			ZonaWaktu:     location.Zona_waktu,
+		})
+	}
+	return simCameras, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"backend/models"
+)
+
+// Komposisi kelas kendaraan per tipe lokasi (nomor kelas sesuai klasifikasi kendaraan lokasi)
+var classMix = map[string]map[int]float64{
+	"perkotaan":      {1: 0.60, 2: 0.33, 3: 0.07},
+	"luar_kota":      {1: 0.45, 2: 0.30, 3: 0.10, 4: 0.10, 5: 0.05},
+	"bebas_hambatan": {1: 0.70, 2: 0.12, 3: 0.12, 4: 0.06},
+	"12_kelas": {1: 0.55, 2: 0.15, 3: 0.06, 4: 0.04, 5: 0.03, 6: 0.04,
+		7: 0.03, 8: 0.02, 9: 0.02, 10: 0.01, 11: 0.01, 12: 0.04},
+}
+
+// Kecepatan arus bebas rata-rata (km/jam) per tipe lokasi
+var freeFlowSpeed = map[string]float64{
+	"perkotaan":      42,
+	"luar_kota":      60,
+	"bebas_hambatan": 85,
+	"12_kelas":       55,
+}
+
+// Pengali kecepatan per kategori MKJI
+var categorySpeedFactor = map[models.KategoriMKJI]float64{
+	models.KategoriMC: 1.05,
+	models.KategoriLV: 1.0,
+	models.KategoriHV: 0.8,
+	models.KategoriUM: 0.25,
+}
+
+// Generator payload satu kamera. DataNumber naik setiap payload seperti perangkat asli.
+type Generator struct {
+	camera     SimCamera
+	peakVolume float64 // kendaraan/jam per zona saat puncak
+	withImage  bool
+	rng        *rand.Rand
+	dataNumber int
+}
+
+func NewGenerator(camera SimCamera, peakVolume float64, withImage bool, seed int64) *Generator {
+	return &Generator{
+		camera:     camera,
+		peakVolume: peakVolume,
+		withImage:  withImage,
+		rng:        rand.New(rand.NewSource(seed)),
+		dataNumber: 1,
+	}
+}
+
+// diurnalFactor mengembalikan faktor volume 0-1 menurut jam lokal: puncak pagi, siang, dan sore
+func diurnalFactor(localHour float64) float64 {
+	gauss := func(center, width float64) float64 {
+		return math.Exp(-math.Pow(localHour-center, 2) / (2 * width * width))
+	}
+	factor := 0.06 + 0.9*gauss(7.5, 1.2) + 0.45*gauss(12.5, 2.5) + 0.8*gauss(17, 1.5)
+	return math.Min(factor, 1)
+}
+
+// Build membuat payload untuk interval yang berakhir pada intervalEnd (UTC)
+func (g *Generator) Build(intervalEnd time.Time) ([]byte, string) {
+	interval := g.camera.Interval
+	if interval <= 0 {
+		interval = 300
+	}
+
+	local := intervalEnd.Add(time.Duration(g.camera.ZonaWaktu * float64(time.Hour)))
+	factor := diurnalFactor(float64(local.Hour()) + float64(local.Minute())/60)
+
+	zones := make([]models.CameraZone, 0, len(g.camera.ZoneIDs))
+	for _, zoneID := range g.camera.ZoneIDs {
+		zones = append(zones, g.buildZone(zoneID, interval, factor))
+	}
+
+	var image string
+	if g.withImage {
+		image = g.snapshot(factor)
+	}
+
+	payload := &models.CameraInterval{
+		APIKey:       g.camera.APIKey,
+		IntervalTime: interval,
+		DataNumber:   g.dataNumber,
+		Utc:          strconv.FormatInt(intervalEnd.Unix(), 10),
+		Image:        image,
+		Zones:        zones,
+	}
+	g.dataNumber++
+
+	if g.camera.TipeKamera == "ai_counter" {
+		return buildJSONCounterPayload(payload, intervalEnd), "application/json"
+	}
+	return buildFlirXMLPayload(payload), "application/xml"
+}
+
+func (g *Generator) buildZone(zoneID, interval int, factor float64) models.CameraZone {
+	// Variasi ±15% antar interval dan antar zona
+	mean := g.peakVolume * factor * float64(interval) / 3600 * (0.85 + 0.3*g.rng.Float64())
+	mix, ok := classMix[g.camera.TipeLokasi]
+	if !ok {
+		mix = classMix["perkotaan"]
+	}
+	baseSpeed, ok := freeFlowSpeed[g.camera.TipeLokasi]
+	if !ok {
+		baseSpeed = freeFlowSpeed["perkotaan"]
+	}
+	// Kecepatan turun saat volume tinggi
+	congestedSpeed := baseSpeed * (1 - 0.4*factor)
+
+	zone := models.CameraZone{ZoneId: zoneID, Confidence: 88 + 11*g.rng.Float64()}
+	total := 0
+	speedSum := 0.0
+	for class := 1; class <= 12; class++ {
+		share, exists := mix[class]
+		if !exists {
+			continue
+		}
+		count := g.poisson(mean * share)
+		speed := 0.0
+		if count > 0 {
+			kategori := models.GetKategoriMKJI(g.camera.TipeLokasi, class)
+			speed = congestedSpeed * categorySpeedFactor[kategori] * (0.9 + 0.2*g.rng.Float64())
+		}
+		gapTime := 0.0
+		if count > 0 {
+			gapTime = float64(interval) / float64(count)
+		}
+		zone.Classes = append(zone.Classes, models.CameraClass{
+			ClassNr: class,
+			NumVeh:  count,
+			Speed:   round1(speed),
+			GapTime: round1(gapTime),
+		})
+		total += count
+		speedSum += speed * float64(count)
+	}
+
+	if total > 0 {
+		avgSpeed := speedSum / float64(total)
+		flow := float64(total) * 3600 / float64(interval)
+		zone.HeadWay = round1(float64(interval) / float64(total))
+		zone.Density = round1(flow / avgSpeed)
+		zone.Length = round1(4 + 2*g.rng.Float64())
+		// Okupansi (%) ≈ kepadatan (kend/km) × panjang kendaraan (m) / 10
+		zone.Occupancy = round1(math.Min(zone.Density*zone.Length/10, 95))
+	}
+	zone.Confidence = round1(zone.Confidence)
+	return zone
+}
+
+// poisson memakai pendekatan normal untuk rata-rata besar agar tetap cepat
+func (g *Generator) poisson(mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	if mean > 30 {
+		return int(math.Max(0, math.Round(mean+math.Sqrt(mean)*g.rng.NormFloat64())))
+	}
+	limit := math.Exp(-mean)
+	k, p := 0, 1.0
+	for {
+		p *= g.rng.Float64()
+		if p <= limit {
+			return k
+		}
+		k++
+	}
+}
+
+// snapshot membuat gambar PNG kecil berwarna sesuai tingkat kepadatan
+func (g *Generator) snapshot(factor float64) string {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 36))
+	fill := color.RGBA{R: uint8(255 * factor), G: uint8(255 * (1 - factor)), B: 60, A: 255}
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 36; y++ {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func buildFlirXMLPayload(interval *models.CameraInterval) []byte {
+	data := models.CameraXMLData{
+		API: interval.APIKey,
+		Message: models.CameraMessage{
+			Type: "Data",
+			Body: models.CameraBody{
+				Type:         "Interval",
+				IntervalTime: interval.IntervalTime,
+				DataNumber:   interval.DataNumber,
+				Utc:          interval.Utc,
+				MilliSeconds: interval.MilliSeconds,
+				Zones:        interval.Zones,
+			},
+		},
+		Image: interval.Image,
+	}
+	payload, _ := xml.Marshal(data)
+	return append([]byte(xml.Header), payload...)
+}
+
+// Format payload adapter json_counter (lihat models.JSONCounterAdapter)
+type jsonCounterClass struct {
+	Class    int     `json:"class"`
+	Count    int     `json:"count"`
+	AvgSpeed float64 `json:"avg_speed"`
+	GapTime  float64 `json:"gap_time,omitempty"`
+}
+
+type jsonCounterZone struct {
+	ZoneID     int                `json:"zone_id"`
+	Occupancy  float64            `json:"occupancy"`
+	Confidence float64            `json:"confidence"`
+	Headway    float64            `json:"headway"`
+	Density    float64            `json:"density"`
+	Counts     []jsonCounterClass `json:"counts"`
+}
+
+type jsonCounterPayload struct {
+	APIKey          string            `json:"api_key"`
+	IntervalSeconds int               `json:"interval_seconds"`
+	Sequence        int               `json:"sequence"`
+	Timestamp       string            `json:"timestamp"`
+	Snapshot        string            `json:"snapshot,omitempty"`
+	Zones           []jsonCounterZone `json:"zones"`
+}
+
+func buildJSONCounterPayload(interval *models.CameraInterval, intervalEnd time.Time) []byte {
+	data := jsonCounterPayload{
+		APIKey:          interval.APIKey,
+		IntervalSeconds: interval.IntervalTime,
+		Sequence:        interval.DataNumber,
+		Timestamp:       intervalEnd.UTC().Format(time.RFC3339),
+		Snapshot:        interval.Image,
+	}
+	for _, zone := range interval.Zones {
+		jz := jsonCounterZone{
+			ZoneID:     zone.ZoneId,
+			Occupancy:  zone.Occupancy,
+			Confidence: round1(zone.Confidence) / 100, // counter AI melaporkan confidence 0-1
+			Headway:    zone.HeadWay,
+			Density:    zone.Density,
+		}
+		for _, class := range zone.Classes {
+			jz.Counts = append(jz.Counts, jsonCounterClass{
+				Class:    class.ClassNr,
+				Count:    class.NumVeh,
+				AvgSpeed: class.Speed,
+				GapTime:  class.GapTime,
+			})
+		}
+		data.Zones = append(data.Zones, jz)
+	}
+	payload, _ := json.Marshal(data)
+	return payload
+}
+
+func round1(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Simulator lalu lintas kamera untuk uji beban dan end-to-end jalur ingest.
+// Kamera dibaca dari database (-source db) atau file JSON (-source file), lalu payload
+// XML FLIR / JSON counter dikirim ke endpoint data kamera dengan laju dan konkurensi tertentu.
+func main() {
+	target := flag.String("target", "http://localhost:8080", "Base URL backend")
+	path := flag.String("path", "/camera/data", "Endpoint penerima data kamera")
+	source := flag.String("source", "db", "Sumber kamera: 'db' atau 'file'")
+	file := flag.String("file", "cameras.json", "File JSON daftar kamera untuk -source file")
+	cameraIDs := flag.String("cameras", "", "Daftar ID kamera dipisah koma (kosong = semua, hanya -source db)")
+	rate := flag.Float64("rate", 5, "Jumlah request per detik untuk semua kamera (0 = tanpa batas)")
+	concurrency := flag.Int("concurrency", 4, "Jumlah request paralel")
+	duration := flag.Duration("duration", time.Minute, "Lama simulasi")
+	count := flag.Int("count", 0, "Jumlah request maksimum (0 = sampai -duration habis)")
+	realtime := flag.Bool("realtime", false, "Setiap kamera mengirim sekali per interval dengan waktu sekarang (mengabaikan -rate)")
+	startStr := flag.String("start", "", "Awal jam virtual RFC3339 untuk mode laju (default 24 jam lalu)")
+	volume := flag.Float64("volume", 1200, "Volume puncak per zona (kendaraan/jam)")
+	withImage := flag.Bool("image", false, "Sertakan gambar base64 pada payload")
+	sign := flag.Bool("sign", true, "Tandatangani payload jika signing secret kamera tersedia")
+	reportEvery := flag.Duration("report", 10*time.Second, "Interval laporan progres")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "Seed generator acak")
+	flag.Parse()
+
+	var cameras []SimCamera
+	var err error
+	switch *source {
+	case "db":
+		cameras, err = loadCamerasFromDB(*cameraIDs)
+	case "file":
+		cameras, err = loadCamerasFromFile(*file)
+	default:
+		fmt.Println("Source tidak valid. Gunakan 'db' atau 'file'")
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Gagal memuat kamera: %v", err)
+	}
+	if len(cameras) == 0 {
+		log.Fatalf("Tidak ada kamera untuk disimulasikan")
+	}
+
+	startTime := time.Now().UTC().Add(-24 * time.Hour)
+	if *startStr != "" {
+		startTime, err = time.Parse(time.RFC3339, *startStr)
+		if err != nil {
+			log.Fatalf("Format -start tidak valid (gunakan RFC3339): %v", err)
+		}
+	}
+
+	generators := make([]*Generator, len(cameras))
+	for i, camera := range cameras {
+		generators[i] = NewGenerator(camera, *volume, *withImage, *seed+int64(i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-quit
+		log.Println("Simulasi dihentikan, menunggu request berjalan...")
+		cancel()
+	}()
+
+	log.Printf("Simulasi %d kamera ke %s%s (concurrency=%d, realtime=%v, rate=%.1f/detik)",
+		len(cameras), *target, *path, *concurrency, *realtime, *rate)
+
+	jobs := make(chan simJob, *concurrency*2)
+	stats := NewStats()
+	client := &http.Client{Timeout: 30 * time.Second}
+	url := strings.TrimRight(*target, "/") + *path
+
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				send(client, url, job, *sign, stats)
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(*reportEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.Printf("Progres: %s", stats.Progress())
+			}
+		}
+	}()
+
+	if *realtime {
+		produceRealtime(ctx, generators, cameras, *count, jobs)
+	} else {
+		produceRated(ctx, generators, cameras, startTime, *rate, *count, jobs)
+	}
+	close(jobs)
+	wg.Wait()
+
+	fmt.Println("------------------------------------------------")
+	fmt.Print(stats.Report())
+	fmt.Println("------------------------------------------------")
+	if stats.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+type simJob struct {
+	camera      SimCamera
+	body        []byte
+	contentType string
+}
+
+// produceRated mengirim payload bergiliran antar kamera dengan jam virtual per kamera.
+// Jam virtual maju satu interval setiap payload dan kembali ke -start jika melewati waktu sekarang.
+func produceRated(ctx context.Context, generators []*Generator, cameras []SimCamera, startTime time.Time, rate float64, count int, jobs chan<- simJob) {
+	clocks := make([]time.Time, len(cameras))
+	for i := range clocks {
+		clocks[i] = startTime
+	}
+
+	var ticker *time.Ticker
+	if rate > 0 {
+		ticker = time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+	}
+
+	for sent := 0; count == 0 || sent < count; sent++ {
+		if ticker != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		i := sent % len(cameras)
+		interval := time.Duration(cameraInterval(cameras[i])) * time.Second
+		clocks[i] = clocks[i].Add(interval)
+		if clocks[i].After(time.Now()) {
+			clocks[i] = startTime.Add(interval)
+		}
+
+		body, contentType := generators[i].Build(clocks[i])
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- simJob{camera: cameras[i], body: body, contentType: contentType}:
+		}
+	}
+}
+
+// produceRealtime meniru kamera di lapangan: setiap kamera mengirim di akhir setiap intervalnya
+func produceRealtime(ctx context.Context, generators []*Generator, cameras []SimCamera, count int, jobs chan<- simJob) {
+	var mu sync.Mutex
+	sent := 0
+	var wg sync.WaitGroup
+	for i := range cameras {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			interval := time.Duration(cameraInterval(cameras[i])) * time.Second
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				mu.Lock()
+				if count > 0 && sent >= count {
+					mu.Unlock()
+					return
+				}
+				sent++
+				body, contentType := generators[i].Build(time.Now().UTC())
+				mu.Unlock()
+
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- simJob{camera: cameras[i], body: body, contentType: contentType}:
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func cameraInterval(camera SimCamera) int {
+	if camera.Interval > 0 {
+		return camera.Interval
+	}
+	return 300
+}
+
+func send(client *http.Client, url string, job simJob, sign bool, stats *Stats) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(job.body))
+	if err != nil {
+		stats.RecordError(err)
+		return
+	}
+	req.Header.Set("Content-Type", job.contentType)
+
+	if sign && job.camera.SigningSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := uuid.New().String()
+		req.Header.Set(models.SignatureTimestampHeader, timestamp)
+		req.Header.Set(models.SignatureNonceHeader, nonce)
+		req.Header.Set(models.SignatureHeader, models.ComputeCameraSignature(job.camera.SigningSecret, timestamp, nonce, job.body))
+	}
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		stats.RecordError(err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	latency := time.Since(started)
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	_ = json.Unmarshal(respBody, &result)
+	stats.Record(latency, resp.StatusCode, result.Status, result.Error, len(job.body))
+}
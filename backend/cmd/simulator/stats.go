@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statistik latensi dan hasil request simulator
+type Stats struct {
+	mu         sync.Mutex
+	startedAt  time.Time
+	latencies  []time.Duration
+	statusCode map[int]int
+	ingest     map[string]int // status dari response (accepted, duplicate, queued, ...)
+	errors     map[string]int // error jaringan / request
+	rejected   map[string]int // pesan error dari response HTTP >= 400
+	bytesSent  int64
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		startedAt:  time.Now(),
+		statusCode: make(map[int]int),
+		ingest:     make(map[string]int),
+		errors:     make(map[string]int),
+		rejected:   make(map[string]int),
+	}
+}
+
+func (s *Stats) Record(latency time.Duration, statusCode int, ingestStatus, errorMessage string, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if statusCode >= 400 && errorMessage != "" {
+		s.rejected[truncateMessage(errorMessage)]++
+	}
+	s.latencies = append(s.latencies, latency)
+	s.statusCode[statusCode]++
+	if ingestStatus != "" {
+		s.ingest[ingestStatus]++
+	}
+	s.bytesSent += int64(size)
+}
+
+func (s *Stats) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[truncateMessage(err.Error())]++
+}
+
+func truncateMessage(msg string) string {
+	if len(msg) > 80 {
+		return msg[:80]
+	}
+	return msg
+}
+
+// Ringkasan satu baris untuk laporan berkala
+func (s *Stats) Progress() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, failed := s.countsLocked()
+	elapsed := time.Since(s.startedAt).Seconds()
+	p50, p99 := percentile(s.latencies, 0.5), percentile(s.latencies, 0.99)
+	return fmt.Sprintf("%d request (%.1f/detik), %d gagal, p50=%v p99=%v",
+		total, float64(total)/elapsed, failed, p50.Round(time.Millisecond), p99.Round(time.Millisecond))
+}
+
+// Laporan akhir: throughput, distribusi latensi, status HTTP, status ingest, dan error
+func (s *Stats) Report() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total, failed := s.countsLocked()
+	elapsed := time.Since(s.startedAt)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Durasi          : %v\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Total request   : %d (%.1f/detik)\n", total, float64(total)/elapsed.Seconds())
+	fmt.Fprintf(&b, "Gagal           : %d\n", failed)
+	fmt.Fprintf(&b, "Data terkirim   : %.1f KB\n", float64(s.bytesSent)/1024)
+
+	if len(s.latencies) > 0 {
+		var sum time.Duration
+		for _, l := range s.latencies {
+			sum += l
+		}
+		fmt.Fprintf(&b, "Latensi         : mean=%v p50=%v p90=%v p99=%v max=%v\n",
+			(sum / time.Duration(len(s.latencies))).Round(time.Millisecond),
+			percentile(s.latencies, 0.5).Round(time.Millisecond),
+			percentile(s.latencies, 0.9).Round(time.Millisecond),
+			percentile(s.latencies, 0.99).Round(time.Millisecond),
+			percentile(s.latencies, 1).Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(&b, "Status HTTP     :")
+	for _, code := range sortedKeys(s.statusCode) {
+		fmt.Fprintf(&b, " %d=%d", code, s.statusCode[code])
+	}
+	b.WriteString("\n")
+
+	if len(s.ingest) > 0 {
+		fmt.Fprintf(&b, "Status ingest   :")
+		for _, status := range sortedStringKeys(s.ingest) {
+			fmt.Fprintf(&b, " %s=%d", status, s.ingest[status])
+		}
+		b.WriteString("\n")
+	}
+
+	if len(s.rejected) > 0 {
+		b.WriteString("Ditolak server:\n")
+		for _, msg := range sortedStringKeys(s.rejected) {
+			fmt.Fprintf(&b, "  %5d × %s\n", s.rejected[msg], msg)
+		}
+	}
+
+	if len(s.errors) > 0 {
+		b.WriteString("Error request:\n")
+		for _, msg := range sortedStringKeys(s.errors) {
+			fmt.Fprintf(&b, "  %5d × %s\n", s.errors[msg], msg)
+		}
+	}
+	return b.String()
+}
+
+// Total request dan jumlah yang gagal (error jaringan atau HTTP >= 400)
+func (s *Stats) countsLocked() (int, int) {
+	total, failed := 0, 0
+	for code, n := range s.statusCode {
+		total += n
+		if code >= 400 {
+			failed += n
+		}
+	}
+	for _, n := range s.errors {
+		total += n
+		failed += n
+	}
+	return total, failed
+}
+
+func (s *Stats) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, failed := s.countsLocked()
+	return failed
+}
+
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+func sortedKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func sortedStringKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"math"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,85 @@ type Config struct {
 	MongoURI  string
 	DBName    string
 	JWTSecret string
+
+	// Toleransi jam kamera terhadap server
+	CameraClockMaxFuture time.Duration
+	CameraClockMaxPast   time.Duration
+	CameraClockPolicy    string
+
+	// Batas selisih timestamp signature payload kamera
+	CameraSignatureMaxSkew time.Duration
+
+	// Batas pemeriksaan kualitas data interval
+	QualityMaxSpeed           float64
+	QualityMinConfidence      float64
+	QualityZeroCountOccupancy float64
+	QualityMinScore           float64
+	QualityExcludeFlagged     bool
+
+	// Batas deviasi (%) kecepatan terukur terhadap VB/VT PKJI sebelum lokasi perlu ditinjau
+	SpeedDeviationThreshold float64
+
+	// Metode pengisian interval hilang untuk analisis: none, linear, last_week
+	GapFillMethod string
+
+	// Estimasi kelas hambatan samping dari data terukur
+	SideFrictionSource     string        // sumber kelas untuk analisis: konfigurasi, observasi
+	SideFrictionInterval   time.Duration // jarak antar estimasi terjadwal, 0 = nonaktif
+	SideFrictionWindowDays int           // jumlah hari data untuk estimasi terjadwal
+
+	// Batas data faktor dan estimasi LHRT
+	LHRTMinKelengkapanHari float64 // persen interval tersedia agar satu hari dipakai
+	LHRTMinHariPermanen    int     // hari lengkap minimum dalam 365 hari agar lokasi menjadi pos permanen
+
+	// Proyeksi pertumbuhan lalu lintas
+	ProjectionGrowthRate   float64 // %/tahun jika riwayat LHR tidak cukup, NaN = tidak ada
+	ProjectionHorizonYears int     // horizon default proyeksi (tahun)
+
+	// Prakiraan lalu lintas jangka pendek
+	ForecastInterval     time.Duration // jarak antar prakiraan tersimpan, 0 = nonaktif
+	ForecastHorizonHours int           // horizon prakiraan (1-3 jam)
+	ForecastHistoryWeeks int           // jumlah minggu riwayat untuk profil hari dan jam
+
+	// Antrian ingest data kamera
+	IngestAsync     bool
+	IngestWorkers   int
+	IngestQueueSize int
+
+	// Listener MQTT data kamera, nonaktif jika MQTTBrokerURL kosong
+	MQTTBrokerURL   string
+	MQTTClientID    string
+	MQTTUsername    string
+	MQTTPassword    string
+	MQTTTopicPrefix string
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
 }
 
 func Load() *Config {
@@ -27,5 +109,45 @@ func Load() *Config {
 		MongoURI:  os.Getenv("MONGO_URI"),
 		DBName:    os.Getenv("DB_NAME"),
 		JWTSecret: os.Getenv("JWT_SECRET"),
+
+		CameraClockMaxFuture: getEnvDuration("CAMERA_CLOCK_MAX_FUTURE", 5*time.Minute),
+		CameraClockMaxPast:   getEnvDuration("CAMERA_CLOCK_MAX_PAST", 7*24*time.Hour),
+		CameraClockPolicy:    os.Getenv("CAMERA_CLOCK_POLICY"),
+
+		CameraSignatureMaxSkew: getEnvDuration("CAMERA_SIGNATURE_MAX_SKEW", 5*time.Minute),
+
+		QualityMaxSpeed:           getEnvFloat("QUALITY_MAX_SPEED", 200),
+		QualityMinConfidence:      getEnvFloat("QUALITY_MIN_CONFIDENCE", 50),
+		QualityZeroCountOccupancy: getEnvFloat("QUALITY_ZERO_COUNT_OCCUPANCY", 20),
+		QualityMinScore:           getEnvFloat("QUALITY_MIN_SCORE", 50),
+		QualityExcludeFlagged:     os.Getenv("QUALITY_EXCLUDE_FLAGGED") == "true",
+
+		SpeedDeviationThreshold: getEnvFloat("SPEED_DEVIATION_THRESHOLD", 20),
+
+		GapFillMethod: os.Getenv("GAP_FILL_METHOD"),
+
+		SideFrictionSource:     os.Getenv("SIDE_FRICTION_SOURCE"),
+		SideFrictionInterval:   getEnvDuration("SIDE_FRICTION_INTERVAL", 24*time.Hour),
+		SideFrictionWindowDays: getEnvInt("SIDE_FRICTION_WINDOW_DAYS", 7),
+
+		LHRTMinKelengkapanHari: getEnvFloat("LHRT_MIN_KELENGKAPAN_HARI", 90),
+		LHRTMinHariPermanen:    getEnvInt("LHRT_MIN_HARI_PERMANEN", 300),
+
+		ProjectionGrowthRate:   getEnvFloat("PROJECTION_GROWTH_RATE", math.NaN()),
+		ProjectionHorizonYears: getEnvInt("PROJECTION_HORIZON_YEARS", 20),
+
+		ForecastInterval:     getEnvDuration("FORECAST_INTERVAL", 15*time.Minute),
+		ForecastHorizonHours: getEnvInt("FORECAST_HORIZON_HOURS", 3),
+		ForecastHistoryWeeks: getEnvInt("FORECAST_HISTORY_WEEKS", 4),
+
+		IngestAsync:     os.Getenv("INGEST_ASYNC") != "false",
+		IngestWorkers:   getEnvInt("INGEST_WORKERS", 4),
+		IngestQueueSize: getEnvInt("INGEST_QUEUE_SIZE", 1000),
+
+		MQTTBrokerURL:   os.Getenv("MQTT_BROKER_URL"),
+		MQTTClientID:    os.Getenv("MQTT_CLIENT_ID"),
+		MQTTUsername:    os.Getenv("MQTT_USERNAME"),
+		MQTTPassword:    os.Getenv("MQTT_PASSWORD"),
+		MQTTTopicPrefix: os.Getenv("MQTT_TOPIC_PREFIX"),
 	}
 }
@@ -2,10 +2,13 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 
 	"backend/database"
 	"backend/models"
@@ -19,9 +22,13 @@ type CameraRequest struct {
 	APIKey           string                  `json:"api_key"`
 	Keterangan       string                  `json:"keterangan"`
 	LokasiID         string                  `json:"lokasi_id"`
+	RequireSignature bool                    `json:"require_signature"`
+	// Tetap simpan konfigurasi zona meskipun tidak sesuai dengan payload terakhir kamera
+	Force bool `json:"force"`
 }
 
 func validateCameraRequest(req CameraRequest) (string, bool) {
+	models.ApplyDefaultZoneIDs(req.ZonaArah)
 	if req.TipeKamera == "" {
 		return "Tipe kamera wajib diisi", false
 	}
@@ -62,7 +69,7 @@ func CreateCamera(c *fiber.Ctx) error {
 	}
 
 	for i := range req.ZonaArah {
-		idZonaArah := models.GenerateZonaArahID(id, i+1)
+		idZonaArah := models.GenerateZonaArahID(id, req.ZonaArah[i].ZoneID)
 		req.ZonaArah[i].IDZonaArah = idZonaArah
 		zonaArah := models.ZonaArah{
 			ID:   idZonaArah,
@@ -74,15 +81,14 @@ func CreateCamera(c *fiber.Ctx) error {
 		}
 	}
 
-	// Generate API key unik
-	apiKey := uuid.New().String()
-	for {
-		var existingCamera models.Camera
-		err := database.DB.Collection("cameras").FindOne(context.Background(), bson.M{"api_key": apiKey}).Decode(&existingCamera)
-		if err != nil {
-			break
-		}
-		apiKey = uuid.New().String()
+	// Generate API key unik dan signing secret untuk payload bertanda tangan
+	apiKey, err := models.GenerateCameraAPIKey()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat API key"})
+	}
+	signingSecret, err := models.GenerateSigningSecret()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat signing secret"})
 	}
 
 	camera := models.Camera{
@@ -93,15 +99,19 @@ func CreateCamera(c *fiber.Ctx) error {
 		APIKey:           apiKey,
 		Keterangan:       req.Keterangan,
 		LokasiID:         req.LokasiID,
+		SigningSecret:    signingSecret,
+		RequireSignature: req.RequireSignature,
 	}
 
 	_, err = database.DB.Collection("cameras").InsertOne(context.Background(), camera)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Gagal membuat kamera"})
 	}
+	// Signing secret hanya ditampilkan sekali di sini; selanjutnya hanya bisa diganti lewat rotasi
 	return c.Status(201).JSON(fiber.Map{
-		"message": "Kamera berhasil dibuat",
-		"data":    camera,
+		"message":        "Kamera berhasil dibuat",
+		"data":           camera,
+		"signing_secret": signingSecret,
 	})
 }
 
@@ -179,6 +189,17 @@ func UpdateCamera(c *fiber.Ctx) error {
 	if errMsg, valid := validateCameraRequest(req); !valid {
 		return c.Status(400).JSON(fiber.Map{"error": errMsg})
 	}
+
+	// Konfigurasi zona dicek terhadap ZoneId yang baru saja dikirim kamera
+	zoneErrors, zoneWarnings := models.ValidateZonaArahAgainstRecentZones(id, req.ZonaArah)
+	if len(zoneErrors) > 0 && !req.Force {
+		return c.Status(409).JSON(fiber.Map{
+			"error":    "Konfigurasi zona tidak sesuai dengan data terakhir kamera, kirim ulang dengan force: true untuk tetap menyimpan",
+			"details":  zoneErrors,
+			"warnings": zoneWarnings,
+		})
+	}
+
 	// Hapus zona arah lama
 	zonaArahList, _ := models.GetZonaArahByCameraID(id)
 	for _, za := range zonaArahList {
@@ -186,7 +207,7 @@ func UpdateCamera(c *fiber.Ctx) error {
 	}
 
 	for i := range req.ZonaArah {
-		idZonaArah := models.GenerateZonaArahID(id, i+1)
+		idZonaArah := models.GenerateZonaArahID(id, req.ZonaArah[i].ZoneID)
 		req.ZonaArah[i].IDZonaArah = idZonaArah
 		zonaArah := models.ZonaArah{
 			ID:   idZonaArah,
@@ -215,8 +236,9 @@ func UpdateCamera(c *fiber.Ctx) error {
 	var updatedCamera models.Camera
 	database.DB.Collection("cameras").FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedCamera)
 	return c.JSON(fiber.Map{
-		"message": "Kamera berhasil diupdate",
-		"data":    updatedCamera,
+		"message":  "Kamera berhasil diupdate",
+		"data":     updatedCamera,
+		"warnings": append(zoneErrors, zoneWarnings...),
 	})
 }
 
@@ -243,6 +265,128 @@ func DeleteCamera(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "Kamera berhasil dihapus"})
 }
 
+// Merotasi API key dan signing secret kamera. API key lama tetap diterima selama overlap_minutes
+// (default 24 jam) agar kamera dapat dikonfigurasi ulang tanpa kehilangan data.
+func RotateCameraAPIKey(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req struct {
+		OverlapMinutes *int `json:"overlap_minutes"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Request tidak valid"})
+		}
+	}
+
+	overlap := models.DefaultKeyRotationOverlap
+	if req.OverlapMinutes != nil {
+		if *req.OverlapMinutes < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "overlap_minutes tidak boleh negatif"})
+		}
+		overlap = time.Duration(*req.OverlapMinutes) * time.Minute
+	}
+
+	camera, err := models.RotateCameraAPIKey(id, overlap)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Kamera tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal merotasi API key kamera"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "API key kamera berhasil dirotasi",
+		"data":           camera,
+		"signing_secret": camera.SigningSecret,
+	})
+}
+
+// Mengaktifkan atau menonaktifkan kewajiban signature pada payload kamera
+func UpdateCameraSignature(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req struct {
+		RequireSignature *bool `json:"require_signature"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.RequireSignature == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "require_signature wajib diisi"})
+	}
+
+	camera, newSecret, err := models.SetCameraSignatureRequirement(id, *req.RequireSignature)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Kamera tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengubah pengaturan signature kamera"})
+	}
+
+	response := fiber.Map{
+		"message": "Pengaturan signature kamera berhasil diubah",
+		"data":    camera,
+	}
+	// Kamera lama tanpa secret mendapat secret baru, ditampilkan sekali seperti saat kamera dibuat
+	if newSecret != "" {
+		response["signing_secret"] = newSecret
+	}
+	return c.JSON(response)
+}
+
+// Mengambil kesehatan semua kamera, bisa difilter berdasarkan lokasi_id atau status
+func GetCamerasHealth(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if lokasiID := c.Query("lokasi_id"); lokasiID != "" {
+		filter["lokasi_id"] = lokasiID
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	healthList, err := models.GetCameraHealthList(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil kesehatan kamera"})
+	}
+	if healthList == nil {
+		healthList = []models.CameraHealth{}
+	}
+	return c.JSON(fiber.Map{
+		"data":  healthList,
+		"count": len(healthList),
+	})
+}
+
+// Mengambil kesehatan kamera beserta riwayat perubahan status online/offline
+func GetCameraHealthByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var camera models.Camera
+	err := database.DB.Collection("cameras").FindOne(context.Background(), bson.M{"_id": id}).Decode(&camera)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Kamera tidak ditemukan"})
+	}
+
+	health, err := models.GetCameraHealth(&camera)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil kesehatan kamera"})
+	}
+
+	limit, err := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	history, err := models.GetCameraStatusHistory(id, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil riwayat status kamera"})
+	}
+	if history == nil {
+		history = []models.CameraStatusHistory{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    health,
+		"history": history,
+	})
+}
+
 // Mengambil daftar tipe kamera yang tersedia
 func GetCameraOptions(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
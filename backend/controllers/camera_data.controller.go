@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"strings"
 
 	"backend/models"
+	"backend/services"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,29 +18,19 @@ type CameraDataRequest struct {
 	XMLData string `json:"xml_data"`
 }
 
-// Menerima data XML dari kamera melalui body request
-func ReceiveCameraData(c *fiber.Ctx) error {
-	xmlData := string(c.Body())
-	if xmlData == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "Data XML tidak boleh kosong",
-			"success": false,
-		})
+// Membentuk response untuk data kamera yang diterima atau terdeteksi duplikat
+func cameraDataResponse(c *fiber.Ctx, trafficData *models.TrafficData, status models.IngestStatus) error {
+	code := 201
+	message := "Data traffic berhasil diterima dan disimpan"
+	if status == models.IngestStatusDuplicate {
+		code = 200
+		message = "Data traffic sudah pernah diterima, tidak disimpan ulang"
 	}
 
-	log.Printf("Data kamera diterima: %d bytes", len(xmlData))
-	trafficData, err := models.ProcessCameraData(xmlData)
-	if err != nil {
-		log.Printf("Gagal memproses data kamera: %v", err)
-		return c.Status(400).JSON(fiber.Map{
-			"error":   err.Error(),
-			"success": false,
-		})
-	}
-
-	return c.Status(201).JSON(fiber.Map{
+	return c.Status(code).JSON(fiber.Map{
 		"success": true,
-		"message": "Data traffic berhasil diterima dan disimpan",
+		"status":  status,
+		"message": message,
 		"data": fiber.Map{
 			"id":              trafficData.ID,
 			"lokasi_id":       trafficData.LokasiID,
@@ -49,6 +43,126 @@ func ReceiveCameraData(c *fiber.Ctx) error {
 	})
 }
 
+// Mencatat asal request untuk disimpan bersama dead-letter jika payload gagal diproses
+func ingestSourceFromRequest(c *fiber.Ctx) *models.IngestSource {
+	return models.NewIngestSource(c.Path(), c.IP(), c.GetReqHeaders())
+}
+
+// Memeriksa signature request terhadap kamera pemilik payload sebelum data diproses.
+// Payload yang gagal parse atau API key-nya tidak dikenal dilewati di sini dan ditangani saat ingest (dead-letter).
+// Request bertanda tangan hanya boleh berisi data dari satu kamera.
+func verifyCameraRequestSignature(c *fiber.Ctx, body []byte, items []string) (int, error) {
+	cameras := make(map[string]*models.Camera)
+	seenKeys := make(map[string]bool)
+	for _, item := range items {
+		apiKey, err := models.ExtractCameraAPIKey(item)
+		if err != nil || seenKeys[apiKey] {
+			continue
+		}
+		seenKeys[apiKey] = true
+
+		camera, err := models.GetCameraByAPIKey(apiKey)
+		if err != nil {
+			continue
+		}
+		cameras[camera.ID] = camera
+	}
+
+	signature := c.Get(models.SignatureHeader)
+	if signature == "" {
+		for _, camera := range cameras {
+			if camera.RequireSignature {
+				return 401, fmt.Errorf("%w (kamera %s)", models.ErrSignatureRequired, camera.ID)
+			}
+		}
+		return 0, nil
+	}
+
+	if len(cameras) != 1 {
+		return 400, errors.New("request bertanda tangan harus berisi data dari tepat satu kamera terdaftar")
+	}
+	for _, camera := range cameras {
+		err := models.VerifyCameraSignature(camera, body, c.Get(models.SignatureTimestampHeader), c.Get(models.SignatureNonceHeader), signature)
+		if err != nil {
+			if errors.Is(err, models.ErrSignatureInvalid) || errors.Is(err, models.ErrSignatureExpired) || errors.Is(err, models.ErrSignatureReplay) {
+				return 401, err
+			}
+			return 500, err
+		}
+	}
+	return 0, nil
+}
+
+// Memproses satu payload kamera, lewat antrian jika ingest asinkron aktif
+func handleCameraPayload(c *fiber.Ctx, body []byte, xmlData string) error {
+	if code, err := verifyCameraRequestSignature(c, body, []string{xmlData}); err != nil {
+		log.Printf("Signature data kamera ditolak: %v", err)
+		return c.Status(code).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	source := ingestSourceFromRequest(c)
+	if queue := services.DefaultIngestionQueue(); queue != nil {
+		return enqueueCameraPayloads(c, queue, []string{xmlData}, source)
+	}
+
+	trafficData, status, err := models.IngestCameraPayload(xmlData, source)
+	if err != nil {
+		log.Printf("Gagal memproses data kamera: %v", err)
+		return c.Status(400).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	return cameraDataResponse(c, trafficData, status)
+}
+
+// Memasukkan payload ke antrian dan langsung menjawab 202 dengan tracking ID.
+// Antrian penuh dijawab 429, antrian tidak berjalan dijawab 503.
+func enqueueCameraPayloads(c *fiber.Ctx, queue *services.IngestionQueueService, items []string, source *models.IngestSource) error {
+	job, err := queue.Enqueue(items, source)
+	if err != nil {
+		if errors.Is(err, services.ErrIngestQueueFull) {
+			c.Set("Retry-After", "5")
+			return c.Status(429).JSON(fiber.Map{
+				"error":   "Antrian data kamera penuh, coba kirim ulang beberapa saat lagi",
+				"success": false,
+			})
+		}
+		c.Set("Retry-After", "30")
+		return c.Status(503).JSON(fiber.Map{
+			"error":   "Layanan penerimaan data kamera tidak tersedia",
+			"success": false,
+		})
+	}
+
+	return c.Status(202).JSON(fiber.Map{
+		"success":     true,
+		"status":      job.Status,
+		"message":     "Data traffic diterima dan masuk antrian proses",
+		"tracking_id": job.ID,
+		"item_count":  job.ItemCount,
+		"queue_depth": queue.Stats().Depth,
+	})
+}
+
+// Menerima data XML dari kamera melalui body request
+func ReceiveCameraData(c *fiber.Ctx) error {
+	xmlData := string(c.Body())
+	if xmlData == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Data XML tidak boleh kosong",
+			"success": false,
+		})
+	}
+
+	log.Printf("Data kamera diterima: %d bytes", len(xmlData))
+	return handleCameraPayload(c, c.Body(), xmlData)
+}
+
 // Menerima data XML dari kamera dalam format JSON
 func ReceiveCameraDataJSON(c *fiber.Ctx) error {
 	var req CameraDataRequest
@@ -65,27 +179,7 @@ func ReceiveCameraDataJSON(c *fiber.Ctx) error {
 		})
 	}
 	log.Printf("Data kamera diterima (JSON wrapper): %d bytes", len(req.XMLData))
-	trafficData, err := models.ProcessCameraData(req.XMLData)
-	if err != nil {
-		log.Printf("Gagal memproses data kamera: %v", err)
-		return c.Status(400).JSON(fiber.Map{
-			"error":   err.Error(),
-			"success": false,
-		})
-	}
-	return c.Status(201).JSON(fiber.Map{
-		"success": true,
-		"message": "data traffic berhasil diterima dan disimpan",
-		"data": fiber.Map{
-			"id":              trafficData.ID,
-			"lokasi_id":       trafficData.LokasiID,
-			"nama_lokasi":     trafficData.NamaLokasi,
-			"total_kendaraan": trafficData.TotalKendaraan,
-			"timestamp":       trafficData.Timestamp,
-			"mkji_analysis":   trafficData.MKJIAnalysis,
-			"pkji_analysis":   trafficData.PKJIAnalysis,
-		},
-	})
+	return handleCameraPayload(c, c.Body(), req.XMLData)
 }
 
 // Menerima data XML dari kamera melalui stream
@@ -105,26 +199,135 @@ func ReceiveCameraDataStream(c *fiber.Ctx) error {
 		})
 	}
 	log.Printf("Data kamera diterima (stream): %d bytes", len(xmlData))
-	trafficData, err := models.ProcessCameraData(xmlData)
+	return handleCameraPayload(c, body, xmlData)
+}
+
+// Menerima banyak interval sekaligus dalam satu request.
+// Body dapat berupa XML multi-dokumen (banyak <Root> / <Root> dengan banyak <Message>),
+// NDJSON ({"xml_data": "..."} per baris), atau JSON {"xml_data": ["...", "..."]}.
+func ReceiveCameraDataBatch(c *fiber.Ctx) error {
+	body := string(c.Body())
+	if strings.TrimSpace(body) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Body batch tidak boleh kosong",
+			"success": false,
+		})
+	}
+
+	var items []string
+	var err error
+
+	contentType := strings.ToLower(string(c.Request().Header.ContentType()))
+	switch {
+	case strings.Contains(contentType, "ndjson") || strings.Contains(contentType, "jsonl"):
+		items, err = models.SplitCameraNDJSONBatch(body)
+	case strings.Contains(contentType, "json"):
+		var req struct {
+			XMLData []string `json:"xml_data"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Format request tidak valid",
+				"success": false,
+			})
+		}
+		items = req.XMLData
+	default:
+		items, err = models.SplitCameraXMLBatch(body)
+	}
 	if err != nil {
-		log.Printf("Gagal memproses data kamera: %v", err)
 		return c.Status(400).JSON(fiber.Map{
 			"error":   err.Error(),
 			"success": false,
 		})
 	}
-	return c.Status(201).JSON(fiber.Map{
+
+	if err := models.ValidateCameraBatch(items); err != nil {
+		if errors.Is(err, models.ErrCameraBatchTooLarge) {
+			return c.Status(413).JSON(fiber.Map{
+				"error":   "Jumlah item batch melebihi batas",
+				"success": false,
+				"max":     models.MaxCameraBatchItems,
+			})
+		}
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Batch tidak berisi data kamera",
+			"success": false,
+		})
+	}
+
+	log.Printf("Batch data kamera diterima: %d item, %d bytes", len(items), len(body))
+	if code, err := verifyCameraRequestSignature(c, c.Body(), items); err != nil {
+		log.Printf("Signature batch data kamera ditolak: %v", err)
+		return c.Status(code).JSON(fiber.Map{
+			"error":   err.Error(),
+			"success": false,
+		})
+	}
+
+	source := ingestSourceFromRequest(c)
+	if queue := services.DefaultIngestionQueue(); queue != nil {
+		return enqueueCameraPayloads(c, queue, items, source)
+	}
+
+	results := models.ProcessCameraBatch(items, source)
+
+	summary := map[models.IngestStatus]int{
+		models.IngestStatusAccepted:  0,
+		models.IngestStatusDuplicate: 0,
+		models.IngestStatusRejected:  0,
+	}
+	for _, r := range results {
+		summary[r.Status]++
+	}
+
+	return c.JSON(fiber.Map{
+		"success": summary[models.IngestStatusRejected] < len(results),
+		"message": "Batch data kamera selesai diproses",
+		"total":   len(results),
+		"summary": summary,
+		"results": results,
+	})
+}
+
+// Mengambil status dan hasil job ingest berdasarkan tracking ID
+func GetCameraIngestStatus(c *fiber.Ctx) error {
+	queue := services.DefaultIngestionQueue()
+	if queue == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "Ingest asinkron tidak aktif",
+			"success": false,
+		})
+	}
+
+	job, ok := queue.GetJob(c.Params("tracking_id"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "Tracking ID tidak ditemukan atau sudah kedaluwarsa",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "data traffic berhasil diterima dan disimpan",
-		"data": fiber.Map{
-			"id":              trafficData.ID,
-			"lokasi_id":       trafficData.LokasiID,
-			"nama_lokasi":     trafficData.NamaLokasi,
-			"total_kendaraan": trafficData.TotalKendaraan,
-			"timestamp":       trafficData.Timestamp,
-			"mkji_analysis":   trafficData.MKJIAnalysis,
-			"pkji_analysis":   trafficData.PKJIAnalysis,
-		},
+		"data":    job,
+	})
+}
+
+// Mengambil kedalaman dan statistik antrian ingest
+func GetCameraIngestQueue(c *fiber.Ctx) error {
+	queue := services.DefaultIngestionQueue()
+	if queue == nil {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"mode":    "sync",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"mode":    "async",
+		"data":    queue.Stats(),
 	})
 }
 
@@ -188,6 +391,13 @@ func GetCameraStatus(c *fiber.Ctx) error {
 			"success": false,
 		})
 	}
+	health, err := models.GetCameraHealth(camera)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Gagal mendapatkan status kesehatan kamera",
+			"success": false,
+		})
+	}
 	return c.JSON(fiber.Map{
 		"success": true,
 		"data": fiber.Map{
@@ -197,6 +407,7 @@ func GetCameraStatus(c *fiber.Ctx) error {
 				"lokasi_penempatan": camera.LokasiPenempatan,
 				"zona_arah":         camera.ZonaArah,
 			},
+			"health": health,
 			"lokasi": fiber.Map{
 				"id":          location.ID,
 				"nama_lokasi": location.Nama_lokasi,
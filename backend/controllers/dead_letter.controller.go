@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Mengambil daftar dead-letter, bisa difilter berdasarkan status, stage, api_key atau camera_id
+func GetDeadLetters(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if stage := c.Query("stage"); stage != "" {
+		filter["stage"] = stage
+	}
+	if apiKey := c.Query("api_key"); apiKey != "" {
+		filter["api_key"] = apiKey
+	}
+	if cameraID := c.Query("camera_id"); cameraID != "" {
+		filter["camera_id"] = cameraID
+	}
+
+	limit, err := strconv.ParseInt(c.Query("limit", "100"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	entries, err := models.GetDeadLetters(filter, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "gagal mengambil dead-letter",
+			"success": false,
+		})
+	}
+	if entries == nil {
+		entries = []models.DeadLetter{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    entries,
+		"count":   len(entries),
+	})
+}
+
+// Mengambil detail dead-letter termasuk body payload asli
+func GetDeadLetterByID(c *fiber.Ctx) error {
+	entry, err := models.GetDeadLetterByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "dead-letter tidak ditemukan",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    entry,
+	})
+}
+
+// Mengubah body payload dead-letter sebelum di-replay
+func UpdateDeadLetter(c *fiber.Ctx) error {
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "request tidak valid",
+			"success": false,
+		})
+	}
+	if req.Body == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "body tidak boleh kosong",
+			"success": false,
+		})
+	}
+
+	if err := models.UpdateDeadLetterBody(c.Params("id"), req.Body); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "dead-letter tidak ditemukan",
+			"success": false,
+		})
+	}
+
+	entry, _ := models.GetDeadLetterByID(c.Params("id"))
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "dead-letter berhasil diupdate",
+		"data":    entry,
+	})
+}
+
+// deadLetterReplayErrorStatus memetakan error replay ke status HTTP
+func deadLetterReplayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return 404
+	case errors.Is(err, models.ErrDeadLetterBukanPending):
+		return 409
+	default:
+		return 500
+	}
+}
+
+func deadLetterReplayErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return "dead-letter tidak ditemukan"
+	case errors.Is(err, models.ErrDeadLetterBukanPending):
+		return err.Error()
+	default:
+		return "gagal memproses ulang dead-letter"
+	}
+}
+
+// Memproses ulang satu dead-letter lewat pipeline normal
+func ReplayDeadLetter(c *fiber.Ctx) error {
+	result, err := models.ReplayDeadLetter(c.Params("id"))
+	if err != nil {
+		return c.Status(deadLetterReplayErrorStatus(err)).JSON(fiber.Map{
+			"error":   deadLetterReplayErrorMessage(err),
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": result.Status != models.IngestStatusRejected,
+		"data":    result,
+	})
+}
+
+// Memproses ulang dead-letter pending secara massal, misalnya setelah kamera yang hilang didaftarkan
+func ReplayDeadLetters(c *fiber.Ctx) error {
+	var req struct {
+		IDs      []string `json:"ids"`
+		Stage    string   `json:"stage"`
+		APIKey   string   `json:"api_key"`
+		CameraID string   `json:"camera_id"`
+		Limit    int64    `json:"limit"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "request tidak valid",
+			"success": false,
+		})
+	}
+
+	ids := req.IDs
+	if len(ids) == 0 {
+		filter := bson.M{}
+		if req.Stage != "" {
+			filter["stage"] = req.Stage
+		}
+		if req.APIKey != "" {
+			filter["api_key"] = req.APIKey
+		}
+		if req.CameraID != "" {
+			filter["camera_id"] = req.CameraID
+		}
+		if req.Limit <= 0 {
+			req.Limit = 500
+		}
+
+		var err error
+		ids, err = models.GetPendingDeadLetterIDs(filter, req.Limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error":   "gagal mengambil dead-letter",
+				"success": false,
+			})
+		}
+	}
+
+	results := make([]*models.DeadLetterReplayResult, 0, len(ids))
+	replayed := 0
+	for _, id := range ids {
+		result, err := models.ReplayDeadLetter(id)
+		if err != nil {
+			results = append(results, &models.DeadLetterReplayResult{
+				ID:     id,
+				Status: models.IngestStatusRejected,
+				Reason: deadLetterReplayErrorMessage(err),
+			})
+			continue
+		}
+		if result.Status != models.IngestStatusRejected {
+			replayed++
+		}
+		results = append(results, result)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"total":    len(results),
+		"replayed": replayed,
+		"failed":   len(results) - replayed,
+		"results":  results,
+	})
+}
+
+// Menandai dead-letter sebagai dibuang (tidak akan ikut replay massal)
+func DiscardDeadLetter(c *fiber.Ctx) error {
+	if err := models.DiscardDeadLetter(c.Params("id")); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error":   "dead-letter tidak ditemukan",
+			"success": false,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "dead-letter berhasil dibuang",
+	})
+}
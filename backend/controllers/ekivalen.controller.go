@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"backend/models"
+)
+
+// Body request tabel ekivalen; berlaku_mulai dalam format RFC3339 (waktu lokal data)
+type EkivalenTableRequest struct {
+	Metode       models.AnalysisMethod `json:"metode"`
+	Nama         string                `json:"nama"`
+	BerlakuMulai time.Time             `json:"berlaku_mulai"`
+	Mapping      []models.KelasMapping `json:"mapping"`
+	Ekivalen     []models.EkivalenRule `json:"ekivalen"`
+	Catatan      string                `json:"catatan"`
+}
+
+// Mengambil seluruh versi tabel ekivalen, bisa difilter berdasarkan metode
+func GetEkivalenTables(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if metode := c.Query("metode"); metode != "" {
+		filter["metode"] = metode
+	}
+
+	tables, err := models.GetEkivalenTables(filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil tabel ekivalen"})
+	}
+	if tables == nil {
+		tables = []models.EkivalenTable{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  tables,
+		"count": len(tables),
+	})
+}
+
+func GetEkivalenTableByID(c *fiber.Ctx) error {
+	table, err := models.GetEkivalenTableByID(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Tabel ekivalen tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil tabel ekivalen"})
+	}
+	return c.JSON(fiber.Map{"data": table})
+}
+
+// Mengambil tabel yang berlaku untuk metode pada waktu tertentu (default sekarang)
+func GetActiveEkivalenTable(c *fiber.Ctx) error {
+	metode := models.AnalysisMethod(c.Query("metode", string(models.AnalysisMethodMKJI1997)))
+
+	waktu := time.Now().Add(7 * time.Hour)
+	if waktuStr := c.Query("waktu"); waktuStr != "" {
+		parsed, err := time.Parse(time.RFC3339, waktuStr)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "format waktu tidak valid (gunakan RFC3339)"})
+		}
+		waktu = parsed
+	}
+
+	table, err := models.GetActiveEkivalenTable(metode, waktu)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  table,
+		"waktu": waktu,
+	})
+}
+
+// Membuat versi baru tabel ekivalen (superadmin)
+func CreateEkivalenTable(c *fiber.Ctx) error {
+	var req EkivalenTableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request tidak valid"})
+	}
+
+	table := models.EkivalenTable{
+		Metode:       req.Metode,
+		Nama:         req.Nama,
+		BerlakuMulai: req.BerlakuMulai,
+		Mapping:      req.Mapping,
+		Ekivalen:     req.Ekivalen,
+		Catatan:      req.Catatan,
+	}
+	if userID, ok := c.Locals("user_id").(string); ok {
+		table.DibuatOleh = userID
+	}
+
+	if err := models.CreateEkivalenTable(&table); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Gagal membuat tabel ekivalen: " + err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "Tabel ekivalen berhasil dibuat",
+		"data":    table,
+	})
+}
+
+// Mengubah tabel ekivalen yang belum dipakai analisis tersimpan (superadmin)
+func UpdateEkivalenTable(c *fiber.Ctx) error {
+	table, err := models.GetEkivalenTableByID(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Tabel ekivalen tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil tabel ekivalen"})
+	}
+
+	var req EkivalenTableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request tidak valid"})
+	}
+	if req.Metode != "" && req.Metode != table.Metode {
+		return c.Status(400).JSON(fiber.Map{"error": "Metode tabel ekivalen tidak dapat diubah"})
+	}
+
+	if req.Nama != "" {
+		table.Nama = req.Nama
+	}
+	if !req.BerlakuMulai.IsZero() {
+		table.BerlakuMulai = req.BerlakuMulai
+	}
+	if req.Mapping != nil {
+		table.Mapping = req.Mapping
+	}
+	if req.Ekivalen != nil {
+		table.Ekivalen = req.Ekivalen
+	}
+	if req.Catatan != "" {
+		table.Catatan = req.Catatan
+	}
+
+	if err := models.UpdateEkivalenTable(table); err != nil {
+		if errors.Is(err, models.ErrEkivalenTableDipakai) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": "Gagal mengubah tabel ekivalen: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Tabel ekivalen berhasil diubah",
+		"data":    table,
+	})
+}
+
+// Menghapus tabel ekivalen yang belum dipakai analisis tersimpan (superadmin)
+func DeleteEkivalenTable(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := models.GetEkivalenTableByID(id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Tabel ekivalen tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil tabel ekivalen"})
+	}
+
+	if err := models.DeleteEkivalenTable(id); err != nil {
+		if errors.Is(err, models.ErrEkivalenTableDipakai) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal menghapus tabel ekivalen"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Tabel ekivalen berhasil dihapus"})
+}
+
+// Membuat versi awal dari nilai bawaan untuk metode yang belum memiliki tabel (superadmin)
+func InitEkivalenTablesHandler(c *fiber.Ctx) error {
+	if err := models.InitEkivalenTables(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal menginisialisasi tabel ekivalen"})
+	}
+	return c.JSON(fiber.Map{"message": "Tabel ekivalen berhasil diinisialisasi"})
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/models"
+)
+
+// Prakiraan volume dan DS per interval untuk 1-3 jam ke depan (tidak disimpan).
+// metode default PKJI2023, jam default FORECAST_HORIZON_HOURS.
+func GetForecast(c *fiber.Ctx) error {
+	metode := models.AnalysisMethod(c.Query("metode", string(models.AnalysisMethodPKJI2023)))
+	if _, err := models.GetCapacityStandard(metode); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	excludeFlagged := c.QueryBool("exclude_flagged", models.DefaultAnalysisOptions().ExcludeFlagged)
+	forecast, err := models.BuatForecast(c.Params("lokasi_id"), metode, c.QueryInt("jam", 0), excludeFlagged)
+	if err != nil {
+		if errors.Is(err, models.ErrHorizonForecastTidakValid) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, models.ErrRiwayatForecastTidakCukup) {
+			return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+		}
+		return analysisError(c, "gagal menghitung prakiraan: ", err)
+	}
+
+	return c.JSON(fiber.Map{"data": forecast})
+}
+
+// Riwayat prakiraan tersimpan lokasi, terbaru lebih dulu (default 24)
+func GetForecastHistory(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 24)
+
+	forecasts, err := models.GetTrafficForecastsByLokasiID(c.Params("lokasi_id"), int64(limit))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil riwayat prakiraan: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"data": forecasts, "total": len(forecasts)})
+}
+
+// Akurasi prakiraan tersimpan yang sudah dibandingkan dengan data aktual (default 7 hari terakhir)
+func GetForecastAkurasi(c *fiber.Ctx) error {
+	startTime, endTime, err := parseAnalysisPeriod(c.Query("start_time"), c.Query("end_time"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if c.Query("start_time") == "" {
+		startTime = endTime.AddDate(0, 0, -7)
+	}
+
+	akurasi, err := models.HitungAkurasiForecast(c.Params("lokasi_id"), models.AnalysisMethod(c.Query("metode")), startTime, endTime)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung akurasi prakiraan: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"data": akurasi})
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"backend/models"
+)
+
+// Body request perhitungan LHRT tahunan
+type lhrtTahunanRequest struct {
+	LokasiID       string `json:"lokasi_id"`
+	Tahun          int    `json:"tahun"`
+	ExcludeFlagged *bool  `json:"exclude_flagged"`
+}
+
+// Estimasi LHRT real-time dari hitungan periode (default 7 hari terakhir), tidak disimpan
+func GetLHRT(c *fiber.Ctx) error {
+	location, err := models.GetLocationByID(c.Params("lokasi_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+	}
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+	if c.Query("start_time") == "" {
+		startTime = endTime.AddDate(0, 0, -7)
+	}
+
+	estimate, err := models.HitungLHRT(location, startTime, endTime, opts.ExcludeFlagged)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengestimasi LHRT: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       estimate,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+// Menghitung dan menyimpan LHRT tahunan lokasi (default tahun berjalan)
+func CreateLHRTTahunan(c *fiber.Ctx) error {
+	var req lhrtTahunanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "request tidak valid"})
+	}
+	if req.LokasiID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "lokasi_id diperlukan"})
+	}
+	if req.Tahun == 0 {
+		req.Tahun = time.Now().Add(7 * time.Hour).Year()
+	}
+
+	location, err := models.GetLocationByID(req.LokasiID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+	}
+
+	excludeFlagged := models.DefaultAnalysisOptions().ExcludeFlagged
+	if req.ExcludeFlagged != nil {
+		excludeFlagged = *req.ExcludeFlagged
+	}
+
+	estimate, err := models.HitungLHRTTahunan(location, req.Tahun, excludeFlagged)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung LHRT tahunan: " + err.Error()})
+	}
+	if err := models.SaveLHRTTahunan(estimate); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menyimpan LHRT tahunan"})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "LHRT tahunan berhasil dihitung",
+		"data":    estimate,
+	})
+}
+
+// Riwayat LHRT tahunan lokasi
+func GetLHRTTahunanLokasi(c *fiber.Ctx) error {
+	estimates, err := models.GetLHRTTahunanByLokasiID(c.Params("lokasi_id"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil LHRT tahunan"})
+	}
+	if estimates == nil {
+		estimates = []models.LHRTEstimate{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  estimates,
+		"count": len(estimates),
+	})
+}
+
+// Detail LHRT tahunan lokasi beserta rincian harian
+func GetLHRTTahunanDetail(c *fiber.Ctx) error {
+	tahun, err := strconv.Atoi(c.Params("tahun"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "tahun tidak valid"})
+	}
+
+	estimate, err := models.GetLHRTTahunan(c.Params("lokasi_id"), tahun)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "LHRT tahunan tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil LHRT tahunan"})
+	}
+	return c.JSON(fiber.Map{"data": estimate})
+}
+
+// LHRT seluruh lokasi pada satu tahun (default tahun berjalan)
+func GetLHRTTahunanSemua(c *fiber.Ctx) error {
+	tahun := c.QueryInt("tahun", time.Now().Add(7*time.Hour).Year())
+
+	estimates, err := models.GetLHRTTahunanByTahun(tahun)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil LHRT tahunan"})
+	}
+	if estimates == nil {
+		estimates = []models.LHRTEstimate{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  estimates,
+		"count": len(estimates),
+		"tahun": tahun,
+	})
+}
+
+// Faktor hari dan bulan terbaru setiap kelompok tipe lokasi
+func GetFaktorLHRT(c *fiber.Ctx) error {
+	faktor, err := models.GetLatestFaktorLHRT()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil faktor LHRT"})
+	}
+	if faktor == nil {
+		faktor = []models.FaktorLHRT{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  faktor,
+		"count": len(faktor),
+	})
+}
+
+func GetFaktorLHRTByID(c *fiber.Ctx) error {
+	faktor, err := models.GetFaktorLHRTByID(c.Params("id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Faktor LHRT tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil faktor LHRT"})
+	}
+	return c.JSON(fiber.Map{"data": faktor})
+}
+
+// Menghitung ulang faktor dari pos permanen untuk periode (default 365 hari terakhir) (superadmin)
+func HitungFaktorLHRT(c *fiber.Ctx) error {
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+	if c.Query("end_time") == "" {
+		// Traffic data disimpan dalam waktu lokal (UTC+7), hari berjalan tidak dihitung
+		now := time.Now().Add(7 * time.Hour)
+		endTime = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if c.Query("start_time") == "" {
+		startTime = endTime.AddDate(-1, 0, 0)
+	}
+
+	faktor, err := models.HitungFaktorLHRT(startTime, endTime, opts.ExcludeFlagged)
+	if err != nil {
+		if errors.Is(err, models.ErrTidakAdaPosPermanen) {
+			return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung faktor LHRT: " + err.Error()})
+	}
+	for i := range faktor {
+		if err := models.SaveFaktorLHRT(&faktor[i]); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Gagal menyimpan faktor LHRT"})
+		}
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "Faktor LHRT berhasil dihitung",
+		"data":    faktor,
+		"count":   len(faktor),
+	})
+}
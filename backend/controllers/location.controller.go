@@ -18,6 +18,7 @@ type LocationRequest struct {
 	Nama_lokasi    string  `json:"nama_lokasi"`
 	Alamat_lokasi  string  `json:"alamat_lokasi"`
 	Tipe_lokasi    string  `json:"tipe_lokasi"`
+	Jenis_lokasi   string  `json:"jenis_lokasi,omitempty"` // ruas (default), simpang_apill, simpang_tak_bersinyal
 	Tipe_arah      string  `json:"tipe_arah"`
 	Lebar_jalur    int     `json:"lebar_jalur"`
 	Persentase     string  `json:"persentase"`
@@ -33,9 +34,13 @@ type LocationRequest struct {
 	Keterangan     string  `json:"keterangan"`
 	SourceType     string  `json:"source_type,omitempty"` // "link" atau "image"
 	SourceData     string  `json:"source_data,omitempty"` // URL untuk link, string base64 untuk image
+
+	Simpang *models.SimpangConfig `json:"simpang,omitempty"` // wajib untuk jenis_lokasi simpang
 }
 
-// validateLocationRequest memvalidasi request lokasi dan mengembalikan pesan error jika tidak valid
+// validateLocationRequest memvalidasi request lokasi dan mengembalikan pesan error jika tidak valid.
+// Lokasi simpang tidak memakai geometri ruas (tipe_arah, lebar_jalur, persentase, tipe_hambatan)
+// dan memvalidasi konfigurasi simpang sebagai gantinya.
 func validateLocationRequest(req LocationRequest) (string, bool) {
 	if req.Nama_lokasi == "" {
 		return "nama_lokasi diperlukan", false
@@ -49,6 +54,27 @@ func validateLocationRequest(req LocationRequest) (string, bool) {
 		return "tipe_lokasi tidak valid.", false
 	}
 
+	if !models.IsValidJenisLokasi(req.Jenis_lokasi) {
+		return "jenis_lokasi tidak valid.", false
+	}
+
+	if models.IsSimpangJenisLokasi(req.Jenis_lokasi) {
+		if req.Simpang == nil {
+			return "simpang diperlukan untuk lokasi simpang", false
+		}
+		req.Simpang.Normalize()
+		if err := req.Simpang.Validate(req.Jenis_lokasi); err != nil {
+			return err.Error(), false
+		}
+		if !models.IsValidKelasHambatan(req.Kelas_hambatan) {
+			return "kelas_hambatan tidak valid.", false
+		}
+		if !models.IsValidInterval(req.Interval) {
+			return "interval tidak valid.", false
+		}
+		return "", true
+	}
+
 	if !models.IsValidTipeArah(req.Tipe_arah) {
 		return "tipe_arah tidak valid.", false
 	}
@@ -116,6 +142,7 @@ func CreateLocation(c *fiber.Ctx) error {
 		Nama_lokasi:      req.Nama_lokasi,
 		Alamat_lokasi:    req.Alamat_lokasi,
 		Tipe_lokasi:      req.Tipe_lokasi,
+		Jenis_lokasi:     req.Jenis_lokasi,
 		Tipe_arah:        req.Tipe_arah,
 		Lebar_jalur:      req.Lebar_jalur,
 		Persentase:       req.Persentase,
@@ -129,6 +156,7 @@ func CreateLocation(c *fiber.Ctx) error {
 		Publik:           req.Publik,
 		Hide_lokasi:      req.Hide_lokasi,
 		Keterangan:       req.Keterangan,
+		Simpang:          simpangConfig(req),
 		Timestamp:        time.Now().Add(7 * time.Hour),
 		LastDataReceived: time.Now().Add(7 * time.Hour),
 	}
@@ -305,6 +333,8 @@ func UpdateLocation(c *fiber.Ctx) error {
 			"alamat_lokasi":  req.Alamat_lokasi,
 			"balai":          req.Balai,
 			"tipe_lokasi":    req.Tipe_lokasi,
+			"jenis_lokasi":   req.Jenis_lokasi,
+			"simpang":        simpangConfig(req),
 			"tipe_arah":      req.Tipe_arah,
 			"lebar_jalur":    req.Lebar_jalur,
 			"persentase":     req.Persentase,
@@ -425,5 +455,22 @@ func GetLocationOptions(c *fiber.Ctx) error {
 		"tipe_hambatan":  models.TipeHambatanOptions,
 		"kelas_hambatan": models.KelasHambatanOptions,
 		"interval":       models.IntervalOptions,
+		"jenis_lokasi":   models.JenisLokasiOptions,
+		"simpang": fiber.Map{
+			"tipe_simpang": models.TipeSimpangOptions,
+			"lingkungan":   models.LingkunganSimpangOptions,
+			"median_mayor": models.MedianMayorOptions,
+			"gerakan":      models.GerakanOptions,
+		},
 	})
 }
+
+// simpangConfig mengembalikan konfigurasi simpang yang sudah dinormalisasi, nil untuk lokasi ruas
+func simpangConfig(req LocationRequest) *models.SimpangConfig {
+	if !models.IsSimpangJenisLokasi(req.Jenis_lokasi) || req.Simpang == nil {
+		return nil
+	}
+	cfg := *req.Simpang
+	cfg.Normalize()
+	return &cfg
+}
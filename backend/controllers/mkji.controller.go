@@ -1,6 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -8,84 +12,154 @@ import (
 	"backend/models"
 )
 
-func GetMKJIAnalysis(c *fiber.Ctx) error {
-	lokasiID := c.Params("lokasi_id")
+var (
+	errInvalidGapFill         = errors.New("gap_fill tidak valid")
+	errInvalidHambatanSamping = errors.New("hambatan_samping tidak valid")
+)
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
+// Parameter analisis dari body POST. Kosong berarti mengikuti konfigurasi
+// QUALITY_EXCLUDE_FLAGGED, GAP_FILL_METHOD, dan SIDE_FRICTION_SOURCE.
+type analysisRequest struct {
+	LokasiID        string `json:"lokasi_id"`
+	StartTime       string `json:"start_time"`
+	EndTime         string `json:"end_time"`
+	ExcludeFlagged  *bool  `json:"exclude_flagged"`
+	GapFill         string `json:"gap_fill"`
+	HambatanSamping string `json:"hambatan_samping"`
+}
 
-	var startTime, endTime time.Time
+// parseAnalysisPeriod membaca start_time/end_time RFC3339, default 24 jam terakhir
+func parseAnalysisPeriod(startTimeStr, endTimeStr string) (time.Time, time.Time, error) {
+	startTime := time.Now().Add(-24 * time.Hour)
+	endTime := time.Now()
 	var err error
 
 	if startTimeStr != "" {
 		startTime, err = time.Parse(time.RFC3339, startTimeStr)
 		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "format start_time tidak valid (gunakan RFC3339)"})
+			return startTime, endTime, errors.New("format start_time tidak valid (gunakan RFC3339)")
 		}
-	} else {
-		startTime = time.Now().Add(-24 * time.Hour)
 	}
 
 	if endTimeStr != "" {
 		endTime, err = time.Parse(time.RFC3339, endTimeStr)
 		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "format end_time tidak valid (gunakan RFC3339)"})
+			return startTime, endTime, errors.New("format end_time tidak valid (gunakan RFC3339)")
 		}
-	} else {
-		endTime = time.Now()
 	}
 
-	analysis, err := models.CalculateMKJIRealtime(lokasiID, startTime, endTime)
+	return startTime, endTime, nil
+}
+
+// parseAnalysisQuery membaca periode dan opsi analisis dari query string.
+// exclude_flagged=true mengabaikan interval yang ditandai kualitas data rendah,
+// gap_fill (none/linear/last_week) mengisi interval yang hilang sebagai estimasi,
+// hambatan_samping=observasi memakai kelas hambatan samping hasil estimasi data
+func parseAnalysisQuery(c *fiber.Ctx) (time.Time, time.Time, models.AnalysisOptions, error) {
+	opts := models.DefaultAnalysisOptions()
+	startTime, endTime, err := parseAnalysisPeriod(c.Query("start_time"), c.Query("end_time"))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung analisis MKJI: " + err.Error()})
+		return startTime, endTime, opts, err
 	}
 
-	return c.JSON(fiber.Map{
-		"data":       analysis,
-		"start_time": startTime,
-		"end_time":   endTime,
-	})
+	opts.ExcludeFlagged = c.QueryBool("exclude_flagged", opts.ExcludeFlagged)
+	opts.GapFill = c.Query("gap_fill", opts.GapFill)
+	if !models.IsValidGapFillMethod(opts.GapFill) {
+		return startTime, endTime, opts, errInvalidGapFill
+	}
+	opts.HambatanSamping = c.Query("hambatan_samping", opts.HambatanSamping)
+	if !models.IsValidHambatanSamping(opts.HambatanSamping) {
+		return startTime, endTime, opts, errInvalidHambatanSamping
+	}
+
+	return startTime, endTime, opts, nil
 }
 
-func CreateMKJIAnalysis(c *fiber.Ctx) error {
-	var req struct {
-		LokasiID  string `json:"lokasi_id"`
-		StartTime string `json:"start_time"`
-		EndTime   string `json:"end_time"`
-	}
+// parseAnalysisRequest membaca body POST analisis beserta periode dan opsinya
+func parseAnalysisRequest(c *fiber.Ctx) (analysisRequest, time.Time, time.Time, models.AnalysisOptions, error) {
+	var req analysisRequest
+	opts := models.DefaultAnalysisOptions()
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "request tidak valid"})
+		return req, time.Time{}, time.Time{}, opts, errors.New("request tidak valid")
 	}
 
 	if req.LokasiID == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "lokasi_id diperlukan"})
+		return req, time.Time{}, time.Time{}, opts, errors.New("lokasi_id diperlukan")
 	}
 
-	var startTime, endTime time.Time
-	var err error
+	startTime, endTime, err := parseAnalysisPeriod(req.StartTime, req.EndTime)
+	if err != nil {
+		return req, startTime, endTime, opts, err
+	}
 
-	if req.StartTime != "" {
-		startTime, err = time.Parse(time.RFC3339, req.StartTime)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "format start_time tidak valid (gunakan RFC3339)"})
+	if req.ExcludeFlagged != nil {
+		opts.ExcludeFlagged = *req.ExcludeFlagged
+	}
+	if req.GapFill != "" {
+		if !models.IsValidGapFillMethod(req.GapFill) {
+			return req, startTime, endTime, opts, errInvalidGapFill
 		}
-	} else {
-		startTime = time.Now().Add(-24 * time.Hour)
+		opts.GapFill = req.GapFill
 	}
-
-	if req.EndTime != "" {
-		endTime, err = time.Parse(time.RFC3339, req.EndTime)
-		if err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "format end_time tidak valid (gunakan RFC3339)"})
+	if req.HambatanSamping != "" {
+		if !models.IsValidHambatanSamping(req.HambatanSamping) {
+			return req, startTime, endTime, opts, errInvalidHambatanSamping
 		}
-	} else {
-		endTime = time.Now()
+		opts.HambatanSamping = req.HambatanSamping
+	}
+
+	return req, startTime, endTime, opts, nil
+}
+
+// analysisParamError mengirim response 400 untuk parameter analisis yang tidak valid
+func analysisParamError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errInvalidGapFill) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "valid_options": models.GapFillOptions})
+	}
+	if errors.Is(err, errInvalidHambatanSamping) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error(), "valid_options": models.HambatanSampingOptions})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
+
+// analysisError mengirim 400 jika lokasi adalah simpang (analisis ruas tidak berlaku), selain itu 500
+func analysisError(c *fiber.Ctx, prefix string, err error) error {
+	if errors.Is(err, models.ErrLokasiSimpang) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": prefix + err.Error()})
+}
+
+func GetMKJIAnalysis(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CalculateMKJIRealtime(lokasiID, startTime, endTime, opts)
+	if err != nil {
+		return analysisError(c, "gagal menghitung analisis MKJI: ", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       analysis,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+func CreateMKJIAnalysis(c *fiber.Ctx) error {
+	req, startTime, endTime, opts, err := parseAnalysisRequest(c)
+	if err != nil {
+		return analysisParamError(c, err)
 	}
 
-	analysis, err := models.CreateMKJIAnalysis(req.LokasiID, startTime, endTime)
+	analysis, err := models.CreateMKJIAnalysis(req.LokasiID, startTime, endTime, opts)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "gagal membuat analisis MKJI: " + err.Error()})
+		return analysisError(c, "gagal membuat analisis MKJI: ", err)
 	}
 
 	return c.Status(201).JSON(fiber.Map{
@@ -97,7 +171,13 @@ func CreateMKJIAnalysis(c *fiber.Ctx) error {
 func GetMKJIAnalysisHistory(c *fiber.Ctx) error {
 	lokasiID := c.Params("lokasi_id")
 
-	analysisList, err := models.GetMKJIAnalysisByLokasiID(lokasiID)
+	// limit=0 berarti semua riwayat
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit tidak boleh negatif"})
+	}
+
+	analysisList, err := models.GetMKJIAnalysisByLokasiID(lokasiID, int64(limit))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil riwayat analisis MKJI"})
 	}
@@ -135,16 +215,29 @@ func GetLatestMKJIAnalysis(c *fiber.Ctx) error {
 }
 
 func GetKapasitasJalan(c *fiber.Ctx) error {
+	// method memilih standar kapasitas terdaftar (default MKJI1997)
+	return kapasitasJalanResponse(c, models.AnalysisMethod(c.Query("method", string(models.AnalysisMethodMKJI1997))))
+}
+
+// kapasitasJalanResponse mengirim rincian kapasitas lokasi menurut standar kapasitas terdaftar
+func kapasitasJalanResponse(c *fiber.Ctx, method models.AnalysisMethod) error {
 	lokasiID := c.Params("lokasi_id")
 
 	location, err := models.GetLocationByID(lokasiID)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "lokasi tidak ditemukan"})
 	}
+	if location.IsSimpang() {
+		return c.Status(400).JSON(fiber.Map{"error": models.ErrLokasiSimpang.Error()})
+	}
 
-	kapasitas, co, fcw, fcsp, fcsf, fccs := models.HitungKapasitas(*location)
+	standard, err := models.GetCapacityStandard(method)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	result := standard.Kapasitas(*location)
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"lokasi_id":       lokasiID,
 		"nama_lokasi":     location.Nama_lokasi,
 		"tipe_lokasi":     location.Tipe_lokasi,
@@ -154,62 +247,104 @@ func GetKapasitasJalan(c *fiber.Ctx) error {
 		"tipe_hambatan":   location.Tipe_hambatan,
 		"kelas_hambatan":  location.Kelas_hambatan,
 		"ukuran_kota":     location.Ukuran_kota,
-		"kapasitas_dasar": co,
-		"fcw":             fcw,
-		"fcsp":            fcsp,
-		"fcsf":            fcsf,
-		"fccs":            fccs,
-		"kapasitas":       kapasitas,
-		"satuan":          "smp/jam",
-	})
+		"metode":          standard.Method(),
+		"kapasitas_dasar": result.KapasitasDasar,
+		"faktor":          result.Faktor,
+		"kapasitas":       result.Kapasitas,
+		"satuan":          standard.UnitEkivalen() + "/jam",
+	}
+	// Field faktor lama (fcw, fcsp, ...) tetap disertakan agar klien lama tidak berubah
+	for _, f := range result.Faktor {
+		response[strings.ToLower(f.Kode)] = f.Nilai
+	}
+
+	return c.JSON(response)
+}
+
+// Nama kelas 12 kelas dan kategori MKJI untuk tampilan; kategori dan nilai smp diambil dari tabel ekivalen aktif
+var namaKelas12 = map[int]string{
+	1:  "Sepeda Motor",
+	2:  "Sedan / Jeep / Minibus",
+	3:  "Angkot / Microbus",
+	4:  "Pick-up / Box Kecil",
+	5:  "Bus Kecil (Metromini/Elf)",
+	6:  "Bus Besar",
+	7:  "Truk 2 Sumbu (Colt Diesel)",
+	8:  "Truk 3 Sumbu",
+	9:  "Truk Gandeng",
+	10: "Truk Tempelan (Trailer)",
+	11: "Kendaraan Tidak Bermotor",
+	12: "Kendaraan Lainnya",
+}
+
+var keteranganKategoriMKJI = map[string][2]string{
+	string(models.KategoriMC): {"Motorcycle", "Sepeda Motor"},
+	string(models.KategoriLV): {"Light Vehicle", "Kendaraan Ringan (Sedan, Minibus, Pick-up, dll)"},
+	string(models.KategoriHV): {"Heavy Vehicle", "Kendaraan Berat (Bus Besar, Truk, dll)"},
+	string(models.KategoriUM): {"Unmotorized", "Kendaraan Tidak Bermotor (Becak, Sepeda)"},
 }
 
 func GetMKJIMapping(c *fiber.Ctx) error {
+	standard := models.MKJI1997Standard{}
+	table, err := models.GetActiveEkivalenTable(standard.Method(), time.Now().Add(7*time.Hour))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil tabel ekivalen aktif"})
+	}
+
+	// Nilai smp umum kategori: aturan tanpa batasan tipe lokasi, tipe arah, dan rentang arus
+	smp := make(map[string]float64)
+	for _, kategori := range standard.Categories() {
+		smp[kategori] = standard.Ekivalen(kategori, "")
+	}
+	for _, rule := range table.Ekivalen {
+		if rule.TipeLokasi == "" && rule.TipeArah == "" && rule.ArusMin == 0 && rule.ArusMax == 0 {
+			smp[rule.Kategori] = rule.Nilai
+		}
+	}
+
+	kategori := fiber.Map{}
+	for _, k := range standard.Categories() {
+		item := fiber.Map{
+			"nama":      keteranganKategoriMKJI[k][0],
+			"deskripsi": keteranganKategoriMKJI[k][1],
+			"smp":       smp[k],
+		}
+		if !standard.IsMotorized(k) {
+			item["catatan"] = "Dianggap sebagai hambatan samping, tidak dihitung dalam volume"
+		}
+		kategori[k] = item
+	}
+
+	mapping := fiber.Map{}
+	for kelas := 1; kelas <= 12; kelas++ {
+		mapping[strconv.Itoa(kelas)] = fiber.Map{"nama": namaKelas12[kelas], "kategori": standard.Kategori("12_kelas", kelas)}
+	}
+	for _, m := range table.Mapping {
+		if m.TipeLokasi == "12_kelas" {
+			nama := namaKelas12[m.Kelas]
+			if nama == "" {
+				nama = fmt.Sprintf("Kelas %d", m.Kelas)
+			}
+			mapping[strconv.Itoa(m.Kelas)] = fiber.Map{"nama": nama, "kategori": m.Kategori}
+		}
+	}
+
+	// Tingkat pelayanan dari batas DS standar; tingkat terakhir tanpa batas atas
+	tingkat := fiber.Map{}
+	batas := standard.BatasTingkatPelayanan()
+	for i := 0; i <= len(batas); i++ {
+		dsMax := 999.0
+		if i < len(batas) {
+			dsMax = batas[i]
+		}
+		los, deskripsi := standard.TingkatPelayanan(dsMax)
+		tingkat[los] = fiber.Map{"ds_max": dsMax, "deskripsi": deskripsi}
+	}
+
 	return c.JSON(fiber.Map{
-		"kategori": fiber.Map{
-			"MC": fiber.Map{
-				"nama":      "Motorcycle",
-				"deskripsi": "Sepeda Motor",
-				"smp":       models.SMPValues[models.KategoriMC],
-			},
-			"LV": fiber.Map{
-				"nama":      "Light Vehicle",
-				"deskripsi": "Kendaraan Ringan (Sedan, Minibus, Pick-up, dll)",
-				"smp":       models.SMPValues[models.KategoriLV],
-			},
-			"HV": fiber.Map{
-				"nama":      "Heavy Vehicle",
-				"deskripsi": "Kendaraan Berat (Bus Besar, Truk, dll)",
-				"smp":       models.SMPValues[models.KategoriHV],
-			},
-			"UM": fiber.Map{
-				"nama":      "Unmotorized",
-				"deskripsi": "Kendaraan Tidak Bermotor (Becak, Sepeda)",
-				"smp":       models.SMPValues[models.KategoriUM],
-				"catatan":   "Dianggap sebagai hambatan samping, tidak dihitung dalam volume",
-			},
-		},
-		"mapping_12_kelas": fiber.Map{
-			"1":  fiber.Map{"nama": "Sepeda Motor", "kategori": "MC"},
-			"2":  fiber.Map{"nama": "Sedan / Jeep / Minibus", "kategori": "LV"},
-			"3":  fiber.Map{"nama": "Angkot / Microbus", "kategori": "LV"},
-			"4":  fiber.Map{"nama": "Pick-up / Box Kecil", "kategori": "LV"},
-			"5":  fiber.Map{"nama": "Bus Kecil (Metromini/Elf)", "kategori": "LV"},
-			"6":  fiber.Map{"nama": "Bus Besar", "kategori": "HV"},
-			"7":  fiber.Map{"nama": "Truk 2 Sumbu (Colt Diesel)", "kategori": "HV"},
-			"8":  fiber.Map{"nama": "Truk 3 Sumbu", "kategori": "HV"},
-			"9":  fiber.Map{"nama": "Truk Gandeng", "kategori": "HV"},
-			"10": fiber.Map{"nama": "Truk Tempelan (Trailer)", "kategori": "HV"},
-			"11": fiber.Map{"nama": "Kendaraan Tidak Bermotor", "kategori": "UM"},
-			"12": fiber.Map{"nama": "Kendaraan Lainnya", "kategori": "LV"},
-		},
-		"tingkat_pelayanan": fiber.Map{
-			"A": fiber.Map{"ds_max": 0.35, "deskripsi": "Arus bebas, kecepatan tinggi"},
-			"B": fiber.Map{"ds_max": 0.55, "deskripsi": "Arus stabil, kecepatan sedikit terbatas"},
-			"C": fiber.Map{"ds_max": 0.75, "deskripsi": "Arus stabil, kebebasan bergerak terbatas"},
-			"D": fiber.Map{"ds_max": 0.85, "deskripsi": "Arus mendekati tidak stabil"},
-			"E": fiber.Map{"ds_max": 1.00, "deskripsi": "Arus tidak stabil, kecepatan rendah"},
-			"F": fiber.Map{"ds_max": 999, "deskripsi": "Arus terhenti, macet total"},
-		},
+		"tabel_ekivalen":    table.Ref(),
+		"kategori":          kategori,
+		"mapping_12_kelas":  mapping,
+		"tingkat_pelayanan": tingkat,
 	})
 }
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend/models"
+)
+
+func GetPKJIAnalysis(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CalculatePKJIRealtime(lokasiID, startTime, endTime, opts)
+	if err != nil {
+		return analysisError(c, "gagal menghitung analisis PKJI: ", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       analysis,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+func CreatePKJIAnalysis(c *fiber.Ctx) error {
+	req, startTime, endTime, opts, err := parseAnalysisRequest(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CreatePKJIAnalysis(req.LokasiID, startTime, endTime, opts)
+	if err != nil {
+		return analysisError(c, "gagal membuat analisis PKJI: ", err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "analisis PKJI berhasil dibuat",
+		"data":    analysis,
+	})
+}
+
+func GetPKJIAnalysisHistory(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	// limit=0 berarti semua riwayat
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit tidak boleh negatif"})
+	}
+
+	analysisList, err := models.GetPKJIAnalysisByLokasiID(lokasiID, int64(limit))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil riwayat analisis PKJI"})
+	}
+
+	if analysisList == nil {
+		analysisList = []models.PKJIAnalysis{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  analysisList,
+		"count": len(analysisList),
+	})
+}
+
+func GetPKJIAnalysisByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	analysis, err := models.GetPKJIAnalysisByID(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "analisis PKJI tidak ditemukan"})
+	}
+
+	return c.JSON(fiber.Map{"data": analysis})
+}
+
+func GetLatestPKJIAnalysis(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	analysis, err := models.GetLatestPKJIAnalysisByLokasiID(lokasiID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "analisis PKJI tidak ditemukan"})
+	}
+
+	return c.JSON(fiber.Map{"data": analysis})
+}
+
+// GetKecepatanPKJI mengembalikan kecepatan arus bebas (VB), kecepatan tempuh (VT), dan deviasinya
+// terhadap kecepatan terukur kamera untuk periode yang diminta
+func GetKecepatanPKJI(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CalculatePKJIRealtime(lokasiID, startTime, endTime, opts)
+	if err != nil {
+		return analysisError(c, "gagal menghitung kecepatan PKJI: ", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"lokasi_id":   lokasiID,
+		"nama_lokasi": analysis.NamaLokasi,
+		"data":        analysis.Kecepatan,
+		"start_time":  startTime,
+		"end_time":    endTime,
+	})
+}
+
+func GetKapasitasJalanPKJI(c *fiber.Ctx) error {
+	return kapasitasJalanResponse(c, models.AnalysisMethodPKJI2023)
+}
+
+// CompareAnalysis mengembalikan analisis MKJI 1997 dan PKJI 2023 berdampingan untuk lokasi dan periode yang sama
+func CompareAnalysis(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	comparison, err := models.CompareAnalyses(lokasiID, startTime, endTime, opts)
+	if err != nil {
+		return analysisError(c, "gagal membandingkan analisis MKJI dan PKJI: ", err)
+	}
+
+	return c.JSON(fiber.Map{"data": comparison})
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend/models"
+)
+
+// Proyeksi pertumbuhan lalu lintas dan tahun tercapainya setiap batas tingkat pelayanan.
+// Periode dasar default 7 hari terakhir; laju_pertumbuhan (%/tahun) opsional, tahun_akhir default
+// tahun dasar + PROJECTION_HORIZON_YEARS.
+func GetProyeksi(c *fiber.Ctx) error {
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+	if c.Query("start_time") == "" {
+		startTime = endTime.AddDate(0, 0, -7)
+	}
+
+	var laju *float64
+	if lajuStr := c.Query("laju_pertumbuhan"); lajuStr != "" {
+		value, err := strconv.ParseFloat(lajuStr, 64)
+		if err != nil || value <= -100 {
+			return c.Status(400).JSON(fiber.Map{"error": "laju_pertumbuhan tidak valid (persen per tahun, lebih dari -100)"})
+		}
+		laju = &value
+	}
+
+	tahunAkhir := c.QueryInt("tahun_akhir", 0)
+
+	projection, err := models.HitungProyeksi(c.Params("lokasi_id"), startTime, endTime, opts, laju, tahunAkhir)
+	if err != nil {
+		if errors.Is(err, models.ErrTahunAkhirTidakValid) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, models.ErrLajuPertumbuhanTidakTersedia) {
+			return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+		}
+		return analysisError(c, "gagal menghitung proyeksi: ", err)
+	}
+
+	return c.JSON(fiber.Map{"data": projection})
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Membuat dan menjalankan job proses ulang traffic_data dari raw data untuk satu lokasi dan rentang waktu
+func CreateReprocessJob(c *fiber.Ctx) error {
+	var req struct {
+		LokasiID   string `json:"lokasi_id"`
+		StartTime  string `json:"start_time"`
+		EndTime    string `json:"end_time"`
+		Mode       string `json:"mode"`
+		BatchSize  int    `json:"batch_size"`
+		Keterangan string `json:"keterangan"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "request tidak valid"})
+	}
+	if req.LokasiID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "lokasi_id diperlukan"})
+	}
+	if req.StartTime == "" || req.EndTime == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "start_time dan end_time diperlukan"})
+	}
+
+	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "format start_time tidak valid (gunakan RFC3339)"})
+	}
+	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "format end_time tidak valid (gunakan RFC3339)"})
+	}
+	if !endTime.After(startTime) {
+		return c.Status(400).JSON(fiber.Map{"error": "end_time harus setelah start_time"})
+	}
+
+	service := services.DefaultReprocessService()
+	if service == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "layanan proses ulang tidak berjalan"})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+	job, err := models.CreateReprocessJob(req.LokasiID, startTime, endTime, req.Mode, req.BatchSize, req.Keterangan, userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := service.Run(job.ID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menjalankan job: " + err.Error()})
+	}
+	job.Status = models.ReprocessStatusRunning
+
+	return c.Status(202).JSON(fiber.Map{
+		"message": "job proses ulang berhasil dibuat",
+		"data":    job,
+	})
+}
+
+// Mengambil daftar job proses ulang, bisa difilter berdasarkan lokasi_id dan status
+func GetReprocessJobs(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if lokasiID := c.Query("lokasi_id"); lokasiID != "" {
+		filter["lokasi_id"] = lokasiID
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	limit, err := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	jobs, err := models.GetReprocessJobs(filter, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil job proses ulang"})
+	}
+	if jobs == nil {
+		jobs = []models.ReprocessJob{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// Mengambil progress job proses ulang
+func GetReprocessJobByID(c *fiber.Ctx) error {
+	job, err := models.GetReprocessJobByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "job proses ulang tidak ditemukan"})
+	}
+
+	running := false
+	if service := services.DefaultReprocessService(); service != nil {
+		running = service.IsRunning(job.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       job,
+		"is_running": running,
+	})
+}
+
+// Menghentikan sementara job; dapat dilanjutkan dari cursor terakhir
+func PauseReprocessJob(c *fiber.Ctx) error {
+	return changeReprocessJobStatus(c, models.ReprocessStatusRunning, models.ReprocessStatusPaused)
+}
+
+// Membatalkan job; batch yang sudah diproses tetap tersimpan
+func CancelReprocessJob(c *fiber.Ctx) error {
+	return changeReprocessJobStatus(c, "", models.ReprocessStatusCancelled)
+}
+
+// Melanjutkan job yang di-pause atau gagal dari cursor terakhir
+func ResumeReprocessJob(c *fiber.Ctx) error {
+	job, err := models.GetReprocessJobByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "job proses ulang tidak ditemukan"})
+	}
+	if job.Status != models.ReprocessStatusPaused && job.Status != models.ReprocessStatusFailed && job.Status != models.ReprocessStatusPending {
+		return c.Status(400).JSON(fiber.Map{"error": "job dengan status " + job.Status + " tidak dapat dilanjutkan"})
+	}
+
+	service := services.DefaultReprocessService()
+	if service == nil {
+		return c.Status(503).JSON(fiber.Map{"error": "layanan proses ulang tidak berjalan"})
+	}
+	if err := service.Run(job.ID); err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	job, _ = models.GetReprocessJobByID(job.ID)
+	return c.JSON(fiber.Map{
+		"message": "job proses ulang dilanjutkan",
+		"data":    job,
+	})
+}
+
+func changeReprocessJobStatus(c *fiber.Ctx, requiredStatus string, newStatus string) error {
+	job, err := models.GetReprocessJobByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "job proses ulang tidak ditemukan"})
+	}
+	if job.Status == models.ReprocessStatusCompleted || job.Status == models.ReprocessStatusCancelled {
+		return c.Status(400).JSON(fiber.Map{"error": "job sudah " + job.Status})
+	}
+	if requiredStatus != "" && job.Status != requiredStatus {
+		return c.Status(400).JSON(fiber.Map{"error": "job tidak sedang " + requiredStatus})
+	}
+
+	if err := models.SetReprocessJobStatus(job.ID, newStatus); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengubah status job"})
+	}
+
+	job, _ = models.GetReprocessJobByID(job.ID)
+	return c.JSON(fiber.Map{
+		"message": "status job diubah menjadi " + newStatus,
+		"data":    job,
+	})
+}
+
+// Mengambil versi traffic_data yang sudah digantikan hasil proses ulang
+func GetTrafficDataVersion(c *fiber.Ctx) error {
+	version, err := models.GetTrafficDataVersionByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "versi traffic data tidak ditemukan"})
+	}
+
+	return c.JSON(fiber.Map{"data": version})
+}
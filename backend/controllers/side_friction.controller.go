@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"backend/models"
+)
+
+// Estimasi kelas hambatan samping real-time dari data periode (default 7 hari terakhir), tidak disimpan
+func GetHambatanSamping(c *fiber.Ctx) error {
+	location, err := models.GetLocationByID(c.Params("lokasi_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+	}
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+	if c.Query("start_time") == "" {
+		startTime = endTime.AddDate(0, 0, -7)
+	}
+
+	estimate, err := models.HitungHambatanSamping(location, startTime, endTime, opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengestimasi hambatan samping: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       estimate,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+// Estimasi terjadwal terbaru untuk lokasi
+func GetLatestHambatanSamping(c *fiber.Ctx) error {
+	estimate, err := models.GetLatestSideFrictionEstimate(c.Params("lokasi_id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Belum ada estimasi hambatan samping untuk lokasi ini"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil estimasi hambatan samping"})
+	}
+	return c.JSON(fiber.Map{"data": estimate})
+}
+
+// Lokasi yang estimasi terbarunya tidak sesuai dengan kelas hambatan samping konfigurasi
+func GetHambatanSampingTidakSesuai(c *fiber.Ctx) error {
+	estimates, err := models.GetSideFrictionMismatches()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil estimasi hambatan samping"})
+	}
+	if estimates == nil {
+		estimates = []models.SideFrictionEstimate{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":      estimates,
+		"count":     len(estimates),
+		"timestamp": time.Now().Add(7 * time.Hour),
+	})
+}
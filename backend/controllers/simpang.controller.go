@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"backend/models"
+)
+
+func GetSimpangAnalysis(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTime, endTime, opts, err := parseAnalysisQuery(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CalculateSimpangRealtime(lokasiID, startTime, endTime, opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung analisis simpang: " + err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       analysis,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+func CreateSimpangAnalysis(c *fiber.Ctx) error {
+	req, startTime, endTime, opts, err := parseAnalysisRequest(c)
+	if err != nil {
+		return analysisParamError(c, err)
+	}
+
+	analysis, err := models.CreateSimpangAnalysis(req.LokasiID, startTime, endTime, opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal membuat analisis simpang: " + err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"message": "analisis simpang berhasil dibuat",
+		"data":    analysis,
+	})
+}
+
+func GetSimpangAnalysisHistory(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	// limit=0 berarti semua riwayat
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "limit tidak boleh negatif"})
+	}
+
+	analysisList, err := models.GetSimpangAnalysisByLokasiID(lokasiID, int64(limit))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal mengambil riwayat analisis simpang"})
+	}
+
+	if analysisList == nil {
+		analysisList = []models.SimpangAnalysis{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  analysisList,
+		"count": len(analysisList),
+	})
+}
+
+func GetSimpangAnalysisByID(c *fiber.Ctx) error {
+	analysis, err := models.GetSimpangAnalysisByID(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "analisis simpang tidak ditemukan"})
+	}
+
+	return c.JSON(fiber.Map{"data": analysis})
+}
+
+func GetLatestSimpangAnalysis(c *fiber.Ctx) error {
+	analysis, err := models.GetLatestSimpangAnalysisByLokasiID(c.Params("lokasi_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "analisis simpang tidak ditemukan"})
+	}
+
+	return c.JSON(fiber.Map{"data": analysis})
+}
+
+// Mengganti geometri pendekat dan pemetaan zona arah ke gerakan (superadmin)
+func UpdateSimpangKonfigurasi(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	location, err := models.GetLocationByID(lokasiID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return c.Status(404).JSON(fiber.Map{"error": "Lokasi tidak ditemukan"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengambil lokasi"})
+	}
+	if !location.IsSimpang() {
+		return c.Status(400).JSON(fiber.Map{"error": "lokasi bukan simpang, ubah jenis_lokasi terlebih dahulu"})
+	}
+
+	var cfg models.SimpangConfig
+	if err := c.BodyParser(&cfg); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Request tidak valid"})
+	}
+	cfg.Normalize()
+	if err := cfg.Validate(location.Jenis_lokasi); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := models.UpdateSimpangConfig(lokasiID, cfg); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Gagal mengubah konfigurasi simpang"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Konfigurasi simpang berhasil diubah",
+		"data":    cfg,
+	})
+}
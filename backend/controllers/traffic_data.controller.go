@@ -93,6 +93,110 @@ func GetTrafficDataByLokasiID(c *fiber.Ctx) error {
 	})
 }
 
+// GetTrafficDataQuality menampilkan ringkasan skor dan flag kualitas data satu lokasi
+func GetTrafficDataQuality(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTimeStr := c.Query("start_time")
+	endTimeStr := c.Query("end_time")
+
+	var startTime, endTime time.Time
+	var err error
+
+	if startTimeStr != "" {
+		startTime, err = time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "format start_time tidak valid (gunakan RFC3339)"})
+		}
+	} else {
+		startTime = time.Now().Add(-24 * time.Hour)
+	}
+
+	if endTimeStr != "" {
+		endTime, err = time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "format end_time tidak valid (gunakan RFC3339)"})
+		}
+	} else {
+		endTime = time.Now()
+	}
+
+	summary, err := models.GetDataQualitySummary(lokasiID, startTime, endTime)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung kualitas data"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":       summary,
+		"start_time": startTime,
+		"end_time":   endTime,
+	})
+}
+
+// GetTrafficDataCompleteness menampilkan interval yang hilang berdasarkan interval lokasi
+func GetTrafficDataCompleteness(c *fiber.Ctx) error {
+	lokasiID := c.Params("lokasi_id")
+
+	startTimeStr := c.Query("start_time")
+	endTimeStr := c.Query("end_time")
+
+	var startTime, endTime time.Time
+	var err error
+
+	if startTimeStr != "" {
+		startTime, err = time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "format start_time tidak valid (gunakan RFC3339)"})
+		}
+	} else {
+		startTime = time.Now().Add(-24 * time.Hour)
+	}
+
+	if endTimeStr != "" {
+		endTime, err = time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "format end_time tidak valid (gunakan RFC3339)"})
+		}
+	} else {
+		endTime = time.Now()
+	}
+
+	location, err := models.GetLocationByID(lokasiID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "lokasi tidak ditemukan"})
+	}
+
+	opts := models.DefaultAnalysisOptions()
+	opts.ExcludeFlagged = c.QueryBool("exclude_flagged", opts.ExcludeFlagged)
+	opts.GapFill = c.Query("gap_fill", opts.GapFill)
+	if !models.IsValidGapFillMethod(opts.GapFill) {
+		return c.Status(400).JSON(fiber.Map{"error": "gap_fill tidak valid", "valid_options": models.GapFillOptions})
+	}
+
+	dataset, err := models.LoadTrafficDataForAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "gagal menghitung kelengkapan data"})
+	}
+
+	response := fiber.Map{
+		"data":       dataset.Kelengkapan,
+		"start_time": startTime,
+		"end_time":   endTime,
+	}
+	// include_estimated=true menyertakan interval hasil estimasi
+	if c.QueryBool("include_estimated") {
+		estimated := []models.TrafficData{}
+		for _, td := range dataset.Data {
+			if td.Estimated {
+				estimated = append(estimated, td)
+			}
+		}
+		response["estimated"] = estimated
+	}
+
+	return c.JSON(response)
+}
+
 func GetLatestTrafficDataByLokasiID(c *fiber.Ctx) error {
 	lokasiID := c.Params("lokasi_id")
 
@@ -96,4 +96,107 @@ func CreateIndexes() {
 	} else {
 		log.Println("Index traffic_data_archive berhasil dipastikan")
 	}
+
+	// Index unik untuk deduplikasi payload kamera (hanya dokumen yang memiliki ingest_key)
+	ingestKeyModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "ingest_key", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"ingest_key": bson.M{"$exists": true}}),
+	}
+
+	for _, collectionName := range []string{"traffic_data", "traffic_raw_data"} {
+		_, err = DB.Collection(collectionName).Indexes().CreateOne(ctx, ingestKeyModel)
+		if err != nil {
+			log.Printf("Gagal membuat index ingest_key %s: %v", collectionName, err)
+		} else {
+			log.Printf("Index ingest_key %s berhasil dipastikan", collectionName)
+		}
+	}
+
+	deadLetterModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "created_at", Value: -1},
+		},
+	}
+
+	_, err = DB.Collection("traffic_dead_letter").Indexes().CreateOne(ctx, deadLetterModel)
+	if err != nil {
+		log.Printf("Gagal membuat index traffic_dead_letter: %v", err)
+	} else {
+		log.Println("Index traffic_dead_letter berhasil dipastikan (status + created_at)")
+	}
+
+	// Index untuk proses ulang raw data per lokasi secara berurutan
+	rawDataModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "lokasi_id", Value: 1},
+			{Key: "timestamp", Value: 1},
+			{Key: "_id", Value: 1},
+		},
+	}
+
+	_, err = DB.Collection("traffic_raw_data").Indexes().CreateOne(ctx, rawDataModel)
+	if err != nil {
+		log.Printf("Gagal membuat index traffic_raw_data: %v", err)
+	} else {
+		log.Println("Index traffic_raw_data berhasil dipastikan (lokasi_id + timestamp)")
+	}
+
+	// Nonce signature kamera, dihapus otomatis setelah expires_at
+	nonceModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err = DB.Collection("camera_nonces").Indexes().CreateOne(ctx, nonceModel)
+	if err != nil {
+		log.Printf("Gagal membuat index camera_nonces: %v", err)
+	} else {
+		log.Println("Index camera_nonces berhasil dipastikan (TTL expires_at)")
+	}
+
+	// Lookup API key lama selama masa overlap rotasi
+	previousKeyModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "previous_api_key", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+
+	_, err = DB.Collection("cameras").Indexes().CreateOne(ctx, previousKeyModel)
+	if err != nil {
+		log.Printf("Gagal membuat index previous_api_key kamera: %v", err)
+	} else {
+		log.Println("Index previous_api_key kamera berhasil dipastikan")
+	}
+
+	// Riwayat status online/offline kamera
+	cameraHistoryModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "camera_id", Value: 1},
+			{Key: "changed_at", Value: -1},
+		},
+	}
+
+	_, err = DB.Collection("camera_status_history").Indexes().CreateOne(ctx, cameraHistoryModel)
+	if err != nil {
+		log.Printf("Gagal membuat index camera_status_history: %v", err)
+	} else {
+		log.Println("Index camera_status_history berhasil dipastikan (camera_id + changed_at)")
+	}
+
+	// Versi tabel ekivalen unik per metode agar dua tabel yang dibuat bersamaan tidak mendapat versi sama
+	ekivalenModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "metode", Value: 1},
+			{Key: "versi", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = DB.Collection("ekivalen_tables").Indexes().CreateOne(ctx, ekivalenModel)
+	if err != nil {
+		log.Printf("Gagal membuat index ekivalen_tables: %v", err)
+	} else {
+		log.Println("Index ekivalen_tables berhasil dipastikan (metode + versi unique)")
+	}
 }
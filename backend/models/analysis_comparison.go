@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Selisih hasil PKJI 2023 terhadap MKJI 1997 (nilai PKJI - MKJI)
+type AnalysisComparisonDiff struct {
+	Kapasitas            float64 `json:"kapasitas"`              // skr/jam - smp/jam
+	KapasitasPersen      float64 `json:"kapasitas_persen"`       // relatif terhadap kapasitas MKJI
+	Arus                 float64 `json:"arus"`                   // volume jam puncak PKJI - arus MKJI
+	DerajatKejenuhan     float64 `json:"derajat_kejenuhan"`      // DJ - DS
+	TingkatPelayananSama bool    `json:"tingkat_pelayanan_sama"` // LoS kedua standar sama
+	JamPuncakSama        bool    `json:"jam_puncak_sama"`
+}
+
+// Perbandingan analisis MKJI 1997 dan PKJI 2023 untuk lokasi dan periode yang sama
+type AnalysisComparison struct {
+	LokasiID   string                 `json:"lokasi_id"`
+	NamaLokasi string                 `json:"nama_lokasi"`
+	TipeLokasi string                 `json:"tipe_lokasi"`
+	StartTime  time.Time              `json:"start_time"`
+	EndTime    time.Time              `json:"end_time"`
+	MKJI       *MKJIAnalysis          `json:"mkji"`
+	PKJI       *PKJIAnalysis          `json:"pkji"`
+	Selisih    AnalysisComparisonDiff `json:"selisih"`
+}
+
+// CompareAnalyses menghitung MKJI dan PKJI dari dataset yang sama sehingga perbedaannya
+// hanya berasal dari standar (ekivalensi, faktor kapasitas, batas LoS), bukan dari data.
+func CompareAnalyses(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*AnalysisComparison, error) {
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	location, dataset, hambatan, err := prepareAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mkji, err := BuildMKJIAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+	pkji, err := BuildPKJIAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+	mkji.HambatanSamping = hambatan
+	pkji.HambatanSamping = hambatan
+
+	selisih := AnalysisComparisonDiff{
+		Kapasitas:            pkji.Kapasitas - mkji.Kapasitas,
+		Arus:                 pkji.VolumeLaluLintas - mkji.ArusLaluLintas,
+		DerajatKejenuhan:     pkji.DerajatKejenuhan - mkji.DerajatKejenuhan,
+		TingkatPelayananSama: pkji.TingkatPelayanan == mkji.TingkatPelayanan,
+		JamPuncakSama:        pkji.JamPuncak == mkji.JamPuncak,
+	}
+	if mkji.Kapasitas > 0 {
+		selisih.KapasitasPersen = selisih.Kapasitas / mkji.Kapasitas * 100
+	}
+
+	return &AnalysisComparison{
+		LokasiID:   location.ID,
+		NamaLokasi: location.Nama_lokasi,
+		TipeLokasi: location.Tipe_lokasi,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		MKJI:       mkji,
+		PKJI:       pkji,
+		Selisih:    selisih,
+	}, nil
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"backend/database"
 
@@ -11,14 +12,41 @@ import (
 )
 
 var (
-	TipeKameraOptions = []string{"trafficam", "x_stream", "thermicam", "cctv"}
+	TipeKameraOptions = []string{"trafficam", "x_stream", "thermicam", "cctv", "ai_counter"}
 	MaxZonaArah       = 8
 	MinZonaArah       = 1
 )
 
 type CameraZonaArah struct {
-	IDZonaArah string `bson:"id_zona_arah" json:"id_zona_arah"`
-	Arah       string `bson:"arah" json:"arah"`
+	IDZonaArah  string   `bson:"id_zona_arah" json:"id_zona_arah"`
+	Arah        string   `bson:"arah" json:"arah"`
+	ZoneID      int      `bson:"zone_id,omitempty" json:"zone_id"`                     // nomor zona pada perangkat (ZoneId di payload)
+	JumlahLajur int      `bson:"jumlah_lajur,omitempty" json:"jumlah_lajur,omitempty"` // jumlah lajur yang dicakup zona
+	Bearing     *float64 `bson:"bearing,omitempty" json:"bearing,omitempty"`           // arah tempuh dalam derajat dari utara (0-360)
+}
+
+// ZoneNumber mengembalikan nomor zona perangkat. Kamera lama tanpa zone_id memakai posisi + 1.
+func (za CameraZonaArah) ZoneNumber(position int) int {
+	if za.ZoneID > 0 {
+		return za.ZoneID
+	}
+	return position + 1
+}
+
+// ZonaArahByZoneID memetakan nomor zona perangkat ke konfigurasi zona arah
+func (c *Camera) ZonaArahByZoneID() map[int]CameraZonaArah {
+	zonaMap := make(map[int]CameraZonaArah)
+	for i, za := range c.ZonaArah {
+		zonaMap[za.ZoneNumber(i)] = za
+	}
+	return zonaMap
+}
+
+// ApplyDefaultZoneIDs mengisi zone_id yang kosong dengan posisi + 1 (perilaku lama)
+func ApplyDefaultZoneIDs(zonaArahList []CameraZonaArah) {
+	for i := range zonaArahList {
+		zonaArahList[i].ZoneID = zonaArahList[i].ZoneNumber(i)
+	}
 }
 
 type Camera struct {
@@ -29,6 +57,14 @@ type Camera struct {
 	APIKey           string           `bson:"api_key" json:"api_key"`
 	Keterangan       string           `bson:"keterangan" json:"keterangan"`
 	LokasiID         string           `bson:"lokasi_id" json:"lokasi_id"`
+
+	// Penandatanganan payload (HMAC-SHA256) dan rotasi API key
+	SigningSecret         string     `bson:"signing_secret,omitempty" json:"-"` // hanya dikirim sekali saat dibuat/dirotasi
+	RequireSignature      bool       `bson:"require_signature" json:"require_signature"`
+	PreviousAPIKey        string     `bson:"previous_api_key,omitempty" json:"previous_api_key,omitempty"`
+	PreviousSigningSecret string     `bson:"previous_signing_secret,omitempty" json:"-"`
+	PreviousKeyExpiresAt  *time.Time `bson:"previous_key_expires_at,omitempty" json:"previous_key_expires_at,omitempty"`
+	APIKeyRotatedAt       *time.Time `bson:"api_key_rotated_at,omitempty" json:"api_key_rotated_at,omitempty"`
 }
 
 func IsValidTipeKamera(value string) bool {
@@ -49,10 +85,25 @@ func ValidateZonaArahList(zonaArahList []CameraZonaArah) (string, bool) {
 	}
 
 	seenIDs := make(map[string]bool)
+	seenZoneIDs := make(map[int]bool)
 	for i, za := range zonaArahList {
 		if za.Arah == "" {
 			return fmt.Sprintf("zona_arah[%d].arah tidak boleh kosong", i), false
 		}
+		if za.ZoneID < 0 {
+			return fmt.Sprintf("zona_arah[%d].zone_id tidak valid", i), false
+		}
+		zoneID := za.ZoneNumber(i)
+		if seenZoneIDs[zoneID] {
+			return fmt.Sprintf("zona_arah[%d].zone_id %d duplikat", i, zoneID), false
+		}
+		seenZoneIDs[zoneID] = true
+		if za.JumlahLajur < 0 {
+			return fmt.Sprintf("zona_arah[%d].jumlah_lajur tidak valid", i), false
+		}
+		if za.Bearing != nil && (*za.Bearing < 0 || *za.Bearing >= 360) {
+			return fmt.Sprintf("zona_arah[%d].bearing harus antara 0 dan 360 derajat", i), false
+		}
 		if za.IDZonaArah != "" {
 			if seenIDs[za.IDZonaArah] {
 				return fmt.Sprintf("zona_arah[%d].id_zona_arah duplikat", i), false
@@ -79,3 +130,17 @@ func NextCameraID() (string, error) {
 	fmt.Sscanf(lastCamera.ID, "CAM-%d", &lastNum)
 	return fmt.Sprintf("CAM-%05d", lastNum+1), nil
 }
+
+// GetCameras mengambil daftar kamera sesuai filter, diurutkan berdasarkan ID
+func GetCameras(filter bson.M) ([]Camera, error) {
+	cursor, err := database.DB.Collection("cameras").Find(context.Background(), filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var cameras []Camera
+	if err = cursor.All(context.Background(), &cameras); err != nil {
+		return nil, err
+	}
+	return cameras, nil
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Model interval internal hasil decode payload kamera, tidak bergantung pada format vendor.
+// Semua adapter protokol menghasilkan struktur ini sebelum dikonversi ke raw data dan traffic data.
+type CameraInterval struct {
+	APIKey       string       `json:"api_key"`
+	IntervalTime int          `json:"interval_time"` // panjang interval dalam detik
+	DataNumber   int          `json:"data_number"`   // nomor urut interval dari kamera
+	Utc          string       `json:"utc"`           // waktu akhir interval: epoch detik, RFC3339, atau offset format lama
+	MilliSeconds int          `json:"milliseconds"`
+	Image        string       `json:"image,omitempty"`
+	Zones        []CameraZone `json:"zones"`
+}
+
+// Adapter protokol kamera. Setiap vendor/dialek payload cukup mengimplementasikan interface ini
+// dan didaftarkan untuk tipe kameranya lewat RegisterCameraAdapter.
+type CameraProtocolAdapter interface {
+	// Name nama protokol, dipakai di log, pesan error dan direktori fixture
+	Name() string
+	// ExtractAPIKey membaca API key dari payload untuk mencari kamera pengirim
+	ExtractAPIKey(payload string) (string, error)
+	// Decode mengubah payload vendor menjadi CameraInterval
+	Decode(payload string) (*CameraInterval, error)
+}
+
+var (
+	cameraAdapterByTipe = make(map[string]CameraProtocolAdapter)
+	cameraAdapterByName = make(map[string]CameraProtocolAdapter)
+	cameraAdapterOrder  []CameraProtocolAdapter
+
+	// Adapter untuk tipe kamera yang belum punya adapter khusus
+	defaultCameraAdapter CameraProtocolAdapter = FlirXMLAdapter{}
+)
+
+func init() {
+	RegisterCameraAdapter("trafficam", FlirXMLAdapter{})
+	RegisterCameraAdapter("x_stream", FlirXMLAdapter{})
+	RegisterCameraAdapter("thermicam", FlirXMLAdapter{})
+	RegisterCameraAdapter("cctv", FlirXMLAdapter{})
+	RegisterCameraAdapter("ai_counter", JSONCounterAdapter{})
+}
+
+// RegisterCameraAdapter memetakan tipe kamera ke adapter protokolnya
+func RegisterCameraAdapter(tipeKamera string, adapter CameraProtocolAdapter) {
+	cameraAdapterByTipe[tipeKamera] = adapter
+	if _, exists := cameraAdapterByName[adapter.Name()]; !exists {
+		cameraAdapterByName[adapter.Name()] = adapter
+		cameraAdapterOrder = append(cameraAdapterOrder, adapter)
+	}
+}
+
+// GetCameraAdapter mengembalikan adapter untuk tipe kamera, atau adapter XML FLIR jika tidak terdaftar
+func GetCameraAdapter(tipeKamera string) CameraProtocolAdapter {
+	if adapter, exists := cameraAdapterByTipe[tipeKamera]; exists {
+		return adapter
+	}
+	return defaultCameraAdapter
+}
+
+// GetCameraAdapterByName mencari adapter berdasarkan nama protokol
+func GetCameraAdapterByName(name string) (CameraProtocolAdapter, bool) {
+	adapter, exists := cameraAdapterByName[name]
+	return adapter, exists
+}
+
+// ExtractCameraAPIKey mencoba setiap adapter terdaftar sampai API key ditemukan.
+// Tipe kamera baru diketahui setelah API key divalidasi, sehingga tahap ini belum bisa memilih adapter.
+func ExtractCameraAPIKey(payload string) (string, error) {
+	var lastErr error
+	for _, adapter := range cameraAdapterOrder {
+		apiKey, err := adapter.ExtractAPIKey(payload)
+		if err == nil && apiKey != "" {
+			return apiKey, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("API key tidak ditemukan pada payload: %v", lastErr)
+	}
+	return "", fmt.Errorf("API key tidak ditemukan pada payload")
+}
+
+// Adapter XML FLIR (TrafiCam, TrafiCam x-stream, ThermiCam) dengan struktur Root/Message/Body/Zone/Class
+type FlirXMLAdapter struct{}
+
+func (FlirXMLAdapter) Name() string {
+	return "flir_xml"
+}
+
+func (FlirXMLAdapter) ExtractAPIKey(payload string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(payload), "{") {
+		return "", fmt.Errorf("payload bukan XML")
+	}
+	cameraData, err := ParseCameraXML(payload)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(cameraData.API), nil
+}
+
+func (FlirXMLAdapter) Decode(payload string) (*CameraInterval, error) {
+	if strings.HasPrefix(strings.TrimSpace(payload), "{") {
+		return nil, fmt.Errorf("payload bukan XML")
+	}
+	cameraData, err := ParseCameraXML(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	body := cameraData.Message.Body
+	return &CameraInterval{
+		APIKey:       strings.TrimSpace(cameraData.API),
+		IntervalTime: body.IntervalTime,
+		DataNumber:   body.DataNumber,
+		Utc:          body.Utc,
+		MilliSeconds: body.MilliSeconds,
+		Image:        cameraData.Image,
+		Zones:        body.Zones,
+	}, nil
+}
+
+// Payload JSON penghitung kendaraan berbasis AI (kamera CCTV dengan analitik di edge)
+type jsonCounterPayload struct {
+	APIKey          string            `json:"api_key"`
+	IntervalSeconds int               `json:"interval_seconds"`
+	Sequence        int               `json:"sequence"`
+	Timestamp       json.RawMessage   `json:"timestamp"`
+	Snapshot        string            `json:"snapshot"`
+	Zones           []jsonCounterZone `json:"zones"`
+}
+
+type jsonCounterZone struct {
+	ZoneID     int                `json:"zone_id"`
+	Occupancy  float64            `json:"occupancy"`
+	Confidence float64            `json:"confidence"`
+	Headway    float64            `json:"headway"`
+	Density    float64            `json:"density"`
+	Counts     []jsonCounterClass `json:"counts"`
+}
+
+type jsonCounterClass struct {
+	Class    int     `json:"class"`
+	Count    int     `json:"count"`
+	AvgSpeed float64 `json:"avg_speed"`
+	GapTime  float64 `json:"gap_time"`
+}
+
+// Adapter JSON untuk penghitung AI. Timestamp berupa akhir interval dalam RFC3339 atau epoch detik.
+type JSONCounterAdapter struct{}
+
+func (JSONCounterAdapter) Name() string {
+	return "json_counter"
+}
+
+func (JSONCounterAdapter) parse(payload string) (*jsonCounterPayload, error) {
+	if !strings.HasPrefix(strings.TrimSpace(payload), "{") {
+		return nil, fmt.Errorf("payload bukan JSON")
+	}
+	var data jsonCounterPayload
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	return &data, nil
+}
+
+func (a JSONCounterAdapter) ExtractAPIKey(payload string) (string, error) {
+	data, err := a.parse(payload)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(data.APIKey), nil
+}
+
+func (a JSONCounterAdapter) Decode(payload string) (*CameraInterval, error) {
+	data, err := a.parse(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	utc, milliSeconds, err := parseJSONCounterTimestamp(data.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	seenZones := make(map[int]bool)
+	zones := make([]CameraZone, 0, len(data.Zones))
+	for i, z := range data.Zones {
+		if z.ZoneID < 1 {
+			return nil, fmt.Errorf("zones[%d].zone_id harus >= 1", i)
+		}
+		if seenZones[z.ZoneID] {
+			return nil, fmt.Errorf("zones[%d].zone_id %d duplikat", i, z.ZoneID)
+		}
+		seenZones[z.ZoneID] = true
+
+		classes := make([]CameraClass, 0, len(z.Counts))
+		for _, c := range z.Counts {
+			classes = append(classes, CameraClass{
+				ClassNr: c.Class,
+				NumVeh:  c.Count,
+				Speed:   c.AvgSpeed,
+				GapTime: c.GapTime,
+			})
+		}
+		// Counter AI melaporkan confidence 0-1, disamakan dengan skala persen kamera FLIR
+		confidence := z.Confidence
+		if confidence > 0 && confidence <= 1 {
+			confidence *= 100
+		}
+		zones = append(zones, CameraZone{
+			ZoneId:     z.ZoneID,
+			Occupancy:  z.Occupancy,
+			Confidence: confidence,
+			HeadWay:    z.Headway,
+			Density:    z.Density,
+			Classes:    classes,
+		})
+	}
+
+	return &CameraInterval{
+		APIKey:       strings.TrimSpace(data.APIKey),
+		IntervalTime: data.IntervalSeconds,
+		DataNumber:   data.Sequence,
+		Utc:          utc,
+		MilliSeconds: milliSeconds,
+		Image:        data.Snapshot,
+		Zones:        zones,
+	}, nil
+}
+
+// parseJSONCounterTimestamp menerima string RFC3339 atau angka epoch detik (boleh pecahan)
+func parseJSONCounterTimestamp(raw json.RawMessage) (string, int, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", 0, nil
+	}
+
+	if raw[0] == '"' {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "", 0, fmt.Errorf("timestamp tidak valid: %v", err)
+		}
+		return value, 0, nil
+	}
+
+	epoch, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("timestamp tidak valid: %v", err)
+	}
+	seconds, fraction := math.Modf(epoch)
+	return strconv.FormatInt(int64(seconds), 10), int(math.Round(fraction * 1000)), nil
+}
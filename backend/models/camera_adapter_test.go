@@ -0,0 +1,91 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "Tulis ulang golden file adapter kamera dari hasil decode saat ini")
+
+// Setiap testdata/camera_adapters/<adapter>/<nama>.input didecode dengan adapter <adapter> lalu
+// dibandingkan dengan <nama>.golden.json
+func TestCameraAdapterGolden(t *testing.T) {
+	dir := filepath.Join("testdata", "camera_adapters")
+	adapterDirs, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("gagal membaca direktori fixture: %v", err)
+	}
+
+	checked := 0
+	for _, adapterDir := range adapterDirs {
+		if !adapterDir.IsDir() {
+			continue
+		}
+		adapter, ok := GetCameraAdapterByName(adapterDir.Name())
+		if !ok {
+			t.Errorf("%s: adapter tidak terdaftar", adapterDir.Name())
+			continue
+		}
+
+		inputs, err := filepath.Glob(filepath.Join(dir, adapterDir.Name(), "*.input"))
+		if err != nil {
+			t.Fatalf("gagal membaca fixture %s: %v", adapterDir.Name(), err)
+		}
+		for _, inputPath := range inputs {
+			checked++
+			t.Run(adapterDir.Name()+"/"+strings.TrimSuffix(filepath.Base(inputPath), ".input"), func(t *testing.T) {
+				checkCameraAdapterFixture(t, adapter, inputPath)
+			})
+		}
+	}
+	if checked == 0 {
+		t.Fatal("tidak ada fixture adapter kamera")
+	}
+}
+
+func checkCameraAdapterFixture(t *testing.T, adapter CameraProtocolAdapter, inputPath string) {
+	payload, err := os.ReadFile(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interval, err := adapter.Decode(string(payload))
+	if err != nil {
+		t.Fatalf("decode gagal: %v", err)
+	}
+
+	apiKey, err := adapter.ExtractAPIKey(string(payload))
+	if err != nil {
+		t.Fatalf("ekstrak API key gagal: %v", err)
+	}
+	if apiKey != interval.APIKey {
+		t.Fatalf("API key %q tidak sama dengan hasil decode %q", apiKey, interval.APIKey)
+	}
+
+	got, err := json.MarshalIndent(interval, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	goldenPath := strings.TrimSuffix(inputPath, ".input") + ".golden.json"
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("golden file tidak ditemukan (jalankan dengan -update): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("hasil decode berbeda dengan %s:\n%s", filepath.Base(goldenPath), got)
+	}
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	IngestStatusRejected IngestStatus = "rejected"
+
+	MaxCameraBatchItems = 1000
+)
+
+var (
+	ErrCameraBatchEmpty    = errors.New("batch tidak berisi data kamera")
+	ErrCameraBatchTooLarge = fmt.Errorf("jumlah item batch melebihi batas %d", MaxCameraBatchItems)
+)
+
+// ingestBatchItem memproses satu item batch; diganti pada test agar tidak memerlukan database
+var ingestBatchItem = IngestCameraPayload
+
+// ValidateCameraBatch memeriksa jumlah item hasil pemecahan batch (minimal satu, maksimal MaxCameraBatchItems)
+func ValidateCameraBatch(items []string) error {
+	if len(items) == 0 {
+		return ErrCameraBatchEmpty
+	}
+	if len(items) > MaxCameraBatchItems {
+		return ErrCameraBatchTooLarge
+	}
+	return nil
+}
+
+// Hasil proses satu item dalam batch ingest
+type CameraBatchResult struct {
+	Index     int          `json:"index"`
+	Status    IngestStatus `json:"status"`
+	ID        string       `json:"id,omitempty"`
+	LokasiID  string       `json:"lokasi_id,omitempty"`
+	Timestamp *time.Time   `json:"timestamp,omitempty"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// Root dengan lebih dari satu <Message>, dipakai kamera yang mengirim ulang buffer interval
+type cameraBatchRoot struct {
+	XMLName  xml.Name        `xml:"Root"`
+	API      string          `xml:"API"`
+	Messages []CameraMessage `xml:"Message"`
+	Image    string          `xml:"Image"`
+}
+
+// SplitCameraXMLBatch memecah body XML berisi banyak <Root> (multi-dokumen atau dibungkus elemen lain)
+// dan/atau <Root> dengan banyak <Message> menjadi payload tunggal per interval, sesuai urutan kirim.
+func SplitCameraXMLBatch(body string) ([]string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	var items []string
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML batch: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Root" {
+			continue
+		}
+
+		var root cameraBatchRoot
+		if err := decoder.DecodeElement(&root, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse XML batch item %d: %v", len(items), err)
+		}
+
+		for _, message := range root.Messages {
+			single := CameraXMLData{API: root.API, Message: message, Image: root.Image}
+			encoded, err := xml.Marshal(single)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode XML batch item %d: %v", len(items), err)
+			}
+			items = append(items, string(encoded))
+		}
+	}
+
+	return items, nil
+}
+
+// SplitCameraNDJSONBatch membaca body NDJSON, satu objek {"xml_data": "..."} per baris
+func SplitCameraNDJSONBatch(body string) ([]string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 50*1024*1024)
+
+	var items []string
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var item struct {
+			XMLData string `json:"xml_data"`
+		}
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return nil, fmt.Errorf("baris %d bukan JSON yang valid: %v", line, err)
+		}
+		items = append(items, item.XMLData)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ProcessCameraBatch memproses payload satu per satu sesuai urutan dan mengembalikan hasil per item.
+// Kegagalan satu item tidak menghentikan item berikutnya; item yang gagal masuk dead-letter.
+func ProcessCameraBatch(items []string, source *IngestSource) []CameraBatchResult {
+	results := make([]CameraBatchResult, 0, len(items))
+
+	for i, xmlData := range items {
+		result := CameraBatchResult{Index: i}
+
+		if strings.TrimSpace(xmlData) == "" {
+			result.Status = IngestStatusRejected
+			result.Reason = "xml_data tidak boleh kosong"
+			results = append(results, result)
+			continue
+		}
+
+		trafficData, status, err := ingestBatchItem(xmlData, source)
+		if err != nil {
+			result.Status = IngestStatusRejected
+			result.Reason = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = status
+		result.ID = trafficData.ID
+		result.LokasiID = trafficData.LokasiID
+		timestamp := trafficData.Timestamp
+		result.Timestamp = &timestamp
+		results = append(results, result)
+	}
+
+	return results
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func messageXMLUji(dataNumber int) string {
+	return fmt.Sprintf(`<Message Type="Data"><Body Type="Interval" IntervalTime="300" DataNumber="%d" Utc="1736150400">`+
+		`<Zone ZoneId="1"><Class ClassNr="2" NumVeh="%d" Speed="40"/></Zone></Body></Message>`, dataNumber, dataNumber*10)
+}
+
+func rootXMLUji(apiKey string, dataNumbers ...int) string {
+	var b strings.Builder
+	b.WriteString("<Root><API>" + apiKey + "</API>")
+	for _, n := range dataNumbers {
+		b.WriteString(messageXMLUji(n))
+	}
+	b.WriteString("</Root>")
+	return b.String()
+}
+
+func TestSplitCameraXMLBatch(t *testing.T) {
+	tests := []struct {
+		nama   string
+		body   string
+		apiKey []string
+		nomor  []int
+		gagal  bool
+	}{
+		{"satu root", rootXMLUji("KEY-A", 1), []string{"KEY-A"}, []int{1}, false},
+		{"root dengan banyak message", rootXMLUji("KEY-A", 1, 2, 3), []string{"KEY-A", "KEY-A", "KEY-A"}, []int{1, 2, 3}, false},
+		{"multi-dokumen", rootXMLUji("KEY-A", 1) + "\n" + rootXMLUji("KEY-B", 7), []string{"KEY-A", "KEY-B"}, []int{1, 7}, false},
+		{"dibungkus elemen lain", "<Batch>" + rootXMLUji("KEY-A", 4, 5) + rootXMLUji("KEY-B", 6) + "</Batch>",
+			[]string{"KEY-A", "KEY-A", "KEY-B"}, []int{4, 5, 6}, false},
+		{"tanpa root", "<Batch><Item/></Batch>", nil, nil, false},
+		{"XML terpotong", rootXMLUji("KEY-A", 1) + "<Root><API>KEY-B</API><Message>", nil, nil, true},
+		{"atribut rusak", `<Root><API>KEY-A</API><Message><Body DataNumber="satu"></Body></Message></Root>`, nil, nil, true},
+	}
+	for _, tt := range tests {
+		items, err := SplitCameraXMLBatch(tt.body)
+		if tt.gagal {
+			if err == nil {
+				t.Errorf("%s: ingin error, dapat %d item", tt.nama, len(items))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error %v", tt.nama, err)
+			continue
+		}
+		if len(items) != len(tt.nomor) {
+			t.Errorf("%s: %d item, ingin %d", tt.nama, len(items), len(tt.nomor))
+			continue
+		}
+		// Setiap item harus dapat dibaca ulang sebagai payload tunggal dengan API key root-nya
+		for i, item := range items {
+			data, err := ParseCameraXML(item)
+			if err != nil {
+				t.Errorf("%s item %d: %v", tt.nama, i, err)
+				continue
+			}
+			if data.API != tt.apiKey[i] || data.Message.Body.DataNumber != tt.nomor[i] || len(data.Message.Body.Zones) != 1 {
+				t.Errorf("%s item %d: API %s DataNumber %d, ingin %s dan %d", tt.nama, i, data.API,
+					data.Message.Body.DataNumber, tt.apiKey[i], tt.nomor[i])
+			}
+		}
+	}
+}
+
+func TestSplitCameraNDJSONBatch(t *testing.T) {
+	tests := []struct {
+		nama  string
+		body  string
+		want  []string
+		error string
+	}{
+		{"dua baris", `{"xml_data":"<Root>1</Root>"}` + "\n" + `{"xml_data":"<Root>2</Root>"}`, []string{"<Root>1</Root>", "<Root>2</Root>"}, ""},
+		{"baris kosong dan CRLF", "\r\n" + `{"xml_data":"a"}` + "\r\n\n" + `{"xml_data":"b"}` + "\n", []string{"a", "b"}, ""},
+		// Item tanpa xml_data tetap dihitung agar indeks hasil sesuai baris, lalu ditolak saat diproses
+		{"xml_data kosong", `{"xml_data":""}` + "\n" + `{"lain":1}`, []string{"", ""}, ""},
+		{"baris rusak", `{"xml_data":"a"}` + "\n\n" + `{"xml_data":`, nil, "baris 3"},
+		{"bukan objek", `["a"]`, nil, "baris 1"},
+	}
+	for _, tt := range tests {
+		items, err := SplitCameraNDJSONBatch(tt.body)
+		if tt.error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("%s: error %v, ingin menyebut %q", tt.nama, err, tt.error)
+			}
+			continue
+		}
+		if err != nil || strings.Join(items, "|") != strings.Join(tt.want, "|") || len(items) != len(tt.want) {
+			t.Errorf("%s: item %q error %v, ingin %q", tt.nama, items, err, tt.want)
+		}
+	}
+}
+
+func TestValidateCameraBatch(t *testing.T) {
+	tests := []struct {
+		jumlah int
+		want   error
+	}{
+		{0, ErrCameraBatchEmpty},
+		{1, nil},
+		{MaxCameraBatchItems, nil},
+		{MaxCameraBatchItems + 1, ErrCameraBatchTooLarge},
+	}
+	for _, tt := range tests {
+		if err := ValidateCameraBatch(make([]string, tt.jumlah)); !errors.Is(err, tt.want) {
+			t.Errorf("%d item: error %v, ingin %v", tt.jumlah, err, tt.want)
+		}
+	}
+
+	// Batas berlaku untuk jumlah interval, bukan jumlah dokumen <Root>
+	nomor := make([]int, MaxCameraBatchItems+1)
+	for i := range nomor {
+		nomor[i] = i + 1
+	}
+	items, err := SplitCameraXMLBatch(rootXMLUji("KEY-A", nomor...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCameraBatch(items); !errors.Is(err, ErrCameraBatchTooLarge) {
+		t.Fatalf("%d interval dalam satu root: error %v, ingin ErrCameraBatchTooLarge", len(items), err)
+	}
+}
+
+func TestProcessCameraBatchHasilCampuran(t *testing.T) {
+	asli := ingestBatchItem
+	defer func() { ingestBatchItem = asli }()
+
+	var diproses []string
+	ingestBatchItem = func(xmlData string, source *IngestSource) (*TrafficData, IngestStatus, error) {
+		diproses = append(diproses, xmlData)
+		switch xmlData {
+		case "baru":
+			return &TrafficData{ID: "TRF_2", LokasiID: "LOK-1"}, IngestStatusAccepted, nil
+		case "ulang":
+			return &TrafficData{ID: "TRF_1", LokasiID: "LOK-1"}, IngestStatusDuplicate, nil
+		default:
+			return nil, "", &CameraIngestError{Stage: IngestStageParse, Err: errors.New("failed to parse camera data")}
+		}
+	}
+
+	results := ProcessCameraBatch([]string{"baru", "  ", "rusak", "ulang"}, nil)
+	want := []struct {
+		status IngestStatus
+		id     string
+		alasan string
+	}{
+		{IngestStatusAccepted, "TRF_2", ""},
+		{IngestStatusRejected, "", "xml_data tidak boleh kosong"},
+		{IngestStatusRejected, "", "failed to parse camera data"},
+		{IngestStatusDuplicate, "TRF_1", ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("%d hasil, ingin %d", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Index != i || r.Status != w.status || r.ID != w.id || r.Reason != w.alasan {
+			t.Errorf("item %d: %+v, ingin status %s ID %q alasan %q", i, r, w.status, w.id, w.alasan)
+		}
+		if w.id != "" && r.Timestamp == nil {
+			t.Errorf("item %d: timestamp kosong", i)
+		}
+	}
+	// Item kosong tidak diteruskan ke ingest, item gagal tidak menghentikan item berikutnya
+	if strings.Join(diproses, ",") != "baru,rusak,ulang" {
+		t.Fatalf("item diproses %v", diproses)
+	}
+}
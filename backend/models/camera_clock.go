@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sumber timestamp interval pada data traffic
+const (
+	TimestampSourceCamera = "camera"
+	TimestampSourceServer = "server"
+)
+
+// Aksi ketika timestamp kamera berada di luar toleransi
+const (
+	ClockSkewActionServer = "server" // pakai waktu server sebagai gantinya (data lama tercatat sebagai data sekarang)
+	ClockSkewActionReject = "reject" // tolak payload (masuk dead-letter), default
+	ClockSkewActionAccept = "accept" // tetap pakai waktu kamera
+)
+
+// Kebijakan toleransi selisih jam kamera terhadap server
+type CameraClockPolicy struct {
+	MaxFutureSkew time.Duration // batas timestamp kamera lebih cepat dari server
+	MaxPastAge    time.Duration // batas umur interval yang masih diterima (data buffer setelah gangguan jaringan)
+	OnViolation   string
+}
+
+var cameraClockPolicy = CameraClockPolicy{
+	MaxFutureSkew: 5 * time.Minute,
+	MaxPastAge:    7 * 24 * time.Hour,
+	OnViolation:   ClockSkewActionReject,
+}
+
+// SetCameraClockPolicy mengganti kebijakan toleransi jam kamera (dipanggil saat startup)
+func SetCameraClockPolicy(policy CameraClockPolicy) {
+	switch policy.OnViolation {
+	case ClockSkewActionServer, ClockSkewActionReject, ClockSkewActionAccept:
+	default:
+		policy.OnViolation = ClockSkewActionReject
+	}
+	cameraClockPolicy = policy
+}
+
+func GetCameraClockPolicy() CameraClockPolicy {
+	return cameraClockPolicy
+}
+
+// Waktu interval hasil resolusi dari payload kamera.
+// Start dan End disimpan sebagai waktu lokal lokasi (konvensi yang sama dengan field timestamp lain).
+type CameraIntervalTime struct {
+	Start  time.Time
+	End    time.Time
+	Source string
+}
+
+// parseCameraUtc membaca atribut Utc sebagai waktu pengukuran.
+// Utc berupa epoch detik (ditambah MilliSeconds) atau RFC3339. Nilai kecil (-12..14) atau "UTC+7"
+// adalah format lama berupa offset zona waktu, bukan waktu pengukuran.
+func parseCameraUtc(interval *CameraInterval) (time.Time, bool) {
+	utcValue := strings.TrimSpace(interval.Utc)
+	if utcValue == "" || utcValue == "$utcVar" {
+		return time.Time{}, false
+	}
+
+	if epoch, err := strconv.ParseInt(utcValue, 10, 64); err == nil {
+		if epoch >= -12 && epoch <= 14 {
+			return time.Time{}, false
+		}
+		return time.Unix(epoch, int64(interval.MilliSeconds)*int64(time.Millisecond)).UTC(), true
+	}
+
+	if t, err := time.Parse(time.RFC3339, utcValue); err == nil {
+		return t.UTC(), true
+	}
+
+	return time.Time{}, false
+}
+
+// parseLegacyUtcOffset membaca format lama atribut Utc berupa offset jam ("7", "UTC+7")
+func parseLegacyUtcOffset(utcValue string) (float64, bool) {
+	utcValue = strings.TrimSpace(utcValue)
+	if len(utcValue) >= 4 && strings.EqualFold(utcValue[:3], "utc") {
+		utcValue = utcValue[3:]
+	}
+	if offset, err := strconv.ParseInt(utcValue, 10, 64); err == nil && offset >= -12 && offset <= 14 {
+		return float64(offset), true
+	}
+	return 0, false
+}
+
+// ResolveCameraIntervalTime menentukan awal dan akhir interval dari payload kamera.
+// Utc dianggap sebagai akhir interval; awal interval = akhir - IntervalTime.
+// Jika payload tidak membawa waktu pengukuran, dipakai waktu terima server.
+func ResolveCameraIntervalTime(cameraInterval *CameraInterval, location *Location, now time.Time) (*CameraIntervalTime, error) {
+	interval := time.Duration(cameraInterval.IntervalTime) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	offsetHours := location.Zona_waktu
+	if legacyOffset, ok := parseLegacyUtcOffset(cameraInterval.Utc); ok {
+		offsetHours = legacyOffset
+	}
+	toLocal := func(t time.Time) time.Time {
+		return t.Add(time.Duration(offsetHours * float64(time.Hour)))
+	}
+
+	serverTime := func() *CameraIntervalTime {
+		end := toLocal(now.UTC())
+		return &CameraIntervalTime{Start: end.Add(-interval), End: end, Source: TimestampSourceServer}
+	}
+
+	measured, ok := parseCameraUtc(cameraInterval)
+	if !ok {
+		return serverTime(), nil
+	}
+
+	policy := cameraClockPolicy
+	skew := measured.Sub(now.UTC())
+	var violation string
+	if policy.MaxFutureSkew > 0 && skew > policy.MaxFutureSkew {
+		violation = fmt.Sprintf("timestamp kamera %s lebih cepat %v dari server", measured.Format(time.RFC3339), skew.Round(time.Second))
+	} else if policy.MaxPastAge > 0 && -skew > policy.MaxPastAge {
+		violation = fmt.Sprintf("timestamp kamera %s lebih lama dari batas %v", measured.Format(time.RFC3339), policy.MaxPastAge)
+	}
+
+	if violation != "" {
+		switch policy.OnViolation {
+		case ClockSkewActionReject:
+			return nil, fmt.Errorf("%s", violation)
+		case ClockSkewActionServer:
+			log.Printf("Warning: %s, memakai waktu server", violation)
+			return serverTime(), nil
+		default:
+			log.Printf("Warning: %s, tetap memakai waktu kamera", violation)
+		}
+	}
+
+	// Bulatkan ke batas interval agar data terlambat/tidak berurutan jatuh di bucket yang benar
+	end := toLocal(measured).Round(interval)
+	return &CameraIntervalTime{Start: end.Add(-interval), End: end, Source: TimestampSourceCamera}, nil
+}
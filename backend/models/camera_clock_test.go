@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveCameraIntervalTimeKebijakan(t *testing.T) {
+	defer SetCameraClockPolicy(GetCameraClockPolicy())
+
+	now := time.Date(2025, 1, 15, 1, 7, 0, 0, time.UTC)
+	location := &Location{Zona_waktu: 7}
+	intervalAt := func(measured time.Time) *CameraInterval {
+		return &CameraInterval{IntervalTime: 300, Utc: measured.Format(time.RFC3339)}
+	}
+	lama := now.Add(-8 * 24 * time.Hour)
+
+	SetCameraClockPolicy(CameraClockPolicy{MaxFutureSkew: 5 * time.Minute, MaxPastAge: 7 * 24 * time.Hour})
+	if policy := GetCameraClockPolicy(); policy.OnViolation != ClockSkewActionReject {
+		t.Fatalf("kebijakan default %q, ingin %q", policy.OnViolation, ClockSkewActionReject)
+	}
+
+	tests := []struct {
+		nama     string
+		aksi     string
+		measured time.Time
+		wantErr  bool
+		wantEnd  time.Time
+		source   string
+	}{
+		{"dalam toleransi", ClockSkewActionReject, now.Add(-2 * time.Minute), false, time.Date(2025, 1, 15, 8, 5, 0, 0, time.UTC), TimestampSourceCamera},
+		{"terlalu lama ditolak", ClockSkewActionReject, lama, true, time.Time{}, ""},
+		{"terlalu cepat ditolak", ClockSkewActionReject, now.Add(time.Hour), true, time.Time{}, ""},
+		{"terlalu lama diterima", ClockSkewActionAccept, lama, false, lama.Add(7 * time.Hour).Round(5 * time.Minute), TimestampSourceCamera},
+		{"terlalu lama waktu server", ClockSkewActionServer, lama, false, now.Add(7 * time.Hour), TimestampSourceServer},
+	}
+	for _, tt := range tests {
+		SetCameraClockPolicy(CameraClockPolicy{MaxFutureSkew: 5 * time.Minute, MaxPastAge: 7 * 24 * time.Hour, OnViolation: tt.aksi})
+		got, err := ResolveCameraIntervalTime(intervalAt(tt.measured), location, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ingin error, dapat %+v", tt.nama, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: error %v", tt.nama, err)
+			continue
+		}
+		if !got.End.Equal(tt.wantEnd) || got.Source != tt.source {
+			t.Errorf("%s: akhir %s sumber %s, ingin %s %s", tt.nama, got.End, got.Source, tt.wantEnd, tt.source)
+		}
+	}
+}
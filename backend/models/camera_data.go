@@ -5,13 +5,14 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
 	"time"
 
 	"backend/database"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type CameraXMLData struct {
@@ -36,20 +37,64 @@ type CameraBody struct {
 }
 
 type CameraZone struct {
-	ZoneId     int           `xml:"ZoneId,attr"`
-	Occupancy  float64       `xml:"Occupancy,attr"`
-	Confidence float64       `xml:"Confidence,attr"`
-	Length     float64       `xml:"Length,attr"`
-	HeadWay    float64       `xml:"HeadWay,attr"`
-	Density    float64       `xml:"Density,attr"`
-	Classes    []CameraClass `xml:"Class"`
+	ZoneId     int           `xml:"ZoneId,attr" json:"zone_id"`
+	Occupancy  float64       `xml:"Occupancy,attr" json:"occupancy"`
+	Confidence float64       `xml:"Confidence,attr" json:"confidence"`
+	Length     float64       `xml:"Length,attr" json:"length"`
+	HeadWay    float64       `xml:"HeadWay,attr" json:"headway"`
+	Density    float64       `xml:"Density,attr" json:"density"`
+	Classes    []CameraClass `xml:"Class" json:"classes"`
 }
 
 type CameraClass struct {
-	ClassNr int     `xml:"ClassNr,attr"`
-	NumVeh  int     `xml:"NumVeh,attr"`
-	Speed   float64 `xml:"Speed,attr"`
-	GapTime float64 `xml:"GapTime,attr"`
+	ClassNr int     `xml:"ClassNr,attr" json:"class_nr"`
+	NumVeh  int     `xml:"NumVeh,attr" json:"num_veh"`
+	Speed   float64 `xml:"Speed,attr" json:"speed"`
+	GapTime float64 `xml:"GapTime,attr" json:"gap_time"`
+}
+
+// Status hasil ingest satu payload kamera
+type IngestStatus string
+
+const (
+	IngestStatusAccepted  IngestStatus = "accepted"
+	IngestStatusDuplicate IngestStatus = "duplicate"
+)
+
+// Tahap ingest tempat payload kamera gagal diproses
+const (
+	IngestStageParse     = "parse"
+	IngestStageAPIKey    = "api_key"
+	IngestStageConvert   = "convert"
+	IngestStageSignature = "signature"
+	IngestStageProcess   = "process" // gagal sementara (misalnya database) setelah semua percobaan ulang
+)
+
+// Error ingest beserta tahap kegagalannya, dipakai untuk mengisi dead-letter
+type CameraIngestError struct {
+	Stage    string
+	APIKey   string
+	CameraID string
+	Err      error
+}
+
+func (e *CameraIngestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CameraIngestError) Unwrap() error {
+	return e.Err
+}
+
+// BuildIngestKey membentuk kunci idempotensi dari kamera, DataNumber dan waktu pengukuran interval.
+// Mengembalikan string kosong jika payload tidak membawa waktu pengukuran (Utc kosong atau format lama
+// berupa offset zona waktu): DataNumber berulang setelah kamera restart, sehingga interval baru akan
+// dianggap duplikat data lama.
+func BuildIngestKey(cameraID string, interval *CameraInterval) string {
+	if _, ok := parseCameraUtc(interval); !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s|%d|%s|%d", cameraID, interval.DataNumber, strings.TrimSpace(interval.Utc), interval.MilliSeconds)
 }
 
 func ParseCameraXML(xmlData string) (*CameraXMLData, error) {
@@ -70,11 +115,22 @@ func ParseCameraXML(xmlData string) (*CameraXMLData, error) {
 	return &data, nil
 }
 
+// cameraAPIKeyFilter mencocokkan API key aktif atau API key lama yang masih dalam masa overlap rotasi
+func cameraAPIKeyFilter(apiKey string) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"api_key": apiKey},
+		{
+			"previous_api_key":        apiKey,
+			"previous_key_expires_at": bson.M{"$gt": time.Now().Add(7 * time.Hour)},
+		},
+	}}
+}
+
 func ValidateCameraAPIKey(apiKey string) (*Camera, error) {
 	collection := database.DB.Collection("cameras")
 
 	var camera Camera
-	err := collection.FindOne(context.Background(), bson.M{"api_key": apiKey}).Decode(&camera)
+	err := collection.FindOne(context.Background(), cameraAPIKeyFilter(apiKey)).Decode(&camera)
 	if err != nil {
 		return nil, fmt.Errorf("invalid API key: %v", err)
 	}
@@ -86,7 +142,7 @@ func GetCameraByAPIKey(apiKey string) (*Camera, error) {
 	collection := database.DB.Collection("cameras")
 
 	var camera Camera
-	err := collection.FindOne(context.Background(), bson.M{"api_key": apiKey}).Decode(&camera)
+	err := collection.FindOne(context.Background(), cameraAPIKeyFilter(apiKey)).Decode(&camera)
 	if err != nil {
 		return nil, err
 	}
@@ -94,13 +150,9 @@ func GetCameraByAPIKey(apiKey string) (*Camera, error) {
 	return &camera, nil
 }
 
-func ConvertCameraDataToTrafficData(cameraData *CameraXMLData, camera *Camera) (*TrafficData, error) {
-	location, err := GetLocationByID(camera.LokasiID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get location: %v", err)
-	}
-
-	klasifikasiList, err := GetMasterKlasifikasiByTipeLokasi(location.Tipe_lokasi)
+// GetKlasifikasiMap mengambil master klasifikasi kendaraan per nomor kelas untuk satu tipe lokasi
+func GetKlasifikasiMap(tipeLokasi string) map[int]KlasifikasiKendaraan {
+	klasifikasiList, err := GetMasterKlasifikasiByTipeLokasi(tipeLokasi)
 	if err != nil {
 		log.Printf("Warning: failed to get klasifikasi: %v", err)
 	}
@@ -109,16 +161,31 @@ func ConvertCameraDataToTrafficData(cameraData *CameraXMLData, camera *Camera) (
 	for _, k := range klasifikasiList {
 		klasifikasiMap[k.Kelas] = k
 	}
+	return klasifikasiMap
+}
 
-	// Build a map of configured zona_arah from camera
-	configuredZonaMap := make(map[int]CameraZonaArah)
-	for i, za := range camera.ZonaArah {
-		configuredZonaMap[i+1] = za // ZoneId is 1-based
+// resolveKelasInfo mengembalikan ID dan nama klasifikasi, dengan fallback jika kelas tidak ada di master
+func resolveKelasInfo(klasifikasiMap map[int]KlasifikasiKendaraan, tipeLokasi string, kelas int) (string, string) {
+	if k, exists := klasifikasiMap[kelas]; exists {
+		return k.ID, k.NamaKelas
 	}
+	return fmt.Sprintf("KK-%s-%d", tipeLokasi, kelas), fmt.Sprintf("Kelas %d", kelas)
+}
+
+func ConvertCameraDataToTrafficData(interval *CameraInterval, camera *Camera) (*TrafficData, error) {
+	location, err := GetLocationByID(camera.LokasiID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location: %v", err)
+	}
+
+	klasifikasiMap := GetKlasifikasiMap(location.Tipe_lokasi)
+
+	// Build a map of configured zona_arah from camera, keyed by device ZoneId
+	configuredZonaMap := camera.ZonaArahByZoneID()
 
 	// Build a map of incoming zones from camera data
 	incomingZonesMap := make(map[int]CameraZone)
-	for _, zone := range cameraData.Message.Body.Zones {
+	for _, zone := range interval.Zones {
 		incomingZonesMap[zone.ZoneId] = zone
 	}
 
@@ -154,14 +221,7 @@ func ConvertCameraDataToTrafficData(cameraData *CameraXMLData, camera *Camera) (
 		// Check if we have data for this zone
 		if zone, exists := incomingZonesMap[zoneId]; exists {
 			for _, class := range zone.Classes {
-				var idKlasifikasi, namaKelas string
-				if k, exists := klasifikasiMap[class.ClassNr]; exists {
-					idKlasifikasi = k.ID
-					namaKelas = k.NamaKelas
-				} else {
-					idKlasifikasi = fmt.Sprintf("KK-%s-%d", location.Tipe_lokasi, class.ClassNr)
-					namaKelas = fmt.Sprintf("Kelas %d", class.ClassNr)
-				}
+				idKlasifikasi, namaKelas := resolveKelasInfo(klasifikasiMap, location.Tipe_lokasi, class.ClassNr)
 
 				kelasDetail := TrafficKelasDetail{
 					IDKlasifikasi:     idKlasifikasi,
@@ -188,45 +248,14 @@ func ConvertCameraDataToTrafficData(cameraData *CameraXMLData, camera *Camera) (
 		zonaArahData = append(zonaArahData, zonaArah)
 	}
 
-	intervalMenit := cameraData.Message.Body.IntervalTime / 60
+	intervalMenit := interval.IntervalTime / 60
 	if intervalMenit <= 0 {
 		intervalMenit = 5
 	}
 
-	var timestamp time.Time
-
-	if cameraData.Message.Body.Utc != "" && cameraData.Message.Body.Utc != "$utcVar" {
-		utcValue := cameraData.Message.Body.Utc
-
-		var zoneOffset int64
-		var parsed bool
-
-		if offset, err := strconv.ParseInt(utcValue, 10, 64); err == nil && offset >= -12 && offset <= 14 {
-			zoneOffset = offset
-			parsed = true
-		} else if len(utcValue) >= 4 && (utcValue[:3] == "utc" || utcValue[:3] == "UTC") {
-			if offset, err := strconv.ParseInt(utcValue[3:], 10, 64); err == nil && offset >= -12 && offset <= 14 {
-				zoneOffset = offset
-				parsed = true
-			}
-		}
-
-		if parsed {
-			offsetDuration := time.Duration(zoneOffset * int64(time.Hour))
-			timestamp = time.Now().UTC().Add(offsetDuration)
-		} else {
-			timestamp = time.Now().UTC()
-			if location.Zona_waktu != 0 {
-				offsetDuration := time.Duration(location.Zona_waktu * float64(time.Hour))
-				timestamp = timestamp.Add(offsetDuration)
-			}
-		}
-	} else {
-		timestamp = time.Now().UTC()
-		if location.Zona_waktu != 0 {
-			offsetDuration := time.Duration(location.Zona_waktu * float64(time.Hour))
-			timestamp = timestamp.Add(offsetDuration)
-		}
+	intervalTime, err := ResolveCameraIntervalTime(interval, location, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval timestamp: %v", err)
 	}
 
 	id, err := NextTrafficDataID()
@@ -240,14 +269,17 @@ func ConvertCameraDataToTrafficData(cameraData *CameraXMLData, camera *Camera) (
 	}
 
 	trafficData := &TrafficData{
-		ID:             id,
-		LokasiID:       camera.LokasiID,
-		NamaLokasi:     location.Nama_lokasi,
-		TipeLokasi:     location.Tipe_lokasi,
-		Timestamp:      timestamp,
-		ZonaArahData:   zonaArahData,
-		TotalKendaraan: totalKendaraan,
-		IntervalMenit:  intervalMenit,
+		ID:              id,
+		LokasiID:        camera.LokasiID,
+		CameraID:        camera.ID,
+		NamaLokasi:      location.Nama_lokasi,
+		TipeLokasi:      location.Tipe_lokasi,
+		Timestamp:       intervalTime.Start,
+		IntervalEnd:     intervalTime.End,
+		TimestampSource: intervalTime.Source,
+		ZonaArahData:    zonaArahData,
+		TotalKendaraan:  totalKendaraan,
+		IntervalMenit:   intervalMenit,
 	}
 
 	return trafficData, nil
@@ -258,7 +290,7 @@ func SaveCameraTrafficData(trafficData *TrafficData) error {
 
 	_, err := collection.InsertOne(context.Background(), trafficData)
 	if err != nil {
-		return fmt.Errorf("failed to save traffic data: %v", err)
+		return fmt.Errorf("failed to save traffic data: %w", err)
 	}
 
 	log.Printf("Saved traffic data from camera: ID=%s, Location=%s, Total=%d vehicles",
@@ -274,33 +306,60 @@ func SaveCameraTrafficData(trafficData *TrafficData) error {
 	return nil
 }
 
-func ProcessCameraData(xmlData string) (*TrafficData, error) {
+func ProcessCameraData(payload string) (*TrafficData, IngestStatus, error) {
 
-	cameraData, err := ParseCameraXML(xmlData)
+	apiKey, err := ExtractCameraAPIKey(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse camera data: %v", err)
+		return nil, "", &CameraIngestError{Stage: IngestStageParse, Err: fmt.Errorf("failed to parse camera data: %v", err)}
 	}
 
-	camera, err := ValidateCameraAPIKey(cameraData.API)
+	camera, err := ValidateCameraAPIKey(apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("API key validation failed: %v", err)
+		return nil, "", &CameraIngestError{Stage: IngestStageAPIKey, APIKey: apiKey, Err: fmt.Errorf("API key validation failed: %v", err)}
 	}
 
-	// Update location source image if provided in XML
-	if cameraData.Image != "" && cameraData.Image != "base64" {
-		go UpdateLocationSourceImage(camera.LokasiID, cameraData.Image)
+	// Payload didecode dengan adapter sesuai tipe kamera yang terdaftar
+	adapter := GetCameraAdapter(camera.TipeKamera)
+	interval, err := adapter.Decode(payload)
+	if err == nil && interval.APIKey != apiKey {
+		err = fmt.Errorf("API key pada payload tidak sesuai")
+	}
+	if err != nil {
+		return nil, "", &CameraIngestError{Stage: IngestStageParse, APIKey: apiKey, CameraID: camera.ID, Err: fmt.Errorf("failed to decode %s payload for camera type %s: %v", adapter.Name(), camera.TipeKamera, err)}
+	}
+
+	// Payload yang dikirim ulang (retry setelah timeout) dikembalikan sebagai duplikat
+	ingestKey := BuildIngestKey(camera.ID, interval)
+	if ingestKey != "" {
+		if existing, err := GetTrafficDataByIngestKey(ingestKey); err == nil {
+			log.Printf("Payload duplikat dari kamera %s (DataNumber=%d), mengembalikan %s",
+				camera.ID, interval.DataNumber, existing.ID)
+			return existing, IngestStatusDuplicate, nil
+		}
+	}
+
+	// Update location source image if provided in payload
+	if interval.Image != "" && interval.Image != "base64" {
+		go UpdateLocationSourceImage(camera.LokasiID, interval.Image)
 	}
 
-	rawData, err := SaveRawDataFromCamera(cameraData, camera)
+	rawData, err := SaveRawDataFromCamera(interval, camera)
 	if err != nil {
 		log.Printf("Warning: failed to save raw data: %v", err)
 	}
 
-	trafficData, err := ConvertCameraDataToTrafficData(cameraData, camera)
+	trafficData, err := ConvertCameraDataToTrafficData(interval, camera)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert camera data: %v", err)
+		discardRawData(rawData)
+		return nil, "", &CameraIngestError{Stage: IngestStageConvert, APIKey: apiKey, CameraID: camera.ID, Err: fmt.Errorf("failed to convert camera data: %v", err)}
 	}
 
+	trafficData.IngestKey = ingestKey
+	trafficData.DataQuality = EvaluateIntervalQuality(interval.Zones, camera.ZonaArahByZoneID())
+	if trafficData.DataQuality.Flagged {
+		log.Printf("Interval kamera %s ditandai kualitas rendah (skor %.0f, %d flag)",
+			camera.ID, trafficData.DataQuality.Score, len(trafficData.DataQuality.Flags))
+	}
 	if rawData != nil {
 		trafficData.RawDataID = rawData.ID
 	}
@@ -320,81 +379,68 @@ func ProcessCameraData(xmlData string) (*TrafficData, error) {
 		trafficData.PKJIAnalysis = pkjiAnalysis
 	}
 
+	err = SaveCameraTrafficData(trafficData)
+	if err != nil {
+		// Request paralel dengan payload yang sama sudah lebih dulu tersimpan
+		discardRawData(rawData)
+		if mongo.IsDuplicateKeyError(err) && ingestKey != "" {
+			if existing, findErr := GetTrafficDataByIngestKey(ingestKey); findErr == nil {
+				return existing, IngestStatusDuplicate, nil
+			}
+		}
+		return nil, "", fmt.Errorf("failed to save traffic data: %v", err)
+	}
+
 	if rawData != nil {
-		_ = MarkRawDataAsProcessed(rawData.ID, trafficData.ID)
+		if err := MarkRawDataAsProcessed(rawData.ID, trafficData.ID); err != nil {
+			log.Printf("Warning: failed to mark raw data %s as processed: %v", rawData.ID, err)
+		}
 	}
 
-	err = SaveCameraTrafficData(trafficData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save traffic data: %v", err)
+	measuredAt := trafficData.Timestamp.Add(time.Duration(trafficData.IntervalMenit) * time.Minute)
+	if err := RecordCameraPayload(camera, interval, measuredAt); err != nil {
+		log.Printf("Warning: failed to update camera health: %v", err)
 	}
 
-	return trafficData, nil
+	return trafficData, IngestStatusAccepted, nil
+}
+
+// discardRawData menghapus raw data payload yang gagal disimpan sebagai traffic data. Payload gagal
+// masuk dead-letter dan diproses ulang dari sana, sehingga raw yatim tidak boleh ikut diproses ulang.
+func discardRawData(rawData *TrafficRawData) {
+	if rawData == nil {
+		return
+	}
+	if err := DeleteRawDataByID(rawData.ID); err != nil {
+		log.Printf("Warning: failed to delete orphan raw data %s: %v", rawData.ID, err)
+	}
 }
 
-func SaveRawDataFromCamera(cameraData *CameraXMLData, camera *Camera) (*TrafficRawData, error) {
+func SaveRawDataFromCamera(interval *CameraInterval, camera *Camera) (*TrafficRawData, error) {
 	location, err := GetLocationByID(camera.LokasiID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get location: %v", err)
 	}
 
-	var timestamp time.Time
-
-	if cameraData.Message.Body.Utc != "" && cameraData.Message.Body.Utc != "$utcVar" {
-		utcValue := cameraData.Message.Body.Utc
-
-		var zoneOffset int64
-		var parsed bool
-
-		if offset, err := strconv.ParseInt(utcValue, 10, 64); err == nil && offset >= -12 && offset <= 14 {
-			zoneOffset = offset
-			parsed = true
-		} else if len(utcValue) >= 4 && (utcValue[:3] == "utc" || utcValue[:3] == "UTC") {
-			if offset, err := strconv.ParseInt(utcValue[3:], 10, 64); err == nil && offset >= -12 && offset <= 14 {
-				zoneOffset = offset
-				parsed = true
-			}
-		}
-
-		if parsed {
-			offsetDuration := time.Duration(zoneOffset * int64(time.Hour))
-			timestamp = time.Now().UTC().Add(offsetDuration)
-			log.Printf("Raw data timestamp: Using server time at UTC%+d", zoneOffset)
-		} else {
-			timestamp = time.Now().UTC()
-			if location.Zona_waktu != 0 {
-				offsetDuration := time.Duration(location.Zona_waktu * float64(time.Hour))
-				timestamp = timestamp.Add(offsetDuration)
-				log.Printf("Raw data timestamp: Invalid Utc format, using server time + zona_waktu from location (%.1f)", location.Zona_waktu)
-			} else {
-				log.Printf("Raw data timestamp: Invalid Utc format, using server UTC time")
-			}
-		}
-	} else {
-		timestamp = time.Now().UTC()
-		if location.Zona_waktu != 0 {
-			offsetDuration := time.Duration(location.Zona_waktu * float64(time.Hour))
-			timestamp = timestamp.Add(offsetDuration)
-			log.Printf("Raw data timestamp: Using server time + zona_waktu from location (%.1f)", location.Zona_waktu)
-		} else {
-			log.Printf("Raw data timestamp: Using server UTC time")
-		}
+	intervalTime, err := ResolveCameraIntervalTime(interval, location, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval timestamp: %v", err)
+	}
+	if intervalTime.Source == TimestampSourceServer {
+		log.Printf("Raw data timestamp: payload tanpa waktu pengukuran, memakai waktu server")
 	}
 
-	intervalMenit := cameraData.Message.Body.IntervalTime / 60
+	intervalMenit := interval.IntervalTime / 60
 	if intervalMenit <= 0 {
 		intervalMenit = 5
 	}
 
-	// Build a map of configured zona_arah from camera
-	configuredZonaMap := make(map[int]CameraZonaArah)
-	for i, za := range camera.ZonaArah {
-		configuredZonaMap[i+1] = za // ZoneId is 1-based
-	}
+	// Build a map of configured zona_arah from camera, keyed by device ZoneId
+	configuredZonaMap := camera.ZonaArahByZoneID()
 
 	// Build a map of incoming zones from camera data
 	incomingZonesMap := make(map[int]CameraZone)
-	for _, zone := range cameraData.Message.Body.Zones {
+	for _, zone := range interval.Zones {
 		incomingZonesMap[zone.ZoneId] = zone
 	}
 
@@ -471,7 +517,7 @@ func SaveRawDataFromCamera(cameraData *CameraXMLData, camera *Camera) (*TrafficR
 		totalKendaraan += zonaTotalKendaraan
 	}
 
-	rawData, err := SaveRawData(camera.LokasiID, camera.ID, timestamp, zonaData, intervalMenit, totalKendaraan)
+	rawData, err := SaveRawData(camera.LokasiID, camera.ID, intervalTime.Start, zonaData, intervalMenit, totalKendaraan, BuildIngestKey(camera.ID, interval))
 	if err != nil {
 		return nil, err
 	}
@@ -479,99 +525,91 @@ func SaveRawDataFromCamera(cameraData *CameraXMLData, camera *Camera) (*TrafficR
 	return rawData, nil
 }
 
-func CalculateRealTimeMKJI(trafficData *TrafficData, lokasiID string) (*TrafficMKJIAnalysis, error) {
-	location, err := GetLocationByID(lokasiID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get location: %v", err)
-	}
-
-	var mc, lv, hv, um int
-	for _, za := range trafficData.ZonaArahData {
-		for _, kd := range za.KelasData {
-			kategori := GetKategoriMKJI(trafficData.TipeLokasi, kd.Kelas)
-			switch kategori {
-			case KategoriMC:
-				mc += kd.JumlahKendaraan
-			case KategoriLV:
-				lv += kd.JumlahKendaraan
-			case KategoriHV:
-				hv += kd.JumlahKendaraan
-			case KategoriUM:
-				um += kd.JumlahKendaraan
-			}
-		}
-	}
-
-	totalMotor := mc + lv + hv
-
-	totalSMP := float64(mc)*SMPValues[KategoriMC] +
-		float64(lv)*SMPValues[KategoriLV] +
-		float64(hv)*SMPValues[KategoriHV]
-
+// realtimeIntervalFilter memilih record lain pada interval yang sama di lokasi. Record itu sendiri dan
+// versi lama yang sedang digantikan proses ulang (previous_version_id, raw data yang sama, ingest_key
+// yang sama) tidak ikut dijumlahkan.
+func realtimeIntervalFilter(location *Location, trafficData *TrafficData) bson.M {
 	intervalMenit := trafficData.IntervalMenit
 	if intervalMenit <= 0 {
 		intervalMenit = 5
 	}
-	multiplier := 60.0 / float64(intervalMenit)
-	arusSMP := totalSMP * multiplier
+	start := trafficData.Timestamp.UTC().Truncate(time.Duration(intervalMenit) * time.Minute)
 
-	kapasitasDasar := GetKapasitasDasar(location.Tipe_arah, location.Tipe_lokasi)
-	fcw := GetFCW(location.Lebar_jalur, location.Tipe_arah)
-	fcsp := GetFCSP(location.Persentase, location.Tipe_arah)
-
-	var fcsf, fccs float64
-	var kapasitas float64
-
-	switch location.Tipe_lokasi {
-	case "perkotaan":
-		fcsf = GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
+	filter := bson.M{
+		"lokasi_id": location.ID,
+		"timestamp": bson.M{"$gte": start, "$lt": start.Add(time.Duration(intervalMenit) * time.Minute)},
+	}
+	var excludedIDs []string
+	for _, id := range []string{trafficData.ID, trafficData.PreviousVersionID} {
+		if id != "" {
+			excludedIDs = append(excludedIDs, id)
+		}
+	}
+	if len(excludedIDs) > 0 {
+		filter["_id"] = bson.M{"$nin": excludedIDs}
+	}
+	if trafficData.RawDataID != "" {
+		filter["raw_data_id"] = bson.M{"$ne": trafficData.RawDataID}
+	}
+	if trafficData.IngestKey != "" {
+		filter["ingest_key"] = bson.M{"$ne": trafficData.IngestKey}
+	}
+	return filter
+}
 
-	case "luar_kota", "12_kelas":
-		fcsf = GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf
+// resolveRealtimeStandard memuat tabel ekivalen yang berlaku untuk satu record. Record kamera lain pada
+// interval yang sama ikut diambil agar aturan ekivalen berbasis arus memakai arus total lokasi.
+func resolveRealtimeStandard(standard CapacityStandard, location *Location, trafficData *TrafficData) (*EkivalenResolver, error) {
+	var interval []TrafficData
+	cursor, err := database.DB.Collection("traffic_data").Find(context.Background(), realtimeIntervalFilter(location, trafficData),
+		options.Find().SetProjection(bson.M{"timestamp": 1, "interval_menit": 1, "total_kendaraan": 1}))
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(context.Background(), &interval); err != nil {
+		return nil, err
+	}
 
-	case "bebas_hambatan":
-		fcsf = 1.0
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp
+	return ResolveCapacityStandard(standard, *location, append(interval, *trafficData))
+}
 
-	default:
-		fcsf = GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
+func CalculateRealTimeMKJI(trafficData *TrafficData, lokasiID string) (*TrafficMKJIAnalysis, error) {
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location: %v", err)
 	}
 
-	derajatKejenuhan := 0.0
-	if kapasitas > 0 {
-		derajatKejenuhan = arusSMP / kapasitas
+	standard, err := resolveRealtimeStandard(MKJI1997Standard{}, location, trafficData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ekivalen table: %v", err)
 	}
-
-	tingkatPelayanan, keterangan := GetTingkatPelayanan(derajatKejenuhan)
+	count := mkjiCountFromCategories(HitungCategoryCount(standard, []TrafficData{*trafficData}, trafficData.TipeLokasi))
+	arusSMP := count.TotalSMP * intervalPerJam(trafficData)
+	kinerja := EvaluateCapacity(standard, *location, arusSMP)
 
 	mkjiAnalysis := &TrafficMKJIAnalysis{
-		MC:               mc,
-		LV:               lv,
-		HV:               hv,
-		UM:               um,
-		TotalMotor:       totalMotor,
-		TotalSMP:         totalSMP,
+		MC:               count.MC,
+		LV:               count.LV,
+		HV:               count.HV,
+		UM:               count.UM,
+		TotalMotor:       count.TotalMotor,
+		TotalSMP:         count.TotalSMP,
 		ArusSMP:          arusSMP,
-		KapasitasDasar:   kapasitasDasar,
-		FCW:              fcw,
-		FCSP:             fcsp,
-		FCSF:             fcsf,
-		FCCS:             fccs,
-		Kapasitas:        kapasitas,
-		DerajatKejenuhan: derajatKejenuhan,
-		TingkatPelayanan: tingkatPelayanan,
-		Keterangan:       keterangan,
+		KapasitasDasar:   kinerja.KapasitasDasar,
+		FCW:              kinerja.Factor("FCW"),
+		FCSP:             kinerja.Factor("FCSP"),
+		FCSF:             kinerja.Factor("FCSF"),
+		FCCS:             kinerja.Factor("FCCS"),
+		Kapasitas:        kinerja.Kapasitas,
+		DerajatKejenuhan: kinerja.DerajatKejenuhan,
+		TingkatPelayanan: kinerja.TingkatPelayanan,
+		Keterangan:       kinerja.Keterangan,
+		PerArah:          HitungKinerjaPerArahInterval(standard, *location, *trafficData),
+		TabelEkivalen:    standard.TabelDipakai(),
 	}
 
 	log.Printf("MKJI Analysis: MC=%d, LV=%d, HV=%d, UM=%d, SMP=%.2f, Q=%.2f smp/jam, C=%.2f, DS=%.3f, LoS=%s",
-		mc, lv, hv, um, totalSMP, arusSMP, kapasitas, derajatKejenuhan, tingkatPelayanan)
+		count.MC, count.LV, count.HV, count.UM, count.TotalSMP, arusSMP, kinerja.Kapasitas, kinerja.DerajatKejenuhan, kinerja.TingkatPelayanan)
 
 	return mkjiAnalysis, nil
 }
@@ -582,66 +620,46 @@ func CalculateRealTimePKJI(trafficData *TrafficData, lokasiID string) (*TrafficP
 		return nil, fmt.Errorf("failed to get location: %v", err)
 	}
 
-	var sm, kr, kb, ktb int
-	for _, za := range trafficData.ZonaArahData {
-		for _, kd := range za.KelasData {
-			kategori := GetKategoriPKJI(trafficData.TipeLokasi, kd.Kelas)
-			switch kategori {
-			case KategoriSM:
-				sm += kd.JumlahKendaraan
-			case KategoriKR:
-				kr += kd.JumlahKendaraan
-			case KategoriKB:
-				kb += kd.JumlahKendaraan
-			case KategoriKTB:
-				ktb += kd.JumlahKendaraan
-			}
-		}
-	}
-
-	totalMotor := sm + kr + kb
-
-	totalSKR := float64(sm)*GetEMPPKJI(KategoriSM, trafficData.TipeLokasi) +
-		float64(kr)*GetEMPPKJI(KategoriKR, trafficData.TipeLokasi) +
-		float64(kb)*GetEMPPKJI(KategoriKB, trafficData.TipeLokasi)
-
-	intervalMenit := trafficData.IntervalMenit
-	if intervalMenit <= 0 {
-		intervalMenit = 5
-	}
-	multiplier := 60.0 / float64(intervalMenit)
-	volumeSKR := totalSKR * multiplier
-
-	kapasitas, c0, fclj, fcpa, fchs, fcuk := HitungKapasitasPKJI(*location)
-
-	derajatKejenuhan := 0.0
-	if kapasitas > 0 {
-		derajatKejenuhan = volumeSKR / kapasitas
+	standard, err := resolveRealtimeStandard(PKJI2023Standard{}, location, trafficData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ekivalen table: %v", err)
 	}
-
-	tingkatPelayanan, keterangan := GetTingkatPelayananPKJI(derajatKejenuhan)
+	count := pkjiCountFromCategories(HitungCategoryCount(standard, []TrafficData{*trafficData}, trafficData.TipeLokasi))
+	volumeSKR := count.TotalSkr * intervalPerJam(trafficData)
+	kinerja := EvaluateCapacity(standard, *location, volumeSKR)
 
 	pkjiAnalysis := &TrafficPKJIAnalysis{
-		SM:               sm,
-		KR:               kr,
-		KB:               kb,
-		KTB:              ktb,
-		TotalMotor:       totalMotor,
-		TotalSKR:         totalSKR,
+		SM:               count.SM,
+		KR:               count.KR,
+		KB:               count.KB,
+		KTB:              count.KTB,
+		TotalMotor:       count.TotalMotor,
+		TotalSKR:         count.TotalSkr,
 		VolumeSKR:        volumeSKR,
-		KapasitasDasar:   c0,
-		FCLJ:             fclj,
-		FCPA:             fcpa,
-		FCHS:             fchs,
-		FCUK:             fcuk,
-		Kapasitas:        kapasitas,
-		DerajatKejenuhan: derajatKejenuhan,
-		TingkatPelayanan: tingkatPelayanan,
-		Keterangan:       keterangan,
+		KapasitasDasar:   kinerja.KapasitasDasar,
+		FCLJ:             kinerja.Factor("FCLJ"),
+		FCPA:             kinerja.Factor("FCPA"),
+		FCHS:             kinerja.Factor("FCHS"),
+		FCUK:             kinerja.Factor("FCUK"),
+		Kapasitas:        kinerja.Kapasitas,
+		DerajatKejenuhan: kinerja.DerajatKejenuhan,
+		TingkatPelayanan: kinerja.TingkatPelayanan,
+		Keterangan:       kinerja.Keterangan,
+		PerArah:          HitungKinerjaPerArahInterval(standard, *location, *trafficData),
+		TabelEkivalen:    standard.TabelDipakai(),
 	}
 
 	log.Printf("PKJI Analysis: SM=%d, KR=%d, KB=%d, KTB=%d, SKR=%.2f, V=%.2f skr/jam, C=%.2f, DJ=%.3f, LoS=%s",
-		sm, kr, kb, ktb, totalSKR, volumeSKR, kapasitas, derajatKejenuhan, tingkatPelayanan)
+		count.SM, count.KR, count.KB, count.KTB, count.TotalSkr, volumeSKR, kinerja.Kapasitas, kinerja.DerajatKejenuhan, kinerja.TingkatPelayanan)
 
 	return pkjiAnalysis, nil
 }
+
+// intervalPerJam mengembalikan pengali untuk mengubah jumlah per interval menjadi per jam
+func intervalPerJam(trafficData *TrafficData) float64 {
+	intervalMenit := trafficData.IntervalMenit
+	if intervalMenit <= 0 {
+		intervalMenit = 5
+	}
+	return 60.0 / float64(intervalMenit)
+}
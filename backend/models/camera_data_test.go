@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestBuildIngestKey(t *testing.T) {
+	tests := []struct {
+		nama     string
+		interval CameraInterval
+		want     string
+	}{
+		{"epoch", CameraInterval{DataNumber: 12, Utc: "1736150400", MilliSeconds: 250}, "CAM-1|12|1736150400|250"},
+		{"epoch tanpa DataNumber", CameraInterval{Utc: " 1736150400 "}, "CAM-1|0|1736150400|0"},
+		{"RFC3339", CameraInterval{DataNumber: 3, Utc: "2025-01-06T08:00:00+07:00"}, "CAM-1|3|2025-01-06T08:00:00+07:00|0"},
+		{"offset format lama", CameraInterval{DataNumber: 12, Utc: "7", MilliSeconds: 250}, ""},
+		{"offset UTC+7", CameraInterval{DataNumber: 12, Utc: "UTC+7"}, ""},
+		{"Utc kosong", CameraInterval{DataNumber: 12}, ""},
+		{"placeholder template", CameraInterval{DataNumber: 12, Utc: "$utcVar"}, ""},
+		{"Utc tidak dikenal", CameraInterval{DataNumber: 12, Utc: "kemarin"}, ""},
+	}
+	for _, tt := range tests {
+		if got := BuildIngestKey("CAM-1", &tt.interval); got != tt.want {
+			t.Errorf("%s: key %q, ingin %q", tt.nama, got, tt.want)
+		}
+	}
+}
+
+// Kamera format lama yang restart mengulang DataNumber dari awal: interval baru tidak boleh
+// mendapat kunci yang sama dengan interval lama
+func TestBuildIngestKeyKameraRestart(t *testing.T) {
+	sebelum := CameraInterval{DataNumber: 1, Utc: "7", MilliSeconds: 0}
+	sesudah := CameraInterval{DataNumber: 1, Utc: "7", MilliSeconds: 0}
+	if a, b := BuildIngestKey("CAM-1", &sebelum), BuildIngestKey("CAM-1", &sesudah); a != "" || b != "" {
+		t.Fatalf("kunci format lama %q dan %q, ingin kosong (tanpa deduplikasi)", a, b)
+	}
+
+	// Dengan waktu pengukuran, DataNumber yang berulang tetap dibedakan oleh timestamp
+	sebelum = CameraInterval{DataNumber: 65535, Utc: "1736150400"}
+	sesudah = CameraInterval{DataNumber: 65535, Utc: "1736755200"}
+	if BuildIngestKey("CAM-1", &sebelum) == BuildIngestKey("CAM-1", &sesudah) {
+		t.Fatal("DataNumber berulang dengan timestamp berbeda mendapat kunci yang sama")
+	}
+
+	// Payload yang dikirim ulang mendapat kunci yang sama, tetapi tidak lintas kamera
+	retry := sebelum
+	if BuildIngestKey("CAM-1", &sebelum) != BuildIngestKey("CAM-1", &retry) {
+		t.Fatal("payload retry mendapat kunci berbeda")
+	}
+	if BuildIngestKey("CAM-1", &sebelum) == BuildIngestKey("CAM-2", &retry) {
+		t.Fatal("kamera berbeda mendapat kunci yang sama")
+	}
+}
+
+// Versi lama yang sedang diganti proses ulang tidak boleh ikut dihitung sebagai arus lokasi
+func TestRealtimeIntervalFilter(t *testing.T) {
+	location := &Location{ID: "LOK-1"}
+	ts := time.Date(2025, 1, 6, 8, 7, 0, 0, time.FixedZone("WIB", 7*3600))
+
+	tests := []struct {
+		nama        string
+		trafficData TrafficData
+		idDikecuali []string
+		rawID       string
+		ingestKey   string
+	}{
+		{"ingest baru", TrafficData{Timestamp: ts, IntervalMenit: 5, RawDataID: "RAW-00009", IngestKey: "CAM-1|1|1736150400|0"},
+			nil, "RAW-00009", "CAM-1|1|1736150400|0"},
+		{"proses ulang raw tanpa ingest_key", TrafficData{Timestamp: ts, IntervalMenit: 5, RawDataID: "RAW-00001", PreviousVersionID: "TRF_00001"},
+			[]string{"TRF_00001"}, "RAW-00001", ""},
+		{"versi baru tersimpan", TrafficData{ID: "TRF_00002", Timestamp: ts, PreviousVersionID: "TRF_00001"},
+			[]string{"TRF_00002", "TRF_00001"}, "", ""},
+	}
+	for _, tt := range tests {
+		filter := realtimeIntervalFilter(location, &tt.trafficData)
+
+		start := time.Date(2025, 1, 6, 1, 5, 0, 0, time.UTC)
+		rentang, _ := filter["timestamp"].(bson.M)
+		if filter["lokasi_id"] != "LOK-1" || !rentang["$gte"].(time.Time).Equal(start) ||
+			!rentang["$lt"].(time.Time).Equal(start.Add(5*time.Minute)) {
+			t.Errorf("%s: rentang = %v", tt.nama, filter)
+		}
+
+		var ids []string
+		if id, ok := filter["_id"].(bson.M); ok {
+			ids, _ = id["$nin"].([]string)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.idDikecuali, ",") {
+			t.Errorf("%s: _id dikecualikan %v, ingin %v", tt.nama, ids, tt.idDikecuali)
+		}
+		if raw, ok := filter["raw_data_id"].(bson.M); (tt.rawID != "") != ok || (ok && raw["$ne"] != tt.rawID) {
+			t.Errorf("%s: raw_data_id = %v, ingin %q", tt.nama, filter["raw_data_id"], tt.rawID)
+		}
+		if key, ok := filter["ingest_key"].(bson.M); (tt.ingestKey != "") != ok || (ok && key["$ne"] != tt.ingestKey) {
+			t.Errorf("%s: ingest_key = %v, ingin %q", tt.nama, filter["ingest_key"], tt.ingestKey)
+		}
+	}
+}
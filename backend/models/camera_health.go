@@ -0,0 +1,388 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Status kesehatan kamera
+const (
+	CameraHealthOnline  = "online"
+	CameraHealthOffline = "offline"
+	CameraHealthUnknown = "unknown"
+)
+
+const (
+	// Jumlah payload terakhir yang disimpan untuk menghitung laju payload dan rata-rata confidence
+	cameraHealthSampleSize = 120
+	// Batas minimum tanpa payload sebelum kamera dianggap offline
+	CameraMinOfflineAfter = 10 * time.Minute
+	// Kamera dianggap offline setelah tidak mengirim selama kelipatan interval yang diharapkan
+	cameraOfflineIntervalMultiplier = 3
+)
+
+// Sampel satu payload yang diterima
+type CameraPayloadSample struct {
+	At         time.Time `bson:"at" json:"at"`
+	Confidence float64   `bson:"confidence" json:"confidence"`
+}
+
+// Kesehatan satu kamera, satu dokumen per kamera di koleksi camera_health
+type CameraHealth struct {
+	CameraID         string                `bson:"_id" json:"camera_id"`
+	LokasiID         string                `bson:"lokasi_id" json:"lokasi_id"`
+	Status           string                `bson:"status" json:"status"`
+	StatusSince      time.Time             `bson:"status_since" json:"status_since"`
+	LastPayloadAt    *time.Time            `bson:"last_payload_at,omitempty" json:"last_payload_at,omitempty"`
+	ExpectedInterval int                   `bson:"expected_interval" json:"expected_interval"` // detik
+	LastZoneIDs      []int                 `bson:"last_zone_ids" json:"last_zone_ids"`         // ZoneId pada payload terakhir
+	PayloadCount     int64                 `bson:"payload_count" json:"payload_count"`
+	ErrorCount       int64                 `bson:"error_count" json:"error_count"`
+	LastErrorAt      *time.Time            `bson:"last_error_at,omitempty" json:"last_error_at,omitempty"`
+	LastError        string                `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	RecentPayloads   []CameraPayloadSample `bson:"recent_payloads" json:"-"`
+	UpdatedAt        time.Time             `bson:"updated_at" json:"updated_at"`
+
+	// Dihitung saat dibaca dari RecentPayloads, tidak disimpan
+	PayloadRate      float64 `bson:"-" json:"payload_rate"`          // payload per jam dalam 1 jam terakhir
+	ExpectedRate     float64 `bson:"-" json:"expected_rate"`         // payload per jam sesuai interval
+	AvgConfidence    float64 `bson:"-" json:"avg_confidence"`        // rata-rata Confidence zona pada sampel terakhir
+	OfflineAfter     int     `bson:"-" json:"offline_after_seconds"` // batas tanpa payload sebelum offline
+	SecondsSinceLast *int    `bson:"-" json:"seconds_since_last_payload,omitempty"`
+}
+
+// Riwayat perubahan status online/offline kamera
+type CameraStatusHistory struct {
+	ID         string    `bson:"_id" json:"id"`
+	CameraID   string    `bson:"camera_id" json:"camera_id"`
+	LokasiID   string    `bson:"lokasi_id" json:"lokasi_id"`
+	FromStatus string    `bson:"from_status" json:"from_status"`
+	ToStatus   string    `bson:"to_status" json:"to_status"`
+	Reason     string    `bson:"reason" json:"reason"`
+	ChangedAt  time.Time `bson:"changed_at" json:"changed_at"`
+}
+
+// CameraOfflineThreshold menghitung lama tanpa payload sebelum kamera dianggap offline
+func CameraOfflineThreshold(expectedInterval int) time.Duration {
+	threshold := time.Duration(expectedInterval*cameraOfflineIntervalMultiplier) * time.Second
+	if threshold < CameraMinOfflineAfter {
+		return CameraMinOfflineAfter
+	}
+	return threshold
+}
+
+// averageZoneConfidence menghitung rata-rata Confidence zona dalam satu payload
+func averageZoneConfidence(zones []CameraZone) float64 {
+	if len(zones) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, z := range zones {
+		total += z.Confidence
+	}
+	return total / float64(len(zones))
+}
+
+// computeMetrics mengisi field turunan dari sampel payload terakhir
+func (h *CameraHealth) computeMetrics(now time.Time) {
+	h.OfflineAfter = int(CameraOfflineThreshold(h.ExpectedInterval).Seconds())
+	if h.ExpectedInterval > 0 {
+		h.ExpectedRate = 3600.0 / float64(h.ExpectedInterval)
+	}
+
+	hourAgo := now.Add(-time.Hour)
+	inLastHour := 0
+	confidenceTotal := 0.0
+	for _, sample := range h.RecentPayloads {
+		if sample.At.After(hourAgo) {
+			inLastHour++
+		}
+		confidenceTotal += sample.Confidence
+	}
+	h.PayloadRate = float64(inLastHour)
+	if len(h.RecentPayloads) > 0 {
+		h.AvgConfidence = confidenceTotal / float64(len(h.RecentPayloads))
+	}
+
+	if h.LastPayloadAt != nil {
+		seconds := int(now.Sub(*h.LastPayloadAt).Seconds())
+		h.SecondsSinceLast = &seconds
+	}
+}
+
+// recordCameraStatusTransition mengubah status kamera jika berbeda dan mencatatnya ke riwayat
+func recordCameraStatusTransition(cameraID, lokasiID, toStatus, reason string, now time.Time) error {
+	collection := database.DB.Collection("camera_health")
+
+	var current CameraHealth
+	err := collection.FindOneAndUpdate(context.Background(),
+		bson.M{"_id": cameraID, "status": bson.M{"$ne": toStatus}},
+		bson.M{"$set": bson.M{"status": toStatus, "status_since": now}},
+	).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		// Status sudah sama (tidak ada transisi) atau dokumen belum ada
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gagal mengubah status kamera %s: %v", cameraID, err)
+	}
+
+	history := CameraStatusHistory{
+		ID:         uuid.New().String(),
+		CameraID:   cameraID,
+		LokasiID:   lokasiID,
+		FromStatus: current.Status,
+		ToStatus:   toStatus,
+		Reason:     reason,
+		ChangedAt:  now,
+	}
+	if _, err := database.DB.Collection("camera_status_history").InsertOne(context.Background(), history); err != nil {
+		return fmt.Errorf("gagal menyimpan riwayat status kamera: %v", err)
+	}
+
+	log.Printf("Status kamera %s berubah: %s -> %s (%s)", cameraID, current.Status, toStatus, reason)
+	return nil
+}
+
+// RecordCameraPayload mencatat payload yang berhasil disimpan. measuredAt adalah akhir interval yang
+// diukur kamera (waktu lokal), sehingga replay dead-letter dan backfill batch tidak menandai kamera
+// yang sudah mati sebagai online.
+func RecordCameraPayload(camera *Camera, interval *CameraInterval, measuredAt time.Time) error {
+	collection := database.DB.Collection("camera_health")
+
+	now := time.Now().Add(7 * time.Hour)
+	update, online := cameraPayloadUpdate(camera, interval, measuredAt, now)
+	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": camera.ID}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if !online {
+		return nil
+	}
+
+	return recordCameraStatusTransition(camera.ID, camera.LokasiID, CameraHealthOnline, "payload diterima", now)
+}
+
+// cameraPayloadUpdate menyusun update camera_health untuk satu payload dan menentukan apakah payload
+// cukup baru untuk menandai kamera online. last_payload_at tidak pernah mundur oleh payload lama,
+// dan konfigurasi zona terakhir hanya diambil dari payload yang masih baru.
+func cameraPayloadUpdate(camera *Camera, interval *CameraInterval, measuredAt, now time.Time) (bson.M, bool) {
+	expectedInterval := interval.IntervalTime
+	if expectedInterval <= 0 {
+		expectedInterval = 300
+	}
+
+	// Jam kamera yang lebih cepat dari server tidak boleh membuat payload terlihat lebih baru
+	if measuredAt.After(now) {
+		measuredAt = now
+	}
+	online := now.Sub(measuredAt) <= CameraOfflineThreshold(expectedInterval)
+
+	set := bson.M{
+		"lokasi_id":  camera.LokasiID,
+		"updated_at": now,
+	}
+	setOnInsert := bson.M{
+		"status":       CameraHealthUnknown,
+		"status_since": now,
+		"error_count":  0,
+	}
+	if online {
+		zoneIDs := make([]int, 0, len(interval.Zones))
+		for _, z := range interval.Zones {
+			zoneIDs = append(zoneIDs, z.ZoneId)
+		}
+		sort.Ints(zoneIDs)
+		set["expected_interval"] = expectedInterval
+		set["last_zone_ids"] = zoneIDs
+	} else {
+		setOnInsert["expected_interval"] = expectedInterval
+	}
+
+	update := bson.M{
+		"$set":         set,
+		"$max":         bson.M{"last_payload_at": measuredAt},
+		"$inc":         bson.M{"payload_count": 1},
+		"$setOnInsert": setOnInsert,
+		"$push": bson.M{"recent_payloads": bson.M{
+			"$each":  []CameraPayloadSample{{At: measuredAt, Confidence: averageZoneConfidence(interval.Zones)}},
+			"$slice": -cameraHealthSampleSize,
+		}},
+	}
+	return update, online
+}
+
+// RecordCameraError mencatat payload kamera yang gagal diproses
+func RecordCameraError(cameraID string, message string) error {
+	collection := database.DB.Collection("camera_health")
+
+	var camera Camera
+	if err := database.DB.Collection("cameras").FindOne(context.Background(), bson.M{"_id": cameraID}).Decode(&camera); err != nil {
+		return err
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	update := bson.M{
+		"$set": bson.M{
+			"lokasi_id":     camera.LokasiID,
+			"last_error_at": now,
+			"last_error":    message,
+			"updated_at":    now,
+		},
+		"$inc": bson.M{"error_count": 1},
+		"$setOnInsert": bson.M{
+			"status":            CameraHealthUnknown,
+			"status_since":      now,
+			"payload_count":     0,
+			"expected_interval": 300,
+			"recent_payloads":   []CameraPayloadSample{},
+		},
+	}
+
+	_, err := collection.UpdateOne(context.Background(), bson.M{"_id": cameraID}, update, options.UpdateOne().SetUpsert(true))
+	return err
+}
+
+// Batas umur payload terakhir yang dipakai untuk memvalidasi konfigurasi zona
+const zoneValidationWindow = 24 * time.Hour
+
+// ValidateZonaArahAgainstRecentZones membandingkan konfigurasi zona baru dengan ZoneId pada payload terakhir kamera.
+// Error dikembalikan untuk zona terkonfigurasi yang tidak dikirim kamera (kemungkinan salah nomor),
+// warning untuk zona yang dikirim kamera tetapi tidak dipetakan (datanya akan diabaikan).
+// Validasi dilewati jika kamera belum mengirim payload dalam 24 jam terakhir.
+func ValidateZonaArahAgainstRecentZones(cameraID string, zonaArahList []CameraZonaArah) ([]string, []string) {
+	collection := database.DB.Collection("camera_health")
+
+	var health CameraHealth
+	err := collection.FindOne(context.Background(), bson.M{"_id": cameraID},
+		options.FindOne().SetProjection(bson.M{"recent_payloads": 0})).Decode(&health)
+	if err != nil || health.LastPayloadAt == nil || len(health.LastZoneIDs) == 0 {
+		return nil, nil
+	}
+	if time.Now().Add(7*time.Hour).Sub(*health.LastPayloadAt) > zoneValidationWindow {
+		return nil, nil
+	}
+
+	received := make(map[int]bool)
+	for _, zoneID := range health.LastZoneIDs {
+		received[zoneID] = true
+	}
+
+	var errs, warnings []string
+	configured := make(map[int]bool)
+	for i, za := range zonaArahList {
+		zoneID := za.ZoneNumber(i)
+		configured[zoneID] = true
+		if !received[zoneID] {
+			errs = append(errs, fmt.Sprintf("zone_id %d (%s) tidak ada pada payload terakhir kamera (zona diterima: %v)", zoneID, za.Arah, health.LastZoneIDs))
+		}
+	}
+	for _, zoneID := range health.LastZoneIDs {
+		if !configured[zoneID] {
+			warnings = append(warnings, fmt.Sprintf("zone_id %d dikirim kamera tetapi tidak dipetakan, datanya akan diabaikan", zoneID))
+		}
+	}
+	return errs, warnings
+}
+
+// CheckInactiveCameras menandai kamera offline jika tidak mengirim payload melewati batas interval yang diharapkan
+func CheckInactiveCameras() error {
+	collection := database.DB.Collection("camera_health")
+
+	cursor, err := collection.Find(context.Background(), bson.M{"status": CameraHealthOnline},
+		options.Find().SetProjection(bson.M{"recent_payloads": 0}))
+	if err != nil {
+		return err
+	}
+
+	var healthList []CameraHealth
+	if err = cursor.All(context.Background(), &healthList); err != nil {
+		return err
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	for _, h := range healthList {
+		threshold := CameraOfflineThreshold(h.ExpectedInterval)
+		if h.LastPayloadAt != nil && now.Sub(*h.LastPayloadAt) <= threshold {
+			continue
+		}
+		reason := fmt.Sprintf("tidak ada payload selama lebih dari %v", threshold)
+		if err := recordCameraStatusTransition(h.CameraID, h.LokasiID, CameraHealthOffline, reason, now); err != nil {
+			log.Printf("Error mencatat status offline kamera %s: %v", h.CameraID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetCameraHealth mengambil kesehatan satu kamera. Kamera yang belum pernah mengirim payload berstatus unknown.
+func GetCameraHealth(camera *Camera) (*CameraHealth, error) {
+	collection := database.DB.Collection("camera_health")
+
+	var health CameraHealth
+	err := collection.FindOne(context.Background(), bson.M{"_id": camera.ID}).Decode(&health)
+	if err != nil {
+		health = CameraHealth{
+			CameraID: camera.ID,
+			LokasiID: camera.LokasiID,
+			Status:   CameraHealthUnknown,
+		}
+		if location, locErr := GetLocationByID(camera.LokasiID); locErr == nil {
+			health.ExpectedInterval = location.Interval
+		}
+	}
+
+	health.computeMetrics(time.Now().Add(7 * time.Hour))
+	return &health, nil
+}
+
+// GetCameraHealthList mengambil kesehatan semua kamera, bisa difilter per lokasi atau status
+func GetCameraHealthList(filter bson.M) ([]CameraHealth, error) {
+	collection := database.DB.Collection("camera_health")
+
+	cursor, err := collection.Find(context.Background(), filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var healthList []CameraHealth
+	if err = cursor.All(context.Background(), &healthList); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	for i := range healthList {
+		healthList[i].computeMetrics(now)
+	}
+	return healthList, nil
+}
+
+// GetCameraStatusHistory mengambil riwayat transisi status kamera, terbaru lebih dulu
+func GetCameraStatusHistory(cameraID string, limit int64) ([]CameraStatusHistory, error) {
+	collection := database.DB.Collection("camera_status_history")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "changed_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(context.Background(), bson.M{"camera_id": cameraID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []CameraStatusHistory
+	if err = cursor.All(context.Background(), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
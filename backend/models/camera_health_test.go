@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCameraPayloadUpdate(t *testing.T) {
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	camera := &Camera{ID: "CAM-1", LokasiID: "LOK-1"}
+	interval := &CameraInterval{IntervalTime: 300, Zones: []CameraZone{{ZoneId: 2}, {ZoneId: 1}}}
+	batas := CameraOfflineThreshold(300) // 3 × 5 menit
+
+	tests := []struct {
+		nama       string
+		measuredAt time.Time
+		online     bool
+		lastAt     time.Time
+	}{
+		{"payload live", now.Add(-30 * time.Second), true, now.Add(-30 * time.Second)},
+		{"tepat di batas offline", now.Add(-batas), true, now.Add(-batas)},
+		{"replay dead-letter kemarin", now.Add(-24 * time.Hour), false, now.Add(-24 * time.Hour)},
+		{"backfill lewat batas", now.Add(-batas - time.Second), false, now.Add(-batas - time.Second)},
+		{"jam kamera lebih cepat", now.Add(time.Hour), true, now},
+	}
+	for _, tt := range tests {
+		update, online := cameraPayloadUpdate(camera, interval, tt.measuredAt, now)
+		if online != tt.online {
+			t.Errorf("%s: online %v, ingin %v", tt.nama, online, tt.online)
+		}
+
+		maks := update["$max"].(bson.M)
+		if at := maks["last_payload_at"].(time.Time); !at.Equal(tt.lastAt) {
+			t.Errorf("%s: last_payload_at %v, ingin %v", tt.nama, at, tt.lastAt)
+		}
+		sampel := update["$push"].(bson.M)["recent_payloads"].(bson.M)["$each"].([]CameraPayloadSample)
+		if !sampel[0].At.Equal(tt.lastAt) {
+			t.Errorf("%s: sampel payload pada %v, ingin %v", tt.nama, sampel[0].At, tt.lastAt)
+		}
+
+		// Payload lama tidak boleh menimpa konfigurasi zona dan interval terbaru
+		set := update["$set"].(bson.M)
+		_, adaZona := set["last_zone_ids"]
+		_, adaInterval := set["expected_interval"]
+		if adaZona != tt.online || adaInterval != tt.online {
+			t.Errorf("%s: $set = %v", tt.nama, set)
+		}
+		if tt.online {
+			if zona := set["last_zone_ids"].([]int); len(zona) != 2 || zona[0] != 1 || zona[1] != 2 {
+				t.Errorf("%s: last_zone_ids %v, ingin [1 2]", tt.nama, zona)
+			}
+		} else if update["$setOnInsert"].(bson.M)["expected_interval"] != 300 {
+			t.Errorf("%s: expected_interval tidak diisi saat dokumen baru", tt.nama)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/database"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Header penandatanganan payload kamera.
+// X-Signature = hex(HMAC-SHA256(signing_secret, X-Signature-Timestamp + "\n" + X-Signature-Nonce + "\n" + body))
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+
+	DefaultKeyRotationOverlap = 24 * time.Hour
+)
+
+var (
+	ErrSignatureRequired = errors.New("payload kamera wajib ditandatangani")
+	ErrSignatureInvalid  = errors.New("signature payload tidak valid")
+	ErrSignatureExpired  = errors.New("timestamp signature di luar batas waktu")
+	ErrSignatureReplay   = errors.New("nonce signature sudah pernah dipakai")
+)
+
+// Batas selisih X-Signature-Timestamp terhadap waktu server
+var cameraSignatureMaxSkew = 5 * time.Minute
+
+// SetCameraSignatureMaxSkew mengganti batas selisih timestamp signature (dipanggil saat startup)
+func SetCameraSignatureMaxSkew(skew time.Duration) {
+	if skew > 0 {
+		cameraSignatureMaxSkew = skew
+	}
+}
+
+// Nonce yang sudah dipakai, dihapus otomatis oleh TTL index setelah expires_at
+type CameraNonce struct {
+	ID        string    `bson:"_id"`
+	CameraID  string    `bson:"camera_id"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// GenerateSigningSecret membuat secret HMAC acak 256 bit
+func GenerateSigningSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateCameraAPIKey membuat API key baru yang belum dipakai kamera lain (termasuk API key lama dalam masa overlap).
+// Hanya mongo.ErrNoDocuments yang dianggap API key belum dipakai; error database lain dikembalikan.
+func GenerateCameraAPIKey() (string, error) {
+	collection := database.DB.Collection("cameras")
+
+	for {
+		apiKey := uuid.New().String()
+		filter := bson.M{"$or": []bson.M{{"api_key": apiKey}, {"previous_api_key": apiKey}}}
+		var existingCamera Camera
+		err := collection.FindOne(context.Background(), filter).Decode(&existingCamera)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return apiKey, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("gagal memeriksa API key: %v", err)
+		}
+	}
+}
+
+// ComputeCameraSignature menghitung signature HMAC-SHA256 untuk body payload
+func ComputeCameraSignature(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// activeSigningSecrets mengembalikan secret aktif dan secret lama yang masih dalam masa overlap rotasi
+func activeSigningSecrets(camera *Camera) []string {
+	var secrets []string
+	if camera.SigningSecret != "" {
+		secrets = append(secrets, camera.SigningSecret)
+	}
+	if camera.PreviousSigningSecret != "" && camera.PreviousKeyExpiresAt != nil &&
+		camera.PreviousKeyExpiresAt.After(time.Now().Add(7*time.Hour)) {
+		secrets = append(secrets, camera.PreviousSigningSecret)
+	}
+	return secrets
+}
+
+// VerifyCameraSignature memeriksa signature payload kamera beserta proteksi replay (timestamp + nonce).
+// Request tanpa signature hanya diterima jika kamera tidak mewajibkan signature.
+func VerifyCameraSignature(camera *Camera, body []byte, timestamp, nonce, signature string) error {
+	signature = strings.TrimSpace(strings.TrimPrefix(signature, "sha256="))
+	if signature == "" {
+		if camera.RequireSignature {
+			return ErrSignatureRequired
+		}
+		return nil
+	}
+	if timestamp == "" || nonce == "" {
+		return fmt.Errorf("%w: header %s dan %s wajib diisi", ErrSignatureInvalid, SignatureTimestampHeader, SignatureNonceHeader)
+	}
+
+	epoch, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s harus berupa epoch detik", ErrSignatureInvalid, SignatureTimestampHeader)
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(epoch, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > cameraSignatureMaxSkew {
+		return ErrSignatureExpired
+	}
+
+	valid := false
+	for _, secret := range activeSigningSecrets(camera) {
+		expected := ComputeCameraSignature(secret, timestamp, nonce, body)
+		if hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrSignatureInvalid
+	}
+
+	// Nonce dicatat setelah signature valid agar request palsu tidak bisa menghabiskan nonce kamera.
+	// expires_at memakai waktu UTC sebenarnya karena dipakai TTL index MongoDB.
+	entry := CameraNonce{
+		ID:        camera.ID + "|" + nonce,
+		CameraID:  camera.ID,
+		CreatedAt: now.Add(7 * time.Hour),
+		ExpiresAt: now.Add(2 * cameraSignatureMaxSkew),
+	}
+	return recordCameraNonce(entry)
+}
+
+// recordCameraNonce mencatat nonce dan mengembalikan ErrSignatureReplay jika sudah pernah dipakai.
+// Diganti pada test agar tidak memerlukan database.
+var recordCameraNonce = func(entry CameraNonce) error {
+	_, err := database.DB.Collection("camera_nonces").InsertOne(context.Background(), entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrSignatureReplay
+		}
+		return fmt.Errorf("gagal mencatat nonce: %v", err)
+	}
+	return nil
+}
+
+// RotateCameraAPIKey membuat API key dan signing secret baru. API key dan secret lama tetap diterima
+// selama masa overlap agar kamera dapat dikonfigurasi ulang tanpa kehilangan data.
+func RotateCameraAPIKey(cameraID string, overlap time.Duration) (*Camera, error) {
+	collection := database.DB.Collection("cameras")
+
+	var camera Camera
+	if err := collection.FindOne(context.Background(), bson.M{"_id": cameraID}).Decode(&camera); err != nil {
+		return nil, err
+	}
+
+	secret, err := GenerateSigningSecret()
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat signing secret: %v", err)
+	}
+
+	apiKey, err := GenerateCameraAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	set := bson.M{
+		"api_key":            apiKey,
+		"signing_secret":     secret,
+		"api_key_rotated_at": now,
+	}
+	update := bson.M{"$set": set}
+	if overlap > 0 {
+		expiresAt := now.Add(overlap)
+		set["previous_api_key"] = camera.APIKey
+		set["previous_signing_secret"] = camera.SigningSecret
+		set["previous_key_expires_at"] = expiresAt
+	} else {
+		update["$unset"] = bson.M{
+			"previous_api_key":        "",
+			"previous_signing_secret": "",
+			"previous_key_expires_at": "",
+		}
+	}
+
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": cameraID}, update); err != nil {
+		return nil, err
+	}
+
+	var updated Camera
+	if err := collection.FindOne(context.Background(), bson.M{"_id": cameraID}).Decode(&updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// SetCameraSignatureRequirement mengaktifkan/menonaktifkan kewajiban signature.
+// Signing secret dibuat otomatis jika kamera belum memilikinya dan dikembalikan sebagai newSecret.
+func SetCameraSignatureRequirement(cameraID string, required bool) (*Camera, string, error) {
+	collection := database.DB.Collection("cameras")
+
+	var camera Camera
+	if err := collection.FindOne(context.Background(), bson.M{"_id": cameraID}).Decode(&camera); err != nil {
+		return nil, "", err
+	}
+
+	set := bson.M{"require_signature": required}
+	var newSecret string
+	if camera.SigningSecret == "" {
+		var err error
+		newSecret, err = GenerateSigningSecret()
+		if err != nil {
+			return nil, "", fmt.Errorf("gagal membuat signing secret: %v", err)
+		}
+		set["signing_secret"] = newSecret
+	}
+
+	if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": cameraID}, bson.M{"$set": set}); err != nil {
+		return nil, "", err
+	}
+
+	var updated Camera
+	if err := collection.FindOne(context.Background(), bson.M{"_id": cameraID}).Decode(&updated); err != nil {
+		return nil, "", err
+	}
+	return &updated, newSecret, nil
+}
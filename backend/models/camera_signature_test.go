@@ -0,0 +1,149 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nonceStoreUji menggantikan koleksi camera_nonces selama test
+func nonceStoreUji(t *testing.T) map[string]CameraNonce {
+	t.Helper()
+	asli := recordCameraNonce
+	t.Cleanup(func() { recordCameraNonce = asli })
+
+	store := make(map[string]CameraNonce)
+	recordCameraNonce = func(entry CameraNonce) error {
+		if _, ok := store[entry.ID]; ok {
+			return ErrSignatureReplay
+		}
+		store[entry.ID] = entry
+		return nil
+	}
+	return store
+}
+
+func TestComputeCameraSignature(t *testing.T) {
+	// HMAC-SHA256("rahasia", "1736150400\nn-1\n<Root/>"), dihitung terpisah dengan hmac Python
+	const want = "133b83157494cd93dc25cc48d4b6ff96f87338ce8cbe35920ec4f2f6ac00f991"
+	got := ComputeCameraSignature("rahasia", "1736150400", "n-1", []byte("<Root/>"))
+	if got != want {
+		t.Fatalf("signature %s, ingin %s", got, want)
+	}
+	// Setiap bagian pesan ikut ditandatangani, termasuk pemisah antara timestamp dan nonce
+	lain := []string{
+		ComputeCameraSignature("rahasia2", "1736150400", "n-1", []byte("<Root/>")),
+		ComputeCameraSignature("rahasia", "1736150401", "n-1", []byte("<Root/>")),
+		ComputeCameraSignature("rahasia", "1736150400", "n-2", []byte("<Root/>")),
+		ComputeCameraSignature("rahasia", "1736150400", "n-1", []byte("<Root />")),
+		ComputeCameraSignature("rahasia", "173615040", "0n-1", []byte("<Root/>")),
+	}
+	for i, sig := range lain {
+		if sig == got {
+			t.Errorf("variasi %d menghasilkan signature yang sama", i)
+		}
+	}
+}
+
+func TestVerifyCameraSignature(t *testing.T) {
+	nonceStoreUji(t)
+
+	now := time.Now()
+	lokalSekarang := now.Add(7 * time.Hour)
+	body := []byte("<Root><API>KEY-A</API></Root>")
+	ts := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+	dalamOverlap := lokalSekarang.Add(time.Hour)
+	lewatOverlap := lokalSekarang.Add(-time.Hour)
+
+	kamera := func(require bool, expires *time.Time) *Camera {
+		return &Camera{
+			ID:                    "CAM-00001",
+			SigningSecret:         "secret-baru",
+			PreviousSigningSecret: "secret-lama",
+			PreviousKeyExpiresAt:  expires,
+			RequireSignature:      require,
+		}
+	}
+
+	tests := []struct {
+		nama      string
+		camera    *Camera
+		timestamp string
+		nonce     string
+		signature string
+		want      error
+	}{
+		{"valid", kamera(true, nil), ts(0), "n-1", ComputeCameraSignature("secret-baru", ts(0), "n-1", body), nil},
+		{"prefix sha256= dan huruf besar", kamera(true, nil), ts(0), "n-2",
+			"sha256=" + strings.ToUpper(ComputeCameraSignature("secret-baru", ts(0), "n-2", body)), nil},
+		{"secret salah", kamera(true, nil), ts(0), "n-3", ComputeCameraSignature("secret-lain", ts(0), "n-3", body), ErrSignatureInvalid},
+		{"nonce berbeda dari yang ditandatangani", kamera(true, nil), ts(0), "n-4",
+			ComputeCameraSignature("secret-baru", ts(0), "n-x", body), ErrSignatureInvalid},
+		{"timestamp kedaluwarsa", kamera(true, nil), ts(-10 * time.Minute), "n-5",
+			ComputeCameraSignature("secret-baru", ts(-10*time.Minute), "n-5", body), ErrSignatureExpired},
+		{"timestamp terlalu maju", kamera(true, nil), ts(10 * time.Minute), "n-6",
+			ComputeCameraSignature("secret-baru", ts(10*time.Minute), "n-6", body), ErrSignatureExpired},
+		{"timestamp dalam batas", kamera(true, nil), ts(-4 * time.Minute), "n-7",
+			ComputeCameraSignature("secret-baru", ts(-4*time.Minute), "n-7", body), nil},
+		{"timestamp bukan epoch", kamera(true, nil), "2025-01-06T08:00:00Z", "n-8",
+			ComputeCameraSignature("secret-baru", "2025-01-06T08:00:00Z", "n-8", body), ErrSignatureInvalid},
+		{"secret lama dalam overlap", kamera(true, &dalamOverlap), ts(0), "n-9",
+			ComputeCameraSignature("secret-lama", ts(0), "n-9", body), nil},
+		{"secret lama setelah overlap", kamera(true, &lewatOverlap), ts(0), "n-10",
+			ComputeCameraSignature("secret-lama", ts(0), "n-10", body), ErrSignatureInvalid},
+		{"secret lama tanpa masa overlap", kamera(true, nil), ts(0), "n-11",
+			ComputeCameraSignature("secret-lama", ts(0), "n-11", body), ErrSignatureInvalid},
+		{"tanpa header saat wajib", kamera(true, nil), "", "", "", ErrSignatureRequired},
+		{"tanpa header saat tidak wajib", kamera(false, nil), "", "", "", nil},
+		{"signature tanpa timestamp", kamera(false, nil), "", "n-12", ComputeCameraSignature("secret-baru", "", "n-12", body), ErrSignatureInvalid},
+		{"signature tanpa nonce", kamera(false, nil), ts(0), "", ComputeCameraSignature("secret-baru", ts(0), "", body), ErrSignatureInvalid},
+		// Kamera yang tidak mewajibkan signature tetap diperiksa jika mengirim signature
+		{"signature salah saat tidak wajib", kamera(false, nil), ts(0), "n-13", "abcdef", ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		err := VerifyCameraSignature(tt.camera, body, tt.timestamp, tt.nonce, tt.signature)
+		if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: error %v, ingin %v", tt.nama, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyCameraSignatureReplay(t *testing.T) {
+	store := nonceStoreUji(t)
+
+	camera := &Camera{ID: "CAM-00001", SigningSecret: "secret-baru", RequireSignature: true}
+	body := []byte("<Root/>")
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := ComputeCameraSignature("secret-baru", ts, "n-1", body)
+
+	// Request palsu dengan nonce yang sama tidak boleh menghabiskan nonce kamera
+	if err := VerifyCameraSignature(camera, body, ts, "n-1", "abcdef"); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("signature palsu: error %v", err)
+	}
+	if len(store) != 0 {
+		t.Fatalf("nonce tercatat dari signature palsu: %v", store)
+	}
+
+	if err := VerifyCameraSignature(camera, body, ts, "n-1", signature); err != nil {
+		t.Fatalf("request pertama: %v", err)
+	}
+	if err := VerifyCameraSignature(camera, body, ts, "n-1", signature); !errors.Is(err, ErrSignatureReplay) {
+		t.Fatalf("request ulang: error %v, ingin ErrSignatureReplay", err)
+	}
+
+	// Nonce dicatat per kamera dan kedaluwarsa setelah dua kali batas selisih timestamp
+	entry, ok := store["CAM-00001|n-1"]
+	if !ok || entry.CameraID != "CAM-00001" {
+		t.Fatalf("nonce tersimpan = %v", store)
+	}
+	if sisa := time.Until(entry.ExpiresAt); sisa < 2*cameraSignatureMaxSkew-time.Minute || sisa > 2*cameraSignatureMaxSkew {
+		t.Fatalf("nonce kedaluwarsa dalam %v, ingin %v", sisa, 2*cameraSignatureMaxSkew)
+	}
+
+	other := &Camera{ID: "CAM-00002", SigningSecret: "secret-baru"}
+	if err := VerifyCameraSignature(other, body, ts, "n-1", signature); err != nil {
+		t.Fatalf("nonce sama dari kamera lain: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CapacityStandard adalah standar perhitungan kapasitas jalan (MKJI 1997, PKJI 2023, ...).
+// Setiap standar menentukan pemetaan kelas kendaraan ke kategori, nilai ekivalen (smp/skr),
+// faktor penyesuaian kapasitas, dan batas tingkat pelayanan.
+type CapacityStandard interface {
+	Method() AnalysisMethod
+	// Satuan ekivalen arus, misalnya "smp" atau "skr"
+	UnitEkivalen() string
+	// Urutan kategori kendaraan standar (misalnya MC, LV, HV, UM)
+	Categories() []string
+	Kategori(tipeLokasi string, kelas int) string
+	Ekivalen(kategori string, tipeLokasi string) float64
+	// Kategori yang dihitung sebagai kendaraan bermotor
+	IsMotorized(kategori string) bool
+	Kapasitas(location Location) CapacityResult
+	TingkatPelayanan(derajatKejenuhan float64) (string, string)
+	// Batas atas derajat kejenuhan tingkat pelayanan A sampai E; di atas batas terakhir = F
+	BatasTingkatPelayanan() []float64
+}
+
+// tingkatPelayananDariBatas memilih tingkat pelayanan A-F dari batas atas DS A-E dan keterangan A-F
+func tingkatPelayananDariBatas(derajatKejenuhan float64, batas []float64, keterangan []string) (string, string) {
+	for i, b := range batas {
+		if derajatKejenuhan <= b {
+			return string(rune('A' + i)), keterangan[i]
+		}
+	}
+	return string(rune('A' + len(batas))), keterangan[len(batas)]
+}
+
+// Satu faktor penyesuaian kapasitas (misalnya FCW = 1.00)
+type CapacityFactor struct {
+	Kode  string  `bson:"kode" json:"kode"`
+	Nilai float64 `bson:"nilai" json:"nilai"`
+}
+
+// Hasil perhitungan kapasitas: C = C0 × semua faktor
+type CapacityResult struct {
+	KapasitasDasar float64          `bson:"kapasitas_dasar" json:"kapasitas_dasar"`
+	Faktor         []CapacityFactor `bson:"faktor" json:"faktor"`
+	Kapasitas      float64          `bson:"kapasitas" json:"kapasitas"`
+}
+
+// Factor mengembalikan nilai faktor berdasarkan kode, 1.0 jika tidak dipakai standar
+func (r CapacityResult) Factor(kode string) float64 {
+	for _, f := range r.Faktor {
+		if f.Kode == kode {
+			return f.Nilai
+		}
+	}
+	return 1.0
+}
+
+// newCapacityResult menghitung kapasitas dari kapasitas dasar dan faktor-faktornya
+func newCapacityResult(kapasitasDasar float64, faktor ...CapacityFactor) CapacityResult {
+	kapasitas := kapasitasDasar
+	for _, f := range faktor {
+		kapasitas *= f.Nilai
+	}
+	return CapacityResult{KapasitasDasar: kapasitasDasar, Faktor: faktor, Kapasitas: kapasitas}
+}
+
+var (
+	capacityStandardsMu sync.RWMutex
+	capacityStandards   = map[AnalysisMethod]CapacityStandard{}
+)
+
+// RegisterCapacityStandard mendaftarkan standar kapasitas berdasarkan AnalysisMethod-nya
+func RegisterCapacityStandard(standard CapacityStandard) {
+	capacityStandardsMu.Lock()
+	defer capacityStandardsMu.Unlock()
+	capacityStandards[standard.Method()] = standard
+}
+
+func GetCapacityStandard(method AnalysisMethod) (CapacityStandard, error) {
+	capacityStandardsMu.RLock()
+	defer capacityStandardsMu.RUnlock()
+	standard, ok := capacityStandards[method]
+	if !ok {
+		return nil, fmt.Errorf("standar kapasitas %s tidak terdaftar", method)
+	}
+	return standard, nil
+}
+
+// CapacityStandards mengembalikan semua standar terdaftar, diurutkan berdasarkan method
+func CapacityStandards() []CapacityStandard {
+	capacityStandardsMu.RLock()
+	defer capacityStandardsMu.RUnlock()
+	standards := make([]CapacityStandard, 0, len(capacityStandards))
+	for _, standard := range capacityStandards {
+		standards = append(standards, standard)
+	}
+	sort.Slice(standards, func(i, j int) bool { return standards[i].Method() < standards[j].Method() })
+	return standards
+}
+
+func init() {
+	RegisterCapacityStandard(MKJI1997Standard{})
+	RegisterCapacityStandard(PKJI2023Standard{})
+}
+
+// RecordStandard diimplementasikan standar yang kategori dan nilai ekivalennya bergantung pada record
+// (waktu dan arus data), misalnya EkivalenResolver dengan tabel berversi dari database
+type RecordStandard interface {
+	KategoriRecord(td TrafficData, tipeLokasi string, kelas int) string
+	EkivalenRecord(td TrafficData, kategori string, tipeLokasi string) float64
+}
+
+// kategoriKelas memetakan kelas kendaraan pada record ke kategori standar
+func kategoriKelas(standard CapacityStandard, td TrafficData, tipeLokasi string, kelas int) string {
+	if rs, ok := standard.(RecordStandard); ok {
+		return rs.KategoriRecord(td, tipeLokasi, kelas)
+	}
+	return standard.Kategori(tipeLokasi, kelas)
+}
+
+// ekivalenKategori mengembalikan nilai ekivalen kategori yang berlaku untuk record
+func ekivalenKategori(standard CapacityStandard, td TrafficData, kategori string, tipeLokasi string) float64 {
+	if rs, ok := standard.(RecordStandard); ok {
+		return rs.EkivalenRecord(td, kategori, tipeLokasi)
+	}
+	return standard.Ekivalen(kategori, tipeLokasi)
+}
+
+// Jumlah kendaraan per kategori standar beserta total ekivalennya
+type CategoryCount struct {
+	Counts        map[string]int `json:"counts"`
+	TotalMotor    int            `json:"total_motor"`
+	TotalEkivalen float64        `json:"total_ekivalen"`
+}
+
+// HitungCategoryCount menjumlahkan kendaraan per kategori dan total ekivalen (smp/skr)
+func HitungCategoryCount(standard CapacityStandard, trafficDataList []TrafficData, tipeLokasi string) CategoryCount {
+	count := CategoryCount{Counts: make(map[string]int)}
+	for _, kategori := range standard.Categories() {
+		count.Counts[kategori] = 0
+	}
+
+	for _, td := range trafficDataList {
+		for _, za := range td.ZonaArahData {
+			for _, kd := range za.KelasData {
+				kategori := kategoriKelas(standard, td, tipeLokasi, kd.Kelas)
+				count.Counts[kategori] += kd.JumlahKendaraan
+				if !standard.IsMotorized(kategori) {
+					continue
+				}
+				count.TotalMotor += kd.JumlahKendaraan
+				count.TotalEkivalen += float64(kd.JumlahKendaraan) * ekivalenKategori(standard, td, kategori, tipeLokasi)
+			}
+		}
+	}
+	return count
+}
+
+// HitungArusJamPuncak mengembalikan arus ekivalen jam puncak (jendela 60 menit bergulir) dan rentang jam puncaknya
+func HitungArusJamPuncak(standard CapacityStandard, trafficDataList []TrafficData, tipeLokasi string) (float64, string) {
+	if len(trafficDataList) == 0 {
+		return 0, ""
+	}
+	peak := HitungPeakHour(standard, trafficDataList, tipeLokasi)
+	return peak.Puncak.Arus, peak.Puncak.JamPuncak
+}
+
+// Kinerja ruas untuk satu nilai arus: kapasitas, derajat kejenuhan, dan tingkat pelayanan
+type CapacityPerformance struct {
+	CapacityResult   `bson:",inline"`
+	Arus             float64 `bson:"arus" json:"arus"`
+	DerajatKejenuhan float64 `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`
+	TingkatPelayanan string  `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"`
+	Keterangan       string  `bson:"keterangan" json:"keterangan"`
+}
+
+// EvaluateCapacity menghitung kapasitas lokasi lalu DS/DJ dan tingkat pelayanan untuk arus (ekivalen/jam)
+func EvaluateCapacity(standard CapacityStandard, location Location, arus float64) CapacityPerformance {
+	result := standard.Kapasitas(location)
+	derajatKejenuhan := HitungDerajatKejenuhan(arus, result.Kapasitas)
+	tingkatPelayanan, keterangan := standard.TingkatPelayanan(derajatKejenuhan)
+	return CapacityPerformance{
+		CapacityResult:   result,
+		Arus:             arus,
+		DerajatKejenuhan: derajatKejenuhan,
+		TingkatPelayanan: tingkatPelayanan,
+		Keterangan:       keterangan,
+	}
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// Lokasi uji dengan faktor penyesuaian yang tidak bernilai 1 (lebar 6 m, 60-40, kereb kelas M, kota 0.8 juta)
+func lokasiKapasitasUji(tipeLokasi, tipeArah string) Location {
+	return Location{
+		Tipe_lokasi:    tipeLokasi,
+		Tipe_arah:      tipeArah,
+		Lebar_jalur:    6,
+		Persentase:     "60-40",
+		Tipe_hambatan:  "kereb",
+		Kelas_hambatan: "M",
+		Ukuran_kota:    0.8,
+	}
+}
+
+// Nilai baseline sebelum mesin CapacityStandard: C0 dan kapasitas satu arah (jalan terbagi) serta LoS
+// untuk arus 2500 ekivalen/jam terhadap kapasitas tersebut. Diambil dari perhitungan real-time lama
+// (switch Tipe_lokasi MKJI dan HitungKapasitasPKJI).
+var kapasitasBaseline = []struct {
+	tipeLokasi string
+	tipeArah   string
+	co         float64
+	kapasitas  float64
+	los        string
+	c0PKJI     float64
+	kapasitasP float64
+	losPKJI    string
+}{
+	{"perkotaan", "22ud", 2900, 2028.6837, "F", 2900, 1984.0973, "F"},
+	{"perkotaan", "42ud", 3000, 2195.1275, "F", 3000, 2146.8829, "F"},
+	{"perkotaan", "42d", 3300, 2568.7662, "E", 3300, 2512.3098, "E"},
+	{"perkotaan", "62d", 4950, 3853.1493, "C", 4950, 3768.4647, "C"},
+	{"luar_kota", "22ud", 2900, 2158.1742, "F", 3100, 2256.3102, "F"},
+	{"luar_kota", "42ud", 3000, 2335.2420, "F", 3100, 2360.0486, "F"},
+	{"luar_kota", "42d", 3300, 2732.7300, "E", 3400, 2753.6600, "D"},
+	{"luar_kota", "62d", 4950, 4099.0950, "C", 5100, 4130.4900, "C"},
+	{"bebas_hambatan", "22ud", 4600, 3761.8800, "C", 4600, 4107.8000, "C"},
+	{"bebas_hambatan", "42ud", 4600, 3934.8400, "C", 4600, 4107.8000, "C"},
+	{"bebas_hambatan", "42d", 4600, 4186.0000, "C", 4600, 4370.0000, "C"},
+	{"bebas_hambatan", "62d", 6900, 6279.0000, "B", 6900, 6555.0000, "B"},
+	{"12_kelas", "22ud", 2900, 2158.1742, "F", 3100, 2256.3102, "F"},
+	{"12_kelas", "42ud", 3000, 2335.2420, "F", 3100, 2360.0486, "F"},
+	{"12_kelas", "42d", 3300, 2732.7300, "E", 3400, 2753.6600, "D"},
+	{"12_kelas", "62d", 4950, 4099.0950, "C", 5100, 4130.4900, "C"},
+}
+
+// Kapasitas lokasi jalan terbagi adalah dua kali kapasitas satu arah baseline; kinerja per arah dan LoS
+// jalan tak terbagi harus sama dengan baseline.
+func TestKapasitasBaseline(t *testing.T) {
+	const arus = 2500
+	for _, tt := range kapasitasBaseline {
+		location := lokasiKapasitasUji(tt.tipeLokasi, tt.tipeArah)
+		jumlahArah := float64(jumlahArahKapasitas(tt.tipeArah))
+
+		for _, s := range []struct {
+			standard  CapacityStandard
+			c0        float64
+			kapasitas float64
+			los       string
+		}{
+			{MKJI1997Standard{}, tt.co, tt.kapasitas, tt.los},
+			{PKJI2023Standard{}, tt.c0PKJI, tt.kapasitasP, tt.losPKJI},
+		} {
+			nama := string(s.standard.Method()) + " " + tt.tipeLokasi + " " + tt.tipeArah
+
+			result := s.standard.Kapasitas(location)
+			if result.KapasitasDasar != s.c0*jumlahArah || math.Abs(result.Kapasitas-s.kapasitas*jumlahArah) > 1e-3 {
+				t.Errorf("%s: C0 %.0f C %.4f, ingin %.0f dan %.4f", nama, result.KapasitasDasar, result.Kapasitas,
+					s.c0*jumlahArah, s.kapasitas*jumlahArah)
+			}
+
+			perArah := evaluateCapacityPerArah(s.standard, location, arus)
+			if math.Abs(perArah.Kapasitas-s.kapasitas) > 1e-3 || perArah.TingkatPelayanan != s.los {
+				t.Errorf("%s: kapasitas per arah %.4f LoS %s, ingin %.4f dan %s", nama, perArah.Kapasitas,
+					perArah.TingkatPelayanan, s.kapasitas, s.los)
+			}
+
+			if !IsDividedRoad(tt.tipeArah) {
+				kinerja := EvaluateCapacity(s.standard, location, arus)
+				if kinerja.TingkatPelayanan != s.los || math.Abs(kinerja.DerajatKejenuhan-arus/s.kapasitas) > 1e-6 {
+					t.Errorf("%s: DS %.4f LoS %s, ingin %.4f dan %s", nama, kinerja.DerajatKejenuhan,
+						kinerja.TingkatPelayanan, arus/s.kapasitas, s.los)
+				}
+			}
+		}
+	}
+}
+
+// HitungKapasitas dan HitungKapasitasPKJI (dipakai analisis tersimpan) harus sama dengan standar
+func TestHitungKapasitasSamaDenganStandar(t *testing.T) {
+	for _, tt := range kapasitasBaseline {
+		location := lokasiKapasitasUji(tt.tipeLokasi, tt.tipeArah)
+		if kapasitas, _, _, _, _, _ := HitungKapasitas(location); kapasitas != (MKJI1997Standard{}).Kapasitas(location).Kapasitas {
+			t.Errorf("MKJI %s %s: HitungKapasitas %.4f berbeda dengan standar", tt.tipeLokasi, tt.tipeArah, kapasitas)
+		}
+		if kapasitas, _, _, _, _, _ := HitungKapasitasPKJI(location); kapasitas != (PKJI2023Standard{}).Kapasitas(location).Kapasitas {
+			t.Errorf("PKJI %s %s: HitungKapasitasPKJI %.4f berbeda dengan standar", tt.tipeLokasi, tt.tipeArah, kapasitas)
+		}
+	}
+}
+
+func TestTingkatPelayananBatas(t *testing.T) {
+	tests := []struct {
+		standard CapacityStandard
+		ds       float64
+		want     string
+	}{
+		{MKJI1997Standard{}, 0, "A"},
+		{MKJI1997Standard{}, 0.35, "A"},
+		{MKJI1997Standard{}, 0.3501, "B"},
+		{MKJI1997Standard{}, 0.55, "B"},
+		{MKJI1997Standard{}, 0.75, "C"},
+		{MKJI1997Standard{}, 0.85, "D"},
+		{MKJI1997Standard{}, 1.00, "E"},
+		{MKJI1997Standard{}, 1.0001, "F"},
+		{PKJI2023Standard{}, 0.35, "A"},
+		{PKJI2023Standard{}, 0.54, "B"},
+		{PKJI2023Standard{}, 0.55, "C"},
+		{PKJI2023Standard{}, 0.77, "C"},
+		{PKJI2023Standard{}, 0.93, "D"},
+		{PKJI2023Standard{}, 0.94, "E"},
+		{PKJI2023Standard{}, 1.00, "E"},
+		{PKJI2023Standard{}, 1.2, "F"},
+	}
+	for _, tt := range tests {
+		got, keterangan := tt.standard.TingkatPelayanan(tt.ds)
+		if got != tt.want || keterangan == "" {
+			t.Errorf("%s DS %.4f: LoS %s (%q), ingin %s", tt.standard.Method(), tt.ds, got, keterangan, tt.want)
+		}
+	}
+}
+
+func TestGetCapacityStandard(t *testing.T) {
+	for _, method := range []AnalysisMethod{AnalysisMethodMKJI1997, AnalysisMethodPKJI2023} {
+		standard, err := GetCapacityStandard(method)
+		if err != nil || standard.Method() != method {
+			t.Errorf("%s: standar %v, error %v", method, standard, err)
+		}
+	}
+	if _, err := GetCapacityStandard("HCM2016"); err == nil {
+		t.Error("metode tidak terdaftar harus mengembalikan error")
+	}
+}
+
+func TestHitungCategoryCount(t *testing.T) {
+	td := TrafficData{ZonaArahData: []TrafficZonaArahData{{
+		IDZonaArah: "ZA-1",
+		KelasData: []TrafficKelasDetail{
+			{Kelas: 1, JumlahKendaraan: 10}, // MC 0.4 smp
+			{Kelas: 2, JumlahKendaraan: 5},  // LV 1.0 smp
+			{Kelas: 3, JumlahKendaraan: 2},  // HV 1.3 smp
+		},
+	}}}
+	count := HitungCategoryCount(MKJI1997Standard{}, []TrafficData{td}, "perkotaan")
+	if count.Counts["MC"] != 10 || count.Counts["LV"] != 5 || count.Counts["HV"] != 2 || count.Counts["UM"] != 0 {
+		t.Fatalf("counts = %v", count.Counts)
+	}
+	if count.TotalMotor != 17 || math.Abs(count.TotalEkivalen-11.6) > 1e-9 {
+		t.Fatalf("total motor %d ekivalen %.2f, ingin 17 dan 11.60", count.TotalMotor, count.TotalEkivalen)
+	}
+}
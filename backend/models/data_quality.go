@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Kode flag kualitas data interval
+const (
+	QualityFlagSpeedCeiling     = "speed_ceiling"      // kecepatan di atas batas wajar
+	QualityFlagOccupancyOver100 = "occupancy_over_100" // okupansi lebih dari 100%
+	QualityFlagZeroCountHighOcc = "zero_count_high_occupancy"
+	QualityFlagLowConfidence    = "low_confidence"
+	QualityFlagNegativeValue    = "negative_value" // headway, density, gap time, atau kecepatan negatif
+	QualityFlagMissingZone      = "missing_zone"   // zona terdaftar tidak ada di payload
+	QualityFlagExtraZone        = "extra_zone"     // zona di payload tidak terdaftar di kamera
+)
+
+// Tingkat keparahan flag. Interval dengan flag error dianggap tidak layak untuk analisis.
+const (
+	QualitySeverityWarning = "warning"
+	QualitySeverityError   = "error"
+)
+
+// Batas-batas pemeriksaan kualitas data
+type DataQualityConfig struct {
+	MaxSpeed               float64 // km/jam
+	MinConfidence          float64 // persen, 0 dianggap tidak dilaporkan kamera
+	ZeroCountOccupancy     float64 // okupansi (%) minimum untuk flag zero_count_high_occupancy
+	MinScore               float64 // interval dengan skor di bawah ini ditandai flagged
+	ExcludeFlaggedAnalysis bool    // analisis terjadwal mengecualikan interval flagged
+}
+
+var dataQualityConfig = DataQualityConfig{
+	MaxSpeed:           200,
+	MinConfidence:      50,
+	ZeroCountOccupancy: 20,
+	MinScore:           50,
+}
+
+// Pengurang skor per flag
+var qualityPenalty = map[string]float64{
+	QualitySeverityWarning: 10,
+	QualitySeverityError:   40,
+}
+
+// SetDataQualityConfig mengganti batas pemeriksaan kualitas data (dipanggil saat startup)
+func SetDataQualityConfig(cfg DataQualityConfig) {
+	dataQualityConfig = cfg
+}
+
+func GetDataQualityConfig() DataQualityConfig {
+	return dataQualityConfig
+}
+
+// Satu temuan kualitas data pada zona/kelas tertentu
+type QualityFlag struct {
+	Code     string  `bson:"code" json:"code"`
+	Severity string  `bson:"severity" json:"severity"`
+	ZoneID   int     `bson:"zone_id,omitempty" json:"zone_id,omitempty"`
+	Kelas    int     `bson:"kelas,omitempty" json:"kelas,omitempty"`
+	Value    float64 `bson:"value,omitempty" json:"value,omitempty"`
+	Message  string  `bson:"message" json:"message"`
+}
+
+// Hasil pemeriksaan kualitas satu interval
+type TrafficDataQuality struct {
+	Score   float64       `bson:"score" json:"score"` // 0-100
+	Flagged bool          `bson:"flagged" json:"flagged"`
+	Flags   []QualityFlag `bson:"flags" json:"flags"`
+}
+
+// EvaluateIntervalQuality memeriksa nilai zona dan kelas pada satu interval.
+// configuredZones berisi zona terdaftar di kamera (key = ZoneId); nil melewati pemeriksaan zona hilang/berlebih.
+func EvaluateIntervalQuality(zones []CameraZone, configuredZones map[int]CameraZonaArah) *TrafficDataQuality {
+	cfg := dataQualityConfig
+	flags := []QualityFlag{}
+	add := func(flag QualityFlag) {
+		flags = append(flags, flag)
+	}
+
+	incoming := make(map[int]bool)
+	for _, zone := range zones {
+		incoming[zone.ZoneId] = true
+		if configuredZones != nil {
+			if _, exists := configuredZones[zone.ZoneId]; !exists {
+				add(QualityFlag{Code: QualityFlagExtraZone, Severity: QualitySeverityWarning, ZoneID: zone.ZoneId,
+					Message: fmt.Sprintf("zona %d tidak terdaftar di kamera", zone.ZoneId)})
+				continue
+			}
+		}
+
+		if zone.Occupancy > 100 {
+			add(QualityFlag{Code: QualityFlagOccupancyOver100, Severity: QualitySeverityError, ZoneID: zone.ZoneId, Value: zone.Occupancy,
+				Message: fmt.Sprintf("okupansi zona %d %.1f%% melebihi 100%%", zone.ZoneId, zone.Occupancy)})
+		}
+		if zone.Occupancy < 0 || zone.HeadWay < 0 || zone.Density < 0 {
+			add(QualityFlag{Code: QualityFlagNegativeValue, Severity: QualitySeverityError, ZoneID: zone.ZoneId,
+				Message: fmt.Sprintf("zona %d memiliki okupansi/headway/density negatif", zone.ZoneId)})
+		}
+		if zone.Confidence > 0 && zone.Confidence < cfg.MinConfidence {
+			add(QualityFlag{Code: QualityFlagLowConfidence, Severity: QualitySeverityWarning, ZoneID: zone.ZoneId, Value: zone.Confidence,
+				Message: fmt.Sprintf("confidence zona %d %.1f%% di bawah %.0f%%", zone.ZoneId, zone.Confidence, cfg.MinConfidence)})
+		}
+
+		zoneCount := 0
+		for _, class := range zone.Classes {
+			zoneCount += class.NumVeh
+			if class.NumVeh < 0 || class.Speed < 0 || class.GapTime < 0 {
+				add(QualityFlag{Code: QualityFlagNegativeValue, Severity: QualitySeverityError, ZoneID: zone.ZoneId, Kelas: class.ClassNr,
+					Message: fmt.Sprintf("kelas %d zona %d memiliki jumlah/kecepatan/gap time negatif", class.ClassNr, zone.ZoneId)})
+			}
+			if cfg.MaxSpeed > 0 && class.Speed > cfg.MaxSpeed {
+				add(QualityFlag{Code: QualityFlagSpeedCeiling, Severity: QualitySeverityError, ZoneID: zone.ZoneId, Kelas: class.ClassNr, Value: class.Speed,
+					Message: fmt.Sprintf("kecepatan kelas %d zona %d %.1f km/jam melebihi %.0f km/jam", class.ClassNr, zone.ZoneId, class.Speed, cfg.MaxSpeed)})
+			}
+		}
+		if zoneCount == 0 && cfg.ZeroCountOccupancy > 0 && zone.Occupancy >= cfg.ZeroCountOccupancy {
+			add(QualityFlag{Code: QualityFlagZeroCountHighOcc, Severity: QualitySeverityWarning, ZoneID: zone.ZoneId, Value: zone.Occupancy,
+				Message: fmt.Sprintf("zona %d tanpa kendaraan tetapi okupansi %.1f%%", zone.ZoneId, zone.Occupancy)})
+		}
+	}
+
+	if configuredZones != nil {
+		var missing []int
+		for zoneID := range configuredZones {
+			if !incoming[zoneID] {
+				missing = append(missing, zoneID)
+			}
+		}
+		sort.Ints(missing)
+		for _, zoneID := range missing {
+			add(QualityFlag{Code: QualityFlagMissingZone, Severity: QualitySeverityWarning, ZoneID: zoneID,
+				Message: fmt.Sprintf("zona %d terdaftar di kamera tetapi tidak ada di payload", zoneID)})
+		}
+	}
+
+	quality := &TrafficDataQuality{Score: 100, Flags: flags}
+	for _, flag := range flags {
+		quality.Score -= qualityPenalty[flag.Severity]
+		if flag.Severity == QualitySeverityError {
+			quality.Flagged = true
+		}
+	}
+	if quality.Score < 0 {
+		quality.Score = 0
+	}
+	if quality.Score < cfg.MinScore {
+		quality.Flagged = true
+	}
+	return quality
+}
+
+// rawZonesToCameraZones mengubah zona raw data ke bentuk CameraZone untuk pemeriksaan ulang kualitas
+func rawZonesToCameraZones(zonaData []RawZonaData) []CameraZone {
+	zones := make([]CameraZone, 0, len(zonaData))
+	for _, z := range zonaData {
+		classes := make([]CameraClass, 0, len(z.KelasData))
+		for _, k := range z.KelasData {
+			classes = append(classes, CameraClass{ClassNr: k.Kelas, NumVeh: k.JumlahKendaraan, Speed: k.Kecepatan, GapTime: k.GapTime})
+		}
+		zones = append(zones, CameraZone{
+			ZoneId:     z.ZonaID,
+			Occupancy:  z.Occupancy,
+			Confidence: z.Confidence,
+			Length:     z.Length,
+			HeadWay:    z.HeadWay,
+			Density:    z.Density,
+			Classes:    classes,
+		})
+	}
+	return zones
+}
+
+// GetTrafficDataForAnalysis mengambil traffic data untuk analisis, opsional tanpa interval flagged.
+// Mengembalikan juga jumlah interval yang dikecualikan.
+func GetTrafficDataForAnalysis(lokasiID string, startTime, endTime time.Time, excludeFlagged bool) ([]TrafficData, int, error) {
+	trafficDataList, err := GetTrafficDataByLokasiID(lokasiID, startTime, endTime)
+	if err != nil || !excludeFlagged {
+		return trafficDataList, 0, err
+	}
+
+	filtered := make([]TrafficData, 0, len(trafficDataList))
+	for _, td := range trafficDataList {
+		if td.DataQuality != nil && td.DataQuality.Flagged {
+			continue
+		}
+		filtered = append(filtered, td)
+	}
+	return filtered, len(trafficDataList) - len(filtered), nil
+}
+
+// Ringkasan kualitas data per lokasi dalam rentang waktu
+type DataQualitySummary struct {
+	LokasiID        string         `json:"lokasi_id"`
+	TotalInterval   int            `json:"total_interval"`
+	FlaggedInterval int            `json:"flagged_interval"`
+	UncheckedCount  int            `json:"unchecked_interval"` // data lama tanpa pemeriksaan kualitas
+	AvgScore        float64        `json:"avg_score"`
+	FlagCounts      map[string]int `json:"flag_counts"`
+}
+
+// GetDataQualitySummary menghitung ringkasan kualitas traffic data satu lokasi
+func GetDataQualitySummary(lokasiID string, startTime, endTime time.Time) (*DataQualitySummary, error) {
+	collection := database.DB.Collection("traffic_data")
+
+	filter := bson.M{
+		"lokasi_id": lokasiID,
+		"timestamp": bson.M{"$gte": startTime, "$lte": endTime},
+	}
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var trafficDataList []TrafficData
+	if err = cursor.All(context.Background(), &trafficDataList); err != nil {
+		return nil, err
+	}
+
+	summary := &DataQualitySummary{LokasiID: lokasiID, FlagCounts: make(map[string]int)}
+	scoreTotal := 0.0
+	for _, td := range trafficDataList {
+		summary.TotalInterval++
+		if td.DataQuality == nil {
+			summary.UncheckedCount++
+			continue
+		}
+		scoreTotal += td.DataQuality.Score
+		if td.DataQuality.Flagged {
+			summary.FlaggedInterval++
+		}
+		for _, flag := range td.DataQuality.Flags {
+			summary.FlagCounts[flag.Code]++
+		}
+	}
+	if checked := summary.TotalInterval - summary.UncheckedCount; checked > 0 {
+		summary.AvgScore = scoreTotal / float64(checked)
+	}
+	return summary, nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func zonaNormalUji(zoneID int) CameraZone {
+	return CameraZone{ZoneId: zoneID, Occupancy: 12, Confidence: 90, HeadWay: 4, Density: 15,
+		Classes: []CameraClass{{ClassNr: 2, NumVeh: 20, Speed: 45, GapTime: 2}}}
+}
+
+func TestEvaluateIntervalQuality(t *testing.T) {
+	terdaftar := map[int]CameraZonaArah{1: {}, 2: {}}
+	ubah := func(fn func(z *CameraZone)) []CameraZone {
+		z1, z2 := zonaNormalUji(1), zonaNormalUji(2)
+		fn(&z1)
+		return []CameraZone{z1, z2}
+	}
+
+	tests := []struct {
+		nama       string
+		zones      []CameraZone
+		configured map[int]CameraZonaArah
+		flags      []string // kode flag berurutan
+		skor       float64
+		flagged    bool
+	}{
+		{"bersih", ubah(func(z *CameraZone) {}), terdaftar, nil, 100, false},
+		{"kecepatan di atas batas", ubah(func(z *CameraZone) { z.Classes[0].Speed = 250 }), terdaftar,
+			[]string{QualityFlagSpeedCeiling}, 60, true},
+		{"kecepatan tepat di batas", ubah(func(z *CameraZone) { z.Classes[0].Speed = 200 }), terdaftar, nil, 100, false},
+		{"okupansi lebih dari 100", ubah(func(z *CameraZone) { z.Occupancy = 120 }), terdaftar,
+			[]string{QualityFlagOccupancyOver100}, 60, true},
+		{"tanpa kendaraan dengan okupansi tinggi", ubah(func(z *CameraZone) { z.Classes[0].NumVeh = 0; z.Occupancy = 35 }), terdaftar,
+			[]string{QualityFlagZeroCountHighOcc}, 90, false},
+		{"tanpa kendaraan dengan okupansi rendah", ubah(func(z *CameraZone) { z.Classes[0].NumVeh = 0; z.Occupancy = 5 }), terdaftar, nil, 100, false},
+		{"confidence rendah", ubah(func(z *CameraZone) { z.Confidence = 30 }), terdaftar,
+			[]string{QualityFlagLowConfidence}, 90, false},
+		// Confidence 0 berarti kamera tidak melaporkannya
+		{"confidence tidak dilaporkan", ubah(func(z *CameraZone) { z.Confidence = 0 }), terdaftar, nil, 100, false},
+		{"headway negatif", ubah(func(z *CameraZone) { z.HeadWay = -1 }), terdaftar,
+			[]string{QualityFlagNegativeValue}, 60, true},
+		{"gap time kelas negatif", ubah(func(z *CameraZone) { z.Classes[0].GapTime = -0.5 }), terdaftar,
+			[]string{QualityFlagNegativeValue}, 60, true},
+		{"zona terdaftar hilang", []CameraZone{zonaNormalUji(1)}, terdaftar,
+			[]string{QualityFlagMissingZone}, 90, false},
+		// Zona berlebih tidak diperiksa lebih lanjut karena datanya diabaikan
+		{"zona tidak terdaftar", append(ubah(func(z *CameraZone) {}), CameraZone{ZoneId: 9, Occupancy: 150}), terdaftar,
+			[]string{QualityFlagExtraZone}, 90, false},
+		{"tanpa konfigurasi zona", []CameraZone{zonaNormalUji(9)}, nil, nil, 100, false},
+		// Skor di bawah MinScore ditandai walaupun semua flag hanya warning
+		{"banyak warning", []CameraZone{
+			{ZoneId: 1, Confidence: 10, Occupancy: 40},
+			{ZoneId: 2, Confidence: 10, Occupancy: 40},
+			{ZoneId: 5}, {ZoneId: 6},
+		}, terdaftar, []string{
+			QualityFlagLowConfidence, QualityFlagZeroCountHighOcc,
+			QualityFlagLowConfidence, QualityFlagZeroCountHighOcc,
+			QualityFlagExtraZone, QualityFlagExtraZone,
+		}, 40, true},
+		// Skor tidak turun di bawah 0
+		{"skor minimum", ubah(func(z *CameraZone) {
+			z.Occupancy = 120
+			z.HeadWay = -1
+			z.Classes = append(z.Classes, CameraClass{ClassNr: 3, NumVeh: 1, Speed: 300}, CameraClass{ClassNr: 4, NumVeh: 1, Speed: -5})
+		}), terdaftar, []string{
+			QualityFlagOccupancyOver100, QualityFlagNegativeValue, QualityFlagSpeedCeiling, QualityFlagNegativeValue,
+		}, 0, true},
+	}
+	for _, tt := range tests {
+		q := EvaluateIntervalQuality(tt.zones, tt.configured)
+		var kode []string
+		for _, f := range q.Flags {
+			kode = append(kode, f.Code)
+			if f.Message == "" {
+				t.Errorf("%s: flag %s tanpa pesan", tt.nama, f.Code)
+			}
+		}
+		if strings.Join(kode, ",") != strings.Join(tt.flags, ",") {
+			t.Errorf("%s: flag %v, ingin %v", tt.nama, kode, tt.flags)
+		}
+		if q.Score != tt.skor || q.Flagged != tt.flagged {
+			t.Errorf("%s: skor %.0f flagged %v, ingin %.0f dan %v", tt.nama, q.Score, q.Flagged, tt.skor, tt.flagged)
+		}
+		if q.Score < 0 || q.Score > 100 {
+			t.Errorf("%s: skor %.0f di luar 0-100", tt.nama, q.Score)
+		}
+	}
+}
+
+func TestEvaluateIntervalQualityConfig(t *testing.T) {
+	asli := GetDataQualityConfig()
+	defer SetDataQualityConfig(asli)
+
+	// Batas 0 menonaktifkan pemeriksaan kecepatan dan zero count
+	SetDataQualityConfig(DataQualityConfig{MinConfidence: 50, MinScore: 95})
+	z := zonaNormalUji(1)
+	z.Classes[0].Speed = 400
+	z.Classes[0].NumVeh = 0
+	z.Occupancy = 80
+	z.Confidence = 40
+	q := EvaluateIntervalQuality([]CameraZone{z}, nil)
+	if len(q.Flags) != 1 || q.Flags[0].Code != QualityFlagLowConfidence || q.Flags[0].Value != 40 {
+		t.Fatalf("flag %+v, ingin hanya low_confidence", q.Flags)
+	}
+	if q.Score != 90 || !q.Flagged {
+		t.Fatalf("skor %.0f flagged %v, ingin 90 dan flagged karena MinScore 95", q.Score, q.Flagged)
+	}
+}
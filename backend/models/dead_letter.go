@@ -0,0 +1,321 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Status entri dead-letter
+const (
+	DeadLetterStatusPending   = "pending"
+	DeadLetterStatusReplayed  = "replayed"
+	DeadLetterStatusDiscarded = "discarded"
+)
+
+// Header yang tidak ikut disimpan karena berisi kredensial
+var deadLetterExcludedHeaders = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"x-signature":   true,
+}
+
+// ErrDeadLetterBukanPending dikembalikan saat replay entri yang sudah di-replay atau dibuang.
+// Payload tanpa kunci idempotensi akan tersimpan dua kali jika diproses ulang.
+var ErrDeadLetterBukanPending = errors.New("dead-letter sudah tidak berstatus pending")
+
+// Asal payload kamera, disimpan bersama dead-letter
+type IngestSource struct {
+	Endpoint string            `bson:"endpoint" json:"endpoint"`
+	SourceIP string            `bson:"source_ip" json:"source_ip"`
+	Headers  map[string]string `bson:"headers" json:"headers"`
+}
+
+type DeadLetter struct {
+	ID              string            `bson:"_id" json:"id"`
+	Body            string            `bson:"body" json:"body"`
+	Endpoint        string            `bson:"endpoint" json:"endpoint"`
+	SourceIP        string            `bson:"source_ip" json:"source_ip"`
+	Headers         map[string]string `bson:"headers" json:"headers"`
+	Stage           string            `bson:"stage" json:"stage"`
+	ErrorMessage    string            `bson:"error_message" json:"error_message"`
+	APIKey          string            `bson:"api_key,omitempty" json:"api_key,omitempty"`
+	CameraID        string            `bson:"camera_id,omitempty" json:"camera_id,omitempty"`
+	Status          string            `bson:"status" json:"status"`
+	ReplayCount     int               `bson:"replay_count" json:"replay_count"`
+	LastReplayAt    *time.Time        `bson:"last_replay_at,omitempty" json:"last_replay_at,omitempty"`
+	LastReplayError string            `bson:"last_replay_error,omitempty" json:"last_replay_error,omitempty"`
+	TrafficDataID   string            `bson:"traffic_data_id,omitempty" json:"traffic_data_id,omitempty"`
+	CreatedAt       time.Time         `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// Hasil replay satu entri dead-letter
+type DeadLetterReplayResult struct {
+	ID            string       `json:"id"`
+	Status        IngestStatus `json:"status"`
+	TrafficDataID string       `json:"traffic_data_id,omitempty"`
+	Stage         string       `json:"stage,omitempty"`
+	Reason        string       `json:"reason,omitempty"`
+}
+
+func NewIngestSource(endpoint string, sourceIP string, headers map[string][]string) *IngestSource {
+	filtered := make(map[string]string)
+	for key, values := range headers {
+		if deadLetterExcludedHeaders[strings.ToLower(key)] {
+			continue
+		}
+		filtered[key] = strings.Join(values, ", ")
+	}
+	return &IngestSource{Endpoint: endpoint, SourceIP: sourceIP, Headers: filtered}
+}
+
+func NextDeadLetterID() (string, error) {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last DeadLetter
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "DLQ-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "DLQ-%d", &lastNum)
+	return fmt.Sprintf("DLQ-%05d", lastNum+1), nil
+}
+
+// SaveDeadLetter menyimpan payload yang gagal diproses beserta tahap dan pesan errornya
+func SaveDeadLetter(body string, source *IngestSource, ingestErr *CameraIngestError) (*DeadLetter, error) {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	if source == nil {
+		source = &IngestSource{}
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	entry := &DeadLetter{
+		Body:         body,
+		Endpoint:     source.Endpoint,
+		SourceIP:     source.SourceIP,
+		Headers:      source.Headers,
+		Stage:        ingestErr.Stage,
+		ErrorMessage: ingestErr.Error(),
+		APIKey:       ingestErr.APIKey,
+		CameraID:     ingestErr.CameraID,
+		Status:       DeadLetterStatusPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// ID berurutan bisa bentrok saat beberapa worker menyimpan bersamaan, coba ulang beberapa kali
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		entry.ID, err = NextDeadLetterID()
+		if err != nil {
+			return nil, err
+		}
+		_, err = collection.InsertOne(context.Background(), entry)
+		if err == nil || !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// IngestCameraPayload memproses satu payload kamera dan menyimpannya ke dead-letter jika gagal
+// pada tahap parse, validasi API key, atau konversi.
+func IngestCameraPayload(xmlData string, source *IngestSource) (*TrafficData, IngestStatus, error) {
+	trafficData, status, err := ProcessCameraData(xmlData)
+	if err != nil {
+		var ingestErr *CameraIngestError
+		if errors.As(err, &ingestErr) {
+			if ingestErr.CameraID != "" {
+				if healthErr := RecordCameraError(ingestErr.CameraID, ingestErr.Error()); healthErr != nil {
+					log.Printf("Warning: failed to update camera health: %v", healthErr)
+				}
+			}
+			if entry, saveErr := SaveDeadLetter(xmlData, source, ingestErr); saveErr != nil {
+				log.Printf("Gagal menyimpan dead-letter: %v", saveErr)
+			} else {
+				log.Printf("Payload kamera disimpan ke dead-letter %s (tahap %s)", entry.ID, entry.Stage)
+			}
+		}
+		return nil, "", err
+	}
+
+	return trafficData, status, nil
+}
+
+func GetDeadLetters(filter bson.M, limit int64) ([]DeadLetter, error) {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"body": 0})
+
+	cursor, err := collection.Find(context.Background(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DeadLetter
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func GetDeadLetterByID(id string) (*DeadLetter, error) {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	var entry DeadLetter
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// UpdateDeadLetterBody mengganti body payload (misalnya memperbaiki XML rusak) sebelum di-replay
+func UpdateDeadLetterBody(id string, body string) error {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	update := bson.M{
+		"$set": bson.M{
+			"body":       body,
+			"updated_at": time.Now().Add(7 * time.Hour),
+		},
+	}
+
+	result, err := collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func DiscardDeadLetter(id string) error {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":     DeadLetterStatusDiscarded,
+			"updated_at": time.Now().Add(7 * time.Hour),
+		},
+	}
+
+	result, err := collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// checkDeadLetterReplay memastikan hanya entri pending yang diproses ulang
+func checkDeadLetterReplay(entry *DeadLetter) error {
+	if entry.Status != DeadLetterStatusPending {
+		return fmt.Errorf("%w (status %s)", ErrDeadLetterBukanPending, entry.Status)
+	}
+	return nil
+}
+
+// ReplayDeadLetter memproses ulang payload lewat pipeline normal dan mencatat hasilnya pada entri.
+// Entri yang tidak ada mengembalikan mongo.ErrNoDocuments, entri non-pending ErrDeadLetterBukanPending.
+func ReplayDeadLetter(id string) (*DeadLetterReplayResult, error) {
+	entry, err := GetDeadLetterByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDeadLetterReplay(entry); err != nil {
+		return nil, err
+	}
+
+	result := &DeadLetterReplayResult{ID: entry.ID}
+	now := time.Now().Add(7 * time.Hour)
+	set := bson.M{
+		"last_replay_at": now,
+		"updated_at":     now,
+	}
+
+	trafficData, status, err := ProcessCameraData(entry.Body)
+	if err != nil {
+		result.Status = IngestStatusRejected
+		result.Reason = err.Error()
+		set["last_replay_error"] = err.Error()
+
+		var ingestErr *CameraIngestError
+		if errors.As(err, &ingestErr) {
+			result.Stage = ingestErr.Stage
+			set["stage"] = ingestErr.Stage
+			set["error_message"] = ingestErr.Error()
+		}
+	} else {
+		result.Status = status
+		result.TrafficDataID = trafficData.ID
+		set["status"] = DeadLetterStatusReplayed
+		set["traffic_data_id"] = trafficData.ID
+		set["last_replay_error"] = ""
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"replay_count": 1},
+	}
+	_, err = database.DB.Collection("traffic_dead_letter").UpdateOne(context.Background(), bson.M{"_id": entry.ID}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetPendingDeadLetterIDs mengambil ID entri pending sesuai filter, terurut dari yang paling lama
+func GetPendingDeadLetterIDs(filter bson.M, limit int64) ([]string, error) {
+	collection := database.DB.Collection("traffic_dead_letter")
+
+	filter["status"] = DeadLetterStatusPending
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := collection.Find(context.Background(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DeadLetter
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
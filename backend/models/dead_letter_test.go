@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDeadLetterReplay(t *testing.T) {
+	tests := []struct {
+		status string
+		want   error
+	}{
+		{DeadLetterStatusPending, nil},
+		{DeadLetterStatusReplayed, ErrDeadLetterBukanPending},
+		{DeadLetterStatusDiscarded, ErrDeadLetterBukanPending},
+	}
+	for _, tt := range tests {
+		if err := checkDeadLetterReplay(&DeadLetter{ID: "DLQ-00001", Status: tt.status}); !errors.Is(err, tt.want) {
+			t.Errorf("status %s: error %v, ingin %v", tt.status, err, tt.want)
+		}
+	}
+}
+
+func TestNewIngestSourceTanpaKredensial(t *testing.T) {
+	source := NewIngestSource("/api/camera/data", "10.0.0.1", map[string][]string{
+		"Authorization": {"Bearer rahasia"},
+		"X-Signature":   {"abc"},
+		"Content-Type":  {"application/json"},
+		"X-Forwarded":   {"a", "b"},
+	})
+	if len(source.Headers) != 2 || source.Headers["Content-Type"] != "application/json" || source.Headers["X-Forwarded"] != "a, b" {
+		t.Fatalf("headers = %v", source.Headers)
+	}
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinerja satu arah lalu lintas (gabungan semua zona kamera dengan nama arah yang sama)
+type DirectionPerformance struct {
+	Arah        string   `bson:"arah" json:"arah"`
+	ZonaArahIDs []string `bson:"zona_arah_ids" json:"zona_arah_ids"`
+	// Arus ekivalen arah ini pada jam puncak gabungan, dasar perhitungan pemisahan arah
+	ArusJamPuncakGabungan float64 `bson:"arus_jam_puncak_gabungan" json:"arus_jam_puncak_gabungan"`
+	Proporsi              float64 `bson:"proporsi" json:"proporsi"` // % terhadap arus jam puncak gabungan
+	// Jam puncak arah ini sendiri (bisa berbeda dengan jam puncak gabungan)
+	Arus      float64 `bson:"arus" json:"arus"`
+	JamPuncak string  `bson:"jam_puncak" json:"jam_puncak"`
+	PHF       float64 `bson:"phf" json:"phf"`
+	// Kapasitas, DS/DJ, dan LoS per arah, hanya untuk jalan terbagi
+	Kinerja *CapacityPerformance `bson:"kinerja,omitempty" json:"kinerja,omitempty"`
+}
+
+// Analisis per arah: pemisahan arah aktual dari data zona dan kinerja setiap arah
+type DirectionalAnalysis struct {
+	Terbagi           bool                   `bson:"terbagi" json:"terbagi"`                         // tipe arah terbagi (42d, 62d)
+	PemisahArah       string                 `bson:"pemisah_arah" json:"pemisah_arah"`               // pemisahan arah aktual, misal "58-42"
+	PemisahArahLokasi string                 `bson:"pemisah_arah_lokasi" json:"pemisah_arah_lokasi"` // konfigurasi lokasi (dipakai FCSP/FCPA)
+	Arah              []DirectionPerformance `bson:"arah" json:"arah"`
+	ArahKritis        string                 `bson:"arah_kritis,omitempty" json:"arah_kritis,omitempty"` // arah dengan DS/DJ tertinggi
+}
+
+// IsDividedRoad mengembalikan true untuk tipe arah terbagi (median), misalnya 42d dan 62d
+func IsDividedRoad(tipeArah string) bool {
+	return strings.HasSuffix(tipeArah, "d") && !strings.HasSuffix(tipeArah, "ud")
+}
+
+// directionKey mengelompokkan zona berdasarkan nama arah; zona tanpa nama memakai ID zona arah
+func directionKey(za TrafficZonaArahData) (string, string) {
+	nama := strings.TrimSpace(za.NamaArah)
+	if nama == "" {
+		return za.IDZonaArah, za.IDZonaArah
+	}
+	return strings.ToLower(nama), nama
+}
+
+// splitByDirection memecah traffic data menjadi satu daftar per arah (urut sesuai kemunculan pertama)
+func splitByDirection(trafficDataList []TrafficData) ([]string, map[string]string, map[string][]TrafficData, map[string][]string) {
+	var keys []string
+	nama := make(map[string]string)
+	perArah := make(map[string][]TrafficData)
+	zonaIDs := make(map[string][]string)
+
+	for _, td := range trafficDataList {
+		zonaPerArah := make(map[string][]TrafficZonaArahData)
+		for _, za := range td.ZonaArahData {
+			key, label := directionKey(za)
+			if _, exists := nama[key]; !exists {
+				keys = append(keys, key)
+				nama[key] = label
+			}
+			if !containsString(zonaIDs[key], za.IDZonaArah) {
+				zonaIDs[key] = append(zonaIDs[key], za.IDZonaArah)
+			}
+			zonaPerArah[key] = append(zonaPerArah[key], za)
+		}
+		for key, zonas := range zonaPerArah {
+			arahTD := td
+			arahTD.ZonaArahData = zonas
+			perArah[key] = append(perArah[key], arahTD)
+		}
+	}
+	return keys, nama, perArah, zonaIDs
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jumlahArahKapasitas mengembalikan jumlah arah yang dijumlahkan pada kapasitas lokasi: C0 tabel jalan terbagi
+// adalah kapasitas satu arah, sehingga kapasitas lokasi (dibandingkan dengan arus dua arah) dikalikan dua
+func jumlahArahKapasitas(tipeArah string) int {
+	if IsDividedRoad(tipeArah) {
+		return 2
+	}
+	return 1
+}
+
+// evaluateCapacityPerArah menilai arus satu arah terhadap kapasitas satu arah jalan terbagi
+func evaluateCapacityPerArah(standard CapacityStandard, location Location, arus float64) CapacityPerformance {
+	result := standard.Kapasitas(location)
+	jumlahArah := float64(jumlahArahKapasitas(location.Tipe_arah))
+	result.KapasitasDasar /= jumlahArah
+	result.Kapasitas /= jumlahArah
+
+	derajatKejenuhan := HitungDerajatKejenuhan(arus, result.Kapasitas)
+	tingkatPelayanan, keterangan := standard.TingkatPelayanan(derajatKejenuhan)
+	return CapacityPerformance{
+		CapacityResult:   result,
+		Arus:             arus,
+		DerajatKejenuhan: derajatKejenuhan,
+		TingkatPelayanan: tingkatPelayanan,
+		Keterangan:       keterangan,
+	}
+}
+
+// HitungKinerjaPerArah menghitung arus, pemisahan arah, dan (untuk jalan terbagi) kapasitas serta DS/DJ per arah.
+// Pada jalan terbagi kapasitas setiap arah adalah kapasitas lokasi dibagi jumlah arah (C0 tabel satu arah).
+func HitungKinerjaPerArah(standard CapacityStandard, location Location, trafficDataList []TrafficData, peak PeakHourAnalysis) *DirectionalAnalysis {
+	if len(trafficDataList) == 0 {
+		return nil
+	}
+
+	analysis := newDirectionalAnalysis(location)
+	keys, nama, perArah, zonaIDs := splitByDirection(trafficDataList)
+	interval := time.Duration(peak.IntervalMenit) * time.Minute
+	for _, key := range keys {
+		dataArah := perArah[key]
+		peakArah := HitungPeakHour(standard, dataArah, location.Tipe_lokasi)
+
+		// Arus arah ini pada jam puncak gabungan
+		arusGabungan := 0.0
+		for _, td := range dataArah {
+			slot := td.Timestamp.Truncate(interval)
+			if slot.Before(peak.Puncak.Mulai) || !slot.Before(peak.Puncak.Selesai) {
+				continue
+			}
+			arusGabungan += trafficDataEkivalen(standard, td, location.Tipe_lokasi)
+		}
+
+		analysis.tambahArah(standard, location, DirectionPerformance{
+			Arah:                  nama[key],
+			ZonaArahIDs:           zonaIDs[key],
+			ArusJamPuncakGabungan: arusGabungan,
+			Arus:                  peakArah.Puncak.Arus,
+			JamPuncak:             peakArah.Puncak.JamPuncak,
+			PHF:                   peakArah.Puncak.PHF,
+		})
+	}
+	analysis.hitungPemisahArah()
+	return analysis
+}
+
+// HitungKinerjaPerArahInterval menghitung analisis per arah untuk satu record (analisis real-time).
+// Arus setiap arah adalah arus ekivalen record dikonversi ke per jam; jam puncak dan PHF tidak diisi.
+func HitungKinerjaPerArahInterval(standard CapacityStandard, location Location, trafficData TrafficData) *DirectionalAnalysis {
+	if len(trafficData.ZonaArahData) == 0 {
+		return nil
+	}
+
+	analysis := newDirectionalAnalysis(location)
+	keys, nama, perArah, zonaIDs := splitByDirection([]TrafficData{trafficData})
+	perJam := intervalPerJam(&trafficData)
+	for _, key := range keys {
+		arus := 0.0
+		for _, td := range perArah[key] {
+			arus += trafficDataEkivalen(standard, td, location.Tipe_lokasi) * perJam
+		}
+		analysis.tambahArah(standard, location, DirectionPerformance{
+			Arah:                  nama[key],
+			ZonaArahIDs:           zonaIDs[key],
+			ArusJamPuncakGabungan: arus,
+			Arus:                  arus,
+		})
+	}
+	analysis.hitungPemisahArah()
+	return analysis
+}
+
+func newDirectionalAnalysis(location Location) *DirectionalAnalysis {
+	return &DirectionalAnalysis{
+		Terbagi:           IsDividedRoad(location.Tipe_arah),
+		PemisahArahLokasi: location.Persentase,
+		Arah:              []DirectionPerformance{},
+	}
+}
+
+// tambahArah menambahkan satu arah; pada jalan terbagi kinerja arah dihitung dan arah kritis diperbarui
+func (a *DirectionalAnalysis) tambahArah(standard CapacityStandard, location Location, arah DirectionPerformance) {
+	if a.Terbagi {
+		kinerja := evaluateCapacityPerArah(standard, location, arah.Arus)
+		arah.Kinerja = &kinerja
+		if a.ArahKritis == "" || kinerja.DerajatKejenuhan > a.dsKritis() {
+			a.ArahKritis = arah.Arah
+		}
+	}
+	a.Arah = append(a.Arah, arah)
+}
+
+func (a *DirectionalAnalysis) dsKritis() float64 {
+	for _, arah := range a.Arah {
+		if arah.Arah == a.ArahKritis && arah.Kinerja != nil {
+			return arah.Kinerja.DerajatKejenuhan
+		}
+	}
+	return -1
+}
+
+// hitungPemisahArah mengisi proporsi setiap arah dan pemisahan arah aktual dari arus jam puncak gabungan
+func (a *DirectionalAnalysis) hitungPemisahArah() {
+	total := 0.0
+	for _, arah := range a.Arah {
+		total += arah.ArusJamPuncakGabungan
+	}
+	if total <= 0 {
+		return
+	}
+	for i := range a.Arah {
+		a.Arah[i].Proporsi = a.Arah[i].ArusJamPuncakGabungan / total * 100
+	}
+	a.PemisahArah = formatPemisahArah(a.Arah)
+}
+
+// formatPemisahArah menyusun pemisahan arah seperti konfigurasi lokasi ("60-40"), arah terbesar lebih dulu
+func formatPemisahArah(arah []DirectionPerformance) string {
+	if len(arah) < 2 {
+		return ""
+	}
+	proporsi := make([]float64, 0, len(arah))
+	for _, a := range arah {
+		proporsi = append(proporsi, a.Proporsi)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(proporsi)))
+
+	parts := make([]string, 0, len(proporsi))
+	sisa := 100
+	for i, p := range proporsi {
+		nilai := int(p + 0.5)
+		if i == len(proporsi)-1 {
+			nilai = sisa
+		}
+		sisa -= nilai
+		parts = append(parts, fmt.Sprintf("%d", nilai))
+	}
+	return strings.Join(parts, "-")
+}
+
+// trafficDataEkivalen menjumlahkan arus ekivalen (smp/skr) satu record traffic data
+func trafficDataEkivalen(standard CapacityStandard, td TrafficData, tipeLokasi string) float64 {
+	total := 0.0
+	for _, za := range td.ZonaArahData {
+		for _, kd := range za.KelasData {
+			kategori := kategoriKelas(standard, td, tipeLokasi, kd.Kelas)
+			total += float64(kd.JumlahKendaraan) * ekivalenKategori(standard, td, kategori, tipeLokasi)
+		}
+	}
+	return total
+}
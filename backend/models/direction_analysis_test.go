@@ -0,0 +1,66 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Jalan 4/2 D perkotaan dengan semua faktor penyesuaian MKJI bernilai 1
+func lokasiTerbagiUji() Location {
+	return Location{
+		Tipe_lokasi:    "perkotaan",
+		Tipe_arah:      "42d",
+		Lebar_jalur:    7,
+		Tipe_hambatan:  "bahu_jalan",
+		Kelas_hambatan: "VL",
+		Ukuran_kota:    1.5,
+		Persentase:     "50-50",
+	}
+}
+
+func TestHitungKinerjaPerArahIntervalJalanTerbagi(t *testing.T) {
+	location := lokasiTerbagiUji()
+	// 5 menit: arah utara 150 LV, arah selatan 50 LV → 1800 dan 600 smp/jam
+	td := TrafficData{
+		Timestamp:     time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC),
+		IntervalMenit: 5,
+		ZonaArahData: []TrafficZonaArahData{
+			{IDZonaArah: "ZA-1", NamaArah: "Utara", KelasData: []TrafficKelasDetail{{Kelas: 2, JumlahKendaraan: 150}}},
+			{IDZonaArah: "ZA-2", NamaArah: "Selatan", KelasData: []TrafficKelasDetail{{Kelas: 2, JumlahKendaraan: 50}}},
+		},
+	}
+
+	gabungan := EvaluateCapacity(MKJI1997Standard{}, location, 2400)
+	if gabungan.Kapasitas != 6600 || math.Abs(gabungan.DerajatKejenuhan-2400.0/6600) > 1e-9 {
+		t.Fatalf("kapasitas gabungan %.0f DS %.3f, ingin 6600 dan %.3f", gabungan.Kapasitas, gabungan.DerajatKejenuhan, 2400.0/6600)
+	}
+
+	perArah := HitungKinerjaPerArahInterval(MKJI1997Standard{}, location, td)
+	if perArah == nil || !perArah.Terbagi || len(perArah.Arah) != 2 {
+		t.Fatalf("per arah = %+v", perArah)
+	}
+	if perArah.PemisahArah != "75-25" || perArah.ArahKritis != "Utara" {
+		t.Fatalf("pemisah arah %s, arah kritis %s, ingin 75-25 dan Utara", perArah.PemisahArah, perArah.ArahKritis)
+	}
+	for _, tt := range []struct {
+		arah string
+		arus float64
+	}{{"Utara", 1800}, {"Selatan", 600}} {
+		var arah *DirectionPerformance
+		for i := range perArah.Arah {
+			if perArah.Arah[i].Arah == tt.arah {
+				arah = &perArah.Arah[i]
+			}
+		}
+		if arah == nil || arah.Kinerja == nil {
+			t.Fatalf("arah %s tidak ada atau tanpa kinerja", tt.arah)
+		}
+		if arah.Arus != tt.arus || arah.Kinerja.Kapasitas != 3300 {
+			t.Errorf("%s: arus %.0f kapasitas %.0f, ingin %.0f dan 3300", tt.arah, arah.Arus, arah.Kinerja.Kapasitas, tt.arus)
+		}
+		if math.Abs(arah.Kinerja.DerajatKejenuhan-tt.arus/3300) > 1e-9 {
+			t.Errorf("%s: DS %.3f, ingin %.3f", tt.arah, arah.Kinerja.DerajatKejenuhan, tt.arus/3300)
+		}
+	}
+}
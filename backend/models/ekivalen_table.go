@@ -0,0 +1,549 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Pemetaan satu kelas kendaraan lokasi ke kategori standar
+type KelasMapping struct {
+	TipeLokasi string `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	Kelas      int    `bson:"kelas" json:"kelas"`
+	Kategori   string `bson:"kategori" json:"kategori"`
+}
+
+// Nilai ekivalen (smp/skr) satu kategori. Aturan dapat dibatasi tipe lokasi, tipe arah, dan rentang arus total
+// (kend/jam); aturan yang paling spesifik dipakai.
+type EkivalenRule struct {
+	TipeLokasi string  `bson:"tipe_lokasi,omitempty" json:"tipe_lokasi,omitempty"` // kosong = semua tipe lokasi
+	TipeArah   string  `bson:"tipe_arah,omitempty" json:"tipe_arah,omitempty"`     // kosong = semua tipe arah
+	Kategori   string  `bson:"kategori" json:"kategori"`
+	ArusMin    float64 `bson:"arus_min" json:"arus_min"` // batas bawah arus total (inklusif)
+	ArusMax    float64 `bson:"arus_max" json:"arus_max"` // batas atas arus total (eksklusif), 0 = tanpa batas
+	Nilai      float64 `bson:"nilai" json:"nilai"`
+}
+
+// Tabel ekivalen berversi untuk satu metode analisis, berlaku sejak BerlakuMulai (waktu lokal data)
+type EkivalenTable struct {
+	ID           string         `bson:"_id" json:"id"`
+	Metode       AnalysisMethod `bson:"metode" json:"metode"`
+	Versi        int            `bson:"versi" json:"versi"`
+	Nama         string         `bson:"nama" json:"nama"`
+	BerlakuMulai time.Time      `bson:"berlaku_mulai" json:"berlaku_mulai"`
+	Mapping      []KelasMapping `bson:"mapping" json:"mapping"`
+	Ekivalen     []EkivalenRule `bson:"ekivalen" json:"ekivalen"`
+	Catatan      string         `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	DibuatOleh   string         `bson:"dibuat_oleh,omitempty" json:"dibuat_oleh,omitempty"`
+	CreatedAt    time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// Referensi tabel ekivalen yang dipakai sebuah analisis
+type EkivalenTableRef struct {
+	ID    string `bson:"id" json:"id"`
+	Versi int    `bson:"versi" json:"versi"`
+	Nama  string `bson:"nama" json:"nama"`
+}
+
+// ID referensi untuk nilai bawaan kode saat belum ada tabel di database
+const EkivalenTableBawaan = "bawaan"
+
+var ErrEkivalenTableDipakai = errors.New("tabel ekivalen sudah dipakai analisis, buat versi baru")
+
+func (t EkivalenTable) Ref() EkivalenTableRef {
+	return EkivalenTableRef{ID: t.ID, Versi: t.Versi, Nama: t.Nama}
+}
+
+// Validate memeriksa kategori terhadap standar metode dan rentang arus setiap aturan
+func (t EkivalenTable) Validate() error {
+	standard, err := GetCapacityStandard(t.Metode)
+	if err != nil {
+		return err
+	}
+	if t.BerlakuMulai.IsZero() {
+		return fmt.Errorf("berlaku_mulai diperlukan")
+	}
+	if len(t.Ekivalen) == 0 {
+		return fmt.Errorf("ekivalen tidak boleh kosong")
+	}
+
+	valid := make(map[string]bool)
+	for _, kategori := range standard.Categories() {
+		valid[kategori] = true
+	}
+	for i, m := range t.Mapping {
+		if m.Kelas <= 0 || m.TipeLokasi == "" {
+			return fmt.Errorf("mapping[%d]: tipe_lokasi dan kelas diperlukan", i)
+		}
+		if !valid[m.Kategori] {
+			return fmt.Errorf("mapping[%d]: kategori %s tidak dikenal untuk %s", i, m.Kategori, t.Metode)
+		}
+	}
+	for i, r := range t.Ekivalen {
+		if !valid[r.Kategori] {
+			return fmt.Errorf("ekivalen[%d]: kategori %s tidak dikenal untuk %s", i, r.Kategori, t.Metode)
+		}
+		if r.Nilai < 0 {
+			return fmt.Errorf("ekivalen[%d]: nilai tidak boleh negatif", i)
+		}
+		if r.ArusMin < 0 || (r.ArusMax != 0 && r.ArusMax <= r.ArusMin) {
+			return fmt.Errorf("ekivalen[%d]: rentang arus tidak valid", i)
+		}
+	}
+	return nil
+}
+
+// kategori mengembalikan kategori kelas menurut tabel, ok=false jika tidak dipetakan
+func (t *EkivalenTable) kategori(tipeLokasi string, kelas int) (string, bool) {
+	for _, m := range t.Mapping {
+		if m.TipeLokasi == tipeLokasi && m.Kelas == kelas {
+			return m.Kategori, true
+		}
+	}
+	return "", false
+}
+
+// nilai mengembalikan nilai ekivalen aturan paling spesifik untuk kategori dan arus total (kend/jam)
+func (t *EkivalenTable) nilai(kategori, tipeLokasi, tipeArah string, arus float64) (float64, bool) {
+	best, bestScore := 0.0, -1
+	for _, r := range t.Ekivalen {
+		if r.Kategori != kategori {
+			continue
+		}
+		if (r.TipeLokasi != "" && r.TipeLokasi != tipeLokasi) || (r.TipeArah != "" && r.TipeArah != tipeArah) {
+			continue
+		}
+		if arus < r.ArusMin || (r.ArusMax > 0 && arus >= r.ArusMax) {
+			continue
+		}
+		score := 0
+		if r.TipeLokasi != "" {
+			score += 4
+		}
+		if r.TipeArah != "" {
+			score += 2
+		}
+		if r.ArusMin > 0 || r.ArusMax > 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r.Nilai, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// builtinEkivalenTable menyusun tabel dari nilai bawaan kode, dipakai sebagai versi awal saat inisialisasi
+func builtinEkivalenTable(method AnalysisMethod) EkivalenTable {
+	table := EkivalenTable{
+		Metode:       method,
+		Nama:         fmt.Sprintf("%s bawaan", method),
+		BerlakuMulai: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	switch method {
+	case AnalysisMethodMKJI1997:
+		mappings := map[string]map[int]KategoriMKJI{
+			"perkotaan":      KelasPerkotaanToMKJI,
+			"luar_kota":      KelasLuarKotaToMKJI,
+			"bebas_hambatan": KelasBebasHambatanToMKJI,
+			"12_kelas":       Kelas12ToMKJI,
+		}
+		for tipeLokasi, mapping := range mappings {
+			for kelas, kategori := range mapping {
+				table.Mapping = append(table.Mapping, KelasMapping{TipeLokasi: tipeLokasi, Kelas: kelas, Kategori: string(kategori)})
+			}
+		}
+		for kategori, nilai := range SMPValues {
+			table.Ekivalen = append(table.Ekivalen, EkivalenRule{Kategori: string(kategori), Nilai: nilai})
+		}
+
+	case AnalysisMethodPKJI2023:
+		mappings := map[string]map[int]KategoriPKJI{
+			"perkotaan":      KelasPerkotaanToPKJI,
+			"luar_kota":      KelasLuarKotaToPKJI,
+			"bebas_hambatan": KelasBebasHambatanToPKJI,
+			"12_kelas":       Kelas12ToPKJI,
+		}
+		for tipeLokasi, mapping := range mappings {
+			for kelas, kategori := range mapping {
+				table.Mapping = append(table.Mapping, KelasMapping{TipeLokasi: tipeLokasi, Kelas: kelas, Kategori: string(kategori)})
+			}
+		}
+		for kategori, perLokasi := range EMPValuesPKJI {
+			for tipeLokasi, nilai := range perLokasi {
+				table.Ekivalen = append(table.Ekivalen, EkivalenRule{TipeLokasi: tipeLokasi, Kategori: string(kategori), Nilai: nilai})
+			}
+		}
+		table.Ekivalen = append(table.Ekivalen, empArusPerkotaanPKJI()...)
+	}
+
+	sort.Slice(table.Mapping, func(i, j int) bool {
+		if table.Mapping[i].TipeLokasi != table.Mapping[j].TipeLokasi {
+			return table.Mapping[i].TipeLokasi < table.Mapping[j].TipeLokasi
+		}
+		return table.Mapping[i].Kelas < table.Mapping[j].Kelas
+	})
+	sort.Slice(table.Ekivalen, func(i, j int) bool {
+		a, b := table.Ekivalen[i], table.Ekivalen[j]
+		if a.Kategori != b.Kategori {
+			return a.Kategori < b.Kategori
+		}
+		if a.TipeLokasi != b.TipeLokasi {
+			return a.TipeLokasi < b.TipeLokasi
+		}
+		if a.TipeArah != b.TipeArah {
+			return a.TipeArah < b.TipeArah
+		}
+		return a.ArusMin < b.ArusMin
+	})
+	return table
+}
+
+// Batas arus total dua arah (kend/jam) EMP jalan perkotaan PKJI 2023. Jalan terbagi memakai batas per arah
+// (1050 untuk 2/1 dan 4/2-T, 1100 untuk 3/1 dan 6/2-T) yang dikalikan dua karena arus lokasi adalah dua arah.
+var batasArusEMPPerkotaanPKJI = map[string]float64{
+	"22ud": 3700,
+	"42ud": 5000,
+	"42d":  2 * 1050,
+	"62d":  2 * 1100,
+}
+
+// empArusPerkotaanPKJI menyusun aturan EMP SM dan KB jalan perkotaan yang bergantung arus total:
+// di bawah batas KB 1.3 dan SM 0.40, di atas batas KB 1.2 dan SM 0.25 (SM 2/2-TT untuk lebar jalur > 6 m)
+func empArusPerkotaanPKJI() []EkivalenRule {
+	var rules []EkivalenRule
+	for tipeArah, batas := range batasArusEMPPerkotaanPKJI {
+		rules = append(rules,
+			EkivalenRule{TipeLokasi: "perkotaan", TipeArah: tipeArah, Kategori: string(KategoriKB), ArusMax: batas, Nilai: 1.3},
+			EkivalenRule{TipeLokasi: "perkotaan", TipeArah: tipeArah, Kategori: string(KategoriKB), ArusMin: batas, Nilai: 1.2},
+			EkivalenRule{TipeLokasi: "perkotaan", TipeArah: tipeArah, Kategori: string(KategoriSM), ArusMax: batas, Nilai: 0.40},
+			EkivalenRule{TipeLokasi: "perkotaan", TipeArah: tipeArah, Kategori: string(KategoriSM), ArusMin: batas, Nilai: 0.25},
+		)
+	}
+	return rules
+}
+
+func NextEkivalenTableID() (string, error) {
+	collection := database.DB.Collection("ekivalen_tables")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last EkivalenTable
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "EKV-00001", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "EKV-%d", &lastNum)
+	return fmt.Sprintf("EKV-%05d", lastNum+1), nil
+}
+
+func nextEkivalenVersi(method AnalysisMethod) (int, error) {
+	collection := database.DB.Collection("ekivalen_tables")
+
+	var last EkivalenTable
+	err := collection.FindOne(
+		context.Background(),
+		bson.M{"metode": method},
+		options.FindOne().SetSort(bson.D{{Key: "versi", Value: -1}}),
+	).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Versi + 1, nil
+}
+
+// CreateEkivalenTable menyimpan tabel sebagai versi baru untuk metodenya
+func CreateEkivalenTable(table *EkivalenTable) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+
+	table.CreatedAt = time.Now().Add(7 * time.Hour)
+	table.UpdatedAt = table.CreatedAt
+
+	// ID dan versi berurutan bisa bentrok saat dua tabel dibuat bersamaan; index unik _id dan
+	// (metode, versi) menolak salah satunya, lalu ID dan versi dihitung ulang
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		if table.ID, err = NextEkivalenTableID(); err != nil {
+			return err
+		}
+		if table.Versi, err = nextEkivalenVersi(table.Metode); err != nil {
+			return err
+		}
+		_, err = database.DB.Collection("ekivalen_tables").InsertOne(context.Background(), table)
+		if err == nil || !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	return err
+}
+
+// InitEkivalenTables membuat versi awal dari nilai bawaan untuk metode yang belum memiliki tabel
+func InitEkivalenTables() error {
+	collection := database.DB.Collection("ekivalen_tables")
+
+	for _, standard := range CapacityStandards() {
+		count, err := collection.CountDocuments(context.Background(), bson.M{"metode": standard.Method()})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		table := builtinEkivalenTable(standard.Method())
+		table.Catatan = "Dibuat dari nilai bawaan aplikasi"
+		if err := CreateEkivalenTable(&table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetEkivalenTables(filter bson.M) ([]EkivalenTable, error) {
+	collection := database.DB.Collection("ekivalen_tables")
+
+	cursor, err := collection.Find(
+		context.Background(),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "metode", Value: 1}, {Key: "berlaku_mulai", Value: 1}, {Key: "versi", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []EkivalenTable
+	if err = cursor.All(context.Background(), &tables); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func GetEkivalenTableByID(id string) (*EkivalenTable, error) {
+	collection := database.DB.Collection("ekivalen_tables")
+
+	var table EkivalenTable
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&table)
+	if err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+// isEkivalenTableDipakai memeriksa apakah analisis tersimpan atau analisis realtime traffic data sudah mereferensikan tabel
+func isEkivalenTableDipakai(id string) (bool, error) {
+	referensi := []struct {
+		collection string
+		field      string
+	}{
+		{"mkji_analysis", "tabel_ekivalen.id"},
+		{"pkji_analysis", "tabel_ekivalen.id"},
+		{"simpang_analysis", "tabel_ekivalen.id"},
+		{"traffic_data", "mkji_analysis.tabel_ekivalen.id"},
+		{"traffic_data", "pkji_analysis.tabel_ekivalen.id"},
+	}
+	for _, ref := range referensi {
+		count, err := database.DB.Collection(ref.collection).CountDocuments(context.Background(), bson.M{ref.field: id})
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateEkivalenTable mengubah tabel yang belum dipakai analisis tersimpan. Tabel yang sudah dipakai
+// tidak boleh diubah agar hasil analisis lama tetap dapat ditelusuri; buat versi baru sebagai gantinya.
+func UpdateEkivalenTable(table *EkivalenTable) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	dipakai, err := isEkivalenTableDipakai(table.ID)
+	if err != nil {
+		return err
+	}
+	if dipakai {
+		return ErrEkivalenTableDipakai
+	}
+
+	table.UpdatedAt = time.Now().Add(7 * time.Hour)
+	_, err = database.DB.Collection("ekivalen_tables").UpdateOne(context.Background(), bson.M{"_id": table.ID}, bson.M{"$set": bson.M{
+		"nama":          table.Nama,
+		"berlaku_mulai": table.BerlakuMulai,
+		"mapping":       table.Mapping,
+		"ekivalen":      table.Ekivalen,
+		"catatan":       table.Catatan,
+		"updated_at":    table.UpdatedAt,
+	}})
+	return err
+}
+
+func DeleteEkivalenTable(id string) error {
+	dipakai, err := isEkivalenTableDipakai(id)
+	if err != nil {
+		return err
+	}
+	if dipakai {
+		return ErrEkivalenTableDipakai
+	}
+	_, err = database.DB.Collection("ekivalen_tables").DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+// EkivalenResolver membungkus standar kapasitas dengan tabel ekivalen berversi dari database.
+// Kategori dan nilai ekivalen setiap record diambil dari tabel yang berlaku pada waktu data tersebut;
+// sebelum tabel pertama berlaku dipakai tabel bawaan aplikasi, dan kelas atau kategori yang tidak ada di
+// tabel memakai nilai bawaan standar. Aturan yang bergantung arus dipilih dari arus total lokasi
+// (semua kamera) pada interval record.
+type EkivalenResolver struct {
+	CapacityStandard
+	tipeArah     string
+	tables       []EkivalenTable // urut berlaku_mulai naik
+	bawaan       *EkivalenTable
+	arusInterval map[int64]float64 // arus total lokasi kend/jam per awal interval (Unix)
+	dipakai      map[string]EkivalenTableRef
+}
+
+// ResolveCapacityStandard memuat tabel ekivalen metode standar untuk dipakai analisis lokasi.
+// trafficDataList adalah seluruh data lokasi yang akan dihitung, dipakai untuk arus total per interval.
+func ResolveCapacityStandard(standard CapacityStandard, location Location, trafficDataList []TrafficData) (*EkivalenResolver, error) {
+	tables, err := GetEkivalenTables(bson.M{"metode": standard.Method()})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		if !tables[i].BerlakuMulai.Equal(tables[j].BerlakuMulai) {
+			return tables[i].BerlakuMulai.Before(tables[j].BerlakuMulai)
+		}
+		return tables[i].Versi < tables[j].Versi
+	})
+	bawaan := builtinEkivalenTable(standard.Method())
+	resolver := &EkivalenResolver{
+		CapacityStandard: standard,
+		tipeArah:         location.Tipe_arah,
+		tables:           tables,
+		bawaan:           &bawaan,
+		arusInterval:     make(map[int64]float64),
+		dipakai:          make(map[string]EkivalenTableRef),
+	}
+	for _, td := range trafficDataList {
+		resolver.arusInterval[arusIntervalKey(td)] += arusTotalPerJam(td)
+	}
+	return resolver, nil
+}
+
+// arusIntervalKey mengelompokkan record beberapa kamera ke awal interval yang sama
+func arusIntervalKey(td TrafficData) int64 {
+	intervalMenit := td.IntervalMenit
+	if intervalMenit <= 0 {
+		intervalMenit = 5
+	}
+	return td.Timestamp.UTC().Truncate(time.Duration(intervalMenit) * time.Minute).Unix()
+}
+
+// arusTotal mengembalikan arus total lokasi pada interval record, atau arus record itu sendiri jika
+// interval tidak ada pada data yang diberikan ke ResolveCapacityStandard
+func (r *EkivalenResolver) arusTotal(td TrafficData) float64 {
+	if arus, ok := r.arusInterval[arusIntervalKey(td)]; ok {
+		return arus
+	}
+	return arusTotalPerJam(td)
+}
+
+// tableAt mengembalikan tabel terakhir yang berlaku pada waktu t, atau tabel bawaan jika belum ada
+func (r *EkivalenResolver) tableAt(t time.Time) *EkivalenTable {
+	var table *EkivalenTable
+	for i := range r.tables {
+		if r.tables[i].BerlakuMulai.After(t) {
+			break
+		}
+		table = &r.tables[i]
+	}
+
+	if table == nil {
+		r.dipakai[EkivalenTableBawaan] = EkivalenTableRef{ID: EkivalenTableBawaan, Nama: r.bawaan.Nama}
+		return r.bawaan
+	}
+	r.dipakai[table.ID] = table.Ref()
+	return table
+}
+
+func (r *EkivalenResolver) KategoriRecord(td TrafficData, tipeLokasi string, kelas int) string {
+	if table := r.tableAt(td.Timestamp); table != nil {
+		if kategori, ok := table.kategori(tipeLokasi, kelas); ok {
+			return kategori
+		}
+	}
+	return r.CapacityStandard.Kategori(tipeLokasi, kelas)
+}
+
+func (r *EkivalenResolver) EkivalenRecord(td TrafficData, kategori string, tipeLokasi string) float64 {
+	if table := r.tableAt(td.Timestamp); table != nil {
+		if nilai, ok := table.nilai(kategori, tipeLokasi, r.tipeArah, r.arusTotal(td)); ok {
+			return nilai
+		}
+	}
+	return r.CapacityStandard.Ekivalen(kategori, tipeLokasi)
+}
+
+// TabelDipakai mengembalikan tabel yang dipakai selama perhitungan, urut ID
+func (r *EkivalenResolver) TabelDipakai() []EkivalenTableRef {
+	refs := make([]EkivalenTableRef, 0, len(r.dipakai))
+	for _, ref := range r.dipakai {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].ID < refs[j].ID })
+	return refs
+}
+
+// arusTotalPerJam mengonversi jumlah kendaraan satu record ke arus total kend/jam
+func arusTotalPerJam(td TrafficData) float64 {
+	intervalMenit := td.IntervalMenit
+	if intervalMenit <= 0 {
+		intervalMenit = 5
+	}
+	return float64(td.TotalKendaraan) * 60 / float64(intervalMenit)
+}
+
+// GetActiveEkivalenTable mengembalikan tabel metode yang berlaku pada waktu t, atau tabel bawaan jika belum ada
+func GetActiveEkivalenTable(method AnalysisMethod, t time.Time) (*EkivalenTable, error) {
+	if _, err := GetCapacityStandard(method); err != nil {
+		return nil, err
+	}
+
+	var table EkivalenTable
+	err := database.DB.Collection("ekivalen_tables").FindOne(
+		context.Background(),
+		bson.M{"metode": method, "berlaku_mulai": bson.M{"$lte": t}},
+		options.FindOne().SetSort(bson.D{{Key: "berlaku_mulai", Value: -1}, {Key: "versi", Value: -1}}),
+	).Decode(&table)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		table = builtinEkivalenTable(method)
+		table.ID = EkivalenTableBawaan
+		return &table, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// resolverBawaanUji menyusun resolver tanpa tabel database, setara ResolveCapacityStandard pada koleksi kosong
+func resolverBawaanUji(standard CapacityStandard, tipeArah string, trafficDataList []TrafficData) *EkivalenResolver {
+	bawaan := builtinEkivalenTable(standard.Method())
+	resolver := &EkivalenResolver{
+		CapacityStandard: standard,
+		tipeArah:         tipeArah,
+		bawaan:           &bawaan,
+		arusInterval:     make(map[int64]float64),
+		dipakai:          make(map[string]EkivalenTableRef),
+	}
+	for _, td := range trafficDataList {
+		resolver.arusInterval[arusIntervalKey(td)] += arusTotalPerJam(td)
+	}
+	return resolver
+}
+
+// Dua kamera masing-masing 2400 kend/jam: satu record di bawah batas 3700, total lokasi di atasnya
+func TestEkivalenRecordArusTotalLokasi(t *testing.T) {
+	ts := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	kameraA, kameraB := trafficDataUji(ts, 200), trafficDataUji(ts.Add(time.Minute), 200)
+
+	tests := []struct {
+		nama     string
+		tipeArah string
+		data     []TrafficData
+		kategori string
+		want     float64
+	}{
+		{"22ud satu kamera", "22ud", []TrafficData{kameraA}, "KB", 1.3},
+		{"22ud dua kamera", "22ud", []TrafficData{kameraA, kameraB}, "KB", 1.2},
+		{"22ud dua kamera SM", "22ud", []TrafficData{kameraA, kameraB}, "SM", 0.25},
+		{"42ud dua kamera", "42ud", []TrafficData{kameraA, kameraB}, "KB", 1.3},
+		{"42d dua kamera", "42d", []TrafficData{kameraA, kameraB}, "KB", 1.2},
+	}
+	for _, tt := range tests {
+		resolver := resolverBawaanUji(PKJI2023Standard{}, tt.tipeArah, tt.data)
+		if got := resolver.EkivalenRecord(kameraA, tt.kategori, "perkotaan"); got != tt.want {
+			t.Errorf("%s: emp %s = %.2f, ingin %.2f", tt.nama, tt.kategori, got, tt.want)
+		}
+	}
+
+	resolver := resolverBawaanUji(PKJI2023Standard{}, "22ud", []TrafficData{kameraA})
+	resolver.EkivalenRecord(kameraA, "KB", "perkotaan")
+	if refs := resolver.TabelDipakai(); len(refs) != 1 || refs[0].ID != EkivalenTableBawaan {
+		t.Fatalf("tabel dipakai = %+v, ingin bawaan", refs)
+	}
+}
@@ -0,0 +1,577 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// Horizon prakiraan maksimum (jam)
+	maksHorizonForecast = 3
+	// Bobot profil jam-harian (semua hari) terhadap profil hari yang sama, setara jumlah minggu
+	bobotProfilJamHarian = 2.0
+	// Jendela observasi terbaru untuk rasio koreksi profil
+	jendelaForecastTerkini = time.Hour
+	// Konstanta waktu peluruhan koreksi observasi terbaru menuju profil historis
+	peluruhanRasioForecast = time.Hour
+	// Batas rasio observasi terbaru terhadap profil
+	minRasioForecast  = 0.2
+	maksRasioForecast = 5.0
+)
+
+var (
+	ErrHorizonForecastTidakValid = fmt.Errorf("jam harus antara 1 dan %d", maksHorizonForecast)
+	ErrRiwayatForecastTidakCukup = errors.New("riwayat traffic data belum cukup untuk membentuk profil prakiraan")
+)
+
+var (
+	forecastHistoryWeeks   = 4
+	defaultHorizonForecast = maksHorizonForecast
+)
+
+// SetForecastConfig mengatur jumlah minggu riwayat profil dan horizon default prakiraan (jam)
+func SetForecastConfig(historyWeeks, horizon int) {
+	if historyWeeks > 0 {
+		forecastHistoryWeeks = historyWeeks
+	}
+	if horizon > 0 && horizon <= maksHorizonForecast {
+		defaultHorizonForecast = horizon
+	}
+}
+
+// Prakiraan satu interval beserta nilai aktual setelah dievaluasi
+type ForecastLangkah struct {
+	Waktu            time.Time `bson:"waktu" json:"waktu"`   // awal interval
+	JamKe            int       `bson:"jam_ke" json:"jam_ke"` // jam horizon ke-1 … 3
+	Profil           float64   `bson:"profil" json:"profil"` // volume profil historis (kendaraan/interval)
+	JumlahHistori    int       `bson:"jumlah_histori" json:"jumlah_histori"`
+	Volume           float64   `bson:"volume" json:"volume"`     // kendaraan/interval
+	Ekivalen         float64   `bson:"ekivalen" json:"ekivalen"` // smp atau skr per interval
+	Arus             float64   `bson:"arus" json:"arus"`         // smp/jam atau skr/jam
+	DerajatKejenuhan float64   `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`
+	TingkatPelayanan string    `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"`
+
+	VolumeAktual           *float64 `bson:"volume_aktual,omitempty" json:"volume_aktual,omitempty"`
+	DerajatKejenuhanAktual *float64 `bson:"derajat_kejenuhan_aktual,omitempty" json:"derajat_kejenuhan_aktual,omitempty"`
+	TingkatPelayananAktual string   `bson:"tingkat_pelayanan_aktual,omitempty" json:"tingkat_pelayanan_aktual,omitempty"`
+}
+
+// Prakiraan volume dan DS jangka pendek satu lokasi
+type TrafficForecast struct {
+	ID            string         `bson:"_id" json:"id"`
+	LokasiID      string         `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi    string         `bson:"nama_lokasi" json:"nama_lokasi"`
+	Metode        AnalysisMethod `bson:"metode" json:"metode"`
+	Satuan        string         `bson:"satuan" json:"satuan"`
+	DibuatPada    time.Time      `bson:"dibuat_pada" json:"dibuat_pada"` // waktu lokal lokasi saat prakiraan dibuat
+	Selesai       time.Time      `bson:"selesai" json:"selesai"`         // akhir interval terakhir
+	IntervalMenit int            `bson:"interval_menit" json:"interval_menit"`
+	HorizonJam    int            `bson:"horizon_jam" json:"horizon_jam"`
+	MingguHistori int            `bson:"minggu_histori" json:"minggu_histori"`
+	Kapasitas     float64        `bson:"kapasitas" json:"kapasitas"`
+	// Interval flagged dikecualikan dari riwayat; evaluasi memakai pilihan yang sama agar sebanding
+	ExcludeFlagged bool `bson:"exclude_flagged" json:"exclude_flagged"`
+	// Rasio volume terukur terhadap profil pada jendela terbaru, meluruh ke 1 seiring horizon
+	RasioTerkini    float64           `bson:"rasio_terkini" json:"rasio_terkini"`
+	IntervalTerkini int               `bson:"interval_terkini" json:"interval_terkini"`
+	Langkah         []ForecastLangkah `bson:"langkah" json:"langkah"`
+
+	DSMaks               float64   `bson:"ds_maks" json:"ds_maks"`
+	TingkatPelayananMaks string    `bson:"tingkat_pelayanan_maks" json:"tingkat_pelayanan_maks"`
+	WaktuDSMaks          time.Time `bson:"waktu_ds_maks" json:"waktu_ds_maks"`
+
+	Dievaluasi bool      `bson:"dievaluasi" json:"dievaluasi"`
+	Catatan    []string  `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Timestamp  time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// Nilai historis satu slot waktu
+type profilSlot struct {
+	volume   float64
+	ekivalen float64
+	n        int
+}
+
+func (p *profilSlot) tambah(volume, ekivalen float64) {
+	p.volume += volume
+	p.ekivalen += ekivalen
+	p.n++
+}
+
+// profilForecast menyimpan rata-rata volume per slot jam (semua hari) dan per hari + slot jam
+type profilForecast struct {
+	jamHarian map[int]*profilSlot
+	hariJam   map[[2]int]*profilSlot
+}
+
+// nilaiBucket menjumlahkan volume dan ekivalen traffic data satu bucket interval
+func nilaiBucket(standard CapacityStandard, tds []TrafficData, tipeLokasi string) (float64, float64) {
+	volume, ekivalen := 0.0, 0.0
+	for _, td := range tds {
+		volume += float64(td.TotalKendaraan)
+		ekivalen += trafficDataEkivalen(standard, td, tipeLokasi)
+	}
+	return volume, ekivalen
+}
+
+func slotMenit(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+func susunProfilForecast(standard CapacityStandard, buckets map[time.Time][]TrafficData, tipeLokasi string) *profilForecast {
+	profil := &profilForecast{
+		jamHarian: make(map[int]*profilSlot),
+		hariJam:   make(map[[2]int]*profilSlot),
+	}
+	for bucket, tds := range buckets {
+		volume, ekivalen := nilaiBucket(standard, tds, tipeLokasi)
+
+		slot := slotMenit(bucket)
+		if profil.jamHarian[slot] == nil {
+			profil.jamHarian[slot] = &profilSlot{}
+		}
+		profil.jamHarian[slot].tambah(volume, ekivalen)
+
+		key := [2]int{int(bucket.Weekday()), slot}
+		if profil.hariJam[key] == nil {
+			profil.hariJam[key] = &profilSlot{}
+		}
+		profil.hariJam[key].tambah(volume, ekivalen)
+	}
+	return profil
+}
+
+// nilai memadukan rata-rata hari yang sama dengan rata-rata jam yang sama semua hari:
+// (n·hari + k·jam) / (n + k). Semakin banyak minggu hari yang sama, semakin dominan profil harinya.
+func (p *profilForecast) nilai(t time.Time) (volume, ekivalen float64, n int, ok bool) {
+	slot := slotMenit(t)
+	jam := p.jamHarian[slot]
+	if jam == nil {
+		return 0, 0, 0, false
+	}
+	volumeJam, ekivalenJam := jam.volume/float64(jam.n), jam.ekivalen/float64(jam.n)
+
+	hari := p.hariJam[[2]int{int(t.Weekday()), slot}]
+	if hari == nil {
+		return volumeJam, ekivalenJam, 0, true
+	}
+	bobot := float64(hari.n)
+	volume = (hari.volume + bobotProfilJamHarian*volumeJam) / (bobot + bobotProfilJamHarian)
+	ekivalen = (hari.ekivalen + bobotProfilJamHarian*ekivalenJam) / (bobot + bobotProfilJamHarian)
+	return volume, ekivalen, hari.n, true
+}
+
+// rasioTerkini membandingkan volume terukur jendela terbaru dengan profil pada slot yang sama, dibatasi
+// minRasioForecast…maksRasioForecast. ok false jika tidak ada slot terbaru yang memiliki profil.
+func rasioTerkini(standard CapacityStandard, profil *profilForecast, recentBuckets map[time.Time][]TrafficData, tipeLokasi string) (rasio float64, jumlah int, ok bool) {
+	aktual, volumeProfil := 0.0, 0.0
+	for bucket, tds := range recentBuckets {
+		v, _, _, ada := profil.nilai(bucket)
+		if !ada {
+			continue
+		}
+		volume, _ := nilaiBucket(standard, tds, tipeLokasi)
+		aktual += volume
+		volumeProfil += v
+		jumlah++
+	}
+	if volumeProfil <= 0 {
+		return 1, jumlah, false
+	}
+	return math.Max(minRasioForecast, math.Min(maksRasioForecast, aktual/volumeProfil)), jumlah, true
+}
+
+// koreksiForecast meluruhkan rasio terbaru menuju 1: 1 + (rasio − 1)·e^(−lead/τ)
+func koreksiForecast(rasio float64, lead time.Duration) float64 {
+	return 1 + (rasio-1)*math.Exp(-float64(lead)/float64(peluruhanRasioForecast))
+}
+
+// isiLangkahForecast mengisi langkah per interval dari current sampai HorizonJam beserta DS maksimum.
+// lead setiap langkah dihitung dari nowLocal ke akhir interval. Mengembalikan jumlah interval tanpa profil.
+func isiLangkahForecast(forecast *TrafficForecast, standard CapacityStandard, profil *profilForecast, current, nowLocal time.Time, interval time.Duration) int {
+	perJam := float64(time.Hour) / float64(interval)
+	langkahKosong := 0
+	for waktu := current; waktu.Before(current.Add(time.Duration(forecast.HorizonJam) * time.Hour)); waktu = waktu.Add(interval) {
+		langkah := ForecastLangkah{
+			Waktu: waktu,
+			JamKe: int(math.Ceil(waktu.Add(interval).Sub(current).Hours())),
+		}
+
+		volumeProfil, ekivalenProfil, n, ok := profil.nilai(waktu)
+		if !ok {
+			langkahKosong++
+		}
+		koreksi := koreksiForecast(forecast.RasioTerkini, waktu.Add(interval).Sub(nowLocal))
+		langkah.Profil = volumeProfil
+		langkah.JumlahHistori = n
+		langkah.Volume = volumeProfil * koreksi
+		langkah.Ekivalen = ekivalenProfil * koreksi
+		langkah.Arus = langkah.Ekivalen * perJam
+		langkah.DerajatKejenuhan = HitungDerajatKejenuhan(langkah.Arus, forecast.Kapasitas)
+		langkah.TingkatPelayanan, _ = standard.TingkatPelayanan(langkah.DerajatKejenuhan)
+
+		if len(forecast.Langkah) == 0 || langkah.DerajatKejenuhan > forecast.DSMaks {
+			forecast.DSMaks = langkah.DerajatKejenuhan
+			forecast.TingkatPelayananMaks = langkah.TingkatPelayanan
+			forecast.WaktuDSMaks = waktu
+		}
+		forecast.Langkah = append(forecast.Langkah, langkah)
+	}
+	forecast.Selesai = current.Add(time.Duration(len(forecast.Langkah)) * interval)
+	return langkahKosong
+}
+
+// BuatForecast memprakirakan volume dan DS per interval untuk jam ke depan dari waktu lokal lokasi saat ini.
+// Profil historis (hari dan jam yang sama selama FORECAST_HISTORY_WEEKS minggu) dikoreksi rasio volume
+// terukur terhadap profil pada satu jam terakhir; koreksi meluruh eksponensial seiring horizon.
+func BuatForecast(lokasiID string, metode AnalysisMethod, jam int, excludeFlagged bool) (*TrafficForecast, error) {
+	if jam == 0 {
+		jam = defaultHorizonForecast
+	}
+	if jam < 1 || jam > maksHorizonForecast {
+		return nil, ErrHorizonForecastTidakValid
+	}
+
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+	if location.IsSimpang() {
+		return nil, ErrLokasiSimpang
+	}
+
+	baseStandard, err := GetCapacityStandard(metode)
+	if err != nil {
+		return nil, err
+	}
+
+	nowLocal := time.Now().UTC().Add(time.Duration(location.Zona_waktu * float64(time.Hour)))
+	historyStart := nowLocal.AddDate(0, 0, -7*forecastHistoryWeeks)
+	history, _, err := GetTrafficDataForAnalysis(location.ID, historyStart, nowLocal, excludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+	standard, err := ResolveCapacityStandard(baseStandard, *location, history)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := expectedIntervalDuration(location, history)
+	current := nowLocal.Truncate(interval)
+	recentStart := current.Add(-jendelaForecastTerkini)
+
+	// Jendela terbaru tidak ikut profil agar rasio koreksi tidak membandingkan data dengan dirinya sendiri
+	historyBuckets := make(map[time.Time][]TrafficData)
+	recentBuckets := make(map[time.Time][]TrafficData)
+	for bucket, tds := range bucketTrafficData(history, interval) {
+		switch {
+		case bucket.Before(recentStart):
+			historyBuckets[bucket] = tds
+		case bucket.Before(current):
+			recentBuckets[bucket] = tds
+		}
+	}
+	if len(historyBuckets) == 0 {
+		return nil, ErrRiwayatForecastTidakCukup
+	}
+	profil := susunProfilForecast(standard, historyBuckets, location.Tipe_lokasi)
+
+	forecast := &TrafficForecast{
+		LokasiID:       location.ID,
+		NamaLokasi:     location.Nama_lokasi,
+		Metode:         baseStandard.Method(),
+		Satuan:         baseStandard.UnitEkivalen(),
+		DibuatPada:     nowLocal,
+		IntervalMenit:  int(interval.Minutes()),
+		HorizonJam:     jam,
+		MingguHistori:  forecastHistoryWeeks,
+		Kapasitas:      baseStandard.Kapasitas(*location).Kapasitas,
+		ExcludeFlagged: excludeFlagged,
+		RasioTerkini:   1,
+		Langkah:        []ForecastLangkah{},
+		Timestamp:      time.Now().Add(7 * time.Hour),
+	}
+
+	rasio, jumlah, ok := rasioTerkini(standard, profil, recentBuckets, location.Tipe_lokasi)
+	forecast.IntervalTerkini = jumlah
+	if ok {
+		forecast.RasioTerkini = rasio
+	} else {
+		forecast.Catatan = append(forecast.Catatan, "Tidak ada data satu jam terakhir, prakiraan memakai profil historis saja")
+	}
+
+	if langkahKosong := isiLangkahForecast(forecast, baseStandard, profil, current, nowLocal, interval); langkahKosong > 0 {
+		forecast.Catatan = append(forecast.Catatan, fmt.Sprintf("%d interval tidak memiliki riwayat pada jam yang sama, volume dianggap 0", langkahKosong))
+	}
+	return forecast, nil
+}
+
+// evaluasiForecast mengisi nilai aktual setiap langkah dari traffic data yang sudah masuk
+func evaluasiForecast(forecast *TrafficForecast, location *Location) error {
+	baseStandard, err := GetCapacityStandard(forecast.Metode)
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(forecast.IntervalMenit) * time.Minute
+	start := forecast.Selesai.Add(-time.Duration(len(forecast.Langkah)) * interval)
+	trafficDataList, _, err := GetTrafficDataForAnalysis(location.ID, start, forecast.Selesai, forecast.ExcludeFlagged)
+	if err != nil {
+		return err
+	}
+	standard, err := ResolveCapacityStandard(baseStandard, *location, trafficDataList)
+	if err != nil {
+		return err
+	}
+	buckets := bucketTrafficData(trafficDataList, interval)
+
+	perJam := float64(time.Hour) / float64(interval)
+	for i := range forecast.Langkah {
+		langkah := &forecast.Langkah[i]
+		tds := buckets[langkah.Waktu]
+		if len(tds) == 0 {
+			continue
+		}
+		volume, ekivalen := nilaiBucket(standard, tds, location.Tipe_lokasi)
+		ds := HitungDerajatKejenuhan(ekivalen*perJam, forecast.Kapasitas)
+		langkah.VolumeAktual = &volume
+		langkah.DerajatKejenuhanAktual = &ds
+		langkah.TingkatPelayananAktual, _ = baseStandard.TingkatPelayanan(ds)
+	}
+	forecast.Dievaluasi = true
+	return nil
+}
+
+// EvaluasiForecastTertunda membandingkan prakiraan tersimpan yang horizonnya sudah lewat dengan data aktual.
+// Satu interval tambahan ditunggu agar data kamera yang terlambat ikut terhitung. Kegagalan satu prakiraan
+// dicatat di log dan tidak menghentikan prakiraan lain; prakiraan untuk lokasi yang sudah dihapus ditandai
+// dievaluasi tanpa nilai aktual agar tidak diproses terus-menerus.
+func EvaluasiForecastTertunda() (int, error) {
+	collection := database.DB.Collection("traffic_forecasts")
+	cursor, err := collection.Find(context.Background(), bson.M{"dievaluasi": false})
+	if err != nil {
+		return 0, err
+	}
+	var forecasts []TrafficForecast
+	if err = cursor.All(context.Background(), &forecasts); err != nil {
+		return 0, err
+	}
+
+	locations := make(map[string]*Location)
+	dievaluasi := 0
+	for i := range forecasts {
+		forecast := &forecasts[i]
+		location, ok := locations[forecast.LokasiID]
+		if !ok {
+			location, err = GetLocationByID(forecast.LokasiID)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("Gagal mengambil lokasi %s untuk evaluasi prakiraan %s: %v", forecast.LokasiID, forecast.ID, err)
+				continue
+			}
+			locations[forecast.LokasiID] = location
+		}
+
+		set := bson.M{"dievaluasi": true}
+		if location == nil {
+			set["catatan"] = append(forecast.Catatan, "Lokasi tidak ditemukan, prakiraan tidak dapat dievaluasi")
+		} else {
+			nowLocal := time.Now().UTC().Add(time.Duration(location.Zona_waktu * float64(time.Hour)))
+			if forecast.Selesai.Add(time.Duration(forecast.IntervalMenit) * time.Minute).After(nowLocal) {
+				continue
+			}
+			if err := evaluasiForecast(forecast, location); err != nil {
+				log.Printf("Gagal mengevaluasi prakiraan %s: %v", forecast.ID, err)
+				continue
+			}
+			set["langkah"] = forecast.Langkah
+		}
+
+		if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": forecast.ID}, bson.M{"$set": set}); err != nil {
+			log.Printf("Gagal menyimpan evaluasi prakiraan %s: %v", forecast.ID, err)
+			continue
+		}
+		dievaluasi++
+	}
+	return dievaluasi, nil
+}
+
+func NextTrafficForecastID() (string, error) {
+	collection := database.DB.Collection("traffic_forecasts")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last TrafficForecast
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "FC-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "FC-%d", &lastNum)
+	return fmt.Sprintf("FC-%05d", lastNum+1), nil
+}
+
+func SaveTrafficForecast(forecast *TrafficForecast) error {
+	id, err := NextTrafficForecastID()
+	if err != nil {
+		return err
+	}
+	forecast.ID = id
+
+	_, err = database.DB.Collection("traffic_forecasts").InsertOne(context.Background(), forecast)
+	return err
+}
+
+// GetTrafficForecastsByLokasiID mengambil prakiraan tersimpan lokasi, terbaru lebih dulu
+func GetTrafficForecastsByLokasiID(lokasiID string, limit int64) ([]TrafficForecast, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "dibuat_pada", Value: -1}})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+
+	cursor, err := database.DB.Collection("traffic_forecasts").Find(context.Background(), bson.M{"lokasi_id": lokasiID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	forecasts := []TrafficForecast{}
+	if err = cursor.All(context.Background(), &forecasts); err != nil {
+		return nil, err
+	}
+	return forecasts, nil
+}
+
+// Ukuran galat prakiraan terhadap data aktual
+type ForecastGalat struct {
+	JamKe            int      `json:"jam_ke,omitempty"` // 0 = seluruh horizon
+	JumlahInterval   int      `json:"jumlah_interval"`
+	MAE              float64  `json:"mae"`            // kendaraan/interval
+	RMSE             float64  `json:"rmse"`           // kendaraan/interval
+	MAPE             *float64 `json:"mape,omitempty"` // %, hanya interval dengan volume aktual > 0
+	MAEDS            float64  `json:"mae_ds"`
+	TingkatPelayanan float64  `json:"tingkat_pelayanan_tepat"` // % interval dengan tingkat pelayanan tepat
+}
+
+// Akurasi prakiraan tersimpan satu lokasi
+type ForecastAkurasi struct {
+	LokasiID          string          `json:"lokasi_id"`
+	Metode            AnalysisMethod  `json:"metode,omitempty"`
+	PeriodeMulai      time.Time       `json:"periode_mulai"`
+	PeriodeSelesai    time.Time       `json:"periode_selesai"`
+	JumlahForecast    int             `json:"jumlah_forecast"`
+	IntervalTanpaData int             `json:"interval_tanpa_data"`
+	Keseluruhan       ForecastGalat   `json:"keseluruhan"`
+	PerJam            []ForecastGalat `json:"per_jam"`
+}
+
+type akumulasiGalat struct {
+	n, nMAPE                        int
+	absolut, kuadrat, persen, absDS float64
+	losTepat                        int
+}
+
+func (a *akumulasiGalat) tambah(langkah ForecastLangkah) {
+	galat := langkah.Volume - *langkah.VolumeAktual
+	a.n++
+	a.absolut += math.Abs(galat)
+	a.kuadrat += galat * galat
+	if *langkah.VolumeAktual > 0 {
+		a.nMAPE++
+		a.persen += math.Abs(galat) / *langkah.VolumeAktual * 100
+	}
+	a.absDS += math.Abs(langkah.DerajatKejenuhan - *langkah.DerajatKejenuhanAktual)
+	if langkah.TingkatPelayanan == langkah.TingkatPelayananAktual {
+		a.losTepat++
+	}
+}
+
+func (a *akumulasiGalat) galat(jamKe int) ForecastGalat {
+	g := ForecastGalat{JamKe: jamKe, JumlahInterval: a.n}
+	if a.n == 0 {
+		return g
+	}
+	n := float64(a.n)
+	g.MAE = math.Round(a.absolut/n*100) / 100
+	g.RMSE = math.Round(math.Sqrt(a.kuadrat/n)*100) / 100
+	g.MAEDS = math.Round(a.absDS/n*1000) / 1000
+	g.TingkatPelayanan = math.Round(float64(a.losTepat)/n*10000) / 100
+	if a.nMAPE > 0 {
+		mape := math.Round(a.persen/float64(a.nMAPE)*100) / 100
+		g.MAPE = &mape
+	}
+	return g
+}
+
+// HitungAkurasiForecast merangkum galat prakiraan lokasi yang sudah dievaluasi dan dibuat pada periode,
+// keseluruhan dan per jam horizon. metode kosong = semua metode.
+func HitungAkurasiForecast(lokasiID string, metode AnalysisMethod, startTime, endTime time.Time) (*ForecastAkurasi, error) {
+	filter := bson.M{
+		"lokasi_id":   lokasiID,
+		"dievaluasi":  true,
+		"dibuat_pada": bson.M{"$gte": startTime, "$lte": endTime},
+	}
+	if metode != "" {
+		filter["metode"] = metode
+	}
+
+	cursor, err := database.DB.Collection("traffic_forecasts").Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	var forecasts []TrafficForecast
+	if err = cursor.All(context.Background(), &forecasts); err != nil {
+		return nil, err
+	}
+
+	akurasi := &ForecastAkurasi{
+		LokasiID:       lokasiID,
+		Metode:         metode,
+		PeriodeMulai:   startTime,
+		PeriodeSelesai: endTime,
+	}
+	rangkumAkurasiForecast(akurasi, forecasts)
+	return akurasi, nil
+}
+
+// rangkumAkurasiForecast mengisi galat keseluruhan dan per jam horizon dari langkah yang memiliki nilai aktual
+func rangkumAkurasiForecast(akurasi *ForecastAkurasi, forecasts []TrafficForecast) {
+	akurasi.JumlahForecast = len(forecasts)
+	akurasi.PerJam = []ForecastGalat{}
+
+	var total akumulasiGalat
+	perJam := make(map[int]*akumulasiGalat)
+	for _, forecast := range forecasts {
+		for _, langkah := range forecast.Langkah {
+			if langkah.VolumeAktual == nil || langkah.DerajatKejenuhanAktual == nil {
+				akurasi.IntervalTanpaData++
+				continue
+			}
+			total.tambah(langkah)
+			if perJam[langkah.JamKe] == nil {
+				perJam[langkah.JamKe] = &akumulasiGalat{}
+			}
+			perJam[langkah.JamKe].tambah(langkah)
+		}
+	}
+
+	akurasi.Keseluruhan = total.galat(0)
+	jamKe := make([]int, 0, len(perJam))
+	for jam := range perJam {
+		jamKe = append(jamKe, jam)
+	}
+	sort.Ints(jamKe)
+	for _, jam := range jamKe {
+		akurasi.PerJam = append(akurasi.PerJam, perJam[jam].galat(jam))
+	}
+}
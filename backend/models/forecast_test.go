@@ -0,0 +1,226 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// bucketForecastUji mengisi bucket 5 menit pada hari-hari tertentu dari jam mulai sampai jam selesai
+func bucketForecastUji(buckets map[time.Time][]TrafficData, hari []time.Time, mulai, selesai int, volume func(ts time.Time) int) {
+	for _, h := range hari {
+		for ts := h.Add(time.Duration(mulai) * time.Hour); ts.Before(h.Add(time.Duration(selesai) * time.Hour)); ts = ts.Add(5 * time.Minute) {
+			buckets[ts] = append(buckets[ts], trafficDataLVUji(ts, 5, volume(ts)))
+		}
+	}
+}
+
+// Empat minggu berurutan mulai Senin 6 Januari 2025 ditambah offset hari
+func mingguForecastUji(offsetHari ...int) []time.Time {
+	var hari []time.Time
+	for minggu := 0; minggu < 4; minggu++ {
+		for _, offset := range offsetHari {
+			hari = append(hari, time.Date(2025, 1, 6+7*minggu+offset, 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return hari
+}
+
+func TestProfilForecastNilai(t *testing.T) {
+	buckets := make(map[time.Time][]TrafficData)
+	// Senin 07:00 empat minggu: 100, 110, 120, 130
+	for i, h := range mingguForecastUji(0) {
+		ts := h.Add(7 * time.Hour)
+		buckets[ts] = []TrafficData{trafficDataLVUji(ts, 5, 100+10*i)}
+	}
+	// Selasa-Jumat 07:00 empat minggu: 50
+	for _, h := range mingguForecastUji(1, 2, 3, 4) {
+		ts := h.Add(7 * time.Hour)
+		buckets[ts] = []TrafficData{trafficDataLVUji(ts, 5, 50)}
+	}
+	profil := susunProfilForecast(MKJI1997Standard{}, buckets, "perkotaan")
+
+	// Rata-rata jam 07:00 semua hari = (460 + 16 × 50) / 20 = 63; profil hari = (Σ hari + 2 × 63) / (n + 2)
+	tests := []struct {
+		nama  string
+		waktu time.Time
+		ingin float64
+		n     int
+		ok    bool
+	}{
+		{"Senin", time.Date(2025, 2, 3, 7, 0, 0, 0, time.UTC), (460 + 2*63.0) / 6, 4, true},
+		{"Selasa", time.Date(2025, 2, 4, 7, 0, 0, 0, time.UTC), (200 + 2*63.0) / 6, 4, true},
+		{"Sabtu tanpa riwayat hari", time.Date(2025, 2, 8, 7, 0, 0, 0, time.UTC), 63, 0, true},
+		{"slot tanpa riwayat", time.Date(2025, 2, 3, 7, 5, 0, 0, time.UTC), 0, 0, false},
+	}
+	for _, tt := range tests {
+		volume, ekivalen, n, ok := profil.nilai(tt.waktu)
+		if ok != tt.ok || n != tt.n || math.Abs(volume-tt.ingin) > 1e-9 {
+			t.Errorf("%s: volume %.4f n %d ok %v, ingin %.4f, %d, %v", tt.nama, volume, n, ok, tt.ingin, tt.n, tt.ok)
+		}
+		// Kendaraan ringan berekivalen 1
+		if math.Abs(ekivalen-volume) > 1e-9 {
+			t.Errorf("%s: ekivalen %.4f, ingin %.4f", tt.nama, ekivalen, volume)
+		}
+	}
+}
+
+func TestRasioTerkini(t *testing.T) {
+	history := make(map[time.Time][]TrafficData)
+	bucketForecastUji(history, mingguForecastUji(0), 6, 9, func(time.Time) int { return 100 })
+	profil := susunProfilForecast(MKJI1997Standard{}, history, "perkotaan")
+
+	senin := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		nama   string
+		volume int
+		rasio  float64
+		ok     bool
+	}{
+		{"lebih ramai", 150, 1.5, true},
+		{"lebih sepi", 80, 0.8, true},
+		{"batas atas", 1000, maksRasioForecast, true},
+		{"batas bawah", 5, minRasioForecast, true},
+	}
+	for _, tt := range tests {
+		recent := make(map[time.Time][]TrafficData)
+		bucketForecastUji(recent, []time.Time{senin}, 6, 7, func(time.Time) int { return tt.volume })
+		// Slot 05:55 tidak memiliki profil sehingga tidak ikut rasio
+		ts := senin.Add(5*time.Hour + 55*time.Minute)
+		recent[ts] = []TrafficData{trafficDataLVUji(ts, 5, 10000)}
+
+		rasio, jumlah, ok := rasioTerkini(MKJI1997Standard{}, profil, recent, "perkotaan")
+		if ok != tt.ok || jumlah != 12 || math.Abs(rasio-tt.rasio) > 1e-9 {
+			t.Errorf("%s: rasio %.4f dari %d interval ok %v, ingin %.4f dari 12", tt.nama, rasio, jumlah, ok, tt.rasio)
+		}
+	}
+
+	if rasio, jumlah, ok := rasioTerkini(MKJI1997Standard{}, profil, map[time.Time][]TrafficData{}, "perkotaan"); ok || jumlah != 0 || rasio != 1 {
+		t.Errorf("tanpa data terbaru: rasio %.2f jumlah %d ok %v, ingin 1, 0, false", rasio, jumlah, ok)
+	}
+}
+
+func TestKoreksiForecast(t *testing.T) {
+	tests := []struct {
+		rasio float64
+		lead  time.Duration
+		ingin float64
+	}{
+		{1.5, 0, 1.5},
+		{1.5, time.Hour, 1 + 0.5/math.E},
+		{1.5, 2 * time.Hour, 1 + 0.5*math.Exp(-2)},
+		{1.5, 30 * time.Minute, 1 + 0.5*math.Exp(-0.5)},
+		{0.5, time.Hour, 1 - 0.5/math.E},
+		{1, 3 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		if got := koreksiForecast(tt.rasio, tt.lead); math.Abs(got-tt.ingin) > 1e-12 {
+			t.Errorf("rasio %.1f lead %v: %.6f, ingin %.6f", tt.rasio, tt.lead, got, tt.ingin)
+		}
+	}
+}
+
+// Profil Senin 06:00-09:00 bernilai 100 kecuali 07:30 bernilai 200; prakiraan dibuat Senin 07:02 dengan
+// rasio terkini 1.5 dan horizon 3 jam, sehingga 09:00-09:55 tidak memiliki profil
+func TestIsiLangkahForecast(t *testing.T) {
+	history := make(map[time.Time][]TrafficData)
+	bucketForecastUji(history, mingguForecastUji(0), 6, 9, func(ts time.Time) int {
+		if ts.Hour() == 7 && ts.Minute() == 30 {
+			return 200
+		}
+		return 100
+	})
+	profil := susunProfilForecast(MKJI1997Standard{}, history, "perkotaan")
+
+	current := time.Date(2025, 2, 3, 7, 0, 0, 0, time.UTC)
+	nowLocal := current.Add(2 * time.Minute)
+	forecast := &TrafficForecast{HorizonJam: 3, Kapasitas: 5000, RasioTerkini: 1.5}
+	kosong := isiLangkahForecast(forecast, MKJI1997Standard{}, profil, current, nowLocal, 5*time.Minute)
+
+	if len(forecast.Langkah) != 36 || kosong != 12 || !forecast.Selesai.Equal(current.Add(3*time.Hour)) {
+		t.Fatalf("%d langkah, %d kosong, selesai %v, ingin 36, 12, 10:00", len(forecast.Langkah), kosong, forecast.Selesai)
+	}
+	for i, l := range forecast.Langkah {
+		profilIngin := 100.0
+		switch {
+		case i == 6:
+			profilIngin = 200
+		case i >= 24:
+			profilIngin = 0
+		}
+		koreksi := 1 + 0.5*math.Exp(-(float64(i+1)*5-2)/60)
+		if !l.Waktu.Equal(current.Add(time.Duration(i)*5*time.Minute)) || l.JamKe != i/12+1 || l.Profil != profilIngin {
+			t.Errorf("langkah %d: waktu %v jam ke %d profil %.0f", i, l.Waktu, l.JamKe, l.Profil)
+		}
+		if math.Abs(l.Volume-profilIngin*koreksi) > 1e-9 || math.Abs(l.Arus-l.Ekivalen*12) > 1e-9 ||
+			math.Abs(l.DerajatKejenuhan-l.Arus/5000) > 1e-12 {
+			t.Errorf("langkah %d: volume %.4f arus %.4f DS %.4f, ingin volume %.4f", i, l.Volume, l.Arus, l.DerajatKejenuhan, profilIngin*koreksi)
+		}
+		if los, _ := GetTingkatPelayanan(l.DerajatKejenuhan); l.TingkatPelayanan != los {
+			t.Errorf("langkah %d: LoS %s, ingin %s", i, l.TingkatPelayanan, los)
+		}
+	}
+	// Koreksi meluruh: jam ke-3 sudah mendekati profil historis
+	if l := forecast.Langkah[23]; l.Volume/l.Profil > 1.07 {
+		t.Errorf("koreksi 08:55 %.3f, ingin mendekati 1", l.Volume/l.Profil)
+	}
+
+	// 07:30: 200 × (1 + 0.5e^−33/60) × 12 / 5000
+	dsMaks := 200 * (1 + 0.5*math.Exp(-33.0/60)) * 12 / 5000
+	if !forecast.WaktuDSMaks.Equal(current.Add(30*time.Minute)) || math.Abs(forecast.DSMaks-dsMaks) > 1e-12 || forecast.TingkatPelayananMaks != "C" {
+		t.Fatalf("DS maks %.4f (%s) pada %v, ingin %.4f (C) pada 07:30", forecast.DSMaks, forecast.TingkatPelayananMaks, forecast.WaktuDSMaks, dsMaks)
+	}
+}
+
+func langkahAkurasiUji(jamKe int, volume, aktual, ds, dsAktual float64, los, losAktual string) ForecastLangkah {
+	return ForecastLangkah{JamKe: jamKe, Volume: volume, VolumeAktual: &aktual, DerajatKejenuhan: ds,
+		DerajatKejenuhanAktual: &dsAktual, TingkatPelayanan: los, TingkatPelayananAktual: losAktual}
+}
+
+func TestRangkumAkurasiForecast(t *testing.T) {
+	forecasts := []TrafficForecast{
+		{Langkah: []ForecastLangkah{
+			langkahAkurasiUji(1, 100, 90, 0.5, 0.45, "B", "B"),
+			langkahAkurasiUji(1, 100, 110, 0.5, 0.55, "B", "C"),
+			{JamKe: 2, Volume: 80, DerajatKejenuhan: 0.4}, // belum ada data aktual
+		}},
+		{Langkah: []ForecastLangkah{
+			langkahAkurasiUji(2, 50, 0, 0.2, 0, "A", "A"),
+			langkahAkurasiUji(1, 120, 100, 0.6, 0.5, "C", "B"),
+		}},
+	}
+	akurasi := &ForecastAkurasi{}
+	rangkumAkurasiForecast(akurasi, forecasts)
+
+	if akurasi.JumlahForecast != 2 || akurasi.IntervalTanpaData != 1 || len(akurasi.PerJam) != 2 {
+		t.Fatalf("forecast %d tanpa data %d per jam %d, ingin 2, 1, 2", akurasi.JumlahForecast, akurasi.IntervalTanpaData, len(akurasi.PerJam))
+	}
+
+	// Galat volume +10, −10, +50, +20; MAPE hanya dari volume aktual > 0: (10/90 + 10/110 + 20/100) / 3
+	mape := 13.4
+	tests := []struct {
+		nama  string
+		got   ForecastGalat
+		ingin ForecastGalat
+	}{
+		{"keseluruhan", akurasi.Keseluruhan, ForecastGalat{JamKe: 0, JumlahInterval: 4, MAE: 22.5, RMSE: 27.84, MAPE: &mape, MAEDS: 0.1, TingkatPelayanan: 50}},
+		{"jam ke-1", akurasi.PerJam[0], ForecastGalat{JamKe: 1, JumlahInterval: 3, MAE: 13.33, RMSE: 14.14, MAPE: &mape, MAEDS: 0.067, TingkatPelayanan: 33.33}},
+		{"jam ke-2", akurasi.PerJam[1], ForecastGalat{JamKe: 2, JumlahInterval: 1, MAE: 50, RMSE: 50, MAEDS: 0.2, TingkatPelayanan: 100}},
+	}
+	for _, tt := range tests {
+		g, w := tt.got, tt.ingin
+		if g.JamKe != w.JamKe || g.JumlahInterval != w.JumlahInterval || g.MAE != w.MAE || g.RMSE != w.RMSE ||
+			g.MAEDS != w.MAEDS || g.TingkatPelayanan != w.TingkatPelayanan {
+			t.Errorf("%s: %+v, ingin %+v", tt.nama, g, w)
+		}
+		if (g.MAPE == nil) != (w.MAPE == nil) || (g.MAPE != nil && *g.MAPE != *w.MAPE) {
+			t.Errorf("%s: MAPE %v, ingin %v", tt.nama, g.MAPE, w.MAPE)
+		}
+	}
+
+	kosong := &ForecastAkurasi{}
+	rangkumAkurasiForecast(kosong, nil)
+	if kosong.PerJam == nil || len(kosong.PerJam) != 0 || kosong.Keseluruhan.JumlahInterval != 0 || kosong.Keseluruhan.MAPE != nil {
+		t.Errorf("tanpa prakiraan: %+v", kosong)
+	}
+}
@@ -0,0 +1,803 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Metode estimasi LHRT
+const (
+	LHRTMetodePosPermanen = "pos_permanen" // lokasi sendiri memiliki data hampir setahun penuh
+	LHRTMetodeFaktor      = "faktor"       // hitungan jangka pendek dikalikan faktor hari dan bulan
+	LHRTMetodeTanpaFaktor = "tanpa_faktor" // belum ada faktor, rata-rata volume harian apa adanya
+)
+
+// Tingkat kepercayaan estimasi LHRT berdasarkan perkiraan galat relatif 95%
+const (
+	KepercayaanTinggi = "tinggi" // galat ≤ 10%
+	KepercayaanSedang = "sedang" // galat ≤ 20%
+	KepercayaanRendah = "rendah"
+)
+
+// Kelompok faktor gabungan seluruh pos permanen, dipakai jika tipe lokasi belum memiliki pos permanen
+const KelompokFaktorSemua = "semua"
+
+var ErrTidakAdaPosPermanen = errors.New("belum ada lokasi yang memenuhi syarat pos permanen pada periode ini")
+
+const (
+	// Variasi volume antar hari yang diasumsikan jika hanya ada satu hari lengkap
+	variasiHarianDefault = 0.15
+	// Jumlah hari dalam seminggu minimum agar rata-rata bulanan pos permanen dihitung
+	minHariPerBulanFaktor = 5
+)
+
+var namaHari = []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+var namaBulan = []string{"", "Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli",
+	"Agustus", "September", "Oktober", "November", "Desember"}
+
+// Batas data untuk faktor dan estimasi LHRT
+type LHRTConfig struct {
+	MinKelengkapanHari float64 // persen interval tersedia agar satu hari dipakai
+	MinHariPermanen    int     // jumlah hari lengkap minimum pada periode agar lokasi menjadi pos permanen
+}
+
+var lhrtConfig = LHRTConfig{
+	MinKelengkapanHari: 90,
+	MinHariPermanen:    300,
+}
+
+// SetLHRTConfig mengganti batas data LHRT (dipanggil saat startup)
+func SetLHRTConfig(cfg LHRTConfig) {
+	if cfg.MinKelengkapanHari > 0 {
+		lhrtConfig.MinKelengkapanHari = cfg.MinKelengkapanHari
+	}
+	if cfg.MinHariPermanen > 0 {
+		lhrtConfig.MinHariPermanen = cfg.MinHariPermanen
+	}
+}
+
+// Volume dan kelengkapan satu kamera pada satu hari
+type VolumeHarianKamera struct {
+	CameraID           string  `bson:"camera_id" json:"camera_id"` // kosong untuk data tanpa kamera
+	Volume             int     `bson:"volume" json:"volume"`
+	IntervalTersedia   int     `bson:"interval_tersedia" json:"interval_tersedia"`
+	IntervalDiharapkan int     `bson:"interval_diharapkan" json:"interval_diharapkan"`
+	Kelengkapan        float64 `bson:"kelengkapan" json:"kelengkapan"` // %
+}
+
+// Volume kendaraan satu hari (waktu lokal) pada lokasi. Interval dan kelengkapan diambil dari kamera
+// dengan kelengkapan terendah, karena volume lokasi kurang jika salah satu kamera tidak mengirim.
+type VolumeHarian struct {
+	Tanggal            time.Time            `bson:"tanggal" json:"tanggal"`
+	Volume             int                  `bson:"volume" json:"volume"`
+	IntervalTersedia   int                  `bson:"interval_tersedia" json:"interval_tersedia"`
+	IntervalDiharapkan int                  `bson:"interval_diharapkan" json:"interval_diharapkan"`
+	Kelengkapan        float64              `bson:"kelengkapan" json:"kelengkapan"` // %
+	Lengkap            bool                 `bson:"lengkap" json:"lengkap"`
+	Kamera             []VolumeHarianKamera `bson:"kamera,omitempty" json:"kamera,omitempty"`
+}
+
+// Hasil agregasi traffic data per hari dan kamera
+type volumeHarianKameraRow struct {
+	Tanggal       string `bson:"tanggal"`
+	CameraID      string `bson:"camera_id"`
+	Volume        int    `bson:"volume"`
+	Interval      int    `bson:"interval"`
+	IntervalMenit int    `bson:"interval_menit"`
+}
+
+// GetVolumeHarian menjumlahkan traffic data per hari dan menandai hari yang kelengkapannya memenuhi
+// MinKelengkapanHari. Kelengkapan dihitung per kamera lalu diambil yang terendah. Hari yang belum selesai
+// tidak dikembalikan.
+func GetVolumeHarian(location *Location, startTime, endTime time.Time, excludeFlagged bool) ([]VolumeHarian, error) {
+	match := bson.M{
+		"lokasi_id": location.ID,
+		"timestamp": bson.M{"$gte": startTime, "$lt": endTime},
+	}
+	if excludeFlagged {
+		match["data_quality.flagged"] = bson.M{"$ne": true}
+	}
+
+	// Data sebelum camera_id disimpan dikenali dari awalan ingest_key (camera_id|data_number|utc|ms)
+	kamera := bson.M{"$ifNull": bson.A{"$camera_id", bson.M{"$arrayElemAt": bson.A{
+		bson.M{"$split": bson.A{bson.M{"$ifNull": bson.A{"$ingest_key", ""}}, "|"}}, 0,
+	}}}}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"tanggal":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$timestamp"}},
+				"camera_id": kamera,
+			},
+			"volume":         bson.M{"$sum": "$total_kendaraan"},
+			"interval":       bson.M{"$addToSet": "$timestamp"},
+			"interval_menit": bson.M{"$max": "$interval_menit"},
+		}},
+		{"$project": bson.M{
+			"_id":            0,
+			"tanggal":        "$_id.tanggal",
+			"camera_id":      "$_id.camera_id",
+			"volume":         1,
+			"interval_menit": 1,
+			"interval":       bson.M{"$size": "$interval"},
+		}},
+		{"$sort": bson.D{{Key: "tanggal", Value: 1}, {Key: "camera_id", Value: 1}}},
+	}
+
+	cursor, err := database.DB.Collection("traffic_data").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []volumeHarianKameraRow
+	if err = cursor.All(context.Background(), &rows); err != nil {
+		return nil, err
+	}
+
+	nowLocal := time.Now().UTC().Add(time.Duration(location.Zona_waktu * float64(time.Hour)))
+	return susunVolumeHarian(location, rows, nowLocal), nil
+}
+
+// susunVolumeHarian menggabungkan baris per hari dan kamera menjadi volume harian lokasi. Kamera dianggap
+// aktif dari hari pertama sampai hari terakhir datanya pada periode; hari dalam rentang itu tanpa data
+// kamera tersebut berkelengkapan 0%. Baris harus terurut per tanggal.
+func susunVolumeHarian(location *Location, rows []volumeHarianKameraRow, nowLocal time.Time) []VolumeHarian {
+	type rentang struct{ awal, akhir string }
+	aktif := make(map[string]rentang)
+	var kameraList []string
+	for _, row := range rows {
+		r, ok := aktif[row.CameraID]
+		if !ok {
+			kameraList = append(kameraList, row.CameraID)
+			r.awal = row.Tanggal
+		}
+		r.akhir = row.Tanggal
+		aktif[row.CameraID] = r
+	}
+	sort.Strings(kameraList)
+
+	// Interval kamera: interval lokasi, atau interval kirim kamera jika lebih panjang
+	intervalKamera := make(map[string]int)
+	perHari := make(map[string]map[string]volumeHarianKameraRow)
+	var tanggalList []string
+	for _, row := range rows {
+		if perHari[row.Tanggal] == nil {
+			perHari[row.Tanggal] = make(map[string]volumeHarianKameraRow)
+			tanggalList = append(tanggalList, row.Tanggal)
+		}
+		perHari[row.Tanggal][row.CameraID] = row
+		if row.IntervalMenit*60 > intervalKamera[row.CameraID] {
+			intervalKamera[row.CameraID] = row.IntervalMenit * 60
+		}
+	}
+
+	volumes := make([]VolumeHarian, 0, len(tanggalList))
+	for _, hari := range tanggalList {
+		tanggal, err := time.Parse("2006-01-02", hari)
+		if err != nil || tanggal.Add(24*time.Hour).After(nowLocal) {
+			continue
+		}
+
+		v := VolumeHarian{Tanggal: tanggal, Kelengkapan: -1}
+		for _, cameraID := range kameraList {
+			if r := aktif[cameraID]; hari < r.awal || hari > r.akhir {
+				continue
+			}
+			row := perHari[hari][cameraID]
+
+			intervalDetik := location.Interval
+			if intervalKamera[cameraID] > intervalDetik {
+				intervalDetik = intervalKamera[cameraID]
+			}
+			if intervalDetik <= 0 {
+				intervalDetik = 300
+			}
+
+			k := VolumeHarianKamera{
+				CameraID:           cameraID,
+				Volume:             row.Volume,
+				IntervalTersedia:   row.Interval,
+				IntervalDiharapkan: 86400 / intervalDetik,
+			}
+			k.Kelengkapan = math.Min(float64(k.IntervalTersedia)/float64(k.IntervalDiharapkan), 1) * 100
+			v.Volume += k.Volume
+			v.Kamera = append(v.Kamera, k)
+			if v.Kelengkapan < 0 || k.Kelengkapan < v.Kelengkapan {
+				v.IntervalTersedia, v.IntervalDiharapkan, v.Kelengkapan = k.IntervalTersedia, k.IntervalDiharapkan, k.Kelengkapan
+			}
+		}
+		v.Lengkap = v.Kelengkapan >= lhrtConfig.MinKelengkapanHari
+		volumes = append(volumes, v)
+	}
+	return volumes
+}
+
+// Faktor penyesuaian satu hari dalam seminggu atau satu bulan
+type FaktorLHRTNilai struct {
+	Indeks    int     `bson:"indeks" json:"indeks"` // hari: 0 = Minggu … 6 = Sabtu; bulan: 1 … 12
+	Nama      string  `bson:"nama" json:"nama"`
+	Faktor    float64 `bson:"faktor" json:"faktor"`
+	CV        float64 `bson:"cv" json:"cv"` // koefisien variasi antar pos permanen (%)
+	JumlahPos int     `bson:"jumlah_pos" json:"jumlah_pos"`
+}
+
+// Pos permanen yang menyumbang faktor
+type FaktorLHRTPos struct {
+	LokasiID    string  `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi  string  `bson:"nama_lokasi" json:"nama_lokasi"`
+	HariLengkap int     `bson:"hari_lengkap" json:"hari_lengkap"`
+	LHRT        float64 `bson:"lhrt" json:"lhrt"`
+}
+
+// Faktor hari dan bulan satu kelompok tipe lokasi dari pos permanen
+type FaktorLHRT struct {
+	ID             string            `bson:"_id" json:"id"`
+	Kelompok       string            `bson:"kelompok" json:"kelompok"` // tipe_lokasi atau "semua"
+	PeriodeMulai   time.Time         `bson:"periode_mulai" json:"periode_mulai"`
+	PeriodeSelesai time.Time         `bson:"periode_selesai" json:"periode_selesai"`
+	PosPermanen    []FaktorLHRTPos   `bson:"pos_permanen" json:"pos_permanen"`
+	FaktorHari     []FaktorLHRTNilai `bson:"faktor_hari" json:"faktor_hari"`
+	FaktorBulan    []FaktorLHRTNilai `bson:"faktor_bulan" json:"faktor_bulan"`
+	Catatan        []string          `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Timestamp      time.Time         `bson:"timestamp" json:"timestamp"`
+}
+
+// faktorPos adalah faktor satu pos permanen; nilai 0 berarti tidak cukup data
+type faktorPos struct {
+	lhrt  float64
+	hari  [7]float64
+	bulan [13]float64
+}
+
+func rataRata(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+func simpanganBaku(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := rataRata(values)
+	total := 0.0
+	for _, v := range values {
+		total += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(total / float64(len(values)-1))
+}
+
+// hitungFaktorPos menghitung LHRT pos permanen dengan rata-rata bertingkat (AASHTO): rata-rata setiap hari
+// dalam seminggu per bulan, lalu rata-rata bulanan, lalu rata-rata tahunan. Faktor hari = rata-rata
+// mingguan / rata-rata hari tersebut, faktor bulan = LHRT / rata-rata bulan tersebut.
+func hitungFaktorPos(volumes []VolumeHarian) (faktorPos, bool) {
+	var sel [13][7][]float64
+	for _, v := range volumes {
+		if !v.Lengkap {
+			continue
+		}
+		bulan, hari := int(v.Tanggal.Month()), int(v.Tanggal.Weekday())
+		sel[bulan][hari] = append(sel[bulan][hari], float64(v.Volume))
+	}
+
+	var hasil faktorPos
+	var rataBulan [13]float64
+	var bulanAda []float64
+	var perHari [7][]float64
+	for bulan := 1; bulan <= 12; bulan++ {
+		var rataHari []float64
+		for hari := 0; hari < 7; hari++ {
+			if len(sel[bulan][hari]) == 0 {
+				continue
+			}
+			r := rataRata(sel[bulan][hari])
+			rataHari = append(rataHari, r)
+			perHari[hari] = append(perHari[hari], r)
+		}
+		if len(rataHari) >= minHariPerBulanFaktor {
+			rataBulan[bulan] = rataRata(rataHari)
+			bulanAda = append(bulanAda, rataBulan[bulan])
+		}
+	}
+	if len(bulanAda) == 0 {
+		return hasil, false
+	}
+	hasil.lhrt = rataRata(bulanAda)
+
+	var rataHari [7]float64
+	var hariAda []float64
+	for hari := 0; hari < 7; hari++ {
+		if len(perHari[hari]) > 0 {
+			rataHari[hari] = rataRata(perHari[hari])
+			hariAda = append(hariAda, rataHari[hari])
+		}
+	}
+	rataMingguan := rataRata(hariAda)
+	for hari := 0; hari < 7; hari++ {
+		if rataHari[hari] > 0 {
+			hasil.hari[hari] = rataMingguan / rataHari[hari]
+		}
+	}
+	for bulan := 1; bulan <= 12; bulan++ {
+		if rataBulan[bulan] > 0 {
+			hasil.bulan[bulan] = hasil.lhrt / rataBulan[bulan]
+		}
+	}
+	return hasil, hasil.lhrt > 0
+}
+
+// gabungFaktor merata-ratakan faktor pos permanen; faktor tanpa data bernilai 1
+func gabungFaktor(nilai [][]float64, nama []string, indeksAwal int) []FaktorLHRTNilai {
+	hasil := make([]FaktorLHRTNilai, len(nilai))
+	for i, values := range nilai {
+		f := FaktorLHRTNilai{Indeks: i + indeksAwal, Nama: nama[i+indeksAwal], Faktor: 1, JumlahPos: len(values)}
+		if len(values) > 0 {
+			f.Faktor = math.Round(rataRata(values)*10000) / 10000
+			f.CV = math.Round(simpanganBaku(values)/rataRata(values)*10000) / 100
+		}
+		hasil[i] = f
+	}
+	return hasil
+}
+
+func susunFaktorLHRT(kelompok string, startTime, endTime time.Time, pos []FaktorLHRTPos, faktor []faktorPos) FaktorLHRT {
+	hari := make([][]float64, 7)
+	bulan := make([][]float64, 12)
+	for _, f := range faktor {
+		for i := 0; i < 7; i++ {
+			if f.hari[i] > 0 {
+				hari[i] = append(hari[i], f.hari[i])
+			}
+		}
+		for i := 1; i <= 12; i++ {
+			if f.bulan[i] > 0 {
+				bulan[i-1] = append(bulan[i-1], f.bulan[i])
+			}
+		}
+	}
+
+	result := FaktorLHRT{
+		Kelompok:       kelompok,
+		PeriodeMulai:   startTime,
+		PeriodeSelesai: endTime,
+		PosPermanen:    pos,
+		FaktorHari:     gabungFaktor(hari, namaHari, 0),
+		FaktorBulan:    gabungFaktor(bulan, namaBulan, 1),
+		Timestamp:      time.Now().Add(7 * time.Hour),
+	}
+	for _, f := range result.FaktorBulan {
+		if f.JumlahPos == 0 {
+			result.Catatan = append(result.Catatan, fmt.Sprintf("Bulan %s tidak memiliki data pos permanen, faktor = 1", f.Nama))
+		}
+	}
+	return result
+}
+
+// HitungFaktorLHRT menentukan pos permanen (lokasi ruas dengan minimal MinHariPermanen hari lengkap pada
+// periode), menghitung faktornya, lalu merata-ratakan per tipe_lokasi dan untuk seluruh pos (kelompok "semua")
+func HitungFaktorLHRT(startTime, endTime time.Time, excludeFlagged bool) ([]FaktorLHRT, error) {
+	cursor, err := database.DB.Collection("locations").Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var locations []Location
+	if err = cursor.All(context.Background(), &locations); err != nil {
+		return nil, err
+	}
+
+	posPerKelompok := make(map[string][]FaktorLHRTPos)
+	faktorPerKelompok := make(map[string][]faktorPos)
+	for i := range locations {
+		location := &locations[i]
+		if location.IsSimpang() {
+			continue
+		}
+		volumes, err := GetVolumeHarian(location, startTime, endTime, excludeFlagged)
+		if err != nil {
+			return nil, err
+		}
+		hariLengkap := 0
+		for _, v := range volumes {
+			if v.Lengkap {
+				hariLengkap++
+			}
+		}
+		if hariLengkap < lhrtConfig.MinHariPermanen {
+			continue
+		}
+		f, ok := hitungFaktorPos(volumes)
+		if !ok {
+			continue
+		}
+
+		pos := FaktorLHRTPos{
+			LokasiID:    location.ID,
+			NamaLokasi:  location.Nama_lokasi,
+			HariLengkap: hariLengkap,
+			LHRT:        math.Round(f.lhrt),
+		}
+		for _, kelompok := range []string{location.Tipe_lokasi, KelompokFaktorSemua} {
+			posPerKelompok[kelompok] = append(posPerKelompok[kelompok], pos)
+			faktorPerKelompok[kelompok] = append(faktorPerKelompok[kelompok], f)
+		}
+	}
+	if len(posPerKelompok) == 0 {
+		return nil, ErrTidakAdaPosPermanen
+	}
+
+	kelompokList := make([]string, 0, len(posPerKelompok))
+	for kelompok := range posPerKelompok {
+		kelompokList = append(kelompokList, kelompok)
+	}
+	sort.Strings(kelompokList)
+
+	hasil := make([]FaktorLHRT, 0, len(kelompokList))
+	for _, kelompok := range kelompokList {
+		hasil = append(hasil, susunFaktorLHRT(kelompok, startTime, endTime, posPerKelompok[kelompok], faktorPerKelompok[kelompok]))
+	}
+	return hasil, nil
+}
+
+func NextFaktorLHRTID() (string, error) {
+	collection := database.DB.Collection("faktor_lhrt")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last FaktorLHRT
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "FLHRT-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "FLHRT-%d", &lastNum)
+	return fmt.Sprintf("FLHRT-%05d", lastNum+1), nil
+}
+
+func SaveFaktorLHRT(faktor *FaktorLHRT) error {
+	id, err := NextFaktorLHRTID()
+	if err != nil {
+		return err
+	}
+	faktor.ID = id
+
+	_, err = database.DB.Collection("faktor_lhrt").InsertOne(context.Background(), faktor)
+	return err
+}
+
+// GetLatestFaktorLHRT mengambil faktor terbaru setiap kelompok
+func GetLatestFaktorLHRT() ([]FaktorLHRT, error) {
+	pipeline := []bson.M{
+		{"$sort": bson.M{"timestamp": -1}},
+		{"$group": bson.M{"_id": "$kelompok", "latest": bson.M{"$first": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$latest"}},
+		{"$sort": bson.M{"kelompok": 1}},
+	}
+
+	cursor, err := database.DB.Collection("faktor_lhrt").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var faktor []FaktorLHRT
+	if err = cursor.All(context.Background(), &faktor); err != nil {
+		return nil, err
+	}
+	return faktor, nil
+}
+
+func GetFaktorLHRTByID(id string) (*FaktorLHRT, error) {
+	var faktor FaktorLHRT
+	err := database.DB.Collection("faktor_lhrt").FindOne(context.Background(), bson.M{"_id": id}).Decode(&faktor)
+	if err != nil {
+		return nil, err
+	}
+	return &faktor, nil
+}
+
+// GetFaktorLHRTBerlaku mengambil faktor terbaru kelompok tipe lokasi yang periodenya dimulai sebelum waktu,
+// atau faktor kelompok "semua" jika tipe lokasi belum memiliki pos permanen. nil jika belum ada faktor.
+func GetFaktorLHRTBerlaku(tipeLokasi string, sebelum time.Time) (*FaktorLHRT, error) {
+	for _, kelompok := range []string{tipeLokasi, KelompokFaktorSemua} {
+		var faktor FaktorLHRT
+		err := database.DB.Collection("faktor_lhrt").FindOne(
+			context.Background(),
+			bson.M{"kelompok": kelompok, "periode_mulai": bson.M{"$lt": sebelum}},
+			options.FindOne().SetSort(bson.D{{Key: "periode_selesai", Value: -1}, {Key: "timestamp", Value: -1}}),
+		).Decode(&faktor)
+		if err == nil {
+			return &faktor, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Volume satu hari lengkap setelah dikalikan faktor hari dan bulan
+type LHRTHarian struct {
+	Tanggal     time.Time `bson:"tanggal" json:"tanggal"`
+	Volume      int       `bson:"volume" json:"volume"`
+	FaktorHari  float64   `bson:"faktor_hari" json:"faktor_hari"`
+	FaktorBulan float64   `bson:"faktor_bulan" json:"faktor_bulan"`
+	LHRT        float64   `bson:"lhrt" json:"lhrt"`
+}
+
+// Estimasi lalu lintas harian rata-rata tahunan (LHRT) lokasi
+type LHRTEstimate struct {
+	ID               string       `bson:"_id,omitempty" json:"id,omitempty"`
+	LokasiID         string       `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi       string       `bson:"nama_lokasi" json:"nama_lokasi"`
+	TipeLokasi       string       `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	Tahun            int          `bson:"tahun,omitempty" json:"tahun,omitempty"` // diisi untuk LHRT tahunan tersimpan
+	PeriodeMulai     time.Time    `bson:"periode_mulai" json:"periode_mulai"`
+	PeriodeSelesai   time.Time    `bson:"periode_selesai" json:"periode_selesai"`
+	Metode           string       `bson:"metode" json:"metode"`
+	FaktorID         string       `bson:"faktor_id,omitempty" json:"faktor_id,omitempty"`
+	KelompokFaktor   string       `bson:"kelompok_faktor,omitempty" json:"kelompok_faktor,omitempty"`
+	HariLengkap      int          `bson:"hari_lengkap" json:"hari_lengkap"`
+	HariTidakLengkap int          `bson:"hari_tidak_lengkap" json:"hari_tidak_lengkap"`
+	LHR              float64      `bson:"lhr" json:"lhr"`                     // rata-rata volume hari lengkap tanpa penyesuaian
+	LHRT             float64      `bson:"lhrt" json:"lhrt"`                   // kendaraan/hari
+	GalatRelatif     float64      `bson:"galat_relatif" json:"galat_relatif"` // perkiraan galat 95% (%)
+	Kepercayaan      string       `bson:"kepercayaan" json:"kepercayaan"`
+	Harian           []LHRTHarian `bson:"harian" json:"harian"`
+	Catatan          []string     `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Timestamp        time.Time    `bson:"timestamp" json:"timestamp"`
+}
+
+func tingkatKepercayaan(galatRelatif float64) string {
+	switch {
+	case galatRelatif <= 10:
+		return KepercayaanTinggi
+	case galatRelatif <= 20:
+		return KepercayaanSedang
+	}
+	return KepercayaanRendah
+}
+
+// EstimasiLHRT mengubah volume harian menjadi LHRT. Lokasi dengan hari lengkap ≥ MinHariPermanen dihitung
+// langsung sebagai pos permanen; selain itu setiap hari lengkap dikalikan faktor hari dan bulan lalu
+// dirata-rata. Galat relatif 95% menggabungkan variasi antar hari hasil penyesuaian dan CV faktor.
+func EstimasiLHRT(location Location, volumes []VolumeHarian, faktor *FaktorLHRT, startTime, endTime time.Time) *LHRTEstimate {
+	estimate := &LHRTEstimate{
+		LokasiID:       location.ID,
+		NamaLokasi:     location.Nama_lokasi,
+		TipeLokasi:     location.Tipe_lokasi,
+		PeriodeMulai:   startTime,
+		PeriodeSelesai: endTime,
+		Harian:         []LHRTHarian{},
+		Kepercayaan:    KepercayaanRendah,
+		Timestamp:      time.Now().Add(7 * time.Hour),
+	}
+
+	var lengkap []VolumeHarian
+	totalVolume := 0
+	for _, v := range volumes {
+		if !v.Lengkap {
+			estimate.HariTidakLengkap++
+			continue
+		}
+		lengkap = append(lengkap, v)
+		totalVolume += v.Volume
+	}
+	estimate.HariLengkap = len(lengkap)
+	if len(lengkap) == 0 {
+		estimate.Metode = LHRTMetodeTanpaFaktor
+		estimate.Catatan = append(estimate.Catatan, fmt.Sprintf("Tidak ada hari dengan kelengkapan data ≥ %.0f%%", lhrtConfig.MinKelengkapanHari))
+		return estimate
+	}
+	estimate.LHR = math.Round(float64(totalVolume) / float64(len(lengkap)))
+
+	// Faktor yang dipakai: faktor lokasi sendiri (pos permanen), faktor kelompok, atau 1
+	var hari [7]float64
+	var bulan [13]float64
+	var cvHari [7]float64
+	var cvBulan [13]float64
+	for i := range hari {
+		hari[i] = 1
+	}
+	for i := range bulan {
+		bulan[i] = 1
+	}
+
+	lhrtPos := 0.0
+	var sendiri faktorPos
+	permanen := false
+	if estimate.HariLengkap >= lhrtConfig.MinHariPermanen {
+		sendiri, permanen = hitungFaktorPos(volumes)
+	}
+	if permanen {
+		f := sendiri
+		estimate.Metode = LHRTMetodePosPermanen
+		lhrtPos = f.lhrt
+		for i, v := range f.hari {
+			if v > 0 {
+				hari[i] = v
+			}
+		}
+		for i, v := range f.bulan {
+			if v > 0 {
+				bulan[i] = v
+			}
+		}
+	} else if faktor != nil {
+		estimate.Metode = LHRTMetodeFaktor
+		estimate.FaktorID = faktor.ID
+		estimate.KelompokFaktor = faktor.Kelompok
+		for _, f := range faktor.FaktorHari {
+			hari[f.Indeks], cvHari[f.Indeks] = f.Faktor, f.CV
+		}
+		for _, f := range faktor.FaktorBulan {
+			bulan[f.Indeks], cvBulan[f.Indeks] = f.Faktor, f.CV
+			if f.JumlahPos == 0 {
+				cvBulan[f.Indeks] = variasiHarianDefault * 100
+			}
+		}
+		if faktor.Kelompok != location.Tipe_lokasi {
+			estimate.Catatan = append(estimate.Catatan, fmt.Sprintf("Tipe lokasi %s belum memiliki pos permanen, memakai faktor kelompok %s", location.Tipe_lokasi, faktor.Kelompok))
+		}
+	} else {
+		estimate.Metode = LHRTMetodeTanpaFaktor
+		estimate.Catatan = append(estimate.Catatan, "Belum ada faktor hari/bulan, LHRT = rata-rata volume harian tanpa penyesuaian musiman")
+	}
+
+	disesuaikan := make([]float64, 0, len(lengkap))
+	cvFaktor := make([]float64, 0, len(lengkap))
+	for _, v := range lengkap {
+		h, b := int(v.Tanggal.Weekday()), int(v.Tanggal.Month())
+		nilai := float64(v.Volume) * hari[h] * bulan[b]
+		disesuaikan = append(disesuaikan, nilai)
+		cvFaktor = append(cvFaktor, math.Hypot(cvHari[h], cvBulan[b])/100)
+		estimate.Harian = append(estimate.Harian, LHRTHarian{
+			Tanggal:     v.Tanggal,
+			Volume:      v.Volume,
+			FaktorHari:  hari[h],
+			FaktorBulan: bulan[b],
+			LHRT:        math.Round(nilai),
+		})
+	}
+
+	estimate.LHRT = rataRata(disesuaikan)
+	if lhrtPos > 0 {
+		estimate.LHRT = lhrtPos
+	}
+	estimate.LHRT = math.Round(estimate.LHRT)
+
+	// Galat sampling rata-rata harian (berkurang dengan jumlah hari) dan galat faktor (tidak berkurang)
+	galatSampel := variasiHarianDefault
+	if len(disesuaikan) > 1 && rataRata(disesuaikan) > 0 {
+		galatSampel = simpanganBaku(disesuaikan) / rataRata(disesuaikan) / math.Sqrt(float64(len(disesuaikan)))
+	}
+	galat := 1.96 * math.Hypot(galatSampel, rataRata(cvFaktor))
+	if estimate.Metode == LHRTMetodeTanpaFaktor {
+		// tanpa faktor, variasi musiman tidak terkoreksi
+		galat = 1.96 * math.Hypot(galatSampel, variasiHarianDefault)
+	}
+	estimate.GalatRelatif = math.Round(galat*10000) / 100
+	estimate.Kepercayaan = tingkatKepercayaan(estimate.GalatRelatif)
+	if estimate.Metode == LHRTMetodeTanpaFaktor {
+		estimate.Kepercayaan = KepercayaanRendah
+	}
+	return estimate
+}
+
+// HitungLHRT mengestimasi LHRT dari hitungan lokasi pada periode memakai faktor yang berlaku
+func HitungLHRT(location *Location, startTime, endTime time.Time, excludeFlagged bool) (*LHRTEstimate, error) {
+	volumes, err := GetVolumeHarian(location, startTime, endTime, excludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+	faktor, err := GetFaktorLHRTBerlaku(location.Tipe_lokasi, endTime)
+	if err != nil {
+		return nil, err
+	}
+	estimate := EstimasiLHRT(*location, volumes, faktor, startTime, endTime)
+	if location.IsSimpang() {
+		estimate.Catatan = append(estimate.Catatan, "Volume simpang adalah jumlah arus masuk seluruh pendekat")
+	}
+	return estimate, nil
+}
+
+// HitungLHRTTahunan mengestimasi LHRT lokasi dari seluruh hari lengkap pada tahun kalender
+func HitungLHRTTahunan(location *Location, tahun int, excludeFlagged bool) (*LHRTEstimate, error) {
+	startTime := time.Date(tahun, time.January, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.AddDate(1, 0, 0)
+
+	estimate, err := HitungLHRT(location, startTime, endTime, excludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+	estimate.Tahun = tahun
+	return estimate, nil
+}
+
+// SaveLHRTTahunan menyimpan LHRT tahunan, menggantikan hasil sebelumnya untuk lokasi dan tahun yang sama
+func SaveLHRTTahunan(estimate *LHRTEstimate) error {
+	collection := database.DB.Collection("lhrt")
+
+	var existing LHRTEstimate
+	err := collection.FindOne(context.Background(), bson.M{"lokasi_id": estimate.LokasiID, "tahun": estimate.Tahun}).Decode(&existing)
+	if err == nil {
+		estimate.ID = existing.ID
+		_, err = collection.ReplaceOne(context.Background(), bson.M{"_id": existing.ID}, estimate)
+		return err
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	id, err := NextLHRTID()
+	if err != nil {
+		return err
+	}
+	estimate.ID = id
+	_, err = collection.InsertOne(context.Background(), estimate)
+	return err
+}
+
+func NextLHRTID() (string, error) {
+	collection := database.DB.Collection("lhrt")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last LHRTEstimate
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "LHRT-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "LHRT-%d", &lastNum)
+	return fmt.Sprintf("LHRT-%05d", lastNum+1), nil
+}
+
+// GetLHRTTahunanByLokasiID mengambil LHRT tahunan lokasi, tahun terbaru lebih dulu (tanpa rincian harian)
+func GetLHRTTahunanByLokasiID(lokasiID string) ([]LHRTEstimate, error) {
+	return findLHRTTahunan(bson.M{"lokasi_id": lokasiID}, bson.D{{Key: "tahun", Value: -1}})
+}
+
+// GetLHRTTahunanByTahun mengambil LHRT seluruh lokasi pada satu tahun (tanpa rincian harian)
+func GetLHRTTahunanByTahun(tahun int) ([]LHRTEstimate, error) {
+	return findLHRTTahunan(bson.M{"tahun": tahun}, bson.D{{Key: "lokasi_id", Value: 1}})
+}
+
+func findLHRTTahunan(filter bson.M, sort bson.D) ([]LHRTEstimate, error) {
+	findOptions := options.Find().SetSort(sort).SetProjection(bson.M{"harian": 0})
+	cursor, err := database.DB.Collection("lhrt").Find(context.Background(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimates []LHRTEstimate
+	if err = cursor.All(context.Background(), &estimates); err != nil {
+		return nil, err
+	}
+	return estimates, nil
+}
+
+func GetLHRTTahunan(lokasiID string, tahun int) (*LHRTEstimate, error) {
+	var estimate LHRTEstimate
+	err := database.DB.Collection("lhrt").FindOne(context.Background(), bson.M{"lokasi_id": lokasiID, "tahun": tahun}).Decode(&estimate)
+	if err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
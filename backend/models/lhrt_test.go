@@ -0,0 +1,299 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Pola volume pos permanen: faktor per hari dalam seminggu (Minggu … Sabtu) dan per bulan
+var (
+	polaHariUji  = [7]float64{0.8, 1.0, 1.05, 1.05, 1.05, 1.1, 0.95}
+	polaBulanUji = [13]float64{0, 0.92, 0.94, 0.96, 0.98, 1.0, 1.02, 1.04, 1.06, 1.08, 1.1, 1.12, 1.14}
+)
+
+// volumeTahunanUji membuat volume harian lengkap satu tahun dengan volume = 10000 × pola hari × pola bulan
+func volumeTahunanUji(tahun int) []VolumeHarian {
+	var volumes []VolumeHarian
+	for tanggal := time.Date(tahun, 1, 1, 0, 0, 0, 0, time.UTC); tanggal.Year() == tahun; tanggal = tanggal.AddDate(0, 0, 1) {
+		volume := int(math.Round(10000 * polaHariUji[tanggal.Weekday()] * polaBulanUji[tanggal.Month()]))
+		volumes = append(volumes, VolumeHarian{Tanggal: tanggal, Volume: volume, Kelengkapan: 100, Lengkap: true})
+	}
+	return volumes
+}
+
+// faktorLHRTUji: Senin 0.95 (CV 4%), Selasa 1.0 (CV 3%), Sabtu 1.2 (CV 8%), hari lain 1 (CV 5%);
+// Maret 1.1 (CV 5%), Desember tanpa pos permanen, bulan lain 1 (CV 5%)
+func faktorLHRTUji(kelompok string) *FaktorLHRT {
+	faktor := &FaktorLHRT{ID: "FLHRT-00001", Kelompok: kelompok}
+	for i := 0; i < 7; i++ {
+		f := FaktorLHRTNilai{Indeks: i, Nama: namaHari[i], Faktor: 1, CV: 5, JumlahPos: 3}
+		switch i {
+		case 1:
+			f.Faktor, f.CV = 0.95, 4
+		case 2:
+			f.CV = 3
+		case 6:
+			f.Faktor, f.CV = 1.2, 8
+		}
+		faktor.FaktorHari = append(faktor.FaktorHari, f)
+	}
+	for i := 1; i <= 12; i++ {
+		f := FaktorLHRTNilai{Indeks: i, Nama: namaBulan[i], Faktor: 1, CV: 5, JumlahPos: 3}
+		switch i {
+		case 3:
+			f.Faktor = 1.1
+		case 12:
+			f.CV, f.JumlahPos = 0, 0
+		}
+		faktor.FaktorBulan = append(faktor.FaktorBulan, f)
+	}
+	return faktor
+}
+
+func hariUji(tahun int, bulan time.Month, tanggal, volume int, lengkap bool) VolumeHarian {
+	return VolumeHarian{Tanggal: time.Date(tahun, bulan, tanggal, 0, 0, 0, 0, time.UTC), Volume: volume, Lengkap: lengkap}
+}
+
+func rataRataPola(pola []float64) float64 {
+	total := 0.0
+	for _, v := range pola {
+		total += v
+	}
+	return total / float64(len(pola))
+}
+
+// Rata-rata bertingkat AASHTO menghilangkan bobot jumlah hari per bulan dan jumlah Senin…Minggu dalam bulan:
+// LHRT = 10000 × rata-rata pola hari × rata-rata pola bulan, bukan rata-rata 365 hari (10306.38)
+func TestHitungFaktorPos(t *testing.T) {
+	volumes := volumeTahunanUji(2025)
+	rataHari, rataBulan := rataRataPola(polaHariUji[:]), rataRataPola(polaBulanUji[1:])
+
+	f, ok := hitungFaktorPos(volumes)
+	if !ok {
+		t.Fatal("pos permanen tidak dihitung")
+	}
+	if want := 10000 * rataHari * rataBulan; math.Abs(f.lhrt-want) > 1e-6 {
+		t.Fatalf("LHRT %.4f, ingin %.4f", f.lhrt, want)
+	}
+	for hari := 0; hari < 7; hari++ {
+		if want := rataHari / polaHariUji[hari]; math.Abs(f.hari[hari]-want) > 1e-9 {
+			t.Errorf("faktor %s %.5f, ingin %.5f", namaHari[hari], f.hari[hari], want)
+		}
+	}
+	for bulan := 1; bulan <= 12; bulan++ {
+		if want := rataBulan / polaBulanUji[bulan]; math.Abs(f.bulan[bulan]-want) > 1e-9 {
+			t.Errorf("faktor %s %.5f, ingin %.5f", namaBulan[bulan], f.bulan[bulan], want)
+		}
+	}
+
+	// Faktor mengembalikan setiap hari lengkap ke LHRT
+	for _, v := range volumes[:14] {
+		if got := float64(v.Volume) * f.hari[v.Tanggal.Weekday()] * f.bulan[v.Tanggal.Month()]; math.Abs(got-f.lhrt) > 1e-6 {
+			t.Fatalf("%s: %d × faktor = %.4f, ingin %.4f", v.Tanggal.Format("2006-01-02"), v.Volume, got, f.lhrt)
+		}
+	}
+}
+
+func TestHitungFaktorPosBulanTidakLengkap(t *testing.T) {
+	volumes := volumeTahunanUji(2025)
+	// Februari hanya Sabtu dan Minggu yang lengkap (kurang dari 5 hari dalam seminggu), volumenya tidak dipakai
+	for i := range volumes {
+		if volumes[i].Tanggal.Month() == time.February {
+			if hari := volumes[i].Tanggal.Weekday(); hari != time.Saturday && hari != time.Sunday {
+				volumes[i].Lengkap = false
+			} else {
+				volumes[i].Volume *= 10
+			}
+		}
+	}
+
+	f, ok := hitungFaktorPos(volumes)
+	if !ok {
+		t.Fatal("pos permanen tidak dihitung")
+	}
+	var bulanAda []float64
+	for bulan := 1; bulan <= 12; bulan++ {
+		if bulan != 2 {
+			bulanAda = append(bulanAda, polaBulanUji[bulan])
+		}
+	}
+	if want := 10000 * rataRataPola(polaHariUji[:]) * rataRataPola(bulanAda); math.Abs(f.lhrt-want) > 1e-6 {
+		t.Fatalf("LHRT %.4f, ingin %.4f tanpa Februari", f.lhrt, want)
+	}
+	if f.bulan[2] != 0 {
+		t.Fatalf("faktor Februari %.4f, ingin 0 (tidak cukup data)", f.bulan[2])
+	}
+
+	if _, ok := hitungFaktorPos([]VolumeHarian{hariUji(2025, 3, 3, 10000, true)}); ok {
+		t.Fatal("satu hari tidak boleh menghasilkan faktor pos permanen")
+	}
+}
+
+func TestEstimasiLHRT(t *testing.T) {
+	location := Location{ID: "LOK-1", Nama_lokasi: "Ruas Uji", Tipe_lokasi: "perkotaan"}
+	mulai := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	selesai := mulai.AddDate(0, 1, 0)
+	// Senin, Selasa, Sabtu lengkap dan satu Rabu tidak lengkap
+	volumes := []VolumeHarian{
+		hariUji(2025, 3, 3, 10000, true),
+		hariUji(2025, 3, 4, 10500, true),
+		hariUji(2025, 3, 5, 4000, false),
+		hariUji(2025, 3, 8, 8000, true),
+	}
+
+	tests := []struct {
+		nama        string
+		volumes     []VolumeHarian
+		faktor      *FaktorLHRT
+		metode      string
+		lhr         float64
+		lhrt        float64
+		harian      []float64
+		galat       float64
+		kepercayaan string
+		catatan     string
+	}{
+		// 10000 × 0.95 × 1.1, 10500 × 1.0 × 1.1, 8000 × 1.2 × 1.1; CV faktor √(CV hari² + CV bulan²)
+		{"faktor tipe lokasi", volumes, faktorLHRTUji("perkotaan"), LHRTMetodeFaktor, 9500, 10853,
+			[]float64{10450, 11550, 10560}, 15.5, KepercayaanSedang, ""},
+		{"faktor kelompok semua", volumes, faktorLHRTUji(KelompokFaktorSemua), LHRTMetodeFaktor, 9500, 10853,
+			[]float64{10450, 11550, 10560}, 15.5, KepercayaanSedang, "memakai faktor kelompok semua"},
+		// Variasi musiman 15% ditambahkan dan kepercayaan selalu rendah
+		{"tanpa faktor", volumes, nil, LHRTMetodeTanpaFaktor, 9500, 9500,
+			[]float64{10000, 10500, 8000}, 33.36, KepercayaanRendah, "Belum ada faktor"},
+		// Satu hari: galat sampel 15%, bulan tanpa pos permanen memakai CV 15%
+		{"satu hari bulan tanpa pos", []VolumeHarian{hariUji(2025, 12, 1, 10000, true)}, faktorLHRTUji("perkotaan"),
+			LHRTMetodeFaktor, 10000, 9500, []float64{9500}, 42.31, KepercayaanRendah, ""},
+	}
+	for _, tt := range tests {
+		estimate := EstimasiLHRT(location, tt.volumes, tt.faktor, mulai, selesai)
+		if estimate.Metode != tt.metode || estimate.LHR != tt.lhr || estimate.LHRT != tt.lhrt {
+			t.Errorf("%s: metode %s LHR %.0f LHRT %.0f, ingin %s, %.0f, %.0f", tt.nama,
+				estimate.Metode, estimate.LHR, estimate.LHRT, tt.metode, tt.lhr, tt.lhrt)
+		}
+		if estimate.HariLengkap != len(tt.harian) || len(estimate.Harian) != len(tt.harian) {
+			t.Errorf("%s: %d hari lengkap, %d harian, ingin %d", tt.nama, estimate.HariLengkap, len(estimate.Harian), len(tt.harian))
+			continue
+		}
+		for i, h := range estimate.Harian {
+			if h.LHRT != tt.harian[i] || math.Abs(float64(h.Volume)*h.FaktorHari*h.FaktorBulan-h.LHRT) > 0.5 {
+				t.Errorf("%s: %s LHRT %.0f (%d × %.2f × %.2f), ingin %.0f", tt.nama, h.Tanggal.Format("2006-01-02"),
+					h.LHRT, h.Volume, h.FaktorHari, h.FaktorBulan, tt.harian[i])
+			}
+		}
+		if estimate.GalatRelatif != tt.galat || estimate.Kepercayaan != tt.kepercayaan {
+			t.Errorf("%s: galat %.2f%% kepercayaan %s, ingin %.2f%% dan %s", tt.nama,
+				estimate.GalatRelatif, estimate.Kepercayaan, tt.galat, tt.kepercayaan)
+		}
+		catatan := strings.Join(estimate.Catatan, "\n")
+		if (tt.catatan == "" && catatan != "") || !strings.Contains(catatan, tt.catatan) {
+			t.Errorf("%s: catatan %q, ingin %q", tt.nama, catatan, tt.catatan)
+		}
+		if tt.faktor != nil && (estimate.FaktorID != tt.faktor.ID || estimate.KelompokFaktor != tt.faktor.Kelompok) {
+			t.Errorf("%s: faktor %s %s", tt.nama, estimate.FaktorID, estimate.KelompokFaktor)
+		}
+	}
+
+	if estimate := EstimasiLHRT(location, volumes, nil, mulai, selesai); estimate.HariTidakLengkap != 1 {
+		t.Errorf("hari tidak lengkap %d, ingin 1", estimate.HariTidakLengkap)
+	}
+	estimate := EstimasiLHRT(location, []VolumeHarian{hariUji(2025, 3, 5, 4000, false)}, faktorLHRTUji("perkotaan"), mulai, selesai)
+	if estimate.LHRT != 0 || estimate.Metode != LHRTMetodeTanpaFaktor || estimate.Kepercayaan != KepercayaanRendah ||
+		!strings.Contains(strings.Join(estimate.Catatan, "\n"), "Tidak ada hari") {
+		t.Errorf("tanpa hari lengkap: LHRT %.0f metode %s catatan %v", estimate.LHRT, estimate.Metode, estimate.Catatan)
+	}
+}
+
+func TestEstimasiLHRTPosPermanen(t *testing.T) {
+	volumes := volumeTahunanUji(2025)
+	mulai := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Lokasi dengan hari lengkap ≥ MinHariPermanen memakai faktornya sendiri walaupun ada faktor kelompok
+	estimate := EstimasiLHRT(Location{ID: "LOK-1", Tipe_lokasi: "perkotaan"}, volumes, faktorLHRTUji("perkotaan"), mulai, mulai.AddDate(1, 0, 0))
+	if estimate.Metode != LHRTMetodePosPermanen || estimate.FaktorID != "" || estimate.LHRT != 10300 {
+		t.Fatalf("metode %s faktor %q LHRT %.0f, ingin pos_permanen tanpa faktor dan 10300", estimate.Metode, estimate.FaktorID, estimate.LHRT)
+	}
+	// Setiap hari yang disesuaikan sama dengan LHRT sehingga galat hanya dari pembulatan
+	if estimate.GalatRelatif > 0.01 || estimate.Kepercayaan != KepercayaanTinggi {
+		t.Fatalf("galat %.2f%% kepercayaan %s, ingin ≈ 0 dan tinggi", estimate.GalatRelatif, estimate.Kepercayaan)
+	}
+
+	// Di bawah MinHariPermanen hari lengkap, faktor kelompok yang dipakai
+	estimate = EstimasiLHRT(Location{ID: "LOK-1", Tipe_lokasi: "perkotaan"}, volumes[:200], faktorLHRTUji("perkotaan"), mulai, mulai.AddDate(1, 0, 0))
+	if estimate.Metode != LHRTMetodeFaktor {
+		t.Fatalf("200 hari: metode %s, ingin faktor", estimate.Metode)
+	}
+}
+
+func TestTingkatKepercayaan(t *testing.T) {
+	tests := []struct {
+		galat float64
+		ingin string
+	}{
+		{0, KepercayaanTinggi}, {10, KepercayaanTinggi}, {10.01, KepercayaanSedang},
+		{20, KepercayaanSedang}, {20.01, KepercayaanRendah}, {80, KepercayaanRendah},
+	}
+	for _, tt := range tests {
+		if got := tingkatKepercayaan(tt.galat); got != tt.ingin {
+			t.Errorf("galat %.2f%%: %s, ingin %s", tt.galat, got, tt.ingin)
+		}
+	}
+}
+
+// Kelengkapan hari dihitung per kamera: satu kamera yang terputus membuat hari tidak lengkap walaupun
+// kamera lain lengkap, dan kamera hanya diharapkan pada rentang hari datanya
+func TestSusunVolumeHarian(t *testing.T) {
+	location := &Location{ID: "LOK-1", Interval: 300}
+	rows := []volumeHarianKameraRow{
+		{Tanggal: "2025-03-01", CameraID: "CAM-1", Volume: 5000, Interval: 288, IntervalMenit: 5},
+		{Tanggal: "2025-03-01", CameraID: "CAM-2", Volume: 4000, Interval: 288, IntervalMenit: 5},
+		{Tanggal: "2025-03-02", CameraID: "CAM-1", Volume: 5000, Interval: 288, IntervalMenit: 5},
+		{Tanggal: "2025-03-02", CameraID: "CAM-2", Volume: 2000, Interval: 144, IntervalMenit: 5},
+		{Tanggal: "2025-03-03", CameraID: "CAM-1", Volume: 5000, Interval: 288, IntervalMenit: 5},
+		// CAM-3 mulai mengirim 4 Maret dengan interval 15 menit
+		{Tanggal: "2025-03-04", CameraID: "CAM-1", Volume: 5000, Interval: 280, IntervalMenit: 5},
+		{Tanggal: "2025-03-04", CameraID: "CAM-2", Volume: 4000, Interval: 288, IntervalMenit: 5},
+		{Tanggal: "2025-03-04", CameraID: "CAM-3", Volume: 1000, Interval: 96, IntervalMenit: 15},
+		{Tanggal: "2025-03-05", CameraID: "CAM-1", Volume: 2000, Interval: 100, IntervalMenit: 5},
+	}
+	volumes := susunVolumeHarian(location, rows, time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		tanggal     string
+		volume      int
+		kamera      int
+		tersedia    int
+		diharapkan  int
+		kelengkapan float64
+		lengkap     bool
+	}{
+		{"2025-03-01", 9000, 2, 288, 288, 100, true},
+		{"2025-03-02", 7000, 2, 144, 288, 50, false},
+		// CAM-2 aktif sebelum dan sesudahnya, jadi hari tanpa datanya berkelengkapan 0%
+		{"2025-03-03", 5000, 2, 0, 288, 0, false},
+		{"2025-03-04", 10000, 3, 280, 288, 280.0 / 288 * 100, true},
+		// 5 Maret belum selesai
+	}
+	if len(volumes) != len(tests) {
+		t.Fatalf("%d hari, ingin %d", len(volumes), len(tests))
+	}
+	for i, tt := range tests {
+		v := volumes[i]
+		if v.Tanggal.Format("2006-01-02") != tt.tanggal || v.Volume != tt.volume || len(v.Kamera) != tt.kamera {
+			t.Errorf("%s: tanggal %s volume %d kamera %d, ingin %d dan %d", tt.tanggal, v.Tanggal.Format("2006-01-02"),
+				v.Volume, len(v.Kamera), tt.volume, tt.kamera)
+		}
+		if v.IntervalTersedia != tt.tersedia || v.IntervalDiharapkan != tt.diharapkan ||
+			math.Abs(v.Kelengkapan-tt.kelengkapan) > 1e-9 || v.Lengkap != tt.lengkap {
+			t.Errorf("%s: interval %d/%d kelengkapan %.2f lengkap %v, ingin %d/%d, %.2f, %v", tt.tanggal,
+				v.IntervalTersedia, v.IntervalDiharapkan, v.Kelengkapan, v.Lengkap, tt.tersedia, tt.diharapkan, tt.kelengkapan, tt.lengkap)
+		}
+	}
+	for _, k := range volumes[3].Kamera {
+		if k.CameraID == "CAM-3" && (k.IntervalDiharapkan != 96 || k.Kelengkapan != 100) {
+			t.Errorf("CAM-3: interval %d/%d, ingin 96/96", k.IntervalTersedia, k.IntervalDiharapkan)
+		}
+	}
+}
@@ -53,6 +53,7 @@ type Location struct {
 	Nama_lokasi      string    `bson:"nama_lokasi" json:"nama_lokasi"`
 	Alamat_lokasi    string    `bson:"alamat_lokasi" json:"alamat_lokasi"`
 	Tipe_lokasi      string    `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	Jenis_lokasi     string    `bson:"jenis_lokasi,omitempty" json:"jenis_lokasi,omitempty"` // ruas (default), simpang_apill, simpang_tak_bersinyal
 	Tipe_arah        string    `bson:"tipe_arah" json:"tipe_arah"`
 	Lebar_jalur      int       `bson:"lebar_jalur" json:"lebar_jalur"`
 	Persentase       string    `bson:"persentase" json:"persentase"`
@@ -68,6 +69,9 @@ type Location struct {
 	Keterangan       string    `bson:"keterangan" json:"keterangan"`
 	Timestamp        time.Time `bson:"timestamp" json:"timestamp"`
 	LastDataReceived time.Time `bson:"last_data_received,omitempty" json:"last_data_received,omitempty"`
+
+	// Geometri pendekat dan pemetaan zona arah ke gerakan, hanya untuk lokasi simpang
+	Simpang *SimpangConfig `bson:"simpang,omitempty" json:"simpang,omitempty"`
 }
 
 func IsValidTipeLokasi(value string) bool {
@@ -74,29 +74,35 @@ type MKJICount struct {
 }
 
 type MKJIAnalysis struct {
-	ID                 string    `bson:"_id" json:"id"`
-	LokasiID           string    `bson:"lokasi_id" json:"lokasi_id"`
-	NamaLokasi         string    `bson:"nama_lokasi" json:"nama_lokasi"`
-	TipeLokasi         string    `bson:"tipe_lokasi" json:"tipe_lokasi"`
-	TipeArah           string    `bson:"tipe_arah" json:"tipe_arah"`
-	Tanggal            time.Time `bson:"tanggal" json:"tanggal"`
-	PeriodeHari        int       `bson:"periode_hari" json:"periode_hari"`
-	Timestamp          time.Time `bson:"timestamp" json:"timestamp"`
-	MKJICount          MKJICount `bson:"mkji_count" json:"mkji_count"`
-	TotalKendaraanHari int       `bson:"total_kendaraan_hari" json:"total_kendaraan_hari"`
-	LHR                float64   `bson:"lhr" json:"lhr"`
-	LHRSMP             float64   `bson:"lhr_smp" json:"lhr_smp"`
-	ArusLaluLintas     float64   `bson:"arus_lalu_lintas" json:"arus_lalu_lintas"`   // Q (smp/jam) - volume jam puncak
-	JamPuncak          string    `bson:"jam_puncak" json:"jam_puncak"`               // Jam puncak (misal "07:00-08:00")
-	KapasitasDasar     float64   `bson:"kapasitas_dasar" json:"kapasitas_dasar"`     // Co (smp/jam)
-	FCW                float64   `bson:"fcw" json:"fcw"`                             // Faktor penyesuaian lebar jalur
-	FCSP               float64   `bson:"fcsp" json:"fcsp"`                           // Faktor penyesuaian pemisah arah
-	FCSF               float64   `bson:"fcsf" json:"fcsf"`                           // Faktor penyesuaian hambatan samping
-	FCCS               float64   `bson:"fccs" json:"fccs"`                           // Faktor penyesuaian ukuran kota
-	Kapasitas          float64   `bson:"kapasitas" json:"kapasitas"`                 // C = Co × FCW × FCSP × FCSF × FCCS (smp/jam)
-	DerajatKejenuhan   float64   `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"` // DS = Q/C
-	TingkatPelayanan   string    `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"` // Level of Service (A-F)
-	Keterangan         string    `bson:"keterangan" json:"keterangan"`
+	ID                   string                `bson:"_id" json:"id"`
+	LokasiID             string                `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi           string                `bson:"nama_lokasi" json:"nama_lokasi"`
+	TipeLokasi           string                `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	TipeArah             string                `bson:"tipe_arah" json:"tipe_arah"`
+	Tanggal              time.Time             `bson:"tanggal" json:"tanggal"`
+	PeriodeHari          int                   `bson:"periode_hari" json:"periode_hari"`
+	Timestamp            time.Time             `bson:"timestamp" json:"timestamp"`
+	MKJICount            MKJICount             `bson:"mkji_count" json:"mkji_count"`
+	TotalKendaraanHari   int                   `bson:"total_kendaraan_hari" json:"total_kendaraan_hari"`
+	LHR                  float64               `bson:"lhr" json:"lhr"`
+	LHRSMP               float64               `bson:"lhr_smp" json:"lhr_smp"`
+	ArusLaluLintas       float64               `bson:"arus_lalu_lintas" json:"arus_lalu_lintas"`                     // Q (smp/jam) - volume jam puncak
+	JamPuncak            string                `bson:"jam_puncak" json:"jam_puncak"`                                 // Jam puncak bergulir 60 menit (misal "07:15-08:15")
+	PeakHour             *PeakHourAnalysis     `bson:"peak_hour,omitempty" json:"peak_hour,omitempty"`               // Jam puncak bergulir per hari, PHF, dan k-faktor
+	PerArah              *DirectionalAnalysis  `bson:"per_arah,omitempty" json:"per_arah,omitempty"`                 // Arus, pemisahan arah, dan DS/DJ per arah (jalan terbagi)
+	KapasitasDasar       float64               `bson:"kapasitas_dasar" json:"kapasitas_dasar"`                       // Co (smp/jam)
+	FCW                  float64               `bson:"fcw" json:"fcw"`                                               // Faktor penyesuaian lebar jalur
+	FCSP                 float64               `bson:"fcsp" json:"fcsp"`                                             // Faktor penyesuaian pemisah arah
+	FCSF                 float64               `bson:"fcsf" json:"fcsf"`                                             // Faktor penyesuaian hambatan samping
+	FCCS                 float64               `bson:"fccs" json:"fccs"`                                             // Faktor penyesuaian ukuran kota
+	Kapasitas            float64               `bson:"kapasitas" json:"kapasitas"`                                   // C = Co × FCW × FCSP × FCSF × FCCS (smp/jam)
+	DerajatKejenuhan     float64               `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`                   // DS = Q/C
+	TingkatPelayanan     string                `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"`                   // Level of Service (A-F)
+	IntervalDikecualikan int                   `bson:"interval_dikecualikan" json:"interval_dikecualikan"`           // Interval flagged kualitas data yang tidak dihitung
+	Kelengkapan          *KelengkapanData      `bson:"kelengkapan,omitempty" json:"kelengkapan,omitempty"`           // Interval hilang dan metode pengisiannya
+	TabelEkivalen        []EkivalenTableRef    `bson:"tabel_ekivalen,omitempty" json:"tabel_ekivalen,omitempty"`     // Versi tabel ekivalen yang dipakai
+	HambatanSamping      *SideFrictionEstimate `bson:"hambatan_samping,omitempty" json:"hambatan_samping,omitempty"` // Estimasi kelas hambatan samping (opsi observasi)
+	Keterangan           string                `bson:"keterangan" json:"keterangan"`
 }
 
 func GetKategoriMKJI(tipeLokasi string, kelas int) KategoriMKJI {
@@ -122,33 +128,18 @@ func GetKategoriMKJI(tipeLokasi string, kelas int) KategoriMKJI {
 }
 
 func HitungMKJICount(trafficDataList []TrafficData, tipeLokasi string) MKJICount {
-	count := MKJICount{}
+	return mkjiCountFromCategories(HitungCategoryCount(MKJI1997Standard{}, trafficDataList, tipeLokasi))
+}
 
-	for _, td := range trafficDataList {
-		for _, za := range td.ZonaArahData {
-			for _, kd := range za.KelasData {
-				kategori := GetKategoriMKJI(tipeLokasi, kd.Kelas)
-				switch kategori {
-				case KategoriMC:
-					count.MC += kd.JumlahKendaraan
-				case KategoriLV:
-					count.LV += kd.JumlahKendaraan
-				case KategoriHV:
-					count.HV += kd.JumlahKendaraan
-				case KategoriUM:
-					count.UM += kd.JumlahKendaraan
-				}
-			}
-		}
+func mkjiCountFromCategories(count CategoryCount) MKJICount {
+	return MKJICount{
+		MC:         count.Counts[string(KategoriMC)],
+		LV:         count.Counts[string(KategoriLV)],
+		HV:         count.Counts[string(KategoriHV)],
+		UM:         count.Counts[string(KategoriUM)],
+		TotalMotor: count.TotalMotor,
+		TotalSMP:   count.TotalEkivalen,
 	}
-
-	count.TotalMotor = count.MC + count.LV + count.HV
-
-	count.TotalSMP = float64(count.MC)*SMPValues[KategoriMC] +
-		float64(count.LV)*SMPValues[KategoriLV] +
-		float64(count.HV)*SMPValues[KategoriHV]
-
-	return count
 }
 
 func HitungLHR(totalKendaraan int, jumlahHari int) float64 {
@@ -166,33 +157,7 @@ func HitungLHRSMP(mkjiCount MKJICount, jumlahHari int) float64 {
 }
 
 func HitungArusLaluLintas(trafficDataList []TrafficData, tipeLokasi string) (float64, string) {
-	if len(trafficDataList) == 0 {
-		return 0, ""
-	}
-
-	jamData := make(map[string]float64)
-	for _, td := range trafficDataList {
-		jamKey := td.Timestamp.Format("15:00")
-
-		for _, za := range td.ZonaArahData {
-			for _, kd := range za.KelasData {
-				kategori := GetKategoriMKJI(tipeLokasi, kd.Kelas)
-				smp := SMPValues[kategori]
-				jamData[jamKey] += float64(kd.JumlahKendaraan) * smp
-			}
-		}
-	}
-
-	var maxSMP float64
-	var jamPuncak string
-	for jam, smp := range jamData {
-		if smp > maxSMP {
-			maxSMP = smp
-			jamPuncak = jam
-		}
-	}
-
-	return maxSMP, jamPuncak
+	return HitungArusJamPuncak(MKJI1997Standard{}, trafficDataList, tipeLokasi)
 }
 
 func GetKapasitasDasar(tipeArah string, tipeLokasi string) float64 {
@@ -317,15 +282,8 @@ func GetFCCS(ukuranKota float64) float64 {
 }
 
 func HitungKapasitas(location Location) (float64, float64, float64, float64, float64, float64) {
-	co := GetKapasitasDasar(location.Tipe_arah, location.Tipe_lokasi)
-	fcw := GetFCW(location.Lebar_jalur, location.Tipe_arah)
-	fcsp := GetFCSP(location.Persentase, location.Tipe_arah)
-	fcsf := GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-	fccs := GetFCCS(location.Ukuran_kota)
-
-	kapasitas := co * fcw * fcsp * fcsf * fccs
-
-	return kapasitas, co, fcw, fcsp, fcsf, fccs
+	result := MKJI1997Standard{}.Kapasitas(location)
+	return result.Kapasitas, result.KapasitasDasar, result.Factor("FCW"), result.Factor("FCSP"), result.Factor("FCSF"), result.Factor("FCCS")
 }
 
 func HitungDerajatKejenuhan(arusLaluLintas float64, kapasitas float64) float64 {
@@ -335,21 +293,71 @@ func HitungDerajatKejenuhan(arusLaluLintas float64, kapasitas float64) float64 {
 	return arusLaluLintas / kapasitas
 }
 
+// Batas atas DS tingkat pelayanan A-E MKJI 1997
+var batasTingkatPelayananMKJI = []float64{0.35, 0.55, 0.75, 0.85, 1.00}
+
+var keteranganTingkatPelayananMKJI = []string{
+	"Arus bebas, kecepatan tinggi, kepadatan lalu lintas rendah",
+	"Arus stabil, kecepatan sedikit terbatas oleh lalu lintas",
+	"Arus stabil, kecepatan dan kebebasan bergerak lebih terbatas",
+	"Arus mendekati tidak stabil, kecepatan menurun",
+	"Arus tidak stabil, kecepatan rendah dan bervariasi",
+	"Arus terhenti, kondisi macet total",
+}
+
 func GetTingkatPelayanan(ds float64) (string, string) {
-	switch {
-	case ds <= 0.35:
-		return "A", "Arus bebas, kecepatan tinggi, kepadatan lalu lintas rendah"
-	case ds <= 0.55:
-		return "B", "Arus stabil, kecepatan sedikit terbatas oleh lalu lintas"
-	case ds <= 0.75:
-		return "C", "Arus stabil, kecepatan dan kebebasan bergerak lebih terbatas"
-	case ds <= 0.85:
-		return "D", "Arus mendekati tidak stabil, kecepatan menurun"
-	case ds <= 1.00:
-		return "E", "Arus tidak stabil, kecepatan rendah dan bervariasi"
-	default:
-		return "F", "Arus terhenti, kondisi macet total"
+	return tingkatPelayananDariBatas(ds, batasTingkatPelayananMKJI, keteranganTingkatPelayananMKJI)
+}
+
+// MKJI1997Standard mengimplementasikan CapacityStandard untuk Manual Kapasitas Jalan Indonesia 1997
+type MKJI1997Standard struct{}
+
+func (MKJI1997Standard) Method() AnalysisMethod { return AnalysisMethodMKJI1997 }
+
+func (MKJI1997Standard) UnitEkivalen() string { return "smp" }
+
+func (MKJI1997Standard) Categories() []string {
+	return []string{string(KategoriMC), string(KategoriLV), string(KategoriHV), string(KategoriUM)}
+}
+
+func (MKJI1997Standard) Kategori(tipeLokasi string, kelas int) string {
+	return string(GetKategoriMKJI(tipeLokasi, kelas))
+}
+
+func (MKJI1997Standard) Ekivalen(kategori string, tipeLokasi string) float64 {
+	return SMPValues[KategoriMKJI(kategori)]
+}
+
+func (MKJI1997Standard) IsMotorized(kategori string) bool {
+	return KategoriMKJI(kategori) != KategoriUM
+}
+
+// Kapasitas C = Co × FCW × FCSP × FCSF × FCCS. Jalan luar kota tidak memakai FCCS,
+// jalan bebas hambatan tidak memakai FCSF dan FCCS.
+func (MKJI1997Standard) Kapasitas(location Location) CapacityResult {
+	// Co tabel jalan terbagi adalah kapasitas satu arah; arus lokasi adalah arus dua arah
+	co := GetKapasitasDasar(location.Tipe_arah, location.Tipe_lokasi) * float64(jumlahArahKapasitas(location.Tipe_arah))
+	fcw := CapacityFactor{Kode: "FCW", Nilai: GetFCW(location.Lebar_jalur, location.Tipe_arah)}
+	fcsp := CapacityFactor{Kode: "FCSP", Nilai: GetFCSP(location.Persentase, location.Tipe_arah)}
+	fcsf := CapacityFactor{Kode: "FCSF", Nilai: GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)}
+	fccs := CapacityFactor{Kode: "FCCS", Nilai: GetFCCS(location.Ukuran_kota)}
+
+	switch location.Tipe_lokasi {
+	case "luar_kota", "12_kelas":
+		fccs.Nilai = 1.0
+	case "bebas_hambatan":
+		fcsf.Nilai = 1.0
+		fccs.Nilai = 1.0
 	}
+	return newCapacityResult(co, fcw, fcsp, fcsf, fccs)
+}
+
+func (MKJI1997Standard) TingkatPelayanan(derajatKejenuhan float64) (string, string) {
+	return GetTingkatPelayanan(derajatKejenuhan)
+}
+
+func (MKJI1997Standard) BatasTingkatPelayanan() []float64 {
+	return batasTingkatPelayananMKJI
 }
 
 func NextMKJIAnalysisID() (string, error) {
@@ -368,27 +376,79 @@ func NextMKJIAnalysisID() (string, error) {
 	return fmt.Sprintf("MKJI-%05d", lastNum+1), nil
 }
 
-func CreateMKJIAnalysis(lokasiID string, startTime, endTime time.Time) (*MKJIAnalysis, error) {
+func CreateMKJIAnalysis(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*MKJIAnalysis, error) {
 	location, err := GetLocationByID(lokasiID)
 	if err != nil {
 		return nil, err
 	}
 
-	trafficDataList, err := GetTrafficDataByLokasiID(lokasiID, startTime, endTime)
+	location, dataset, hambatan, err := prepareAnalysis(location, startTime, endTime, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(trafficDataList) == 0 {
+	if len(dataset.Data) == 0 {
 		return nil, fmt.Errorf("tidak ada data traffic untuk periode yang diminta")
 	}
 
+	analysis, err := BuildMKJIAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+	analysis.HambatanSamping = hambatan
+
+	id, err := NextMKJIAnalysisID()
+	if err != nil {
+		return nil, err
+	}
+	analysis.ID = id
+
+	_, err = database.DB.Collection("mkji_analysis").InsertOne(context.Background(), analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// BuildMKJIAnalysis menghitung analisis MKJI dari dataset yang sudah dimuat (tanpa ID dan tanpa menyimpan)
+func BuildMKJIAnalysis(location *Location, startTime, endTime time.Time, dataset *AnalysisDataset) (*MKJIAnalysis, error) {
+	trafficDataList := dataset.Data
+
+	standard, err := ResolveCapacityStandard(MKJI1997Standard{}, *location, trafficDataList)
+	if err != nil {
+		return nil, err
+	}
+
+	kapasitasResult := standard.Kapasitas(*location)
+	kapasitas, co := kapasitasResult.Kapasitas, kapasitasResult.KapasitasDasar
+	fcw, fcsp := kapasitasResult.Factor("FCW"), kapasitasResult.Factor("FCSP")
+	fcsf, fccs := kapasitasResult.Factor("FCSF"), kapasitasResult.Factor("FCCS")
+
+	if len(trafficDataList) == 0 {
+		return &MKJIAnalysis{
+			LokasiID:             location.ID,
+			NamaLokasi:           location.Nama_lokasi,
+			TipeLokasi:           location.Tipe_lokasi,
+			TipeArah:             location.Tipe_arah,
+			KapasitasDasar:       co,
+			FCW:                  fcw,
+			FCSP:                 fcsp,
+			FCSF:                 fcsf,
+			FCCS:                 fccs,
+			Kapasitas:            kapasitas,
+			IntervalDikecualikan: dataset.IntervalDikecualikan,
+			Kelengkapan:          dataset.Kelengkapan,
+			Keterangan:           "Tidak ada data traffic untuk periode yang diminta",
+		}, nil
+	}
+
 	jumlahHari := int(math.Ceil(endTime.Sub(startTime).Hours() / 24))
 	if jumlahHari < 1 {
 		jumlahHari = 1
 	}
 
-	mkjiCount := HitungMKJICount(trafficDataList, location.Tipe_lokasi)
+	mkjiCount := mkjiCountFromCategories(HitungCategoryCount(standard, trafficDataList, location.Tipe_lokasi))
 
 	totalKendaraan := 0
 	for _, td := range trafficDataList {
@@ -398,60 +458,51 @@ func CreateMKJIAnalysis(lokasiID string, startTime, endTime time.Time) (*MKJIAna
 	lhr := HitungLHR(totalKendaraan, jumlahHari)
 	lhrSMP := HitungLHRSMP(mkjiCount, jumlahHari)
 
-	arusLaluLintas, jamPuncak := HitungArusLaluLintas(trafficDataList, location.Tipe_lokasi)
-
-	kapasitas, co, fcw, fcsp, fcsf, fccs := HitungKapasitas(*location)
+	peakHour := HitungPeakHour(standard, trafficDataList, location.Tipe_lokasi)
+	arusLaluLintas, jamPuncak := peakHour.Puncak.Arus, peakHour.Puncak.JamPuncak
 
 	ds := HitungDerajatKejenuhan(arusLaluLintas, kapasitas)
 
-	tingkatPelayanan, keterangan := GetTingkatPelayanan(ds)
-
-	id, err := NextMKJIAnalysisID()
-	if err != nil {
-		return nil, err
-	}
-
-	analysis := &MKJIAnalysis{
-		ID:                 id,
-		LokasiID:           lokasiID,
-		NamaLokasi:         location.Nama_lokasi,
-		TipeLokasi:         location.Tipe_lokasi,
-		TipeArah:           location.Tipe_arah,
-		Tanggal:            startTime,
-		PeriodeHari:        jumlahHari,
-		Timestamp:          time.Now().Add(7 * time.Hour),
-		MKJICount:          mkjiCount,
-		TotalKendaraanHari: totalKendaraan,
-		LHR:                lhr,
-		LHRSMP:             lhrSMP,
-		ArusLaluLintas:     arusLaluLintas,
-		JamPuncak:          jamPuncak,
-		KapasitasDasar:     co,
-		FCW:                fcw,
-		FCSP:               fcsp,
-		FCSF:               fcsf,
-		FCCS:               fccs,
-		Kapasitas:          kapasitas,
-		DerajatKejenuhan:   ds,
-		TingkatPelayanan:   tingkatPelayanan,
-		Keterangan:         keterangan,
-	}
-
-	_, err = database.DB.Collection("mkji_analysis").InsertOne(context.Background(), analysis)
-	if err != nil {
-		return nil, err
-	}
+	tingkatPelayanan, keterangan := standard.TingkatPelayanan(ds)
 
-	return analysis, nil
+	return &MKJIAnalysis{
+		LokasiID:             location.ID,
+		NamaLokasi:           location.Nama_lokasi,
+		TipeLokasi:           location.Tipe_lokasi,
+		TipeArah:             location.Tipe_arah,
+		Tanggal:              startTime,
+		PeriodeHari:          jumlahHari,
+		IntervalDikecualikan: dataset.IntervalDikecualikan,
+		Kelengkapan:          dataset.Kelengkapan,
+		Timestamp:            time.Now().Add(7 * time.Hour),
+		MKJICount:            mkjiCount,
+		TotalKendaraanHari:   totalKendaraan,
+		LHR:                  lhr,
+		LHRSMP:               lhrSMP,
+		ArusLaluLintas:       arusLaluLintas,
+		JamPuncak:            jamPuncak,
+		PeakHour:             &peakHour,
+		PerArah:              HitungKinerjaPerArah(standard, *location, trafficDataList, peakHour),
+		KapasitasDasar:       co,
+		FCW:                  fcw,
+		FCSP:                 fcsp,
+		FCSF:                 fcsf,
+		FCCS:                 fccs,
+		Kapasitas:            kapasitas,
+		DerajatKejenuhan:     ds,
+		TingkatPelayanan:     tingkatPelayanan,
+		TabelEkivalen:        standard.TabelDipakai(),
+		Keterangan:           keterangan,
+	}, nil
 }
 
-func GetMKJIAnalysisByLokasiID(lokasiID string) ([]MKJIAnalysis, error) {
+func GetMKJIAnalysisByLokasiID(lokasiID string, limit int64) ([]MKJIAnalysis, error) {
 	collection := database.DB.Collection("mkji_analysis")
 
 	cursor, err := collection.Find(
 		context.Background(),
 		bson.M{"lokasi_id": lokasiID},
-		options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}),
+		options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(limit),
 	)
 	if err != nil {
 		return nil, err
@@ -494,76 +545,22 @@ func GetMKJIAnalysisByID(id string) (*MKJIAnalysis, error) {
 	return &analysis, nil
 }
 
-func CalculateMKJIRealtime(lokasiID string, startTime, endTime time.Time) (*MKJIAnalysis, error) {
+func CalculateMKJIRealtime(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*MKJIAnalysis, error) {
 	location, err := GetLocationByID(lokasiID)
 	if err != nil {
 		return nil, err
 	}
 
-	trafficDataList, err := GetTrafficDataByLokasiID(lokasiID, startTime, endTime)
+	location, dataset, hambatan, err := prepareAnalysis(location, startTime, endTime, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(trafficDataList) == 0 {
-		kapasitas, co, fcw, fcsp, fcsf, fccs := HitungKapasitas(*location)
-		return &MKJIAnalysis{
-			LokasiID:       lokasiID,
-			NamaLokasi:     location.Nama_lokasi,
-			TipeLokasi:     location.Tipe_lokasi,
-			TipeArah:       location.Tipe_arah,
-			KapasitasDasar: co,
-			FCW:            fcw,
-			FCSP:           fcsp,
-			FCSF:           fcsf,
-			FCCS:           fccs,
-			Kapasitas:      kapasitas,
-			Keterangan:     "Tidak ada data traffic untuk periode yang diminta",
-		}, nil
-	}
-
-	jumlahHari := int(math.Ceil(endTime.Sub(startTime).Hours() / 24))
-	if jumlahHari < 1 {
-		jumlahHari = 1
-	}
-
-	mkjiCount := HitungMKJICount(trafficDataList, location.Tipe_lokasi)
-
-	totalKendaraan := 0
-	for _, td := range trafficDataList {
-		totalKendaraan += td.TotalKendaraan
+	analysis, err := BuildMKJIAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
 	}
+	analysis.HambatanSamping = hambatan
 
-	lhr := HitungLHR(totalKendaraan, jumlahHari)
-	lhrSMP := HitungLHRSMP(mkjiCount, jumlahHari)
-
-	arusLaluLintas, jamPuncak := HitungArusLaluLintas(trafficDataList, location.Tipe_lokasi)
-	kapasitas, co, fcw, fcsp, fcsf, fccs := HitungKapasitas(*location)
-	ds := HitungDerajatKejenuhan(arusLaluLintas, kapasitas)
-	tingkatPelayanan, keterangan := GetTingkatPelayanan(ds)
-
-	return &MKJIAnalysis{
-		LokasiID:           lokasiID,
-		NamaLokasi:         location.Nama_lokasi,
-		TipeLokasi:         location.Tipe_lokasi,
-		TipeArah:           location.Tipe_arah,
-		Tanggal:            startTime,
-		PeriodeHari:        jumlahHari,
-		Timestamp:          time.Now().Add(7 * time.Hour),
-		MKJICount:          mkjiCount,
-		TotalKendaraanHari: totalKendaraan,
-		LHR:                lhr,
-		LHRSMP:             lhrSMP,
-		ArusLaluLintas:     arusLaluLintas,
-		JamPuncak:          jamPuncak,
-		KapasitasDasar:     co,
-		FCW:                fcw,
-		FCSP:               fcsp,
-		FCSF:               fcsf,
-		FCCS:               fccs,
-		Kapasitas:          kapasitas,
-		DerajatKejenuhan:   ds,
-		TingkatPelayanan:   tingkatPelayanan,
-		Keterangan:         keterangan,
-	}, nil
+	return analysis, nil
 }
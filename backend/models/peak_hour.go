@@ -0,0 +1,176 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Jam puncak satu hari: jendela 60 menit bergulir dengan arus ekivalen tertinggi
+type PeakHour struct {
+	Tanggal          string    `bson:"tanggal" json:"tanggal"` // YYYY-MM-DD (waktu lokal)
+	Mulai            time.Time `bson:"mulai" json:"mulai"`
+	Selesai          time.Time `bson:"selesai" json:"selesai"`
+	JamPuncak        string    `bson:"jam_puncak" json:"jam_puncak"`               // misal "07:15-08:15"
+	Arus             float64   `bson:"arus" json:"arus"`                           // ekivalen/jam pada jam puncak
+	ArusPeriodeMaks  float64   `bson:"arus_periode_maks" json:"arus_periode_maks"` // ekivalen pada sub-periode tertinggi di jam puncak
+	PeriodeMenit     int       `bson:"periode_menit" json:"periode_menit"`         // panjang sub-periode PHF (15 menit jika memungkinkan)
+	PHF              float64   `bson:"phf" json:"phf"`                             // Peak Hour Factor = V / (n × Vperiode maks)
+	ArusHarian       float64   `bson:"arus_harian" json:"arus_harian"`             // total ekivalen pada hari tersebut
+	KFaktor          float64   `bson:"k_faktor" json:"k_faktor"`                   // arus jam puncak / arus harian
+	IntervalTersedia int       `bson:"interval_tersedia" json:"interval_tersedia"` // jumlah interval berisi data pada hari tersebut
+	Lengkap          bool      `bson:"lengkap" json:"lengkap"`                     // ≥ 90% interval hari tersedia (k-faktor harian dapat dipakai)
+}
+
+// Hasil penentuan jam puncak untuk seluruh periode analisis
+type PeakHourAnalysis struct {
+	IntervalMenit int        `bson:"interval_menit" json:"interval_menit"` // interval asli data
+	Puncak        PeakHour   `bson:"puncak" json:"puncak"`                 // jam puncak tertinggi di antara semua hari
+	Harian        []PeakHour `bson:"harian" json:"harian"`
+	// LHR ekivalen yang dinormalisasi dengan durasi data tersedia, sehingga periode parsial tetap sebanding
+	ArusHarianRata float64 `bson:"arus_harian_rata" json:"arus_harian_rata"`
+	KFaktor        float64 `bson:"k_faktor" json:"k_faktor"` // arus jam puncak / LHR ekivalen
+}
+
+// Ambang hari dianggap lengkap untuk k-faktor harian
+const peakHourDayCoverage = 0.9
+
+// nativeIntervalMenit mengembalikan interval data yang paling sering muncul (default 5 menit)
+func nativeIntervalMenit(trafficDataList []TrafficData) int {
+	frekuensi := make(map[int]int)
+	modus, modusCount := 0, 0
+	for _, td := range trafficDataList {
+		if td.IntervalMenit <= 0 {
+			continue
+		}
+		frekuensi[td.IntervalMenit]++
+		if frekuensi[td.IntervalMenit] > modusCount || (frekuensi[td.IntervalMenit] == modusCount && td.IntervalMenit < modus) {
+			modus, modusCount = td.IntervalMenit, frekuensi[td.IntervalMenit]
+		}
+	}
+	if modus <= 0 {
+		return 5
+	}
+	return modus
+}
+
+// HitungPeakHour menentukan jam puncak per hari sebagai jendela 60 menit bergulir pada interval asli data
+// (5 atau 15 menit), beserta Peak Hour Factor dan k-faktor. Data beberapa kamera pada interval yang sama dijumlahkan.
+func HitungPeakHour(standard CapacityStandard, trafficDataList []TrafficData, tipeLokasi string) PeakHourAnalysis {
+	intervalMenit := nativeIntervalMenit(trafficDataList)
+	result := PeakHourAnalysis{IntervalMenit: intervalMenit, Harian: []PeakHour{}}
+	if len(trafficDataList) == 0 {
+		return result
+	}
+	interval := time.Duration(intervalMenit) * time.Minute
+
+	// Arus ekivalen per slot interval, dikelompokkan per hari lokal
+	slots := make(map[time.Time]float64)
+	for _, td := range trafficDataList {
+		slots[td.Timestamp.UTC().Truncate(interval)] += trafficDataEkivalen(standard, td, tipeLokasi)
+	}
+
+	perHari := make(map[string][]time.Time)
+	for slot := range slots {
+		tanggal := slot.Format("2006-01-02")
+		perHari[tanggal] = append(perHari[tanggal], slot)
+	}
+	tanggalList := make([]string, 0, len(perHari))
+	for tanggal := range perHari {
+		tanggalList = append(tanggalList, tanggal)
+	}
+	sort.Strings(tanggalList)
+
+	slotPerHari := int(24 * time.Hour / interval)
+	total := 0.0
+	for _, tanggal := range tanggalList {
+		daySlots := perHari[tanggal]
+		sort.Slice(daySlots, func(i, j int) bool { return daySlots[i].Before(daySlots[j]) })
+		peak := peakHourForDay(slots, daySlots, interval)
+		peak.Tanggal = tanggal
+		peak.IntervalTersedia = len(daySlots)
+		peak.Lengkap = float64(len(daySlots)) >= peakHourDayCoverage*float64(slotPerHari)
+		total += peak.ArusHarian
+
+		result.Harian = append(result.Harian, peak)
+		if peak.Arus > result.Puncak.Arus {
+			result.Puncak = peak
+		}
+	}
+
+	// LHR ekivalen = total × (24 jam / durasi data tersedia)
+	durasiTersedia := float64(len(slots)) * interval.Hours()
+	if durasiTersedia > 0 {
+		result.ArusHarianRata = total * 24 / durasiTersedia
+	}
+	if result.ArusHarianRata > 0 {
+		result.KFaktor = result.Puncak.Arus / result.ArusHarianRata
+	}
+	return result
+}
+
+// peakHourForDay mencari jendela 60 menit dengan arus tertinggi pada satu hari.
+// Jendela selalu dimulai pada slot berisi data karena menggeser awal jendela ke slot data pertama
+// di dalamnya tidak pernah mengurangi jumlahnya. Hanya slot pada hari yang sama yang dijumlahkan.
+func peakHourForDay(slots map[time.Time]float64, daySlots []time.Time, interval time.Duration) PeakHour {
+	peak := PeakHour{}
+	for _, slot := range daySlots {
+		peak.ArusHarian += slots[slot]
+	}
+
+	window := time.Hour
+	if interval > window {
+		window = interval
+	}
+	// Data dengan interval lebih dari 1 jam diskalakan ke arus per jam
+	skala := time.Hour.Hours() / window.Hours()
+
+	periode := interval
+	if interval <= 15*time.Minute && (15*time.Minute)%interval == 0 {
+		periode = 15 * time.Minute
+	}
+
+	best := -1.0
+	for i, start := range daySlots {
+		end := start.Add(window)
+		arus := 0.0
+		for j := i; j < len(daySlots) && daySlots[j].Before(end); j++ {
+			arus += slots[daySlots[j]]
+		}
+		arus *= skala
+		if arus <= best {
+			continue
+		}
+		best = arus
+		peak.Mulai = start
+		peak.Selesai = start.Add(window)
+		peak.Arus = arus
+	}
+
+	peak.JamPuncak = peak.Mulai.Format("15:04") + "-" + peak.Selesai.Format("15:04")
+	peak.PeriodeMenit = int(periode.Minutes())
+
+	// PHF = V / (n × Vperiode maks), sub-periode dihitung dari awal jam puncak
+	if window == time.Hour && periode < time.Hour {
+		n := int(time.Hour / periode)
+		for k := 0; k < n; k++ {
+			periodeStart := peak.Mulai.Add(time.Duration(k) * periode)
+			periodeEnd := periodeStart.Add(periode)
+			volume := 0.0
+			for _, slot := range daySlots {
+				if !slot.Before(periodeStart) && slot.Before(periodeEnd) {
+					volume += slots[slot]
+				}
+			}
+			peak.ArusPeriodeMaks = math.Max(peak.ArusPeriodeMaks, volume)
+		}
+		if peak.ArusPeriodeMaks > 0 {
+			peak.PHF = peak.Arus / (float64(n) * peak.ArusPeriodeMaks)
+		}
+	}
+
+	if peak.ArusHarian > 0 {
+		peak.KFaktor = peak.Arus / peak.ArusHarian
+	}
+	return peak
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// trafficDataLVUji membuat record kendaraan ringan (1 smp MKJI perkotaan) dengan interval tertentu
+func trafficDataLVUji(ts time.Time, intervalMenit, jumlah int) TrafficData {
+	return TrafficData{
+		Timestamp:      ts,
+		IntervalMenit:  intervalMenit,
+		TotalKendaraan: jumlah,
+		ZonaArahData: []TrafficZonaArahData{{
+			IDZonaArah: "ZA-1",
+			KelasData:  []TrafficKelasDetail{{Kelas: 2, JumlahKendaraan: jumlah}},
+		}},
+	}
+}
+
+// Data tiga hari, interval 5 menit:
+//   - 6 Jan: dasar 10 pada 07:00-07:25 dan 08:30-08:55, puncak 07:30-08:25 (100, 07:45-07:55 = 160)
+//   - 7 Jan: 200 pada 23:30-23:55
+//   - 8 Jan: 200 pada 00:00-00:25
+func dataJamPuncakUji() []TrafficData {
+	var data []TrafficData
+	hari1 := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	for i := 0; i < 24; i++ {
+		ts := hari1.Add(time.Duration(i) * 5 * time.Minute)
+		jumlah := 10
+		switch {
+		case i >= 9 && i <= 11:
+			jumlah = 160
+		case i >= 6 && i < 18:
+			jumlah = 100
+		}
+		data = append(data, trafficDataLVUji(ts, 5, jumlah))
+	}
+	// Record kamera kedua pada slot yang sama dengan lokasi WIB harus dijumlahkan ke slot UTC yang sama
+	data[6].TotalKendaraan, data[6].ZonaArahData[0].KelasData[0].JumlahKendaraan = 60, 60
+	data = append(data, trafficDataLVUji(hari1.Add(30*time.Minute).In(time.FixedZone("WIB", 7*3600)), 5, 40))
+
+	for i := 0; i < 6; i++ {
+		data = append(data, trafficDataLVUji(time.Date(2025, 1, 7, 23, 30+5*i, 0, 0, time.UTC), 5, 200))
+		data = append(data, trafficDataLVUji(time.Date(2025, 1, 8, 0, 5*i, 0, 0, time.UTC), 5, 200))
+	}
+	return data
+}
+
+func TestHitungPeakHourJendelaMelewatiJam(t *testing.T) {
+	peak := HitungPeakHour(MKJI1997Standard{}, dataJamPuncakUji(), "perkotaan")
+
+	if peak.IntervalMenit != 5 || len(peak.Harian) != 3 {
+		t.Fatalf("interval %d, %d hari, ingin 5 dan 3", peak.IntervalMenit, len(peak.Harian))
+	}
+	puncak := peak.Puncak
+	if puncak.Tanggal != "2025-01-06" || puncak.JamPuncak != "07:30-08:30" || puncak.Arus != 1380 {
+		t.Fatalf("puncak %s %s arus %.0f, ingin 2025-01-06 07:30-08:30 dan 1380", puncak.Tanggal, puncak.JamPuncak, puncak.Arus)
+	}
+	// Sub-periode 15 menit dari 07:30: 300, 480, 300, 300 → PHF = 1380 / (4 × 480)
+	if puncak.PeriodeMenit != 15 || puncak.ArusPeriodeMaks != 480 || math.Abs(puncak.PHF-1380.0/1920) > 1e-9 {
+		t.Fatalf("periode %d maks %.0f PHF %.4f, ingin 15, 480, %.4f", puncak.PeriodeMenit, puncak.ArusPeriodeMaks,
+			puncak.PHF, 1380.0/1920)
+	}
+	if puncak.ArusHarian != 1500 || puncak.IntervalTersedia != 24 || puncak.Lengkap {
+		t.Fatalf("arus harian %.0f, %d interval, lengkap %v", puncak.ArusHarian, puncak.IntervalTersedia, puncak.Lengkap)
+	}
+}
+
+// Jendela tidak menjumlahkan slot hari berikutnya: puncak 7 Jan 23:30 hanya berisi 6 slot hari itu
+func TestHitungPeakHourBeberapaHari(t *testing.T) {
+	peak := HitungPeakHour(MKJI1997Standard{}, dataJamPuncakUji(), "perkotaan")
+
+	tests := []struct {
+		tanggal   string
+		jamPuncak string
+		arus      float64
+	}{
+		{"2025-01-06", "07:30-08:30", 1380},
+		{"2025-01-07", "23:30-00:30", 1200},
+		{"2025-01-08", "00:00-01:00", 1200},
+	}
+	for i, tt := range tests {
+		harian := peak.Harian[i]
+		if harian.Tanggal != tt.tanggal || harian.JamPuncak != tt.jamPuncak || harian.Arus != tt.arus {
+			t.Errorf("hari %d: %s %s arus %.0f, ingin %s %s %.0f", i, harian.Tanggal, harian.JamPuncak, harian.Arus,
+				tt.tanggal, tt.jamPuncak, tt.arus)
+		}
+	}
+
+	// 36 slot = 3 jam data, total 3900 smp → LHR ekivalen 3900 × 24 / 3
+	if peak.ArusHarianRata != 31200 || math.Abs(peak.KFaktor-1380.0/31200) > 1e-9 {
+		t.Fatalf("arus harian rata %.0f K %.4f, ingin 31200 dan %.4f", peak.ArusHarianRata, peak.KFaktor, 1380.0/31200)
+	}
+}
+
+// Interval asli 15 menit dipakai walaupun ada beberapa record 5 menit
+func TestHitungPeakHourInterval15Menit(t *testing.T) {
+	start := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	var data []TrafficData
+	for i, jumlah := range []int{50, 100, 400, 300, 200, 350, 100} {
+		data = append(data, trafficDataLVUji(start.Add(time.Duration(i)*15*time.Minute), 15, jumlah))
+	}
+	data = append(data, trafficDataLVUji(start.Add(3*time.Hour), 5, 10))
+
+	peak := HitungPeakHour(MKJI1997Standard{}, data, "perkotaan")
+	if peak.IntervalMenit != 15 {
+		t.Fatalf("interval %d, ingin 15", peak.IntervalMenit)
+	}
+	// 07:30-08:30 = 400 + 300 + 200 + 350
+	if peak.Puncak.JamPuncak != "07:30-08:30" || peak.Puncak.Arus != 1250 || math.Abs(peak.Puncak.PHF-1250.0/1600) > 1e-9 {
+		t.Fatalf("puncak %s arus %.0f PHF %.4f, ingin 07:30-08:30, 1250, %.4f", peak.Puncak.JamPuncak,
+			peak.Puncak.Arus, peak.Puncak.PHF, 1250.0/1600)
+	}
+}
+
+func TestHitungArusJamPuncakKosong(t *testing.T) {
+	if arus, jam := HitungArusJamPuncak(PKJI2023Standard{}, nil, "perkotaan"); arus != 0 || jam != "" {
+		t.Fatalf("arus %.0f jam %q, ingin 0 dan kosong", arus, jam)
+	}
+}
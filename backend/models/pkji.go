@@ -25,21 +25,25 @@ var EMPValuesPKJI = map[KategoriPKJI]map[string]float64{
 	KategoriSM: {
 		"perkotaan":      0.25,
 		"luar_kota":      0.5,
+		"12_kelas":       0.5,
 		"bebas_hambatan": 0.0,
 	},
 	KategoriKR: {
 		"perkotaan":      1.0,
 		"luar_kota":      1.0,
+		"12_kelas":       1.0,
 		"bebas_hambatan": 1.0,
 	},
 	KategoriKB: {
 		"perkotaan":      1.2,
 		"luar_kota":      1.2,
+		"12_kelas":       1.2,
 		"bebas_hambatan": 1.2,
 	},
 	KategoriKTB: {
 		"perkotaan":      0.0,
 		"luar_kota":      0.0,
+		"12_kelas":       0.0,
 		"bebas_hambatan": 0.0,
 	},
 }
@@ -90,29 +94,36 @@ type PKJICount struct {
 }
 
 type PKJIAnalysis struct {
-	ID                 string    `bson:"_id" json:"id"`
-	LokasiID           string    `bson:"lokasi_id" json:"lokasi_id"`
-	NamaLokasi         string    `bson:"nama_lokasi" json:"nama_lokasi"`
-	TipeLokasi         string    `bson:"tipe_lokasi" json:"tipe_lokasi"`
-	TipeArah           string    `bson:"tipe_arah" json:"tipe_arah"`
-	Tanggal            time.Time `bson:"tanggal" json:"tanggal"`
-	PeriodeHari        int       `bson:"periode_hari" json:"periode_hari"`
-	Timestamp          time.Time `bson:"timestamp" json:"timestamp"`
-	PKJICount          PKJICount `bson:"pkji_count" json:"pkji_count"`
-	TotalKendaraanHari int       `bson:"total_kendaraan_hari" json:"total_kendaraan_hari"`
-	LHRT               float64   `bson:"lhrt" json:"lhrt"`                             // Lalu lintas Harian Rata-rata Tahunan
-	LHRTSkr            float64   `bson:"lhrt_skr" json:"lhrt_skr"`                     // LHRT dalam SKR
-	VolumeLaluLintas   float64   `bson:"volume_lalu_lintas" json:"volume_lalu_lintas"` // V (skr/jam)
-	JamPuncak          string    `bson:"jam_puncak" json:"jam_puncak"`
-	KapasitasDasar     float64   `bson:"kapasitas_dasar" json:"kapasitas_dasar"`     // C0 (skr/jam)
-	FCLJ               float64   `bson:"fclj" json:"fclj"`                           // Faktor penyesuaian lebar jalur
-	FCPA               float64   `bson:"fcpa" json:"fcpa"`                           // Faktor penyesuaian pemisahan arah
-	FCHS               float64   `bson:"fchs" json:"fchs"`                           // Faktor penyesuaian hambatan samping
-	FCUK               float64   `bson:"fcuk" json:"fcuk"`                           // Faktor penyesuaian ukuran kota
-	Kapasitas          float64   `bson:"kapasitas" json:"kapasitas"`                 // C = C0 × FCLJ × FCPA × FCHS × FCUK
-	DerajatKejenuhan   float64   `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"` // DJ = V/C
-	TingkatPelayanan   string    `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"` // Level of Service (A-F)
-	Keterangan         string    `bson:"keterangan" json:"keterangan"`
+	ID                   string                `bson:"_id" json:"id"`
+	LokasiID             string                `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi           string                `bson:"nama_lokasi" json:"nama_lokasi"`
+	TipeLokasi           string                `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	TipeArah             string                `bson:"tipe_arah" json:"tipe_arah"`
+	Tanggal              time.Time             `bson:"tanggal" json:"tanggal"`
+	PeriodeHari          int                   `bson:"periode_hari" json:"periode_hari"`
+	Timestamp            time.Time             `bson:"timestamp" json:"timestamp"`
+	PKJICount            PKJICount             `bson:"pkji_count" json:"pkji_count"`
+	TotalKendaraanHari   int                   `bson:"total_kendaraan_hari" json:"total_kendaraan_hari"`
+	LHRT                 float64               `bson:"lhrt" json:"lhrt"`                             // Lalu lintas Harian Rata-rata Tahunan
+	LHRTSkr              float64               `bson:"lhrt_skr" json:"lhrt_skr"`                     // LHRT dalam SKR
+	VolumeLaluLintas     float64               `bson:"volume_lalu_lintas" json:"volume_lalu_lintas"` // V (skr/jam)
+	JamPuncak            string                `bson:"jam_puncak" json:"jam_puncak"`
+	PeakHour             *PeakHourAnalysis     `bson:"peak_hour,omitempty" json:"peak_hour,omitempty"`               // Jam puncak bergulir per hari, PHF, dan k-faktor
+	PerArah              *DirectionalAnalysis  `bson:"per_arah,omitempty" json:"per_arah,omitempty"`                 // Arus, pemisahan arah, dan DS/DJ per arah (jalan terbagi)
+	Kecepatan            *PKJISpeedAnalysis    `bson:"kecepatan,omitempty" json:"kecepatan,omitempty"`               // VB dan VT dibandingkan kecepatan terukur kamera
+	KapasitasDasar       float64               `bson:"kapasitas_dasar" json:"kapasitas_dasar"`                       // C0 (skr/jam)
+	FCLJ                 float64               `bson:"fclj" json:"fclj"`                                             // Faktor penyesuaian lebar jalur
+	FCPA                 float64               `bson:"fcpa" json:"fcpa"`                                             // Faktor penyesuaian pemisahan arah
+	FCHS                 float64               `bson:"fchs" json:"fchs"`                                             // Faktor penyesuaian hambatan samping
+	FCUK                 float64               `bson:"fcuk" json:"fcuk"`                                             // Faktor penyesuaian ukuran kota
+	Kapasitas            float64               `bson:"kapasitas" json:"kapasitas"`                                   // C = C0 × FCLJ × FCPA × FCHS × FCUK
+	DerajatKejenuhan     float64               `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`                   // DJ = V/C
+	TingkatPelayanan     string                `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"`                   // Level of Service (A-F)
+	IntervalDikecualikan int                   `bson:"interval_dikecualikan" json:"interval_dikecualikan"`           // Interval flagged kualitas data yang tidak dihitung
+	Kelengkapan          *KelengkapanData      `bson:"kelengkapan,omitempty" json:"kelengkapan,omitempty"`           // Interval hilang dan metode pengisiannya
+	TabelEkivalen        []EkivalenTableRef    `bson:"tabel_ekivalen,omitempty" json:"tabel_ekivalen,omitempty"`     // Versi tabel ekivalen yang dipakai
+	HambatanSamping      *SideFrictionEstimate `bson:"hambatan_samping,omitempty" json:"hambatan_samping,omitempty"` // Estimasi kelas hambatan samping (opsi observasi)
+	Keterangan           string                `bson:"keterangan" json:"keterangan"`
 }
 
 func GetKategoriPKJI(tipeLokasi string, kelas int) KategoriPKJI {
@@ -147,63 +158,22 @@ func GetEMPPKJI(kategori KategoriPKJI, tipeLokasi string) float64 {
 }
 
 func HitungPKJICount(trafficDataList []TrafficData, tipeLokasi string) PKJICount {
-	count := PKJICount{}
+	return pkjiCountFromCategories(HitungCategoryCount(PKJI2023Standard{}, trafficDataList, tipeLokasi))
+}
 
-	for _, td := range trafficDataList {
-		for _, za := range td.ZonaArahData {
-			for _, kd := range za.KelasData {
-				kategori := GetKategoriPKJI(tipeLokasi, kd.Kelas)
-				switch kategori {
-				case KategoriSM:
-					count.SM += kd.JumlahKendaraan
-				case KategoriKR:
-					count.KR += kd.JumlahKendaraan
-				case KategoriKB:
-					count.KB += kd.JumlahKendaraan
-				case KategoriKTB:
-					count.KTB += kd.JumlahKendaraan
-				}
-			}
-		}
+func pkjiCountFromCategories(count CategoryCount) PKJICount {
+	return PKJICount{
+		SM:         count.Counts[string(KategoriSM)],
+		KR:         count.Counts[string(KategoriKR)],
+		KB:         count.Counts[string(KategoriKB)],
+		KTB:        count.Counts[string(KategoriKTB)],
+		TotalMotor: count.TotalMotor,
+		TotalSkr:   count.TotalEkivalen,
 	}
-
-	count.TotalMotor = count.SM + count.KR + count.KB
-
-	count.TotalSkr = float64(count.SM)*GetEMPPKJI(KategoriSM, tipeLokasi) +
-		float64(count.KR)*GetEMPPKJI(KategoriKR, tipeLokasi) +
-		float64(count.KB)*GetEMPPKJI(KategoriKB, tipeLokasi)
-
-	return count
 }
 
 func HitungVolumePKJI(trafficDataList []TrafficData, tipeLokasi string) (float64, string) {
-	if len(trafficDataList) == 0 {
-		return 0, ""
-	}
-
-	jamData := make(map[string]float64)
-	for _, td := range trafficDataList {
-		jamKey := td.Timestamp.Format("15:00")
-
-		for _, za := range td.ZonaArahData {
-			for _, kd := range za.KelasData {
-				kategori := GetKategoriPKJI(tipeLokasi, kd.Kelas)
-				emp := GetEMPPKJI(kategori, tipeLokasi)
-				jamData[jamKey] += float64(kd.JumlahKendaraan) * emp
-			}
-		}
-	}
-
-	var maxSkr float64
-	var jamPuncak string
-	for jam, skr := range jamData {
-		if skr > maxSkr {
-			maxSkr = skr
-			jamPuncak = jam
-		}
-	}
-
-	return maxSkr, jamPuncak
+	return HitungArusJamPuncak(PKJI2023Standard{}, trafficDataList, tipeLokasi)
 }
 
 // Kapasitas Dasar (C0) PKJI 2023
@@ -376,30 +346,8 @@ func GetFCUK(ukuranKota float64, tipeLokasi string) float64 {
 
 // HitungKapasitasPKJI menghitung kapasitas jalan berdasarkan PKJI 2023
 func HitungKapasitasPKJI(location Location) (kapasitas, c0, fclj, fcpa, fchs, fcuk float64) {
-	c0 = GetKapasitasDasarPKJI(location.Tipe_arah, location.Tipe_lokasi)
-	fclj = GetFCLJ(location.Lebar_jalur, location.Tipe_arah, location.Tipe_lokasi)
-	fcpa = GetFCPA(location.Persentase, location.Tipe_arah)
-	fchs = GetFCHS(location.Tipe_hambatan, location.Kelas_hambatan, location.Tipe_lokasi)
-	fcuk = GetFCUK(location.Ukuran_kota, location.Tipe_lokasi)
-
-	switch location.Tipe_lokasi {
-	case "perkotaan":
-		kapasitas = c0 * fclj * fcpa * fchs * fcuk
-
-	case "luar_kota", "12_kelas":
-		fcuk = 1.0
-		kapasitas = c0 * fclj * fcpa * fchs
-
-	case "bebas_hambatan":
-		fchs = 1.0
-		fcuk = 1.0
-		kapasitas = c0 * fclj * fcpa
-
-	default:
-		kapasitas = c0 * fclj * fcpa * fchs * fcuk
-	}
-
-	return
+	result := PKJI2023Standard{}.Kapasitas(location)
+	return result.Kapasitas, result.KapasitasDasar, result.Factor("FCLJ"), result.Factor("FCPA"), result.Factor("FCHS"), result.Factor("FCUK")
 }
 
 // HitungDerajatKejenuhanPKJI menghitung DJ = V/C
@@ -411,21 +359,74 @@ func HitungDerajatKejenuhanPKJI(volume float64, kapasitas float64) float64 {
 }
 
 // GetTingkatPelayananPKJI mengembalikan tingkat pelayanan berdasarkan PKJI 2023
+// Batas atas DJ tingkat pelayanan A-E PKJI 2023
+var batasTingkatPelayananPKJI = []float64{0.35, 0.54, 0.77, 0.93, 1.00}
+
+var keteranganTingkatPelayananPKJI = []string{
+	"Kondisi arus bebas, kecepatan tinggi, volume rendah",
+	"Arus stabil, kecepatan mulai dipengaruhi kondisi lalu lintas",
+	"Arus stabil, kecepatan dan gerak kendaraan dikendalikan",
+	"Arus mendekati tidak stabil, kecepatan menurun",
+	"Volume mendekati/pada kapasitas, arus tidak stabil",
+	"Arus terhenti, terjadi antrian panjang",
+}
+
 func GetTingkatPelayananPKJI(dj float64) (string, string) {
-	switch {
-	case dj <= 0.35:
-		return "A", "Kondisi arus bebas, kecepatan tinggi, volume rendah"
-	case dj <= 0.54:
-		return "B", "Arus stabil, kecepatan mulai dipengaruhi kondisi lalu lintas"
-	case dj <= 0.77:
-		return "C", "Arus stabil, kecepatan dan gerak kendaraan dikendalikan"
-	case dj <= 0.93:
-		return "D", "Arus mendekati tidak stabil, kecepatan menurun"
-	case dj <= 1.00:
-		return "E", "Volume mendekati/pada kapasitas, arus tidak stabil"
-	default:
-		return "F", "Arus terhenti, terjadi antrian panjang"
+	return tingkatPelayananDariBatas(dj, batasTingkatPelayananPKJI, keteranganTingkatPelayananPKJI)
+}
+
+// PKJI2023Standard mengimplementasikan CapacityStandard untuk Pedoman Kapasitas Jalan Indonesia 2023
+type PKJI2023Standard struct{}
+
+func (PKJI2023Standard) Method() AnalysisMethod { return AnalysisMethodPKJI2023 }
+
+func (PKJI2023Standard) UnitEkivalen() string { return "skr" }
+
+func (PKJI2023Standard) Categories() []string {
+	return []string{string(KategoriSM), string(KategoriKR), string(KategoriKB), string(KategoriKTB)}
+}
+
+func (PKJI2023Standard) Kategori(tipeLokasi string, kelas int) string {
+	return string(GetKategoriPKJI(tipeLokasi, kelas))
+}
+
+func (PKJI2023Standard) Ekivalen(kategori string, tipeLokasi string) float64 {
+	if KategoriPKJI(kategori) == KategoriKTB {
+		return 0
 	}
+	return GetEMPPKJI(KategoriPKJI(kategori), tipeLokasi)
+}
+
+func (PKJI2023Standard) IsMotorized(kategori string) bool {
+	return KategoriPKJI(kategori) != KategoriKTB
+}
+
+// Kapasitas C = C0 × FCLJ × FCPA × FCHS × FCUK. Jalan luar kota tidak memakai FCUK,
+// jalan bebas hambatan tidak memakai FCHS dan FCUK.
+func (PKJI2023Standard) Kapasitas(location Location) CapacityResult {
+	// C0 tabel jalan terbagi adalah kapasitas satu arah; arus lokasi adalah arus dua arah
+	c0 := GetKapasitasDasarPKJI(location.Tipe_arah, location.Tipe_lokasi) * float64(jumlahArahKapasitas(location.Tipe_arah))
+	fclj := CapacityFactor{Kode: "FCLJ", Nilai: GetFCLJ(location.Lebar_jalur, location.Tipe_arah, location.Tipe_lokasi)}
+	fcpa := CapacityFactor{Kode: "FCPA", Nilai: GetFCPA(location.Persentase, location.Tipe_arah)}
+	fchs := CapacityFactor{Kode: "FCHS", Nilai: GetFCHS(location.Tipe_hambatan, location.Kelas_hambatan, location.Tipe_lokasi)}
+	fcuk := CapacityFactor{Kode: "FCUK", Nilai: GetFCUK(location.Ukuran_kota, location.Tipe_lokasi)}
+
+	switch location.Tipe_lokasi {
+	case "luar_kota", "12_kelas":
+		fcuk.Nilai = 1.0
+	case "bebas_hambatan":
+		fchs.Nilai = 1.0
+		fcuk.Nilai = 1.0
+	}
+	return newCapacityResult(c0, fclj, fcpa, fchs, fcuk)
+}
+
+func (PKJI2023Standard) TingkatPelayanan(derajatKejenuhan float64) (string, string) {
+	return GetTingkatPelayananPKJI(derajatKejenuhan)
+}
+
+func (PKJI2023Standard) BatasTingkatPelayanan() []float64 {
+	return batasTingkatPelayananPKJI
 }
 
 // NextPKJIAnalysisID generates next PKJI analysis ID
@@ -446,79 +447,146 @@ func NextPKJIAnalysisID() (string, error) {
 }
 
 // CreatePKJIAnalysis creates a new PKJI analysis
-func CreatePKJIAnalysis(lokasiID string, startTime, endTime time.Time) (*PKJIAnalysis, error) {
+func CreatePKJIAnalysis(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*PKJIAnalysis, error) {
 	location, err := GetLocationByID(lokasiID)
 	if err != nil {
 		return nil, err
 	}
 
-	trafficDataList, err := GetTrafficDataByLokasiID(lokasiID, startTime, endTime)
+	location, dataset, hambatan, err := prepareAnalysis(location, startTime, endTime, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(trafficDataList) == 0 {
+	if len(dataset.Data) == 0 {
 		return nil, fmt.Errorf("tidak ada data traffic untuk periode yang diminta")
 	}
 
-	jumlahHari := int(math.Ceil(endTime.Sub(startTime).Hours() / 24))
-	if jumlahHari < 1 {
-		jumlahHari = 1
+	analysis, err := BuildPKJIAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
 	}
+	analysis.HambatanSamping = hambatan
 
-	pkjiCount := HitungPKJICount(trafficDataList, location.Tipe_lokasi)
-	volume, jamPuncak := HitungVolumePKJI(trafficDataList, location.Tipe_lokasi)
-	kapasitas, c0, fclj, fcpa, fchs, fcuk := HitungKapasitasPKJI(*location)
-	dj := HitungDerajatKejenuhanPKJI(volume, kapasitas)
-	tingkatPelayanan, keterangan := GetTingkatPelayananPKJI(dj)
+	id, err := NextPKJIAnalysisID()
+	if err != nil {
+		return nil, err
+	}
+	analysis.ID = id
 
-	totalKendaraanHari := 0
-	for _, td := range trafficDataList {
-		totalKendaraanHari += td.TotalKendaraan
+	_, err = database.DB.Collection("pkji_analysis").InsertOne(context.Background(), analysis)
+	if err != nil {
+		return nil, err
 	}
 
-	lhrt := float64(totalKendaraanHari) / float64(jumlahHari)
-	lhrtSkr := pkjiCount.TotalSkr / float64(jumlahHari)
+	return analysis, nil
+}
 
-	id, err := NextPKJIAnalysisID()
+// CalculatePKJIRealtime menghitung analisis PKJI tanpa menyimpannya
+func CalculatePKJIRealtime(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*PKJIAnalysis, error) {
+	location, err := GetLocationByID(lokasiID)
 	if err != nil {
 		return nil, err
 	}
 
-	analysis := &PKJIAnalysis{
-		ID:                 id,
-		LokasiID:           lokasiID,
-		NamaLokasi:         location.Nama_lokasi,
-		TipeLokasi:         location.Tipe_lokasi,
-		TipeArah:           location.Tipe_arah,
-		Tanggal:            startTime,
-		PeriodeHari:        jumlahHari,
-		Timestamp:          time.Now().Add(7 * time.Hour),
-		PKJICount:          pkjiCount,
-		TotalKendaraanHari: totalKendaraanHari,
-		LHRT:               lhrt,
-		LHRTSkr:            lhrtSkr,
-		VolumeLaluLintas:   volume,
-		JamPuncak:          jamPuncak,
-		KapasitasDasar:     c0,
-		FCLJ:               fclj,
-		FCPA:               fcpa,
-		FCHS:               fchs,
-		FCUK:               fcuk,
-		Kapasitas:          kapasitas,
-		DerajatKejenuhan:   dj,
-		TingkatPelayanan:   tingkatPelayanan,
-		Keterangan:         keterangan,
+	location, dataset, hambatan, err := prepareAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = database.DB.Collection("pkji_analysis").InsertOne(context.Background(), analysis)
+	analysis, err := BuildPKJIAnalysis(location, startTime, endTime, dataset)
 	if err != nil {
 		return nil, err
 	}
+	analysis.HambatanSamping = hambatan
 
 	return analysis, nil
 }
 
+// BuildPKJIAnalysis menghitung analisis PKJI dari dataset yang sudah dimuat (tanpa ID dan tanpa menyimpan)
+func BuildPKJIAnalysis(location *Location, startTime, endTime time.Time, dataset *AnalysisDataset) (*PKJIAnalysis, error) {
+	trafficDataList := dataset.Data
+
+	standard, err := ResolveCapacityStandard(PKJI2023Standard{}, *location, trafficDataList)
+	if err != nil {
+		return nil, err
+	}
+
+	kapasitasResult := standard.Kapasitas(*location)
+	kapasitas, c0 := kapasitasResult.Kapasitas, kapasitasResult.KapasitasDasar
+	fclj, fcpa := kapasitasResult.Factor("FCLJ"), kapasitasResult.Factor("FCPA")
+	fchs, fcuk := kapasitasResult.Factor("FCHS"), kapasitasResult.Factor("FCUK")
+
+	if len(trafficDataList) == 0 {
+		return &PKJIAnalysis{
+			LokasiID:             location.ID,
+			NamaLokasi:           location.Nama_lokasi,
+			TipeLokasi:           location.Tipe_lokasi,
+			TipeArah:             location.Tipe_arah,
+			KapasitasDasar:       c0,
+			FCLJ:                 fclj,
+			FCPA:                 fcpa,
+			FCHS:                 fchs,
+			FCUK:                 fcuk,
+			Kapasitas:            kapasitas,
+			Kecepatan:            HitungKecepatanPKJI(standard, *location, nil, kapasitas, 0, PeakHourAnalysis{}),
+			IntervalDikecualikan: dataset.IntervalDikecualikan,
+			Kelengkapan:          dataset.Kelengkapan,
+			Keterangan:           "Tidak ada data traffic untuk periode yang diminta",
+		}, nil
+	}
+
+	jumlahHari := int(math.Ceil(endTime.Sub(startTime).Hours() / 24))
+	if jumlahHari < 1 {
+		jumlahHari = 1
+	}
+
+	pkjiCount := pkjiCountFromCategories(HitungCategoryCount(standard, trafficDataList, location.Tipe_lokasi))
+	peakHour := HitungPeakHour(standard, trafficDataList, location.Tipe_lokasi)
+	volume, jamPuncak := peakHour.Puncak.Arus, peakHour.Puncak.JamPuncak
+	dj := HitungDerajatKejenuhanPKJI(volume, kapasitas)
+	tingkatPelayanan, keterangan := standard.TingkatPelayanan(dj)
+
+	totalKendaraanHari := 0
+	for _, td := range trafficDataList {
+		totalKendaraanHari += td.TotalKendaraan
+	}
+
+	lhrt := float64(totalKendaraanHari) / float64(jumlahHari)
+	lhrtSkr := pkjiCount.TotalSkr / float64(jumlahHari)
+
+	return &PKJIAnalysis{
+		LokasiID:             location.ID,
+		NamaLokasi:           location.Nama_lokasi,
+		TipeLokasi:           location.Tipe_lokasi,
+		TipeArah:             location.Tipe_arah,
+		Tanggal:              startTime,
+		PeriodeHari:          jumlahHari,
+		IntervalDikecualikan: dataset.IntervalDikecualikan,
+		Kelengkapan:          dataset.Kelengkapan,
+		Timestamp:            time.Now().Add(7 * time.Hour),
+		PKJICount:            pkjiCount,
+		TotalKendaraanHari:   totalKendaraanHari,
+		LHRT:                 lhrt,
+		LHRTSkr:              lhrtSkr,
+		VolumeLaluLintas:     volume,
+		JamPuncak:            jamPuncak,
+		PeakHour:             &peakHour,
+		PerArah:              HitungKinerjaPerArah(standard, *location, trafficDataList, peakHour),
+		Kecepatan:            HitungKecepatanPKJI(standard, *location, trafficDataList, kapasitas, dj, peakHour),
+		KapasitasDasar:       c0,
+		FCLJ:                 fclj,
+		FCPA:                 fcpa,
+		FCHS:                 fchs,
+		FCUK:                 fcuk,
+		Kapasitas:            kapasitas,
+		DerajatKejenuhan:     dj,
+		TingkatPelayanan:     tingkatPelayanan,
+		TabelEkivalen:        standard.TabelDipakai(),
+		Keterangan:           keterangan,
+	}, nil
+}
+
 // GetPKJIAnalysisByLokasiID retrieves PKJI analyses for a location
 func GetPKJIAnalysisByLokasiID(lokasiID string, limit int64) ([]PKJIAnalysis, error) {
 	collection := database.DB.Collection("pkji_analysis")
@@ -539,3 +607,33 @@ func GetPKJIAnalysisByLokasiID(lokasiID string, limit int64) ([]PKJIAnalysis, er
 
 	return analyses, nil
 }
+
+// GetLatestPKJIAnalysisByLokasiID mengambil analisis PKJI terbaru untuk lokasi
+func GetLatestPKJIAnalysisByLokasiID(lokasiID string) (*PKJIAnalysis, error) {
+	collection := database.DB.Collection("pkji_analysis")
+
+	var analysis PKJIAnalysis
+	err := collection.FindOne(
+		context.Background(),
+		bson.M{"lokasi_id": lokasiID},
+		options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}}),
+	).Decode(&analysis)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &analysis, nil
+}
+
+func GetPKJIAnalysisByID(id string) (*PKJIAnalysis, error) {
+	collection := database.DB.Collection("pkji_analysis")
+
+	var analysis PKJIAnalysis
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &analysis, nil
+}
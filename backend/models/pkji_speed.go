@@ -0,0 +1,320 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Kecepatan arus bebas dan kecepatan tempuh PKJI 2023 dibandingkan dengan kecepatan terukur kamera.
+// Semua kecepatan dalam km/jam untuk kendaraan ringan (KR).
+type PKJISpeedAnalysis struct {
+	KecepatanDasar     float64 `bson:"kecepatan_dasar" json:"kecepatan_dasar"`           // VBD
+	PenyesuaianLebar   float64 `bson:"penyesuaian_lebar" json:"penyesuaian_lebar"`       // VBL (km/jam)
+	FVBHS              float64 `bson:"fvbhs" json:"fvbhs"`                               // Faktor hambatan samping
+	FVBUK              float64 `bson:"fvbuk" json:"fvbuk"`                               // Faktor ukuran kota
+	KecepatanArusBebas float64 `bson:"kecepatan_arus_bebas" json:"kecepatan_arus_bebas"` // VB = (VBD + VBL) × FVBHS × FVBUK
+	DerajatKejenuhan   float64 `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`       // DJ jam puncak
+	KecepatanTempuh    float64 `bson:"kecepatan_tempuh" json:"kecepatan_tempuh"`         // VT pada DJ jam puncak
+
+	// Kecepatan terukur KR dari kamera (rata-rata tertimbang jumlah kendaraan)
+	KecepatanUkur          float64  `bson:"kecepatan_ukur" json:"kecepatan_ukur"`
+	KecepatanUkurArusBebas float64  `bson:"kecepatan_ukur_arus_bebas" json:"kecepatan_ukur_arus_bebas"` // interval dengan DJ ≤ 0.35
+	SampelArusBebas        int      `bson:"sampel_arus_bebas" json:"sampel_arus_bebas"`
+	KecepatanUkurJamPuncak float64  `bson:"kecepatan_ukur_jam_puncak" json:"kecepatan_ukur_jam_puncak"`
+	SampelJamPuncak        int      `bson:"sampel_jam_puncak" json:"sampel_jam_puncak"`
+	DeviasiArusBebas       *float64 `bson:"deviasi_arus_bebas,omitempty" json:"deviasi_arus_bebas,omitempty"`             // % (ukur - VB) / VB
+	DeviasiKecepatanTempuh *float64 `bson:"deviasi_kecepatan_tempuh,omitempty" json:"deviasi_kecepatan_tempuh,omitempty"` // % (ukur jam puncak - VT) / VT
+	PerluDitinjau          bool     `bson:"perlu_ditinjau" json:"perlu_ditinjau"`                                         // deviasi melebihi batas, parameter lokasi kemungkinan salah
+	Catatan                []string `bson:"catatan,omitempty" json:"catatan,omitempty"`
+}
+
+const (
+	// DJ maksimum interval yang dianggap arus bebas untuk kecepatan terukur
+	djArusBebas = 0.35
+	// Jumlah kendaraan minimum agar kecepatan terukur dibandingkan
+	minSampelKecepatan = 30
+)
+
+var speedDeviationThreshold = 20.0
+
+// SetSpeedDeviationThreshold mengatur batas deviasi kecepatan (%) sebelum lokasi ditandai perlu ditinjau
+func SetSpeedDeviationThreshold(percent float64) {
+	if percent > 0 {
+		speedDeviationThreshold = percent
+	}
+}
+
+// lajurPerArah mengembalikan jumlah lajur per arah dari tipe arah (42d → 2, 62d → 3, 22ud → 1)
+func lajurPerArah(tipeArah string) int {
+	if len(tipeArah) < 2 {
+		return 1
+	}
+	lajur, err1 := strconv.Atoi(tipeArah[:1])
+	arah, err2 := strconv.Atoi(tipeArah[1:2])
+	if err1 != nil || err2 != nil || arah == 0 || lajur < arah {
+		return 1
+	}
+	return lajur / arah
+}
+
+// GetVBD - Kecepatan arus bebas dasar kendaraan ringan (PKJI 2023)
+func GetVBD(tipeArah string, tipeLokasi string) float64 {
+	switch tipeLokasi {
+	case "bebas_hambatan":
+		if tipeArah == "62d" {
+			return 91
+		}
+		return 88
+
+	case "luar_kota", "12_kelas":
+		switch tipeArah {
+		case "62d":
+			return 83
+		case "42d":
+			return 78
+		case "42ud":
+			return 74
+		default:
+			return 68
+		}
+
+	default:
+		switch tipeArah {
+		case "62d":
+			return 61
+		case "42d":
+			return 57
+		case "42ud":
+			return 53
+		default:
+			return 44
+		}
+	}
+}
+
+// GetVBL - Penyesuaian kecepatan arus bebas akibat lebar jalur (km/jam, PKJI 2023).
+// Jalan 2/2 tak terbagi memakai lebar total, jalan berlajur banyak memakai lebar per lajur.
+func GetVBL(lebarJalur int, tipeArah string) float64 {
+	if tipeArah == "22ud" || lajurPerArah(tipeArah) == 1 {
+		switch {
+		case lebarJalur <= 5:
+			return -9.5
+		case lebarJalur == 6:
+			return -3
+		case lebarJalur == 7:
+			return 0
+		case lebarJalur == 8:
+			return 3
+		case lebarJalur == 9:
+			return 4
+		case lebarJalur == 10:
+			return 6
+		default:
+			return 7
+		}
+	}
+
+	lebarLajur := float64(lebarJalur) / float64(lajurPerArah(tipeArah))
+	switch {
+	case lebarLajur <= 3.0:
+		return -4
+	case lebarLajur <= 3.25:
+		return -2
+	case lebarLajur <= 3.5:
+		return 0
+	case lebarLajur <= 3.75:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// GetFVBHS - Faktor penyesuaian kecepatan arus bebas akibat hambatan samping (PKJI 2023)
+func GetFVBHS(tipeHambatan string, kelasHambatan string, tipeArah string, tipeLokasi string) float64 {
+	if tipeLokasi == "bebas_hambatan" {
+		return 1.0
+	}
+
+	multiLajur := map[string]map[string]float64{
+		"bahu_jalan": {"VL": 1.02, "L": 0.98, "M": 0.94, "H": 0.89, "VH": 0.84},
+		"kereb":      {"VL": 1.00, "L": 0.97, "M": 0.93, "H": 0.87, "VH": 0.81},
+	}
+	duaLajur := map[string]map[string]float64{
+		"bahu_jalan": {"VL": 1.00, "L": 0.96, "M": 0.91, "H": 0.82, "VH": 0.73},
+		"kereb":      {"VL": 0.98, "L": 0.93, "M": 0.87, "H": 0.78, "VH": 0.68},
+	}
+
+	tabel := multiLajur
+	if tipeArah == "22ud" {
+		tabel = duaLajur
+	}
+	if tipeLokasi != "perkotaan" {
+		// Jalan luar kota umumnya berbahu
+		tipeHambatan = "bahu_jalan"
+	}
+
+	if tipeMap, ok := tabel[tipeHambatan]; ok {
+		if value, ok := tipeMap[kelasHambatan]; ok {
+			return value
+		}
+	}
+	return 0.94
+}
+
+// GetFVBUK - Faktor penyesuaian kecepatan arus bebas akibat ukuran kota (PKJI 2023)
+func GetFVBUK(ukuranKota float64, tipeLokasi string) float64 {
+	if tipeLokasi != "perkotaan" {
+		return 1.0
+	}
+
+	switch {
+	case ukuranKota < 0.1:
+		return 0.90
+	case ukuranKota < 0.5:
+		return 0.93
+	case ukuranKota < 1.0:
+		return 0.95
+	case ukuranKota < 3.0:
+		return 1.00
+	default:
+		return 1.03
+	}
+}
+
+// HitungKecepatanArusBebas menghitung VB = (VBD + VBL) × FVBHS × FVBUK
+func HitungKecepatanArusBebas(location Location) (vb, vbd, vbl, fvbhs, fvbuk float64) {
+	vbd = GetVBD(location.Tipe_arah, location.Tipe_lokasi)
+	vbl = GetVBL(location.Lebar_jalur, location.Tipe_arah)
+	fvbhs = GetFVBHS(location.Tipe_hambatan, location.Kelas_hambatan, location.Tipe_arah, location.Tipe_lokasi)
+	fvbuk = GetFVBUK(location.Ukuran_kota, location.Tipe_lokasi)
+	vb = (vbd + vbl) * fvbhs * fvbuk
+	return
+}
+
+// HitungKecepatanTempuh menghitung VT = 0.5 × VB × (1 + (1 - DJ)^0.5).
+// Untuk DJ > 1 kecepatan diekstrapolasi 0.5 × VB / DJ agar tetap menurun.
+func HitungKecepatanTempuh(vb float64, dj float64) float64 {
+	if dj <= 0 {
+		return vb
+	}
+	if dj <= 1 {
+		return 0.5 * vb * (1 + math.Sqrt(1-dj))
+	}
+	return 0.5 * vb / dj
+}
+
+// Akumulator kecepatan tertimbang jumlah kendaraan
+type speedSample struct {
+	sum   float64
+	count int
+}
+
+func (s *speedSample) add(speed float64, count int) {
+	s.sum += speed * float64(count)
+	s.count += count
+}
+
+func (s speedSample) mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+// HitungKecepatanPKJI menghitung VB dan VT lokasi lalu membandingkannya dengan kecepatan KR terukur kamera
+func HitungKecepatanPKJI(standard CapacityStandard, location Location, trafficDataList []TrafficData, kapasitas float64, dj float64, peak PeakHourAnalysis) *PKJISpeedAnalysis {
+	vb, vbd, vbl, fvbhs, fvbuk := HitungKecepatanArusBebas(location)
+	analysis := &PKJISpeedAnalysis{
+		KecepatanDasar:     vbd,
+		PenyesuaianLebar:   vbl,
+		FVBHS:              fvbhs,
+		FVBUK:              fvbuk,
+		KecepatanArusBebas: vb,
+		DerajatKejenuhan:   dj,
+		KecepatanTempuh:    HitungKecepatanTempuh(vb, dj),
+	}
+
+	interval := time.Duration(peak.IntervalMenit) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	// Arus ekivalen dan kecepatan KR per slot interval
+	slotArus := make(map[time.Time]float64)
+	slotSpeed := make(map[time.Time]*speedSample)
+	for _, td := range trafficDataList {
+		slot := td.Timestamp.UTC().Truncate(interval)
+		slotArus[slot] += trafficDataEkivalen(standard, td, location.Tipe_lokasi)
+		if slotSpeed[slot] == nil {
+			slotSpeed[slot] = &speedSample{}
+		}
+		for _, za := range td.ZonaArahData {
+			for _, kd := range za.KelasData {
+				if kd.JumlahKendaraan <= 0 || kd.KecepatanRataRata <= 0 {
+					continue
+				}
+				if kategoriKelas(standard, td, location.Tipe_lokasi, kd.Kelas) != string(KategoriKR) {
+					continue
+				}
+				slotSpeed[slot].add(kd.KecepatanRataRata, kd.JumlahKendaraan)
+			}
+		}
+	}
+
+	var semua, arusBebas, jamPuncak speedSample
+	skalaJam := time.Hour.Hours() / interval.Hours()
+	for slot, sample := range slotSpeed {
+		semua.add(sample.mean(), sample.count)
+		if kapasitas > 0 && slotArus[slot]*skalaJam/kapasitas <= djArusBebas {
+			arusBebas.add(sample.mean(), sample.count)
+		}
+		if !slot.Before(peak.Puncak.Mulai) && slot.Before(peak.Puncak.Selesai) {
+			jamPuncak.add(sample.mean(), sample.count)
+		}
+	}
+
+	analysis.KecepatanUkur = semua.mean()
+	analysis.KecepatanUkurArusBebas = arusBebas.mean()
+	analysis.SampelArusBebas = arusBebas.count
+	analysis.KecepatanUkurJamPuncak = jamPuncak.mean()
+	analysis.SampelJamPuncak = jamPuncak.count
+
+	if len(trafficDataList) == 0 {
+		analysis.Catatan = append(analysis.Catatan, "Tidak ada data traffic untuk dibandingkan")
+		return analysis
+	}
+	if semua.count == 0 {
+		analysis.Catatan = append(analysis.Catatan, "Kamera tidak melaporkan kecepatan kendaraan ringan")
+		return analysis
+	}
+
+	if arusBebas.count >= minSampelKecepatan && vb > 0 {
+		deviasi := (arusBebas.mean() - vb) / vb * 100
+		analysis.DeviasiArusBebas = &deviasi
+		if math.Abs(deviasi) > speedDeviationThreshold {
+			analysis.PerluDitinjau = true
+			analysis.Catatan = append(analysis.Catatan, fmt.Sprintf(
+				"Kecepatan arus bebas terukur %.1f km/jam berbeda %.0f%% dari VB %.1f km/jam; periksa tipe arah, lebar jalur, dan hambatan samping",
+				arusBebas.mean(), deviasi, vb))
+		}
+	} else {
+		analysis.Catatan = append(analysis.Catatan, fmt.Sprintf("Sampel arus bebas (DJ ≤ %.2f) kurang dari %d kendaraan", djArusBebas, minSampelKecepatan))
+	}
+
+	if jamPuncak.count >= minSampelKecepatan && analysis.KecepatanTempuh > 0 {
+		deviasi := (jamPuncak.mean() - analysis.KecepatanTempuh) / analysis.KecepatanTempuh * 100
+		analysis.DeviasiKecepatanTempuh = &deviasi
+		if math.Abs(deviasi) > speedDeviationThreshold {
+			analysis.PerluDitinjau = true
+			analysis.Catatan = append(analysis.Catatan, fmt.Sprintf(
+				"Kecepatan jam puncak terukur %.1f km/jam berbeda %.0f%% dari VT %.1f km/jam pada DJ %.2f; periksa kapasitas lokasi",
+				jamPuncak.mean(), deviasi, analysis.KecepatanTempuh, dj))
+		}
+	} else {
+		analysis.Catatan = append(analysis.Catatan, fmt.Sprintf("Sampel jam puncak kurang dari %d kendaraan", minSampelKecepatan))
+	}
+
+	return analysis
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKomponenKecepatanArusBebas(t *testing.T) {
+	// Nilai tabel PKJI 2023 untuk kendaraan ringan
+	vbd := []struct {
+		tipeArah, tipeLokasi string
+		ingin                float64
+	}{
+		{"22ud", "perkotaan", 44}, {"42ud", "perkotaan", 53}, {"42d", "perkotaan", 57}, {"62d", "perkotaan", 61},
+		{"22ud", "luar_kota", 68}, {"42ud", "luar_kota", 74}, {"42d", "luar_kota", 78}, {"62d", "12_kelas", 83},
+		{"42d", "bebas_hambatan", 88}, {"62d", "bebas_hambatan", 91},
+	}
+	for _, tt := range vbd {
+		if got := GetVBD(tt.tipeArah, tt.tipeLokasi); got != tt.ingin {
+			t.Errorf("VBD %s %s = %v, ingin %v", tt.tipeArah, tt.tipeLokasi, got, tt.ingin)
+		}
+	}
+
+	// 2/2 tak terbagi memakai lebar total, jalan berlajur banyak memakai lebar jalur dibagi jumlah lajur per arah
+	vbl := []struct {
+		lebar    int
+		tipeArah string
+		ingin    float64
+	}{
+		{5, "22ud", -9.5}, {6, "22ud", -3}, {7, "22ud", 0}, {8, "22ud", 3}, {10, "22ud", 6}, {12, "22ud", 7},
+		{5, "42d", -4}, {6, "42d", -4}, {7, "42d", 0}, {8, "42d", 4}, {7, "42ud", 0},
+		{9, "62d", -4}, {10, "62d", 0}, {11, "62d", 2},
+	}
+	for _, tt := range vbl {
+		if got := GetVBL(tt.lebar, tt.tipeArah); got != tt.ingin {
+			t.Errorf("VBL lebar %d %s = %v, ingin %v", tt.lebar, tt.tipeArah, got, tt.ingin)
+		}
+	}
+
+	fvbhs := []struct {
+		tipeHambatan, kelas, tipeArah, tipeLokasi string
+		ingin                                     float64
+	}{
+		{"kereb", "M", "42d", "perkotaan", 0.93},
+		{"bahu_jalan", "VL", "42d", "perkotaan", 1.02},
+		{"kereb", "VH", "22ud", "perkotaan", 0.68},
+		{"bahu_jalan", "H", "22ud", "perkotaan", 0.82},
+		// Jalan luar kota dianggap berbahu
+		{"kereb", "H", "22ud", "luar_kota", 0.82},
+		{"kereb", "VH", "62d", "bebas_hambatan", 1.0},
+		{"kereb", "X", "42d", "perkotaan", 0.94},
+	}
+	for _, tt := range fvbhs {
+		if got := GetFVBHS(tt.tipeHambatan, tt.kelas, tt.tipeArah, tt.tipeLokasi); got != tt.ingin {
+			t.Errorf("FVBHS %s %s %s %s = %v, ingin %v", tt.tipeHambatan, tt.kelas, tt.tipeArah, tt.tipeLokasi, got, tt.ingin)
+		}
+	}
+
+	fvbuk := []struct {
+		ukuran float64
+		ingin  float64
+	}{{0.05, 0.90}, {0.1, 0.93}, {0.5, 0.95}, {1.0, 1.00}, {2.9, 1.00}, {3.0, 1.03}}
+	for _, tt := range fvbuk {
+		if got := GetFVBUK(tt.ukuran, "perkotaan"); got != tt.ingin {
+			t.Errorf("FVBUK %.2f juta = %v, ingin %v", tt.ukuran, got, tt.ingin)
+		}
+	}
+	if got := GetFVBUK(0.05, "luar_kota"); got != 1.0 {
+		t.Errorf("FVBUK luar kota = %v, ingin 1", got)
+	}
+}
+
+func TestHitungKecepatanArusBebas(t *testing.T) {
+	tests := []struct {
+		nama     string
+		location Location
+		vb       float64
+	}{
+		// (57 + 0) × 0.93 × 1.00
+		{"perkotaan 4/2 terbagi", Location{Tipe_arah: "42d", Tipe_lokasi: "perkotaan", Lebar_jalur: 7,
+			Tipe_hambatan: "kereb", Kelas_hambatan: "M", Ukuran_kota: 1.5}, 53.01},
+		// (44 + 0) × 0.96 × 0.93
+		{"perkotaan 2/2 tak terbagi", Location{Tipe_arah: "22ud", Tipe_lokasi: "perkotaan", Lebar_jalur: 7,
+			Tipe_hambatan: "bahu_jalan", Kelas_hambatan: "L", Ukuran_kota: 0.3}, 39.2832},
+		// (68 - 3) × 0.82 × 1.00
+		{"luar kota 2/2 sempit", Location{Tipe_arah: "22ud", Tipe_lokasi: "luar_kota", Lebar_jalur: 6,
+			Tipe_hambatan: "kereb", Kelas_hambatan: "H"}, 53.3},
+		// (91 + 2) × 1.00 × 1.00
+		{"bebas hambatan 6/2", Location{Tipe_arah: "62d", Tipe_lokasi: "bebas_hambatan", Lebar_jalur: 11,
+			Tipe_hambatan: "kereb", Kelas_hambatan: "VH"}, 93},
+	}
+	for _, tt := range tests {
+		vb, vbd, vbl, fvbhs, fvbuk := HitungKecepatanArusBebas(tt.location)
+		if math.Abs(vb-tt.vb) > 1e-9 {
+			t.Errorf("%s: VB %.4f, ingin %.4f", tt.nama, vb, tt.vb)
+		}
+		if math.Abs(vb-(vbd+vbl)*fvbhs*fvbuk) > 1e-9 {
+			t.Errorf("%s: VB %.4f tidak sama dengan (VBD + VBL) × FVBHS × FVBUK", tt.nama, vb)
+		}
+	}
+}
+
+func TestHitungKecepatanTempuh(t *testing.T) {
+	tests := []struct {
+		nama string
+		dj   float64
+		vt   float64
+	}{
+		{"tanpa arus", 0, 60},
+		{"DJ negatif", -0.2, 60},
+		// 0.5 × 60 × (1 + √0.36)
+		{"DJ 0.64", 0.64, 48},
+		// 0.5 × 60 × (1 + √0.25)
+		{"DJ 0.75", 0.75, 45},
+		{"DJ 1", 1, 30},
+		// Lewat jenuh: 0.5 × 60 / DJ
+		{"DJ 1.25", 1.25, 24},
+		{"DJ 2", 2, 15},
+	}
+	for _, tt := range tests {
+		if got := HitungKecepatanTempuh(60, tt.dj); math.Abs(got-tt.vt) > 1e-9 {
+			t.Errorf("%s: VT %.4f, ingin %.4f", tt.nama, got, tt.vt)
+		}
+	}
+
+	// VT terus menurun saat DJ naik, termasuk melewati DJ = 1
+	sebelum := HitungKecepatanTempuh(60, 0)
+	for dj := 0.05; dj <= 3; dj += 0.05 {
+		vt := HitungKecepatanTempuh(60, dj)
+		if vt > sebelum {
+			t.Fatalf("VT naik dari %.3f ke %.3f pada DJ %.2f", sebelum, vt, dj)
+		}
+		sebelum = vt
+	}
+}
+
+func TestLajurPerArah(t *testing.T) {
+	tests := map[string]int{"22ud": 1, "42ud": 2, "42d": 2, "62d": 3, "": 1, "x": 1, "24d": 1}
+	for tipeArah, ingin := range tests {
+		if got := lajurPerArah(tipeArah); got != ingin {
+			t.Errorf("lajurPerArah(%q) = %d, ingin %d", tipeArah, got, ingin)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Sumber laju pertumbuhan lalu lintas proyeksi
+const (
+	PertumbuhanParameter   = "parameter"    // laju dari request
+	PertumbuhanLHRTTahunan = "lhrt_tahunan" // regresi LHRT tahunan tersimpan
+	PertumbuhanLHRHarian   = "lhr_harian"   // regresi volume harian yang disesuaikan faktor musiman
+	PertumbuhanKonfigurasi = "konfigurasi"  // PROJECTION_GROWTH_RATE
+)
+
+const (
+	// Jumlah tahun sebelum akhir periode dasar yang dipakai untuk regresi volume harian
+	tahunRiwayatPertumbuhan = 3
+	// Syarat regresi volume harian: jumlah hari lengkap dan rentang hari minimum
+	minHariRegresiPertumbuhan    = 60
+	minRentangRegresiPertumbuhan = 180
+	// Batas horizon proyeksi (tahun)
+	maksHorizonProyeksi = 50
+)
+
+var (
+	ErrLajuPertumbuhanTidakTersedia = errors.New("riwayat LHR belum cukup untuk menghitung laju pertumbuhan, isi laju_pertumbuhan atau PROJECTION_GROWTH_RATE")
+	ErrTahunAkhirTidakValid         = errors.New("tahun_akhir tidak valid")
+)
+
+var (
+	defaultGrowthRate      = math.NaN() // %/tahun, NaN = tidak dikonfigurasi
+	defaultHorizonProyeksi = 20
+)
+
+// SetProjectionConfig mengatur laju pertumbuhan cadangan (%/tahun, NaN = tidak ada) dan horizon default proyeksi
+func SetProjectionConfig(growthRate float64, horizon int) {
+	defaultGrowthRate = growthRate
+	if horizon > 0 && horizon <= maksHorizonProyeksi {
+		defaultHorizonProyeksi = horizon
+	}
+}
+
+// Laju pertumbuhan lalu lintas yang dipakai proyeksi
+type LajuPertumbuhan struct {
+	Laju       float64    `json:"laju"` // % per tahun
+	Sumber     string     `json:"sumber"`
+	JumlahData int        `json:"jumlah_data,omitempty"` // jumlah tahun atau hari pada regresi
+	DataMulai  *time.Time `json:"data_mulai,omitempty"`
+	DataAkhir  *time.Time `json:"data_akhir,omitempty"`
+	R2         *float64   `json:"r2,omitempty"` // koefisien determinasi regresi ln(LHR) terhadap waktu
+}
+
+// Kinerja satu tahun proyeksi
+type ProyeksiTahun struct {
+	Tahun            int     `json:"tahun"`
+	LHRT             float64 `json:"lhrt"` // kendaraan/hari
+	Arus             float64 `json:"arus"` // arus jam rencana (smp/jam atau skr/jam)
+	DerajatKejenuhan float64 `json:"derajat_kejenuhan"`
+	TingkatPelayanan string  `json:"tingkat_pelayanan"`
+}
+
+// Tahun ketika DS pertama kali melewati batas atas tingkat pelayanan sebelumnya
+type ProyeksiBatas struct {
+	TingkatPelayanan string   `json:"tingkat_pelayanan"` // tingkat pelayanan yang dicapai setelah batas terlampaui
+	BatasDS          float64  `json:"batas_ds"`
+	Tahun            *int     `json:"tahun"`                     // tahun pertama dalam horizon, null jika tidak tercapai
+	TahunPerkiraan   *float64 `json:"tahun_perkiraan,omitempty"` // hasil analitik DS0 × (1+i)^t = batas
+	SudahTerlampaui  bool     `json:"sudah_terlampaui"`          // sudah terlampaui pada tahun dasar
+}
+
+// Proyeksi kinerja ruas untuk satu standar kapasitas
+type ProyeksiStandar struct {
+	Metode           AnalysisMethod  `json:"metode"`
+	Satuan           string          `json:"satuan"`
+	Kapasitas        float64         `json:"kapasitas"`    // kapasitas dianggap tetap (geometri tidak berubah)
+	ArusTerukur      float64         `json:"arus_terukur"` // arus jam puncak periode dasar
+	ArusDasar        float64         `json:"arus_dasar"`   // arus jam rencana tahun dasar setelah penyesuaian musiman
+	DerajatKejenuhan float64         `json:"derajat_kejenuhan"`
+	Tahunan          []ProyeksiTahun `json:"tahunan"`
+	Batas            []ProyeksiBatas `json:"batas"`
+}
+
+// Proyeksi pertumbuhan lalu lintas dan tingkat pelayanan tahun rencana
+type TrafficProjection struct {
+	LokasiID        string          `json:"lokasi_id"`
+	NamaLokasi      string          `json:"nama_lokasi"`
+	PeriodeMulai    time.Time       `json:"periode_mulai"` // periode dasar
+	PeriodeSelesai  time.Time       `json:"periode_selesai"`
+	TahunDasar      int             `json:"tahun_dasar"`
+	TahunAkhir      int             `json:"tahun_akhir"`
+	LHRDasar        float64         `json:"lhr_dasar"`  // rata-rata volume hari lengkap periode dasar
+	LHRTDasar       float64         `json:"lhrt_dasar"` // LHRT tahun dasar setelah penyesuaian musiman
+	KepercayaanLHRT string          `json:"kepercayaan_lhrt"`
+	Pertumbuhan     LajuPertumbuhan `json:"pertumbuhan"`
+	MKJI            ProyeksiStandar `json:"mkji"`
+	PKJI            ProyeksiStandar `json:"pkji"`
+	Catatan         []string        `json:"catatan,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
+
+// regresiPertumbuhan mencocokkan ln(y) = a + b·x (x dalam tahun) dan mengembalikan laju (e^b - 1) dalam persen
+func regresiPertumbuhan(x, y []float64) (laju float64, r2 float64, ok bool) {
+	n := 0.0
+	var sx, sy, sxx, sxy, syy float64
+	for i := range x {
+		if y[i] <= 0 {
+			continue
+		}
+		ly := math.Log(y[i])
+		n++
+		sx += x[i]
+		sy += ly
+		sxx += x[i] * x[i]
+		sxy += x[i] * ly
+		syy += ly * ly
+	}
+	if n < 2 {
+		return 0, 0, false
+	}
+	penyebut := n*sxx - sx*sx
+	if penyebut <= 0 {
+		return 0, 0, false
+	}
+	b := (n*sxy - sx*sy) / penyebut
+	varY := n*syy - sy*sy
+	if varY > 0 {
+		r := (n*sxy - sx*sy) / math.Sqrt(penyebut*varY)
+		r2 = r * r
+	}
+	return (math.Exp(b) - 1) * 100, r2, true
+}
+
+// LajuPertumbuhanLHRTTahunan menghitung laju dari LHRT tahunan tersimpan (minimal dua tahun)
+func LajuPertumbuhanLHRTTahunan(lokasiID string, sampaiTahun int) (*LajuPertumbuhan, error) {
+	estimates, err := GetLHRTTahunanByLokasiID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	var x, y []float64
+	for _, e := range estimates {
+		if e.Tahun > sampaiTahun || e.HariLengkap == 0 || e.LHRT <= 0 {
+			continue
+		}
+		x = append(x, float64(e.Tahun))
+		y = append(y, e.LHRT)
+	}
+	laju, r2, ok := regresiPertumbuhan(x, y)
+	if !ok {
+		return nil, nil
+	}
+
+	r2 = math.Round(r2*10000) / 10000
+	return &LajuPertumbuhan{
+		Laju:       math.Round(laju*100) / 100,
+		Sumber:     PertumbuhanLHRTTahunan,
+		JumlahData: len(x),
+		R2:         &r2,
+	}, nil
+}
+
+// LajuPertumbuhanLHRHarian menghitung laju dari volume hari lengkap beberapa tahun terakhir yang sudah
+// dikalikan faktor hari dan bulan, sehingga pola musiman tidak terbaca sebagai pertumbuhan
+func LajuPertumbuhanLHRHarian(location *Location, sampai time.Time, excludeFlagged bool) (*LajuPertumbuhan, error) {
+	mulai := sampai.AddDate(-tahunRiwayatPertumbuhan, 0, 0)
+	volumes, err := GetVolumeHarian(location, mulai, sampai, excludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+	faktor, err := GetFaktorLHRTBerlaku(location.Tipe_lokasi, sampai)
+	if err != nil {
+		return nil, err
+	}
+
+	harian := EstimasiLHRT(*location, volumes, faktor, mulai, sampai).Harian
+	if len(harian) < minHariRegresiPertumbuhan {
+		return nil, nil
+	}
+	awal, akhir := harian[0].Tanggal, harian[len(harian)-1].Tanggal
+	if akhir.Sub(awal) < minRentangRegresiPertumbuhan*24*time.Hour {
+		return nil, nil
+	}
+
+	x := make([]float64, len(harian))
+	y := make([]float64, len(harian))
+	for i, h := range harian {
+		x[i] = h.Tanggal.Sub(awal).Hours() / 24 / 365.25
+		y[i] = h.LHRT
+	}
+	laju, r2, ok := regresiPertumbuhan(x, y)
+	if !ok {
+		return nil, nil
+	}
+
+	r2 = math.Round(r2*10000) / 10000
+	return &LajuPertumbuhan{
+		Laju:       math.Round(laju*100) / 100,
+		Sumber:     PertumbuhanLHRHarian,
+		JumlahData: len(harian),
+		DataMulai:  &awal,
+		DataAkhir:  &akhir,
+		R2:         &r2,
+	}, nil
+}
+
+// tentukanLajuPertumbuhan memakai laju request, lalu regresi LHRT tahunan, regresi volume harian,
+// dan terakhir PROJECTION_GROWTH_RATE
+func tentukanLajuPertumbuhan(location *Location, lajuParameter *float64, tahunDasar int, sampai time.Time, excludeFlagged bool) (*LajuPertumbuhan, error) {
+	if lajuParameter != nil {
+		return &LajuPertumbuhan{Laju: *lajuParameter, Sumber: PertumbuhanParameter}, nil
+	}
+
+	laju, err := LajuPertumbuhanLHRTTahunan(location.ID, tahunDasar)
+	if err != nil || laju != nil {
+		return laju, err
+	}
+	laju, err = LajuPertumbuhanLHRHarian(location, sampai, excludeFlagged)
+	if err != nil || laju != nil {
+		return laju, err
+	}
+
+	if !math.IsNaN(defaultGrowthRate) {
+		return &LajuPertumbuhan{Laju: defaultGrowthRate, Sumber: PertumbuhanKonfigurasi}, nil
+	}
+	return nil, ErrLajuPertumbuhanTidakTersedia
+}
+
+// proyeksikanStandar menghitung DS setiap tahun dari arus jam rencana tahun dasar yang tumbuh geometrik
+func proyeksikanStandar(standard CapacityStandard, kapasitas, arusTerukur, penyesuaian float64, lhrtDasar float64, laju float64, tahunDasar, tahunAkhir int) ProyeksiStandar {
+	pertumbuhan := 1 + laju/100
+	result := ProyeksiStandar{
+		Metode:      standard.Method(),
+		Satuan:      standard.UnitEkivalen() + "/jam",
+		Kapasitas:   kapasitas,
+		ArusTerukur: arusTerukur,
+		ArusDasar:   arusTerukur * penyesuaian,
+		Tahunan:     []ProyeksiTahun{},
+		Batas:       []ProyeksiBatas{},
+	}
+	result.DerajatKejenuhan = HitungDerajatKejenuhan(result.ArusDasar, kapasitas)
+
+	for tahun := tahunDasar; tahun <= tahunAkhir; tahun++ {
+		g := math.Pow(pertumbuhan, float64(tahun-tahunDasar))
+		arus := result.ArusDasar * g
+		ds := HitungDerajatKejenuhan(arus, kapasitas)
+		los, _ := standard.TingkatPelayanan(ds)
+		result.Tahunan = append(result.Tahunan, ProyeksiTahun{
+			Tahun:            tahun,
+			LHRT:             math.Round(lhrtDasar * g),
+			Arus:             math.Round(arus*100) / 100,
+			DerajatKejenuhan: math.Round(ds*1000) / 1000,
+			TingkatPelayanan: los,
+		})
+	}
+
+	for i, batas := range standard.BatasTingkatPelayanan() {
+		b := ProyeksiBatas{
+			TingkatPelayanan: string(rune('A' + i + 1)),
+			BatasDS:          batas,
+			SudahTerlampaui:  result.DerajatKejenuhan > batas,
+		}
+		for _, t := range result.Tahunan {
+			if t.DerajatKejenuhan > batas {
+				tahun := t.Tahun
+				b.Tahun = &tahun
+				break
+			}
+		}
+		if !b.SudahTerlampaui && result.DerajatKejenuhan > 0 && pertumbuhan > 1 {
+			perkiraan := float64(tahunDasar) + math.Log(batas/result.DerajatKejenuhan)/math.Log(pertumbuhan)
+			perkiraan = math.Round(perkiraan*10) / 10
+			b.TahunPerkiraan = &perkiraan
+		}
+		result.Batas = append(result.Batas, b)
+	}
+	return result
+}
+
+// HitungProyeksi memproyeksikan volume lokasi ke tahun rencana. Periode dasar menentukan arus jam puncak
+// MKJI/PKJI dan LHR; arus jam puncak dikalikan LHRT/LHR agar pola musiman periode dasar terkoreksi, lalu
+// tumbuh (1 + i)^(tahun - tahun dasar). Kapasitas dari HitungKapasitas/HitungKapasitasPKJI dianggap tetap.
+func HitungProyeksi(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions, lajuParameter *float64, tahunAkhir int) (*TrafficProjection, error) {
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	analysisLocation, dataset, _, err := prepareAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataset.Data) == 0 {
+		return nil, fmt.Errorf("tidak ada data traffic untuk periode dasar")
+	}
+
+	tahunDasar := endTime.Year()
+	if tahunAkhir == 0 {
+		tahunAkhir = tahunDasar + defaultHorizonProyeksi
+	}
+	if tahunAkhir < tahunDasar || tahunAkhir > tahunDasar+maksHorizonProyeksi {
+		return nil, fmt.Errorf("%w: harus antara %d dan %d", ErrTahunAkhirTidakValid, tahunDasar, tahunDasar+maksHorizonProyeksi)
+	}
+
+	mkji, err := BuildMKJIAnalysis(analysisLocation, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+	pkji, err := BuildPKJIAnalysis(analysisLocation, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	lhrt, err := HitungLHRT(location, startTime, endTime, opts.ExcludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+
+	laju, err := tentukanLajuPertumbuhan(location, lajuParameter, tahunDasar, endTime, opts.ExcludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+
+	projection := &TrafficProjection{
+		LokasiID:        location.ID,
+		NamaLokasi:      location.Nama_lokasi,
+		PeriodeMulai:    startTime,
+		PeriodeSelesai:  endTime,
+		TahunDasar:      tahunDasar,
+		TahunAkhir:      tahunAkhir,
+		LHRDasar:        lhrt.LHR,
+		LHRTDasar:       lhrt.LHRT,
+		KepercayaanLHRT: lhrt.Kepercayaan,
+		Pertumbuhan:     *laju,
+		Timestamp:       time.Now().Add(7 * time.Hour),
+	}
+
+	// Penyesuaian musiman periode dasar; tanpa hari lengkap arus terukur dipakai apa adanya
+	penyesuaian := 1.0
+	if lhrt.LHR > 0 && lhrt.LHRT > 0 {
+		penyesuaian = lhrt.LHRT / lhrt.LHR
+	} else {
+		projection.LHRTDasar = mkji.LHR
+		projection.Catatan = append(projection.Catatan, "Periode dasar tidak memiliki hari lengkap, arus jam puncak tidak disesuaikan musiman")
+	}
+	if lhrt.Kepercayaan == KepercayaanRendah {
+		projection.Catatan = append(projection.Catatan, "Kepercayaan LHRT tahun dasar rendah, perpanjang periode dasar atau perbarui faktor LHRT")
+	}
+	// R² volume harian selalu rendah karena variasi antar hari, hanya regresi tahunan yang diperiksa
+	if laju.Sumber == PertumbuhanLHRTTahunan && laju.R2 != nil && *laju.R2 < 0.5 {
+		projection.Catatan = append(projection.Catatan, fmt.Sprintf("Regresi pertumbuhan lemah (R² = %.2f)", *laju.R2))
+	}
+
+	projection.MKJI = proyeksikanStandar(MKJI1997Standard{}, mkji.Kapasitas, mkji.ArusLaluLintas, penyesuaian,
+		projection.LHRTDasar, laju.Laju, tahunDasar, tahunAkhir)
+	projection.PKJI = proyeksikanStandar(PKJI2023Standard{}, pkji.Kapasitas, pkji.VolumeLaluLintas, penyesuaian,
+		projection.LHRTDasar, laju.Laju, tahunDasar, tahunAkhir)
+
+	return projection, nil
+}
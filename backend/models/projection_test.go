@@ -0,0 +1,157 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegresiPertumbuhan(t *testing.T) {
+	tahun := []float64{2020, 2021, 2022, 2023, 2024}
+	tumbuh := func(laju float64) []float64 {
+		y := make([]float64, len(tahun))
+		for i, x := range tahun {
+			y[i] = 10000 * math.Pow(1+laju/100, x-2020)
+		}
+		return y
+	}
+
+	tests := []struct {
+		nama string
+		x, y []float64
+		laju float64
+		r2   float64
+		ok   bool
+	}{
+		{"tumbuh 4%", tahun, tumbuh(4), 4, 1, true},
+		{"turun 3%", tahun, tumbuh(-3), -3, 1, true},
+		{"tetap", tahun, tumbuh(0), 0, 0, true},
+		// Nilai ≤ 0 dilewati, sisa titik tetap 4%
+		{"LHRT nol dilewati", tahun, func() []float64 { y := tumbuh(4); y[2] = 0; y[4] = -5; return y }(), 4, 1, true},
+		// ln(y) = ln(10000) + b·x + e dengan e tegak lurus x: b tetap ln(1.05), R² = 1 − Σe² / (10b² + Σe²)
+		{"dengan variasi", tahun, func() []float64 {
+			y := tumbuh(5)
+			for i, e := range []float64{0.02, -0.02, 0, -0.02, 0.02} {
+				y[i] *= math.Exp(e)
+			}
+			return y
+		}(), 5, 1 - 0.0016/(10*math.Log(1.05)*math.Log(1.05)+0.0016), true},
+		{"satu titik", tahun[:1], tumbuh(4)[:1], 0, 0, false},
+		{"tahun sama", []float64{2024, 2024}, []float64{10000, 11000}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		laju, r2, ok := regresiPertumbuhan(tt.x, tt.y)
+		if ok != tt.ok || math.Abs(laju-tt.laju) > 1e-9 || math.Abs(r2-tt.r2) > 1e-9 {
+			t.Errorf("%s: laju %.6f R² %.6f ok %v, ingin %.6f, %.6f, %v", tt.nama, laju, r2, ok, tt.laju, tt.r2, tt.ok)
+		}
+	}
+
+	// Sumbu x dalam pecahan tahun (regresi volume harian) memberi laju yang sama
+	x := []float64{0, 0.25, 0.5, 0.75, 1, 1.5, 2}
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = 8000 * math.Pow(1.06, x[i])
+	}
+	if laju, _, _ := regresiPertumbuhan(x, y); math.Abs(laju-6) > 1e-9 {
+		t.Errorf("pecahan tahun: laju %.6f, ingin 6", laju)
+	}
+}
+
+// Arus terukur 400 × penyesuaian musiman 1.1 = 440 dengan kapasitas 1000 (DS 0.44) tumbuh 5% per tahun:
+// tahun perkiraan = 2025 + ln(batas / 0.44) / ln(1.05)
+func TestProyeksikanStandar(t *testing.T) {
+	result := proyeksikanStandar(MKJI1997Standard{}, 1000, 400, 1.1, 12000, 5, 2025, 2040)
+
+	if result.Metode != AnalysisMethodMKJI1997 || result.Satuan != "smp/jam" || result.ArusTerukur != 400 ||
+		math.Abs(result.ArusDasar-440) > 1e-9 || math.Abs(result.DerajatKejenuhan-0.44) > 1e-9 {
+		t.Fatalf("metode %s satuan %s arus %.2f → %.2f DS %.3f", result.Metode, result.Satuan,
+			result.ArusTerukur, result.ArusDasar, result.DerajatKejenuhan)
+	}
+	if len(result.Tahunan) != 16 {
+		t.Fatalf("%d tahun, ingin 16", len(result.Tahunan))
+	}
+	tahunan := []struct {
+		tahun int
+		lhrt  float64
+		arus  float64
+		ds    float64
+		los   string
+	}{
+		{2025, 12000, 440, 0.44, "B"},
+		{2029, 14586, 534.82, 0.535, "B"},
+		{2030, 15315, 561.56, 0.562, "C"},
+		{2036, 20524, 752.55, 0.753, "D"},
+		{2040, 24947, 914.73, 0.915, "E"},
+	}
+	for _, tt := range tahunan {
+		p := result.Tahunan[tt.tahun-2025]
+		if p.Tahun != tt.tahun || p.LHRT != tt.lhrt || p.Arus != tt.arus || p.DerajatKejenuhan != tt.ds || p.TingkatPelayanan != tt.los {
+			t.Errorf("tahun %d: %+v, ingin LHRT %.0f arus %.2f DS %.3f LoS %s", tt.tahun, p, tt.lhrt, tt.arus, tt.ds, tt.los)
+		}
+	}
+
+	batas := []struct {
+		los       string
+		ds        float64
+		tahun     int // 0 = tidak tercapai dalam horizon
+		perkiraan float64
+		sudah     bool
+	}{
+		{"B", 0.35, 2025, 0, true},
+		{"C", 0.55, 2030, 2029.6, false},
+		{"D", 0.75, 2036, 2035.9, false},
+		{"E", 0.85, 2039, 2038.5, false},
+		{"F", 1.00, 0, 2041.8, false},
+	}
+	if len(result.Batas) != len(batas) {
+		t.Fatalf("%d batas, ingin %d", len(result.Batas), len(batas))
+	}
+	for i, tt := range batas {
+		b := result.Batas[i]
+		if b.TingkatPelayanan != tt.los || b.BatasDS != tt.ds || b.SudahTerlampaui != tt.sudah {
+			t.Errorf("batas %d: %+v, ingin %s DS %.2f sudah %v", i, b, tt.los, tt.ds, tt.sudah)
+		}
+		if (tt.tahun == 0) != (b.Tahun == nil) || (b.Tahun != nil && *b.Tahun != tt.tahun) {
+			t.Errorf("batas %s: tahun %v, ingin %d", tt.los, b.Tahun, tt.tahun)
+		}
+		if tt.sudah {
+			if b.TahunPerkiraan != nil {
+				t.Errorf("batas %s sudah terlampaui tetapi ada tahun perkiraan %.1f", tt.los, *b.TahunPerkiraan)
+			}
+			continue
+		}
+		if b.TahunPerkiraan == nil || *b.TahunPerkiraan != tt.perkiraan {
+			t.Errorf("batas %s: tahun perkiraan %v, ingin %.1f", tt.los, b.TahunPerkiraan, tt.perkiraan)
+			continue
+		}
+		// Tahun pertama terlampaui adalah tahun bulat pertama setelah perkiraan analitik
+		if b.Tahun != nil && (float64(*b.Tahun) < *b.TahunPerkiraan || float64(*b.Tahun)-1 > *b.TahunPerkiraan) {
+			t.Errorf("batas %s: tahun %d tidak sesuai perkiraan %.1f", tt.los, *b.Tahun, *b.TahunPerkiraan)
+		}
+	}
+}
+
+func TestProyeksikanStandarTanpaPertumbuhan(t *testing.T) {
+	for _, laju := range []float64{0, -2} {
+		result := proyeksikanStandar(PKJI2023Standard{}, 2000, 1000, 1, 15000, laju, 2025, 2030)
+		if result.Satuan != "skr/jam" || result.DerajatKejenuhan != 0.5 {
+			t.Fatalf("laju %.0f%%: satuan %s DS %.3f", laju, result.Satuan, result.DerajatKejenuhan)
+		}
+		for _, b := range result.Batas {
+			// DS tidak naik sehingga batas di atas DS tahun dasar tidak pernah tercapai
+			if b.TahunPerkiraan != nil || (b.BatasDS > 0.5 && b.Tahun != nil) || b.SudahTerlampaui != (b.BatasDS < 0.5) {
+				t.Errorf("laju %.0f%% batas %s: %+v", laju, b.TingkatPelayanan, b)
+			}
+		}
+		if last := result.Tahunan[len(result.Tahunan)-1]; laju < 0 && last.DerajatKejenuhan >= 0.5 {
+			t.Errorf("laju %.0f%%: DS 2030 %.3f, ingin turun", laju, last.DerajatKejenuhan)
+		}
+	}
+
+	// Kapasitas 0 tidak menghasilkan tahun perkiraan
+	result := proyeksikanStandar(MKJI1997Standard{}, 0, 500, 1, 10000, 5, 2025, 2030)
+	for _, b := range result.Batas {
+		if b.Tahun != nil || b.TahunPerkiraan != nil {
+			t.Errorf("kapasitas 0 batas %s: %+v", b.TingkatPelayanan, b)
+		}
+	}
+}
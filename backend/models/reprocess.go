@@ -0,0 +1,436 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Status job proses ulang
+const (
+	ReprocessStatusPending   = "pending"
+	ReprocessStatusRunning   = "running"
+	ReprocessStatusPaused    = "paused"
+	ReprocessStatusCompleted = "completed"
+	ReprocessStatusFailed    = "failed"
+	ReprocessStatusCancelled = "cancelled"
+)
+
+// Mode job proses ulang
+const (
+	ReprocessModeRebuild     = "rebuild"     // bangun ulang semua raw data dalam rentang waktu
+	ReprocessModeUnprocessed = "unprocessed" // hanya raw data yang belum pernah diproses
+)
+
+const DefaultReprocessBatchSize = 200
+
+type ReprocessJob struct {
+	ID             string     `bson:"_id" json:"id"`
+	LokasiID       string     `bson:"lokasi_id" json:"lokasi_id"`
+	StartTime      time.Time  `bson:"start_time" json:"start_time"`
+	EndTime        time.Time  `bson:"end_time" json:"end_time"`
+	Mode           string     `bson:"mode" json:"mode"`
+	BatchSize      int        `bson:"batch_size" json:"batch_size"`
+	Status         string     `bson:"status" json:"status"`
+	TotalRaw       int64      `bson:"total_raw" json:"total_raw"`
+	ProcessedCount int64      `bson:"processed_count" json:"processed_count"`
+	CreatedCount   int64      `bson:"created_count" json:"created_count"`   // traffic_data baru (raw belum pernah diproses)
+	ReplacedCount  int64      `bson:"replaced_count" json:"replaced_count"` // traffic_data lama diganti versi baru
+	ErrorCount     int64      `bson:"error_count" json:"error_count"`
+	LastError      string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CursorTime     *time.Time `bson:"cursor_time,omitempty" json:"cursor_time,omitempty"` // Posisi terakhir untuk melanjutkan job
+	CursorID       string     `bson:"cursor_id,omitempty" json:"cursor_id,omitempty"`
+	Keterangan     string     `bson:"keterangan,omitempty" json:"keterangan,omitempty"`
+	CreatedBy      string     `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
+	StartedAt      *time.Time `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updated_at"`
+	FinishedAt     *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	ProgressPersen float64    `bson:"-" json:"progress_persen"`
+}
+
+// Versi traffic_data yang sudah digantikan hasil proses ulang
+type TrafficDataVersion struct {
+	TrafficData  `bson:",inline"`
+	SupersededBy string    `bson:"superseded_by" json:"superseded_by"`
+	SupersededAt time.Time `bson:"superseded_at" json:"superseded_at"`
+	ReprocessJob string    `bson:"reprocess_job,omitempty" json:"reprocess_job,omitempty"`
+}
+
+func (j *ReprocessJob) UpdateProgress() {
+	if j.TotalRaw > 0 {
+		j.ProgressPersen = float64(j.ProcessedCount) / float64(j.TotalRaw) * 100
+	} else if j.Status == ReprocessStatusCompleted {
+		j.ProgressPersen = 100
+	}
+}
+
+func NextReprocessJobID() (string, error) {
+	collection := database.DB.Collection("reprocess_jobs")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last ReprocessJob
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "RPJ-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "RPJ-%d", &lastNum)
+	return fmt.Sprintf("RPJ-%05d", lastNum+1), nil
+}
+
+// reprocessRawFilter membentuk filter raw data untuk job, dimulai setelah cursor terakhir
+func reprocessRawFilter(job *ReprocessJob) bson.M {
+	filter := bson.M{
+		"lokasi_id": job.LokasiID,
+		"timestamp": bson.M{"$gte": job.StartTime, "$lte": job.EndTime},
+	}
+	if job.Mode == ReprocessModeUnprocessed {
+		filter["is_processed"] = false
+	}
+	if job.CursorTime != nil {
+		filter["$or"] = []bson.M{
+			{"timestamp": bson.M{"$gt": *job.CursorTime}},
+			{"timestamp": *job.CursorTime, "_id": bson.M{"$gt": job.CursorID}},
+		}
+	}
+	return filter
+}
+
+func CreateReprocessJob(lokasiID string, startTime, endTime time.Time, mode string, batchSize int, keterangan string, createdBy string) (*ReprocessJob, error) {
+	if _, err := GetLocationByID(lokasiID); err != nil {
+		return nil, fmt.Errorf("lokasi tidak ditemukan")
+	}
+	if mode == "" {
+		mode = ReprocessModeRebuild
+	}
+	if mode != ReprocessModeRebuild && mode != ReprocessModeUnprocessed {
+		return nil, fmt.Errorf("mode tidak valid (rebuild atau unprocessed)")
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultReprocessBatchSize
+	}
+
+	id, err := NextReprocessJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Add(7 * time.Hour)
+	job := &ReprocessJob{
+		ID:         id,
+		LokasiID:   lokasiID,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Mode:       mode,
+		BatchSize:  batchSize,
+		Status:     ReprocessStatusPending,
+		Keterangan: keterangan,
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	total, err := GetRawDataCollection().CountDocuments(context.Background(), reprocessRawFilter(job))
+	if err != nil {
+		return nil, err
+	}
+	job.TotalRaw = total
+
+	_, err = database.DB.Collection("reprocess_jobs").InsertOne(context.Background(), job)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func GetReprocessJobByID(id string) (*ReprocessJob, error) {
+	collection := database.DB.Collection("reprocess_jobs")
+
+	var job ReprocessJob
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	job.UpdateProgress()
+
+	return &job, nil
+}
+
+func GetReprocessJobs(filter bson.M, limit int64) ([]ReprocessJob, error) {
+	collection := database.DB.Collection("reprocess_jobs")
+
+	cursor, err := collection.Find(
+		context.Background(),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []ReprocessJob
+	if err = cursor.All(context.Background(), &jobs); err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		jobs[i].UpdateProgress()
+	}
+
+	return jobs, nil
+}
+
+func SetReprocessJobStatus(id string, status string) error {
+	set := bson.M{
+		"status":     status,
+		"updated_at": time.Now().Add(7 * time.Hour),
+	}
+	_, err := database.DB.Collection("reprocess_jobs").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// SaveReprocessJobProgress menyimpan counter dan cursor setelah satu batch selesai.
+// Status hanya ditulis untuk status akhir agar pause/cancel dari API tidak tertimpa.
+func SaveReprocessJobProgress(job *ReprocessJob) error {
+	job.UpdatedAt = time.Now().Add(7 * time.Hour)
+	set := bson.M{
+		"processed_count": job.ProcessedCount,
+		"created_count":   job.CreatedCount,
+		"replaced_count":  job.ReplacedCount,
+		"error_count":     job.ErrorCount,
+		"last_error":      job.LastError,
+		"cursor_time":     job.CursorTime,
+		"cursor_id":       job.CursorID,
+		"started_at":      job.StartedAt,
+		"finished_at":     job.FinishedAt,
+		"updated_at":      job.UpdatedAt,
+	}
+	if job.Status == ReprocessStatusCompleted || job.Status == ReprocessStatusFailed {
+		set["status"] = job.Status
+	}
+	_, err := database.DB.Collection("reprocess_jobs").UpdateOne(context.Background(), bson.M{"_id": job.ID}, bson.M{"$set": set})
+	return err
+}
+
+// GetNextReprocessBatch mengambil batch raw data berikutnya setelah cursor job
+func GetNextReprocessBatch(job *ReprocessJob) ([]TrafficRawData, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(job.BatchSize))
+
+	cursor, err := GetRawDataCollection().Find(context.Background(), reprocessRawFilter(job), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawDataList []TrafficRawData
+	if err = cursor.All(context.Background(), &rawDataList); err != nil {
+		return nil, err
+	}
+
+	return rawDataList, nil
+}
+
+// BuildTrafficDataFromRaw membangun TrafficData beserta analisis MKJI/PKJI dari satu raw data
+// memakai konfigurasi lokasi dan master klasifikasi saat ini.
+func BuildTrafficDataFromRaw(raw *TrafficRawData, location *Location, klasifikasiMap map[int]KlasifikasiKendaraan) (*TrafficData, error) {
+	var zonaArahData []TrafficZonaArahData
+	totalKendaraan := 0
+
+	for _, zona := range raw.ZonaData {
+		kelasData := []TrafficKelasDetail{}
+		zonaTotal := 0
+		for _, kd := range zona.KelasData {
+			idKlasifikasi, namaKelas := resolveKelasInfo(klasifikasiMap, location.Tipe_lokasi, kd.Kelas)
+			kelasData = append(kelasData, TrafficKelasDetail{
+				IDKlasifikasi:     idKlasifikasi,
+				NamaKelas:         namaKelas,
+				Kelas:             kd.Kelas,
+				JumlahKendaraan:   kd.JumlahKendaraan,
+				KecepatanRataRata: kd.Kecepatan,
+			})
+			zonaTotal += kd.JumlahKendaraan
+		}
+
+		zonaArahData = append(zonaArahData, TrafficZonaArahData{
+			IDZonaArah:     zona.IDZonaArah,
+			NamaArah:       zona.NamaArah,
+			KelasData:      kelasData,
+			TotalKendaraan: zonaTotal,
+		})
+		totalKendaraan += zonaTotal
+	}
+
+	intervalMenit := raw.IntervalDetik / 60
+	if intervalMenit <= 0 {
+		intervalMenit = 5
+	}
+
+	trafficData := &TrafficData{
+		LokasiID:       raw.LokasiID,
+		CameraID:       raw.CameraID,
+		NamaLokasi:     location.Nama_lokasi,
+		TipeLokasi:     location.Tipe_lokasi,
+		Timestamp:      raw.Timestamp,
+		IntervalEnd:    raw.Timestamp.Add(time.Duration(intervalMenit) * time.Minute),
+		ZonaArahData:   zonaArahData,
+		TotalKendaraan: totalKendaraan,
+		IntervalMenit:  intervalMenit,
+		RawDataID:      raw.ID,
+		IngestKey:      raw.IngestKey,
+		// Versi yang akan digantikan tidak boleh ikut dihitung sebagai arus lokasi pada interval ini
+		PreviousVersionID: raw.ProcessedID,
+		// Zona raw data sudah dipetakan ke kamera saat disimpan, jadi pemeriksaan zona hilang/berlebih dilewati
+		DataQuality: EvaluateIntervalQuality(rawZonesToCameraZones(raw.ZonaData), nil),
+	}
+
+	mkjiAnalysis, err := CalculateRealTimeMKJI(trafficData, raw.LokasiID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate MKJI: %v", err)
+	}
+	trafficData.MKJIAnalysis = mkjiAnalysis
+
+	pkjiAnalysis, err := CalculateRealTimePKJI(trafficData, raw.LokasiID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate PKJI: %v", err)
+	}
+	trafficData.PKJIAnalysis = pkjiAnalysis
+
+	return trafficData, nil
+}
+
+// findReprocessPrevious mencari traffic_data yang sedang mewakili raw data: dari processed_id, atau dari
+// raw_data_id jika penandaan raw sebelumnya gagal atau proses ulang terhenti di tengah penggantian.
+func findReprocessPrevious(raw *TrafficRawData) (*TrafficData, error) {
+	if raw.ProcessedID != "" {
+		previous, err := GetTrafficDataByID(raw.ProcessedID)
+		if err == nil {
+			return previous, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+	}
+
+	var previous TrafficData
+	err := database.DB.Collection("traffic_data").FindOne(context.Background(), bson.M{"raw_data_id": raw.ID},
+		options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})).Decode(&previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// ReprocessRawData membangun ulang traffic_data untuk satu raw data.
+// Jika raw sudah punya traffic_data, versi lama dipindah ke traffic_data_versions dan
+// versi baru menyimpan previous_version_id. Mengembalikan true jika menggantikan versi lama.
+//
+// Tanpa transaksi (MongoDB standalone), versi baru disimpan lebih dulu lalu ditukar: jika proses
+// terhenti di tengah, traffic_data tetap berisi versi lama atau versi baru, tidak pernah kosong.
+func ReprocessRawData(raw *TrafficRawData, location *Location, klasifikasiMap map[int]KlasifikasiKendaraan, jobID string) (bool, error) {
+	ctx := context.Background()
+	trafficCollection := database.DB.Collection("traffic_data")
+	versionCollection := database.DB.Collection("traffic_data_versions")
+
+	previous, err := findReprocessPrevious(raw)
+	if err != nil {
+		return false, err
+	}
+
+	trafficData, err := BuildTrafficDataFromRaw(raw, location, klasifikasiMap)
+	if err != nil {
+		return false, err
+	}
+
+	id, err := NextTrafficDataID()
+	if err != nil {
+		return false, err
+	}
+	trafficData.ID = id
+	trafficData.PreviousVersionID = ""
+	ingestKey := trafficData.IngestKey
+	now := time.Now().Add(7 * time.Hour)
+
+	if previous != nil {
+		trafficData.Version = previous.Version + 1
+		if previous.Version == 0 {
+			trafficData.Version = 2
+		}
+		trafficData.PreviousVersionID = previous.ID
+		trafficData.TimestampSource = previous.TimestampSource
+		trafficData.ReprocessedAt = &now
+
+		version := TrafficDataVersion{
+			TrafficData:  *previous,
+			SupersededBy: trafficData.ID,
+			SupersededAt: now,
+			ReprocessJob: jobID,
+		}
+		// Upsert agar percobaan ulang setelah proses terhenti tidak gagal karena _id duplikat
+		if _, err := versionCollection.ReplaceOne(ctx, bson.M{"_id": previous.ID}, version, options.Replace().SetUpsert(true)); err != nil {
+			return false, fmt.Errorf("gagal menyimpan versi lama %s: %v", previous.ID, err)
+		}
+		// Sisa versi baru dari percobaan yang terhenti sebelum versi lama dihapus
+		if _, err := trafficCollection.DeleteMany(ctx, bson.M{"raw_data_id": raw.ID, "_id": bson.M{"$ne": previous.ID}}); err != nil {
+			return false, err
+		}
+		// ingest_key unik di traffic_data: versi baru disimpan tanpa kunci, kunci dipindah setelah versi lama dihapus
+		trafficData.IngestKey = ""
+	}
+
+	if _, err := trafficCollection.InsertOne(ctx, trafficData); err != nil {
+		if previous != nil {
+			versionCollection.DeleteOne(ctx, bson.M{"_id": previous.ID})
+		}
+		return false, err
+	}
+
+	if previous != nil {
+		if _, err := trafficCollection.DeleteOne(ctx, bson.M{"_id": previous.ID}); err != nil {
+			if _, rollbackErr := trafficCollection.DeleteOne(ctx, bson.M{"_id": trafficData.ID}); rollbackErr != nil {
+				log.Printf("Gagal menghapus versi baru %s setelah penggantian gagal: %v", trafficData.ID, rollbackErr)
+			} else {
+				versionCollection.DeleteOne(ctx, bson.M{"_id": previous.ID})
+			}
+			return false, err
+		}
+		if ingestKey != "" {
+			if _, err := trafficCollection.UpdateOne(ctx, bson.M{"_id": trafficData.ID}, bson.M{"$set": bson.M{"ingest_key": ingestKey}}); err != nil {
+				return true, fmt.Errorf("gagal memindahkan ingest_key ke %s: %v", trafficData.ID, err)
+			}
+			trafficData.IngestKey = ingestKey
+		}
+	}
+
+	if err := MarkRawDataAsProcessed(raw.ID, trafficData.ID); err != nil {
+		return previous != nil, err
+	}
+
+	return previous != nil, nil
+}
+
+func GetTrafficDataVersionByID(id string) (*TrafficDataVersion, error) {
+	collection := database.DB.Collection("traffic_data_versions")
+
+	var version TrafficDataVersion
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
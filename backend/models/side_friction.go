@@ -0,0 +1,391 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Sumber kelas hambatan samping yang dipakai analisis
+const (
+	HambatanSampingKonfigurasi = "konfigurasi" // Kelas_hambatan lokasi
+	HambatanSampingObservasi   = "observasi"   // kelas hasil estimasi data periode analisis
+)
+
+var HambatanSampingOptions = []string{HambatanSampingKonfigurasi, HambatanSampingObservasi}
+
+var defaultHambatanSampingSource = HambatanSampingKonfigurasi
+
+func IsValidHambatanSamping(value string) bool {
+	for _, v := range HambatanSampingOptions {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDefaultHambatanSampingSource mengatur sumber kelas hambatan samping untuk request tanpa parameter hambatan_samping
+func SetDefaultHambatanSampingSource(source string) {
+	if IsValidHambatanSamping(source) {
+		defaultHambatanSampingSource = source
+	}
+}
+
+const (
+	// Bobot kendaraan lambat/tidak bermotor pada frekuensi kejadian hambatan samping (PKJI 2023)
+	bobotKendaraanLambat = 0.4
+	// Durasi data minimum (jam) agar satu jam dalam sehari diestimasi
+	minDurasiJamHambatan = 0.5
+)
+
+// Batas bawah frekuensi kejadian berbobot per jam per 200 m untuk setiap kelas hambatan samping
+var batasKejadianHambatan = []struct {
+	Kelas string
+	Min   float64
+}{
+	{"VH", 900},
+	{"H", 500},
+	{"M", 300},
+	{"L", 100},
+	{"VL", 0},
+}
+
+// Estimasi kelas hambatan samping satu jam dalam sehari (gabungan semua hari pada periode)
+type SideFrictionPeriode struct {
+	Jam              int     `bson:"jam" json:"jam"` // jam mulai (waktu lokal), 0-23
+	Periode          string  `bson:"periode" json:"periode"`
+	DurasiJam        float64 `bson:"durasi_jam" json:"durasi_jam"` // total durasi data tersedia pada jam ini
+	ArusKendaraan    float64 `bson:"arus_kendaraan" json:"arus_kendaraan"`
+	KTBPerJam        float64 `bson:"ktb_per_jam" json:"ktb_per_jam"`
+	KejadianBerbobot float64 `bson:"kejadian_berbobot" json:"kejadian_berbobot"` // 0.4 × KTB per jam
+	KelasKejadian    string  `bson:"kelas_kejadian" json:"kelas_kejadian"`
+	// Rasio kecepatan KR terukur saat arus bebas terhadap VB tanpa hambatan samping (≈ FVBHS)
+	RasioKecepatan  float64 `bson:"rasio_kecepatan,omitempty" json:"rasio_kecepatan,omitempty"`
+	SampelKecepatan int     `bson:"sampel_kecepatan" json:"sampel_kecepatan"`
+	KelasKecepatan  string  `bson:"kelas_kecepatan,omitempty" json:"kelas_kecepatan,omitempty"`
+	Kelas           string  `bson:"kelas" json:"kelas"` // kelas tertinggi dari kejadian dan kecepatan
+}
+
+// Estimasi kelas hambatan samping lokasi dari data terukur
+type SideFrictionEstimate struct {
+	ID               string                `bson:"_id,omitempty" json:"id,omitempty"`
+	LokasiID         string                `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi       string                `bson:"nama_lokasi" json:"nama_lokasi"`
+	StartTime        time.Time             `bson:"start_time" json:"start_time"`
+	EndTime          time.Time             `bson:"end_time" json:"end_time"`
+	KelasKonfigurasi string                `bson:"kelas_konfigurasi" json:"kelas_konfigurasi"`
+	KelasObservasi   string                `bson:"kelas_observasi,omitempty" json:"kelas_observasi,omitempty"` // kelas pada jam puncak
+	JamPuncak        string                `bson:"jam_puncak,omitempty" json:"jam_puncak,omitempty"`
+	Selisih          int                   `bson:"selisih" json:"selisih"` // tingkat observasi - konfigurasi (positif = lebih tinggi)
+	TidakSesuai      bool                  `bson:"tidak_sesuai" json:"tidak_sesuai"`
+	KelasDipakai     string                `bson:"kelas_dipakai,omitempty" json:"kelas_dipakai,omitempty"` // kelas yang dipakai analisis (opsi observasi)
+	PerJam           []SideFrictionPeriode `bson:"per_jam" json:"per_jam"`
+	Catatan          []string              `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Timestamp        time.Time             `bson:"timestamp" json:"timestamp"`
+}
+
+// tingkatHambatan mengembalikan urutan kelas hambatan (VL = 0 ... VH = 4), -1 jika tidak dikenal
+func tingkatHambatan(kelas string) int {
+	for i, k := range KelasHambatanOptions {
+		if k == kelas {
+			return i
+		}
+	}
+	return -1
+}
+
+// KelasHambatanDariKejadian mengklasifikasikan frekuensi kejadian berbobot per jam per 200 m
+func KelasHambatanDariKejadian(kejadian float64) string {
+	for _, batas := range batasKejadianHambatan {
+		if kejadian >= batas.Min {
+			return batas.Kelas
+		}
+	}
+	return "VL"
+}
+
+// kelasHambatanDariKecepatan memilih kelas yang FVBHS-nya paling dekat dengan rasio kecepatan terukur
+func kelasHambatanDariKecepatan(location Location, rasio float64) string {
+	best, bestSelisih := "", -1.0
+	for _, kelas := range KelasHambatanOptions {
+		fvbhs := GetFVBHS(location.Tipe_hambatan, kelas, location.Tipe_arah, location.Tipe_lokasi)
+		selisih := fvbhs - rasio
+		if selisih < 0 {
+			selisih = -selisih
+		}
+		if bestSelisih < 0 || selisih < bestSelisih {
+			best, bestSelisih = kelas, selisih
+		}
+	}
+	return best
+}
+
+// EstimasiHambatanSamping memperkirakan kelas hambatan samping per jam dalam sehari dari dua indikator:
+//   - frekuensi kendaraan tidak bermotor (KTB/UM) berbobot 0.4 sesuai pembobotan PKJI 2023
+//   - penurunan kecepatan KR saat arus bebas terhadap VB tanpa hambatan samping, dicocokkan ke tabel FVBHS
+//
+// KTB hanya mewakili satu jenis kejadian (pejalan kaki, parkir, dan kendaraan keluar-masuk tidak terukur kamera),
+// sehingga kelas setiap jam adalah kelas tertinggi dari kedua indikator. Kelas observasi lokasi adalah kelas
+// pada jam puncak, karena kapasitas dievaluasi pada jam puncak.
+func EstimasiHambatanSamping(standard CapacityStandard, location Location, trafficDataList []TrafficData, startTime, endTime time.Time) *SideFrictionEstimate {
+	estimate := &SideFrictionEstimate{
+		LokasiID:         location.ID,
+		NamaLokasi:       location.Nama_lokasi,
+		StartTime:        startTime,
+		EndTime:          endTime,
+		KelasKonfigurasi: location.Kelas_hambatan,
+		PerJam:           []SideFrictionPeriode{},
+		Timestamp:        time.Now().Add(7 * time.Hour),
+	}
+
+	if location.Tipe_lokasi == "bebas_hambatan" {
+		estimate.Catatan = append(estimate.Catatan, "Hambatan samping tidak berlaku untuk jalan bebas hambatan")
+		return estimate
+	}
+	if location.IsSimpang() {
+		estimate.Catatan = append(estimate.Catatan, "Estimasi hambatan samping berbasis kecepatan ruas tidak berlaku untuk simpang")
+		return estimate
+	}
+	if len(trafficDataList) == 0 {
+		estimate.Catatan = append(estimate.Catatan, "Tidak ada data traffic untuk periode yang diminta")
+		return estimate
+	}
+
+	peak := HitungPeakHour(standard, trafficDataList, location.Tipe_lokasi)
+	interval := time.Duration(peak.IntervalMenit) * time.Minute
+	skalaJam := time.Hour.Hours() / interval.Hours()
+	kapasitas := standard.Kapasitas(location).Kapasitas
+
+	// VB tanpa hambatan samping; rasio kecepatan terukur terhadap nilai ini sebanding dengan FVBHS
+	vbd := GetVBD(location.Tipe_arah, location.Tipe_lokasi)
+	vbl := GetVBL(location.Lebar_jalur, location.Tipe_arah)
+	vbTanpaHambatan := (vbd + vbl) * GetFVBUK(location.Ukuran_kota, location.Tipe_lokasi)
+
+	// Akumulasi per slot interval agar beberapa kamera pada interval yang sama digabung
+	type slotData struct {
+		arus     float64
+		ekivalen float64
+		ktb      int
+		speed    speedSample
+	}
+	slots := make(map[time.Time]*slotData)
+	adaKTB := false
+	for _, td := range trafficDataList {
+		slot := td.Timestamp.UTC().Truncate(interval)
+		if slots[slot] == nil {
+			slots[slot] = &slotData{}
+		}
+		s := slots[slot]
+		s.ekivalen += trafficDataEkivalen(standard, td, location.Tipe_lokasi)
+		for _, za := range td.ZonaArahData {
+			for _, kd := range za.KelasData {
+				kategori := kategoriKelas(standard, td, location.Tipe_lokasi, kd.Kelas)
+				if standard.IsMotorized(kategori) {
+					s.arus += float64(kd.JumlahKendaraan)
+				} else {
+					s.ktb += kd.JumlahKendaraan
+					adaKTB = true
+				}
+				if kategori == string(KategoriKR) && kd.JumlahKendaraan > 0 && kd.KecepatanRataRata > 0 {
+					s.speed.add(kd.KecepatanRataRata, kd.JumlahKendaraan)
+				}
+			}
+		}
+	}
+	if !adaKTB {
+		estimate.Catatan = append(estimate.Catatan, "Klasifikasi lokasi tidak memiliki kelas kendaraan tidak bermotor; estimasi hanya dari kecepatan")
+	}
+
+	type jamData struct {
+		durasi float64
+		arus   float64
+		ktb    int
+		speed  speedSample
+	}
+	perJam := make(map[int]*jamData)
+	for slot, s := range slots {
+		jam := slot.Hour()
+		if perJam[jam] == nil {
+			perJam[jam] = &jamData{}
+		}
+		j := perJam[jam]
+		j.durasi += interval.Hours()
+		j.arus += s.arus
+		j.ktb += s.ktb
+		if kapasitas > 0 && s.ekivalen*skalaJam/kapasitas <= djArusBebas && s.speed.count > 0 {
+			j.speed.add(s.speed.mean(), s.speed.count)
+		}
+	}
+
+	jamList := make([]int, 0, len(perJam))
+	for jam := range perJam {
+		jamList = append(jamList, jam)
+	}
+	sort.Ints(jamList)
+
+	for _, jam := range jamList {
+		j := perJam[jam]
+		if j.durasi < minDurasiJamHambatan {
+			continue
+		}
+		periode := SideFrictionPeriode{
+			Jam:             jam,
+			Periode:         fmt.Sprintf("%02d:00-%02d:00", jam, (jam+1)%24),
+			DurasiJam:       j.durasi,
+			ArusKendaraan:   j.arus / j.durasi,
+			KTBPerJam:       float64(j.ktb) / j.durasi,
+			SampelKecepatan: j.speed.count,
+		}
+		periode.KejadianBerbobot = bobotKendaraanLambat * periode.KTBPerJam
+		periode.KelasKejadian = KelasHambatanDariKejadian(periode.KejadianBerbobot)
+		periode.Kelas = periode.KelasKejadian
+
+		if j.speed.count >= minSampelKecepatan && vbTanpaHambatan > 0 {
+			periode.RasioKecepatan = j.speed.mean() / vbTanpaHambatan
+			periode.KelasKecepatan = kelasHambatanDariKecepatan(location, periode.RasioKecepatan)
+			if tingkatHambatan(periode.KelasKecepatan) > tingkatHambatan(periode.Kelas) {
+				periode.Kelas = periode.KelasKecepatan
+			}
+		}
+		estimate.PerJam = append(estimate.PerJam, periode)
+	}
+
+	// Kelas observasi = kelas tertinggi pada jam-jam yang beririsan dengan jam puncak
+	estimate.JamPuncak = peak.Puncak.JamPuncak
+	for _, periode := range estimate.PerJam {
+		mulai := time.Date(peak.Puncak.Mulai.Year(), peak.Puncak.Mulai.Month(), peak.Puncak.Mulai.Day(), periode.Jam, 0, 0, 0, peak.Puncak.Mulai.Location())
+		if !mulai.Before(peak.Puncak.Selesai) || !mulai.Add(time.Hour).After(peak.Puncak.Mulai) {
+			continue
+		}
+		if tingkatHambatan(periode.Kelas) > tingkatHambatan(estimate.KelasObservasi) {
+			estimate.KelasObservasi = periode.Kelas
+		}
+	}
+
+	if estimate.KelasObservasi == "" {
+		estimate.Catatan = append(estimate.Catatan, fmt.Sprintf("Data jam puncak kurang dari %.0f menit, kelas hambatan samping tidak dapat diestimasi", minDurasiJamHambatan*60))
+		return estimate
+	}
+
+	if tingkatHambatan(location.Kelas_hambatan) >= 0 {
+		estimate.Selisih = tingkatHambatan(estimate.KelasObservasi) - tingkatHambatan(location.Kelas_hambatan)
+	}
+	estimate.TidakSesuai = estimate.KelasObservasi != location.Kelas_hambatan
+	if estimate.TidakSesuai {
+		estimate.Catatan = append(estimate.Catatan, fmt.Sprintf(
+			"Kelas hambatan samping terukur %s pada jam puncak %s berbeda dengan konfigurasi lokasi %s",
+			estimate.KelasObservasi, estimate.JamPuncak, location.Kelas_hambatan))
+	}
+	return estimate
+}
+
+// HitungHambatanSamping memuat data lokasi pada periode lalu mengestimasi kelas hambatan sampingnya
+func HitungHambatanSamping(location *Location, startTime, endTime time.Time, opts AnalysisOptions) (*SideFrictionEstimate, error) {
+	dataset, err := LoadTrafficDataForAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
+	}
+	standard, err := ResolveCapacityStandard(PKJI2023Standard{}, *location, dataset.Data)
+	if err != nil {
+		return nil, err
+	}
+	return EstimasiHambatanSamping(standard, *location, dataset.Data, startTime, endTime), nil
+}
+
+// prepareAnalysis memuat dataset analisis. Jika opts.HambatanSamping = observasi, kelas hambatan samping
+// salinan lokasi diganti dengan kelas hasil estimasi data periode yang sama (jika dapat diestimasi).
+func prepareAnalysis(location *Location, startTime, endTime time.Time, opts AnalysisOptions) (*Location, *AnalysisDataset, *SideFrictionEstimate, error) {
+	if location.IsSimpang() {
+		return nil, nil, nil, ErrLokasiSimpang
+	}
+
+	dataset, err := LoadTrafficDataForAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if opts.HambatanSamping != HambatanSampingObservasi {
+		return location, dataset, nil, nil
+	}
+
+	standard, err := ResolveCapacityStandard(PKJI2023Standard{}, *location, dataset.Data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	estimate := EstimasiHambatanSamping(standard, *location, dataset.Data, startTime, endTime)
+
+	observed := *location
+	if estimate.KelasObservasi != "" {
+		observed.Kelas_hambatan = estimate.KelasObservasi
+	}
+	estimate.KelasDipakai = observed.Kelas_hambatan
+	return &observed, dataset, estimate, nil
+}
+
+func NextSideFrictionEstimateID() (string, error) {
+	collection := database.DB.Collection("hambatan_samping")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last SideFrictionEstimate
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "HS-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "HS-%d", &lastNum)
+	return fmt.Sprintf("HS-%05d", lastNum+1), nil
+}
+
+func SaveSideFrictionEstimate(estimate *SideFrictionEstimate) error {
+	id, err := NextSideFrictionEstimateID()
+	if err != nil {
+		return err
+	}
+	estimate.ID = id
+
+	_, err = database.DB.Collection("hambatan_samping").InsertOne(context.Background(), estimate)
+	return err
+}
+
+func GetLatestSideFrictionEstimate(lokasiID string) (*SideFrictionEstimate, error) {
+	var estimate SideFrictionEstimate
+	err := database.DB.Collection("hambatan_samping").FindOne(
+		context.Background(),
+		bson.M{"lokasi_id": lokasiID},
+		options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}}),
+	).Decode(&estimate)
+	if err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
+
+// GetSideFrictionMismatches mengambil estimasi terbaru setiap lokasi yang kelasnya tidak sesuai konfigurasi
+func GetSideFrictionMismatches() ([]SideFrictionEstimate, error) {
+	pipeline := []bson.M{
+		{"$sort": bson.M{"timestamp": -1}},
+		{"$group": bson.M{"_id": "$lokasi_id", "latest": bson.M{"$first": "$$ROOT"}}},
+		{"$replaceRoot": bson.M{"newRoot": "$latest"}},
+		{"$match": bson.M{"tidak_sesuai": true}},
+		{"$sort": bson.M{"lokasi_id": 1}},
+	}
+
+	cursor, err := database.DB.Collection("hambatan_samping").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimates []SideFrictionEstimate
+	if err = cursor.All(context.Background(), &estimates); err != nil {
+		return nil, err
+	}
+	return estimates, nil
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lokasiHambatanUji: jalan 2/2 tak terbagi 12 kelas berbahu, VB tanpa hambatan samping (68 + 0) × 1.00 = 68 km/jam
+func lokasiHambatanUji(kelasHambatan string) Location {
+	return Location{ID: "LOK-1", Tipe_lokasi: "12_kelas", Tipe_arah: "22ud", Lebar_jalur: 7, Persentase: "50-50",
+		Tipe_hambatan: "bahu_jalan", Kelas_hambatan: kelasHambatan}
+}
+
+// trafficDataHambatanUji membuat record 5 menit berisi KR (kelas 2) dengan kecepatan tertentu dan KTB (kelas 11)
+func trafficDataHambatanUji(ts time.Time, kr int, kecepatan float64, ktb int) TrafficData {
+	return TrafficData{
+		Timestamp:      ts,
+		IntervalMenit:  5,
+		TotalKendaraan: kr + ktb,
+		ZonaArahData: []TrafficZonaArahData{{
+			IDZonaArah: "ZA-1",
+			KelasData: []TrafficKelasDetail{
+				{Kelas: 2, JumlahKendaraan: kr, KecepatanRataRata: kecepatan},
+				{Kelas: 11, JumlahKendaraan: ktb},
+			},
+		}},
+	}
+}
+
+// dataSatuJamUji berisi 12 record 07:00-07:55 dengan nilai yang sama
+func dataSatuJamUji(kr int, kecepatan float64, ktb int) []TrafficData {
+	var data []TrafficData
+	mulai := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		data = append(data, trafficDataHambatanUji(mulai.Add(time.Duration(i)*5*time.Minute), kr, kecepatan, ktb))
+	}
+	return data
+}
+
+func TestKelasHambatanDariKejadian(t *testing.T) {
+	tests := []struct {
+		kejadian float64
+		ingin    string
+	}{
+		{0, "VL"}, {99.9, "VL"}, {100, "L"}, {299.9, "L"}, {300, "M"}, {499.9, "M"},
+		{500, "H"}, {899.9, "H"}, {900, "VH"}, {2500, "VH"},
+	}
+	for _, tt := range tests {
+		if got := KelasHambatanDariKejadian(tt.kejadian); got != tt.ingin {
+			t.Errorf("kejadian %.1f: kelas %s, ingin %s", tt.kejadian, got, tt.ingin)
+		}
+	}
+}
+
+func TestKelasHambatanDariKecepatan(t *testing.T) {
+	// FVBHS 2/2 berbahu: VL 1.00, L 0.96, M 0.91, H 0.82, VH 0.73
+	location := lokasiHambatanUji("L")
+	tests := []struct {
+		rasio float64
+		ingin string
+	}{
+		{1.05, "VL"}, {0.985, "VL"}, {0.975, "L"}, {0.94, "L"}, {0.93, "M"}, {0.87, "M"},
+		{0.86, "H"}, {0.78, "H"}, {0.77, "VH"}, {0.5, "VH"},
+	}
+	for _, tt := range tests {
+		if got := kelasHambatanDariKecepatan(location, tt.rasio); got != tt.ingin {
+			t.Errorf("rasio %.3f: kelas %s, ingin %s", tt.rasio, got, tt.ingin)
+		}
+	}
+}
+
+func TestEstimasiHambatanSamping(t *testing.T) {
+	standard := PKJI2023Standard{}
+	mulai := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		nama           string
+		konfigurasi    string
+		data           []TrafficData
+		kelasKejadian  string
+		kelasKecepatan string
+		kelas          string
+		selisih        int
+	}{
+		// 110 KTB per 5 menit = 1320/jam, berbobot 528 → H; kecepatan 65.3/68 = 0.96 → L
+		{"kelas dari KTB", "L", dataSatuJamUji(20, 65.3, 110), "H", "L", "H", 2},
+		// KTB 10 per 5 menit = 120/jam, berbobot 48 → VL; kecepatan 55.8/68 = 0.82 → H
+		{"kelas dari kecepatan", "M", dataSatuJamUji(20, 55.8, 10), "VL", "H", "H", 1},
+		// KTB 40 per 5 menit = 480/jam, berbobot 192 → L; kecepatan 0.96 → L
+		{"sesuai konfigurasi", "L", dataSatuJamUji(20, 65.3, 40), "L", "L", "L", 0},
+		// Tanpa kecepatan terukur kelas hanya dari KTB
+		{"tanpa kecepatan", "VH", dataSatuJamUji(20, 0, 70), "M", "", "M", -2},
+	}
+	for _, tt := range tests {
+		location := lokasiHambatanUji(tt.konfigurasi)
+		estimate := EstimasiHambatanSamping(standard, location, tt.data, mulai, mulai.Add(time.Hour))
+		if len(estimate.PerJam) != 1 {
+			t.Errorf("%s: %d jam, ingin 1 (%v)", tt.nama, len(estimate.PerJam), estimate.Catatan)
+			continue
+		}
+		p := estimate.PerJam[0]
+		if p.Jam != 7 || p.Periode != "07:00-08:00" || p.DurasiJam != 1 {
+			t.Errorf("%s: jam %d %s durasi %.2f", tt.nama, p.Jam, p.Periode, p.DurasiJam)
+		}
+		if math.Abs(p.KejadianBerbobot-0.4*p.KTBPerJam) > 1e-9 || p.KTBPerJam != float64(tt.data[0].ZonaArahData[0].KelasData[1].JumlahKendaraan*12) {
+			t.Errorf("%s: KTB %.1f/jam berbobot %.1f", tt.nama, p.KTBPerJam, p.KejadianBerbobot)
+		}
+		if p.KelasKejadian != tt.kelasKejadian || p.KelasKecepatan != tt.kelasKecepatan || p.Kelas != tt.kelas {
+			t.Errorf("%s: kejadian %s kecepatan %s kelas %s, ingin %s, %s, %s", tt.nama,
+				p.KelasKejadian, p.KelasKecepatan, p.Kelas, tt.kelasKejadian, tt.kelasKecepatan, tt.kelas)
+		}
+
+		tidakSesuai := tt.kelas != tt.konfigurasi
+		if estimate.KelasObservasi != tt.kelas || estimate.Selisih != tt.selisih || estimate.TidakSesuai != tidakSesuai {
+			t.Errorf("%s: observasi %s selisih %d tidak sesuai %v, ingin %s, %d, %v", tt.nama,
+				estimate.KelasObservasi, estimate.Selisih, estimate.TidakSesuai, tt.kelas, tt.selisih, tidakSesuai)
+		}
+		adaCatatan := strings.Contains(strings.Join(estimate.Catatan, "\n"), "berbeda dengan konfigurasi lokasi")
+		if adaCatatan != tidakSesuai {
+			t.Errorf("%s: catatan %v", tt.nama, estimate.Catatan)
+		}
+	}
+}
+
+// Kelas observasi diambil dari jam yang beririsan dengan jam puncak 07:30-08:30, bukan dari jam lain
+func TestEstimasiHambatanSampingJamPuncak(t *testing.T) {
+	var data []TrafficData
+	mulai := time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	for i := 0; i < 48; i++ {
+		ts := mulai.Add(time.Duration(i) * 5 * time.Minute)
+		kr, ktb := 10, 0
+		switch ts.Hour() {
+		case 6:
+			ktb = 200 // 2400/jam berbobot 960 → VH, di luar jam puncak
+		case 7:
+			ktb = 70 // 840/jam berbobot 336 → M
+		case 8:
+			ktb = 25 // 300/jam berbobot 120 → L
+		case 9:
+			ktb = 100 // 1200/jam berbobot 480 → M, di luar jam puncak
+		}
+		if !ts.Before(mulai.Add(90*time.Minute)) && ts.Before(mulai.Add(150*time.Minute)) {
+			kr = 100
+		}
+		data = append(data, trafficDataHambatanUji(ts, kr, 0, ktb))
+	}
+	// Jam 10 hanya 15 menit data, di bawah durasi minimum
+	for i := 0; i < 3; i++ {
+		data = append(data, trafficDataHambatanUji(mulai.Add(4*time.Hour+time.Duration(i)*5*time.Minute), 10, 0, 200))
+	}
+
+	estimate := EstimasiHambatanSamping(PKJI2023Standard{}, lokasiHambatanUji("L"), data, mulai, mulai.Add(5*time.Hour))
+	var kelas []string
+	for _, p := range estimate.PerJam {
+		kelas = append(kelas, p.Periode+"="+p.Kelas)
+	}
+	if strings.Join(kelas, ",") != "06:00-07:00=VH,07:00-08:00=M,08:00-09:00=L,09:00-10:00=M" {
+		t.Fatalf("kelas per jam %v", kelas)
+	}
+	if estimate.JamPuncak != "07:30-08:30" || estimate.KelasObservasi != "M" || estimate.Selisih != 1 || !estimate.TidakSesuai {
+		t.Fatalf("jam puncak %s observasi %s selisih %d tidak sesuai %v, ingin 07:30-08:30, M, 1, true",
+			estimate.JamPuncak, estimate.KelasObservasi, estimate.Selisih, estimate.TidakSesuai)
+	}
+}
+
+func TestEstimasiHambatanSampingTidakBerlaku(t *testing.T) {
+	mulai := time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)
+	bebasHambatan := lokasiHambatanUji("L")
+	bebasHambatan.Tipe_lokasi = "bebas_hambatan"
+
+	tests := []struct {
+		nama     string
+		location Location
+		data     []TrafficData
+		catatan  string
+	}{
+		{"bebas hambatan", bebasHambatan, dataSatuJamUji(20, 65, 0), "tidak berlaku untuk jalan bebas hambatan"},
+		{"tanpa data", lokasiHambatanUji("L"), nil, "Tidak ada data traffic"},
+		{"data kurang dari 30 menit", lokasiHambatanUji("L"), dataSatuJamUji(20, 65, 10)[:5], "kurang dari 30 menit"},
+	}
+	for _, tt := range tests {
+		estimate := EstimasiHambatanSamping(PKJI2023Standard{}, tt.location, tt.data, mulai, mulai.Add(time.Hour))
+		if estimate.KelasObservasi != "" || estimate.TidakSesuai ||
+			!strings.Contains(strings.Join(estimate.Catatan, "\n"), tt.catatan) {
+			t.Errorf("%s: observasi %q catatan %v, ingin catatan %q", tt.nama, estimate.KelasObservasi, estimate.Catatan, tt.catatan)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Jenis lokasi: ruas jalan (default) atau simpang
+const (
+	JenisLokasiRuas                = "ruas"
+	JenisLokasiSimpangAPILL        = "simpang_apill"         // simpang bersinyal (Alat Pemberi Isyarat Lalu Lintas)
+	JenisLokasiSimpangTakBersinyal = "simpang_tak_bersinyal" // simpang prioritas tanpa sinyal
+)
+
+// Gerakan kendaraan pada pendekat simpang
+const (
+	GerakanBelokKiri  = "BKi"
+	GerakanLurus      = "LRS"
+	GerakanBelokKanan = "BKa"
+)
+
+var (
+	JenisLokasiOptions       = []string{JenisLokasiRuas, JenisLokasiSimpangAPILL, JenisLokasiSimpangTakBersinyal}
+	TipeSimpangOptions       = []string{"322", "324", "342", "344", "422", "424", "444"}
+	LingkunganSimpangOptions = []string{"KOM", "KIM", "AT"} // komersial, permukiman, akses terbatas
+	MedianMayorOptions       = []string{"tidak_ada", "sempit", "lebar"}
+	GerakanOptions           = []string{GerakanBelokKiri, GerakanLurus, GerakanBelokKanan}
+)
+
+var ErrLokasiSimpang = errors.New("lokasi adalah simpang, gunakan analisis simpang (/simpang)")
+
+// Geometri satu pendekat (lengan) simpang
+type SimpangPendekat struct {
+	Kode              string  `bson:"kode" json:"kode"` // misal U, S, T, B
+	Nama              string  `bson:"nama" json:"nama"`
+	JalanMayor        bool    `bson:"jalan_mayor" json:"jalan_mayor"`
+	LebarPendekat     float64 `bson:"lebar_pendekat" json:"lebar_pendekat"`           // LP / WA (m)
+	LebarMasuk        float64 `bson:"lebar_masuk" json:"lebar_masuk"`                 // lebar efektif masuk (m), APILL; default LP
+	BelokKiriLangsung bool    `bson:"belok_kiri_langsung" json:"belok_kiri_langsung"` // BKiJT, belok kiri jalan terus
+	WaktuHijau        float64 `bson:"waktu_hijau" json:"waktu_hijau"`                 // g (detik), APILL
+}
+
+// Pemetaan zona arah kamera ke gerakan pada pendekat
+type SimpangGerakan struct {
+	IDZonaArah string `bson:"id_zona_arah" json:"id_zona_arah"`
+	Pendekat   string `bson:"pendekat" json:"pendekat"` // kode pendekat
+	Gerakan    string `bson:"gerakan" json:"gerakan"`   // BKi, LRS, BKa
+}
+
+// Konfigurasi simpang pada lokasi berjenis simpang
+type SimpangConfig struct {
+	TipeSimpang string  `bson:"tipe_simpang,omitempty" json:"tipe_simpang,omitempty"` // tak bersinyal: jumlah lengan, lajur minor, lajur mayor (misal 422)
+	Lingkungan  string  `bson:"lingkungan" json:"lingkungan"`                         // KOM, KIM, AT
+	MedianMayor string  `bson:"median_mayor,omitempty" json:"median_mayor,omitempty"` // tak bersinyal
+	WaktuSiklus float64 `bson:"waktu_siklus,omitempty" json:"waktu_siklus,omitempty"` // c (detik), APILL
+	WaktuHilang float64 `bson:"waktu_hilang,omitempty" json:"waktu_hilang,omitempty"` // HH (detik) untuk siklus optimum, APILL
+	// Kelompok kode pendekat yang mendapat hijau bersamaan, APILL; kosong = satu fase per pendekat
+	Fase [][]string `bson:"fase,omitempty" json:"fase,omitempty"`
+	// Peluang beban lebih POL (%) untuk panjang antrian rencana dari NQmax, APILL; 0 = 5%
+	PeluangBebanLebih float64           `bson:"peluang_beban_lebih,omitempty" json:"peluang_beban_lebih,omitempty"`
+	Pendekat          []SimpangPendekat `bson:"pendekat" json:"pendekat"`
+	Gerakan           []SimpangGerakan  `bson:"gerakan" json:"gerakan"`
+}
+
+// Peluang beban lebih bawaan untuk perencanaan (PKJI 2023)
+const defaultPeluangBebanLebih = 5.0
+
+// IsSimpangJenisLokasi mengembalikan true untuk jenis simpang bersinyal maupun tak bersinyal
+func IsSimpangJenisLokasi(jenisLokasi string) bool {
+	return jenisLokasi == JenisLokasiSimpangAPILL || jenisLokasi == JenisLokasiSimpangTakBersinyal
+}
+
+func (l Location) IsSimpang() bool {
+	return IsSimpangJenisLokasi(l.Jenis_lokasi)
+}
+
+func IsValidJenisLokasi(value string) bool {
+	return value == "" || containsString(JenisLokasiOptions, value)
+}
+
+func (cfg SimpangConfig) pendekat(kode string) (SimpangPendekat, bool) {
+	for _, p := range cfg.Pendekat {
+		if p.Kode == kode {
+			return p, true
+		}
+	}
+	return SimpangPendekat{}, false
+}
+
+// gerakanZona mengembalikan pemetaan gerakan zona arah, ok=false jika zona tidak dipetakan
+func (cfg SimpangConfig) gerakanZona(idZonaArah string) (SimpangGerakan, bool) {
+	for _, g := range cfg.Gerakan {
+		if g.IDZonaArah == idZonaArah {
+			return g, true
+		}
+	}
+	return SimpangGerakan{}, false
+}
+
+// faseList mengembalikan kelompok fase APILL, satu fase per pendekat jika tidak dikonfigurasi
+func (cfg SimpangConfig) faseList() [][]string {
+	if len(cfg.Fase) > 0 {
+		return cfg.Fase
+	}
+	fase := make([][]string, 0, len(cfg.Pendekat))
+	for _, p := range cfg.Pendekat {
+		fase = append(fase, []string{p.Kode})
+	}
+	return fase
+}
+
+func (cfg SimpangConfig) peluangBebanLebih() float64 {
+	if cfg.PeluangBebanLebih <= 0 {
+		return defaultPeluangBebanLebih
+	}
+	return cfg.PeluangBebanLebih
+}
+
+// Normalize mengisi nilai default (lebar masuk = lebar pendekat)
+func (cfg *SimpangConfig) Normalize() {
+	for i := range cfg.Pendekat {
+		if cfg.Pendekat[i].LebarMasuk <= 0 {
+			cfg.Pendekat[i].LebarMasuk = cfg.Pendekat[i].LebarPendekat
+		}
+	}
+	if cfg.MedianMayor == "" {
+		cfg.MedianMayor = "tidak_ada"
+	}
+}
+
+// Validate memeriksa geometri dan pemetaan gerakan sesuai jenis simpang
+func (cfg SimpangConfig) Validate(jenisLokasi string) error {
+	if !containsString(LingkunganSimpangOptions, cfg.Lingkungan) {
+		return fmt.Errorf("lingkungan simpang tidak valid")
+	}
+	if len(cfg.Pendekat) < 3 || len(cfg.Pendekat) > 4 {
+		return fmt.Errorf("simpang harus memiliki 3 atau 4 pendekat")
+	}
+
+	kode := make(map[string]bool)
+	mayor, minor := 0, 0
+	for i, p := range cfg.Pendekat {
+		if p.Kode == "" || kode[p.Kode] {
+			return fmt.Errorf("pendekat[%d]: kode kosong atau duplikat", i)
+		}
+		kode[p.Kode] = true
+		if p.LebarPendekat <= 0 {
+			return fmt.Errorf("pendekat %s: lebar_pendekat harus lebih dari 0", p.Kode)
+		}
+		if p.JalanMayor {
+			mayor++
+		} else {
+			minor++
+		}
+
+		if jenisLokasi == JenisLokasiSimpangAPILL {
+			if p.WaktuHijau <= 0 || p.WaktuHijau >= cfg.WaktuSiklus {
+				return fmt.Errorf("pendekat %s: waktu_hijau harus antara 0 dan waktu_siklus", p.Kode)
+			}
+		}
+	}
+
+	switch jenisLokasi {
+	case JenisLokasiSimpangTakBersinyal:
+		if !containsString(TipeSimpangOptions, cfg.TipeSimpang) {
+			return fmt.Errorf("tipe_simpang tidak valid")
+		}
+		if int(cfg.TipeSimpang[0]-'0') != len(cfg.Pendekat) {
+			return fmt.Errorf("tipe_simpang %s tidak sesuai dengan jumlah pendekat (%d)", cfg.TipeSimpang, len(cfg.Pendekat))
+		}
+		if mayor == 0 || minor == 0 {
+			return fmt.Errorf("simpang tak bersinyal memerlukan pendekat jalan mayor dan minor")
+		}
+		if cfg.MedianMayor != "" && !containsString(MedianMayorOptions, cfg.MedianMayor) {
+			return fmt.Errorf("median_mayor tidak valid")
+		}
+	case JenisLokasiSimpangAPILL:
+		if cfg.WaktuSiklus <= 0 {
+			return fmt.Errorf("waktu_siklus harus lebih dari 0")
+		}
+		if cfg.WaktuHilang < 0 || cfg.WaktuHilang >= cfg.WaktuSiklus {
+			return fmt.Errorf("waktu_hilang harus antara 0 dan waktu_siklus")
+		}
+		if cfg.PeluangBebanLebih < 0 || cfg.PeluangBebanLebih >= 50 {
+			return fmt.Errorf("peluang_beban_lebih harus antara 0 dan 50 persen")
+		}
+		if err := cfg.validateFase(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("jenis_lokasi %s bukan simpang", jenisLokasi)
+	}
+
+	zona := make(map[string]bool)
+	for i, g := range cfg.Gerakan {
+		if g.IDZonaArah == "" || zona[g.IDZonaArah] {
+			return fmt.Errorf("gerakan[%d]: id_zona_arah kosong atau duplikat", i)
+		}
+		zona[g.IDZonaArah] = true
+		if !kode[g.Pendekat] {
+			return fmt.Errorf("gerakan[%d]: pendekat %s tidak ada", i, g.Pendekat)
+		}
+		if !containsString(GerakanOptions, g.Gerakan) {
+			return fmt.Errorf("gerakan[%d]: gerakan %s tidak valid", i, g.Gerakan)
+		}
+	}
+	return nil
+}
+
+// validateFase memastikan setiap pendekat masuk tepat satu fase dan pendekat satu fase memiliki waktu hijau yang sama
+func (cfg SimpangConfig) validateFase() error {
+	if len(cfg.Fase) == 0 {
+		return nil
+	}
+	dipakai := make(map[string]bool)
+	for i, fase := range cfg.Fase {
+		if len(fase) == 0 {
+			return fmt.Errorf("fase[%d]: tidak memiliki pendekat", i)
+		}
+		hijau := -1.0
+		for _, kode := range fase {
+			p, ok := cfg.pendekat(kode)
+			if !ok {
+				return fmt.Errorf("fase[%d]: pendekat %s tidak ada", i, kode)
+			}
+			if dipakai[kode] {
+				return fmt.Errorf("fase[%d]: pendekat %s sudah masuk fase lain", i, kode)
+			}
+			dipakai[kode] = true
+			if hijau >= 0 && p.WaktuHijau != hijau {
+				return fmt.Errorf("fase[%d]: waktu_hijau pendekat dalam satu fase harus sama", i)
+			}
+			hijau = p.WaktuHijau
+		}
+	}
+	for _, p := range cfg.Pendekat {
+		if !dipakai[p.Kode] {
+			return fmt.Errorf("pendekat %s belum masuk fase", p.Kode)
+		}
+	}
+	return nil
+}
+
+// UpdateSimpangConfig mengganti konfigurasi simpang lokasi
+func UpdateSimpangConfig(lokasiID string, cfg SimpangConfig) error {
+	_, err := database.DB.Collection("locations").UpdateOne(
+		context.Background(),
+		bson.M{"_id": lokasiID},
+		bson.M{"$set": bson.M{"simpang": cfg}},
+	)
+	return err
+}
+
+// Nilai ekivalen kendaraan ringan simpang (PKJI 2023)
+var ekrSimpang = map[string]map[KategoriPKJI]float64{
+	JenisLokasiSimpangTakBersinyal: {KategoriSM: 0.5, KategoriKR: 1.0, KategoriKB: 1.3},
+	JenisLokasiSimpangAPILL:        {KategoriSM: 0.15, KategoriKR: 1.0, KategoriKB: 1.3}, // pendekat terlindung
+}
+
+// GetEkrSimpang mengembalikan ekr kategori untuk jenis simpang, 0 untuk kendaraan tidak bermotor
+func GetEkrSimpang(jenisLokasi string, kategori KategoriPKJI) float64 {
+	return ekrSimpang[jenisLokasi][kategori]
+}
+
+// GetC0Simpang - Kapasitas dasar simpang tak bersinyal (skr/jam)
+func GetC0Simpang(tipeSimpang string) float64 {
+	switch tipeSimpang {
+	case "322":
+		return 2700
+	case "342", "422":
+		return 2900
+	case "324", "344":
+		return 3200
+	case "424", "444":
+		return 3400
+	}
+	return 2900
+}
+
+// GetFLP - Faktor koreksi lebar pendekat rata-rata
+func GetFLP(tipeSimpang string, lebarRataRata float64) float64 {
+	switch tipeSimpang {
+	case "422":
+		return 0.70 + 0.0866*lebarRataRata
+	case "424", "444":
+		return 0.62 + 0.0740*lebarRataRata
+	case "322":
+		return 0.73 + 0.0760*lebarRataRata
+	case "324", "344":
+		return 0.62 + 0.0646*lebarRataRata
+	case "342":
+		return 0.67 + 0.0698*lebarRataRata
+	}
+	return 1.0
+}
+
+// GetFM - Faktor koreksi median jalan mayor
+func GetFM(median string) float64 {
+	switch median {
+	case "sempit":
+		return 1.05
+	case "lebar":
+		return 1.20
+	}
+	return 1.00
+}
+
+// GetFUKSimpang - Faktor koreksi ukuran kota untuk simpang
+func GetFUKSimpang(ukuranKota float64) float64 {
+	switch {
+	case ukuranKota < 0.1:
+		return 0.82
+	case ukuranKota < 0.5:
+		return 0.88
+	case ukuranKota < 1.0:
+		return 0.94
+	case ukuranKota < 3.0:
+		return 1.00
+	default:
+		return 1.05
+	}
+}
+
+// GetFHSSimpang - Faktor koreksi hambatan samping simpang berdasarkan lingkungan, kelas hambatan,
+// dan rasio kendaraan tak bermotor (RKTB, dibatasi 0.25). Kelas VL/L = rendah, M = sedang, H/VH = tinggi.
+func GetFHSSimpang(lingkungan string, kelasHambatan string, rktb float64) float64 {
+	tingkat := "sedang"
+	switch kelasHambatan {
+	case "VL", "L":
+		tingkat = "rendah"
+	case "H", "VH":
+		tingkat = "tinggi"
+	}
+
+	dasar := map[string]map[string]float64{
+		"KOM": {"tinggi": 0.93, "sedang": 0.94, "rendah": 0.95},
+		"KIM": {"tinggi": 0.96, "sedang": 0.97, "rendah": 0.98},
+		"AT":  {"tinggi": 1.00, "sedang": 1.00, "rendah": 1.00},
+	}
+	fhs := 0.94
+	if perTingkat, ok := dasar[lingkungan]; ok {
+		fhs = perTingkat[tingkat]
+	}
+	return fhs * (1 - math.Min(math.Max(rktb, 0), 0.25))
+}
+
+// GetFBKiSimpang - Faktor koreksi belok kiri simpang tak bersinyal
+func GetFBKiSimpang(rbki float64) float64 {
+	return 0.84 + 1.61*rbki
+}
+
+// GetFBKaSimpang - Faktor koreksi belok kanan simpang tak bersinyal (hanya simpang 3 lengan)
+func GetFBKaSimpang(jumlahLengan int, rbka float64) float64 {
+	if jumlahLengan == 3 {
+		return 1.09 - 0.922*rbka
+	}
+	return 1.0
+}
+
+// GetFRmi - Faktor koreksi rasio arus jalan minor (pMI dibatasi 0.1-0.9)
+func GetFRmi(tipeSimpang string, pmi float64) float64 {
+	p := math.Min(math.Max(pmi, 0.1), 0.9)
+	polinomQuartic := 16.6*math.Pow(p, 4) - 33.3*math.Pow(p, 3) + 25.3*p*p - 8.6*p + 1.95
+
+	switch tipeSimpang {
+	case "422":
+		return 1.19*p*p - 1.19*p + 1.19
+	case "424", "444":
+		if p <= 0.3 {
+			return polinomQuartic
+		}
+		return 1.11*p*p - 1.11*p + 1.11
+	case "322":
+		if p <= 0.5 {
+			return 1.19*p*p - 1.19*p + 1.19
+		}
+		return -0.595*p*p + 0.595*p + 0.74
+	case "342":
+		if p <= 0.5 {
+			return 1.19*p*p - 1.19*p + 1.19
+		}
+		return 2.38*p*p - 2.38*p + 1.49
+	case "324", "344":
+		if p <= 0.3 {
+			return polinomQuartic
+		}
+		if p <= 0.5 {
+			return 1.11*p*p - 1.11*p + 1.11
+		}
+		return -0.555*p*p + 0.555*p + 0.69
+	}
+	return 1.0
+}
+
+// GetTingkatPelayananSimpang menentukan LoS simpang dari tundaan rata-rata (detik/skr); DJ > 1 selalu F
+func GetTingkatPelayananSimpang(jenisLokasi string, tundaan, derajatKejenuhan float64) (string, string) {
+	batas := []float64{5, 15, 25, 40, 60} // simpang APILL
+	if jenisLokasi == JenisLokasiSimpangTakBersinyal {
+		batas = []float64{5, 10, 20, 30, 45}
+	}
+	keterangan := []string{
+		"Sangat baik, tundaan sangat kecil",
+		"Baik, tundaan kecil",
+		"Cukup, tundaan sedang",
+		"Kurang, tundaan mulai besar",
+		"Buruk, tundaan besar",
+		"Sangat buruk, tundaan sangat besar",
+	}
+	if derajatKejenuhan > 1 {
+		return "F", keterangan[5]
+	}
+	for i, b := range batas {
+		if tundaan <= b {
+			return string(rune('A' + i)), keterangan[i]
+		}
+	}
+	return "F", keterangan[5]
+}
@@ -0,0 +1,618 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"backend/database"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Arus satu gerakan pada jam puncak
+type SimpangArusGerakan struct {
+	Pendekat string  `bson:"pendekat" json:"pendekat"`
+	Gerakan  string  `bson:"gerakan" json:"gerakan"`
+	SM       int     `bson:"sm" json:"sm"`
+	KR       int     `bson:"kr" json:"kr"`
+	KB       int     `bson:"kb" json:"kb"`
+	KTB      int     `bson:"ktb" json:"ktb"`
+	Arus     float64 `bson:"arus" json:"arus"`         // kendaraan bermotor/jam
+	ArusSkr  float64 `bson:"arus_skr" json:"arus_skr"` // skr/jam
+}
+
+// Kinerja simpang tak bersinyal: C = C0 × FLP × FM × FUK × FHS × FBKi × FBKa × FRmi
+type SimpangTakBersinyalKinerja struct {
+	LebarPendekatRata  float64 `bson:"lebar_pendekat_rata" json:"lebar_pendekat_rata"`
+	ArusMayor          float64 `bson:"arus_mayor" json:"arus_mayor"` // skr/jam
+	ArusMinor          float64 `bson:"arus_minor" json:"arus_minor"` // skr/jam
+	RasioMinor         float64 `bson:"rasio_minor" json:"rasio_minor"`
+	KapasitasDasar     float64 `bson:"kapasitas_dasar" json:"kapasitas_dasar"`
+	FLP                float64 `bson:"flp" json:"flp"`
+	FM                 float64 `bson:"fm" json:"fm"`
+	FUK                float64 `bson:"fuk" json:"fuk"`
+	FHS                float64 `bson:"fhs" json:"fhs"`
+	FBKi               float64 `bson:"fbki" json:"fbki"`
+	FBKa               float64 `bson:"fbka" json:"fbka"`
+	FRmi               float64 `bson:"frmi" json:"frmi"`
+	Kapasitas          float64 `bson:"kapasitas" json:"kapasitas"`
+	TundaanLaluLintas  float64 `bson:"tundaan_lalu_lintas" json:"tundaan_lalu_lintas"` // TLL
+	TundaanMayor       float64 `bson:"tundaan_mayor" json:"tundaan_mayor"`             // TMA
+	TundaanMinor       float64 `bson:"tundaan_minor" json:"tundaan_minor"`             // TMI
+	TundaanGeometrik   float64 `bson:"tundaan_geometrik" json:"tundaan_geometrik"`     // TG
+	PeluangAntrianMin  float64 `bson:"peluang_antrian_min" json:"peluang_antrian_min"` // %
+	PeluangAntrianMaks float64 `bson:"peluang_antrian_maks" json:"peluang_antrian_maks"`
+}
+
+// Kinerja satu pendekat simpang APILL (terlindung)
+type SimpangPendekatKinerja struct {
+	Kode                string  `bson:"kode" json:"kode"`
+	Nama                string  `bson:"nama" json:"nama"`
+	Arus                float64 `bson:"arus" json:"arus"`             // skr/jam tanpa BKiJT
+	ArusBKiJT           float64 `bson:"arus_bkijt" json:"arus_bkijt"` // skr/jam belok kiri jalan terus
+	RBKi                float64 `bson:"rbki" json:"rbki"`
+	RBKa                float64 `bson:"rbka" json:"rbka"`
+	RKTB                float64 `bson:"rktb" json:"rktb"`
+	LebarEfektif        float64 `bson:"lebar_efektif" json:"lebar_efektif"`
+	ArusJenuhDasar      float64 `bson:"arus_jenuh_dasar" json:"arus_jenuh_dasar"` // S0 = 600 × LE
+	FUK                 float64 `bson:"fuk" json:"fuk"`
+	FHS                 float64 `bson:"fhs" json:"fhs"`
+	FBKa                float64 `bson:"fbka" json:"fbka"`
+	FBKi                float64 `bson:"fbki" json:"fbki"`
+	ArusJenuh           float64 `bson:"arus_jenuh" json:"arus_jenuh"` // S (skr/jam hijau)
+	RasioArus           float64 `bson:"rasio_arus" json:"rasio_arus"` // RQ/S
+	WaktuHijau          float64 `bson:"waktu_hijau" json:"waktu_hijau"`
+	Kapasitas           float64 `bson:"kapasitas" json:"kapasitas"` // C = S × g / c
+	DerajatKejenuhan    float64 `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"`
+	AntrianSisa         float64 `bson:"antrian_sisa" json:"antrian_sisa"`               // NQ1 (skr)
+	AntrianMerah        float64 `bson:"antrian_merah" json:"antrian_merah"`             // NQ2 (skr)
+	JumlahAntrian       float64 `bson:"jumlah_antrian" json:"jumlah_antrian"`           // NQ = NQ1 + NQ2
+	JumlahAntrianMaks   float64 `bson:"jumlah_antrian_maks" json:"jumlah_antrian_maks"` // NQmax pada peluang beban lebih rencana
+	PanjangAntrian      float64 `bson:"panjang_antrian" json:"panjang_antrian"`         // PA = NQmax × 20 / LE (m)
+	RasioKendaraanHenti float64 `bson:"rasio_kendaraan_henti" json:"rasio_kendaraan_henti"`
+	TundaanLaluLintas   float64 `bson:"tundaan_lalu_lintas" json:"tundaan_lalu_lintas"`
+	TundaanGeometrik    float64 `bson:"tundaan_geometrik" json:"tundaan_geometrik"`
+	Tundaan             float64 `bson:"tundaan" json:"tundaan"` // detik/skr
+}
+
+// Rasio arus kritis satu fase APILL: pendekat dengan RQ/S tertinggi di antara pendekat yang hijau bersamaan
+type SimpangFaseKinerja struct {
+	Pendekat        []string `bson:"pendekat" json:"pendekat"`
+	PendekatKritis  string   `bson:"pendekat_kritis" json:"pendekat_kritis"`
+	RasioArusKritis float64  `bson:"rasio_arus_kritis" json:"rasio_arus_kritis"`
+}
+
+// Kinerja simpang APILL
+type SimpangAPILLKinerja struct {
+	WaktuSiklus       float64                  `bson:"waktu_siklus" json:"waktu_siklus"`
+	Fase              []SimpangFaseKinerja     `bson:"fase" json:"fase"`
+	RasioArusSimp     float64                  `bson:"rasio_arus_simpang" json:"rasio_arus_simpang"`             // IFR = Σ RQ/S kritis per fase
+	SiklusOptimum     float64                  `bson:"siklus_optimum,omitempty" json:"siklus_optimum,omitempty"` // (1.5 × HH + 5) / (1 - IFR)
+	PeluangBebanLebih float64                  `bson:"peluang_beban_lebih" json:"peluang_beban_lebih"`           // POL (%) untuk NQmax
+	Pendekat          []SimpangPendekatKinerja `bson:"pendekat" json:"pendekat"`
+}
+
+// Hasil analisis simpang PKJI 2023
+type SimpangAnalysis struct {
+	ID                   string                      `bson:"_id" json:"id"`
+	LokasiID             string                      `bson:"lokasi_id" json:"lokasi_id"`
+	NamaLokasi           string                      `bson:"nama_lokasi" json:"nama_lokasi"`
+	JenisLokasi          string                      `bson:"jenis_lokasi" json:"jenis_lokasi"`
+	TipeSimpang          string                      `bson:"tipe_simpang,omitempty" json:"tipe_simpang,omitempty"`
+	Tanggal              time.Time                   `bson:"tanggal" json:"tanggal"`
+	PeriodeHari          int                         `bson:"periode_hari" json:"periode_hari"`
+	Timestamp            time.Time                   `bson:"timestamp" json:"timestamp"`
+	JamPuncak            string                      `bson:"jam_puncak" json:"jam_puncak"`
+	PeakHour             *PeakHourAnalysis           `bson:"peak_hour,omitempty" json:"peak_hour,omitempty"`
+	Gerakan              []SimpangArusGerakan        `bson:"gerakan" json:"gerakan"`
+	ZonaTidakDipetakan   []string                    `bson:"zona_tidak_dipetakan,omitempty" json:"zona_tidak_dipetakan,omitempty"`
+	ArusTotal            float64                     `bson:"arus_total" json:"arus_total"` // skr/jam
+	RBKi                 float64                     `bson:"rbki" json:"rbki"`
+	RBKa                 float64                     `bson:"rbka" json:"rbka"`
+	RKTB                 float64                     `bson:"rktb" json:"rktb"`
+	TakBersinyal         *SimpangTakBersinyalKinerja `bson:"tak_bersinyal,omitempty" json:"tak_bersinyal,omitempty"`
+	APILL                *SimpangAPILLKinerja        `bson:"apill,omitempty" json:"apill,omitempty"`
+	Kapasitas            float64                     `bson:"kapasitas" json:"kapasitas"`                 // tak bersinyal: C simpang; APILL: Σ C pendekat
+	DerajatKejenuhan     float64                     `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"` // APILL: DJ pendekat tertinggi
+	Tundaan              float64                     `bson:"tundaan" json:"tundaan"`                     // tundaan rata-rata simpang (detik/skr)
+	PanjangAntrianMaks   float64                     `bson:"panjang_antrian_maks,omitempty" json:"panjang_antrian_maks,omitempty"`
+	TingkatPelayanan     string                      `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"`
+	IntervalDikecualikan int                         `bson:"interval_dikecualikan" json:"interval_dikecualikan"`
+	Kelengkapan          *KelengkapanData            `bson:"kelengkapan,omitempty" json:"kelengkapan,omitempty"`
+	TabelEkivalen        []EkivalenTableRef          `bson:"tabel_ekivalen,omitempty" json:"tabel_ekivalen,omitempty"` // tabel pemetaan kelas ke kategori
+	Catatan              []string                    `bson:"catatan,omitempty" json:"catatan,omitempty"`
+	Keterangan           string                      `bson:"keterangan" json:"keterangan"`
+}
+
+// hitungArusGerakan menjumlahkan kendaraan setiap gerakan pada jam puncak (per jam). Zona yang tidak
+// dipetakan ke gerakan dikembalikan terpisah agar konfigurasi dapat dilengkapi.
+func hitungArusGerakan(standard CapacityStandard, location Location, trafficDataList []TrafficData, peak PeakHourAnalysis) ([]SimpangArusGerakan, []string) {
+	cfg := location.Simpang
+	interval := time.Duration(peak.IntervalMenit) * time.Minute
+	window := peak.Puncak.Selesai.Sub(peak.Puncak.Mulai)
+	skala := 1.0
+	if window > time.Hour {
+		skala = time.Hour.Hours() / window.Hours()
+	}
+
+	index := make(map[string]int)
+	var gerakan []SimpangArusGerakan
+	for _, g := range cfg.Gerakan {
+		key := g.Pendekat + "|" + g.Gerakan
+		if _, ok := index[key]; !ok {
+			index[key] = len(gerakan)
+			gerakan = append(gerakan, SimpangArusGerakan{Pendekat: g.Pendekat, Gerakan: g.Gerakan})
+		}
+	}
+
+	var tidakDipetakan []string
+	for _, td := range trafficDataList {
+		slot := td.Timestamp.Truncate(interval)
+		diJamPuncak := !slot.Before(peak.Puncak.Mulai) && slot.Before(peak.Puncak.Selesai)
+		for _, za := range td.ZonaArahData {
+			g, ok := cfg.gerakanZona(za.IDZonaArah)
+			if !ok {
+				if !containsString(tidakDipetakan, za.IDZonaArah) {
+					tidakDipetakan = append(tidakDipetakan, za.IDZonaArah)
+				}
+				continue
+			}
+			if !diJamPuncak {
+				continue
+			}
+			arus := &gerakan[index[g.Pendekat+"|"+g.Gerakan]]
+			for _, kd := range za.KelasData {
+				switch KategoriPKJI(kategoriKelas(standard, td, location.Tipe_lokasi, kd.Kelas)) {
+				case KategoriSM:
+					arus.SM += kd.JumlahKendaraan
+				case KategoriKR:
+					arus.KR += kd.JumlahKendaraan
+				case KategoriKB:
+					arus.KB += kd.JumlahKendaraan
+				case KategoriKTB:
+					arus.KTB += kd.JumlahKendaraan
+				}
+			}
+		}
+	}
+
+	for i := range gerakan {
+		g := &gerakan[i]
+		g.Arus = float64(g.SM+g.KR+g.KB) * skala
+		g.ArusSkr = (float64(g.SM)*GetEkrSimpang(location.Jenis_lokasi, KategoriSM) +
+			float64(g.KR)*GetEkrSimpang(location.Jenis_lokasi, KategoriKR) +
+			float64(g.KB)*GetEkrSimpang(location.Jenis_lokasi, KategoriKB)) * skala
+	}
+	sort.Strings(tidakDipetakan)
+	return gerakan, tidakDipetakan
+}
+
+// rasioGerakan menghitung arus skr total, rasio belok kiri/kanan, dan rasio kendaraan tak bermotor
+func rasioGerakan(gerakan []SimpangArusGerakan) (total, rbki, rbka, rktb float64) {
+	var bki, bka, motor, ktb float64
+	for _, g := range gerakan {
+		total += g.ArusSkr
+		motor += float64(g.SM + g.KR + g.KB)
+		ktb += float64(g.KTB)
+		switch g.Gerakan {
+		case GerakanBelokKiri:
+			bki += g.ArusSkr
+		case GerakanBelokKanan:
+			bka += g.ArusSkr
+		}
+	}
+	if total > 0 {
+		rbki, rbka = bki/total, bka/total
+	}
+	if motor > 0 {
+		rktb = ktb / motor
+	}
+	return
+}
+
+// tundaanLewatJenuh adalah tundaan antrian deterministik rata-rata (detik) selama satu jam ketika arus melebihi
+// kapasitas: antrian tumbuh (Q − C)·t sehingga rata-rata tundaan = (DJ − 1) × 3600 / 2. Nol untuk DJ ≤ 1.
+func tundaanLewatJenuh(dj float64) float64 {
+	if dj <= 1 {
+		return 0
+	}
+	return (dj - 1) * 1800
+}
+
+// HitungSimpangTakBersinyal menghitung kapasitas, DJ, tundaan, dan peluang antrian simpang tak bersinyal
+func HitungSimpangTakBersinyal(location Location, gerakan []SimpangArusGerakan) (*SimpangTakBersinyalKinerja, float64, float64) {
+	cfg := location.Simpang
+	total, rbki, rbka, rktb := rasioGerakan(gerakan)
+
+	kinerja := &SimpangTakBersinyalKinerja{}
+	lebarTotal := 0.0
+	for _, p := range cfg.Pendekat {
+		lebarTotal += p.LebarPendekat
+	}
+	kinerja.LebarPendekatRata = lebarTotal / float64(len(cfg.Pendekat))
+
+	for _, g := range gerakan {
+		if p, ok := cfg.pendekat(g.Pendekat); ok && p.JalanMayor {
+			kinerja.ArusMayor += g.ArusSkr
+		} else {
+			kinerja.ArusMinor += g.ArusSkr
+		}
+	}
+	if total > 0 {
+		kinerja.RasioMinor = kinerja.ArusMinor / total
+	}
+
+	kinerja.KapasitasDasar = GetC0Simpang(cfg.TipeSimpang)
+	kinerja.FLP = GetFLP(cfg.TipeSimpang, kinerja.LebarPendekatRata)
+	kinerja.FM = GetFM(cfg.MedianMayor)
+	kinerja.FUK = GetFUKSimpang(location.Ukuran_kota)
+	kinerja.FHS = GetFHSSimpang(cfg.Lingkungan, location.Kelas_hambatan, rktb)
+	kinerja.FBKi = GetFBKiSimpang(rbki)
+	kinerja.FBKa = GetFBKaSimpang(len(cfg.Pendekat), rbka)
+	kinerja.FRmi = GetFRmi(cfg.TipeSimpang, kinerja.RasioMinor)
+	kinerja.Kapasitas = kinerja.KapasitasDasar * kinerja.FLP * kinerja.FM * kinerja.FUK * kinerja.FHS *
+		kinerja.FBKi * kinerja.FBKa * kinerja.FRmi
+
+	dj := HitungDerajatKejenuhanPKJI(total, kinerja.Kapasitas)
+
+	// Tundaan lalu lintas simpang dan jalan mayor. Kurva PKJI hanya berlaku sampai DJ = 1 (kurva TLL
+	// berkutub pada DJ ≈ 1.343); di atasnya dipakai nilai DJ = 1 ditambah tundaan lewat jenuh.
+	djKurva := math.Min(dj, 1)
+	if djKurva <= 0.6 {
+		kinerja.TundaanLaluLintas = 2 + 8.2078*djKurva - (1-djKurva)*2
+		kinerja.TundaanMayor = 1.8 + 5.8234*djKurva - (1-djKurva)*1.8
+	} else {
+		kinerja.TundaanLaluLintas = 1.0504/(0.2742-0.2042*djKurva) - (1-djKurva)*2
+		kinerja.TundaanMayor = 1.05034/(0.346-0.246*djKurva) - (1-djKurva)*1.8
+	}
+	kinerja.TundaanLaluLintas += tundaanLewatJenuh(dj)
+	kinerja.TundaanMayor += tundaanLewatJenuh(dj)
+	if kinerja.ArusMinor > 0 {
+		kinerja.TundaanMinor = (total*kinerja.TundaanLaluLintas - kinerja.ArusMayor*kinerja.TundaanMayor) / kinerja.ArusMinor
+	}
+
+	// Tundaan geometrik dengan rasio belok total RB
+	rb := rbki + rbka
+	if dj < 1 {
+		kinerja.TundaanGeometrik = (1-dj)*(6*rb+3*(1-rb)) + 4*dj
+	} else {
+		kinerja.TundaanGeometrik = 4
+	}
+
+	kinerja.PeluangAntrianMin = 9.02*dj + 20.66*dj*dj + 10.49*math.Pow(dj, 3)
+	kinerja.PeluangAntrianMaks = 47.71*dj - 24.68*dj*dj + 56.47*math.Pow(dj, 3)
+
+	return kinerja, dj, kinerja.TundaanLaluLintas + kinerja.TundaanGeometrik
+}
+
+// jumlahAntrianMaks menghitung NQmax, jumlah antrian yang hanya dilampaui dengan peluang beban lebih pol (%).
+// Grafik NQmax PKJI didekati dengan menganggap jumlah antrian per siklus berdistribusi Poisson dengan rata-rata NQ.
+func jumlahAntrianMaks(nq, pol float64) float64 {
+	if nq <= 0 {
+		return 0
+	}
+	batas := nq + 10*math.Sqrt(nq) + 20
+	kumulatif := 0.0
+	for k := 0.0; k < batas; k++ {
+		lgamma, _ := math.Lgamma(k + 1)
+		kumulatif += math.Exp(k*math.Log(nq) - nq - lgamma)
+		if 1-kumulatif <= pol/100 {
+			return k
+		}
+	}
+	return math.Ceil(batas)
+}
+
+// HitungSimpangAPILL menghitung arus jenuh, kapasitas, DJ, antrian, dan tundaan setiap pendekat terlindung
+func HitungSimpangAPILL(location Location, gerakan []SimpangArusGerakan) *SimpangAPILLKinerja {
+	cfg := location.Simpang
+	c := cfg.WaktuSiklus
+	kinerja := &SimpangAPILLKinerja{
+		WaktuSiklus:       c,
+		Fase:              []SimpangFaseKinerja{},
+		PeluangBebanLebih: cfg.peluangBebanLebih(),
+		Pendekat:          []SimpangPendekatKinerja{},
+	}
+	fuk := GetFUKSimpang(location.Ukuran_kota)
+
+	for _, p := range cfg.Pendekat {
+		var gerakanPendekat []SimpangArusGerakan
+		var bkijt float64
+		for _, g := range gerakan {
+			if g.Pendekat != p.Kode {
+				continue
+			}
+			if p.BelokKiriLangsung && g.Gerakan == GerakanBelokKiri {
+				bkijt += g.ArusSkr
+				continue
+			}
+			gerakanPendekat = append(gerakanPendekat, g)
+		}
+		q, rbki, rbka, rktb := rasioGerakan(gerakanPendekat)
+
+		pk := SimpangPendekatKinerja{
+			Kode:         p.Kode,
+			Nama:         p.Nama,
+			Arus:         q,
+			ArusBKiJT:    bkijt,
+			RBKi:         rbki,
+			RBKa:         rbka,
+			RKTB:         rktb,
+			LebarEfektif: p.LebarMasuk,
+			FUK:          fuk,
+			FHS:          GetFHSSimpang(cfg.Lingkungan, location.Kelas_hambatan, rktb),
+			FBKa:         1 + 0.26*rbka,
+			FBKi:         1 - 0.16*rbki,
+			WaktuHijau:   p.WaktuHijau,
+		}
+		if pk.LebarEfektif <= 0 {
+			pk.LebarEfektif = p.LebarPendekat
+		}
+		pk.ArusJenuhDasar = 600 * pk.LebarEfektif
+		pk.ArusJenuh = pk.ArusJenuhDasar * pk.FUK * pk.FHS * pk.FBKa * pk.FBKi
+		if pk.ArusJenuh > 0 {
+			pk.RasioArus = q / pk.ArusJenuh
+		}
+		pk.Kapasitas = pk.ArusJenuh * p.WaktuHijau / c
+		pk.DerajatKejenuhan = HitungDerajatKejenuhanPKJI(q, pk.Kapasitas)
+
+		// Antrian: sisa fase sebelumnya (NQ1) dan yang datang selama merah (NQ2)
+		rh := p.WaktuHijau / c
+		dj := pk.DerajatKejenuhan
+		if dj > 0.5 && pk.Kapasitas > 0 {
+			pk.AntrianSisa = 0.25 * pk.Kapasitas * ((dj - 1) + math.Sqrt((dj-1)*(dj-1)+8*(dj-0.5)/pk.Kapasitas))
+		}
+		djTerbatas := math.Min(dj, 1)
+		pk.AntrianMerah = c * (1 - rh) / (1 - rh*djTerbatas) * q / 3600
+		pk.JumlahAntrian = pk.AntrianSisa + pk.AntrianMerah
+		pk.JumlahAntrianMaks = jumlahAntrianMaks(pk.JumlahAntrian, kinerja.PeluangBebanLebih)
+		pk.PanjangAntrian = pk.JumlahAntrianMaks * 20 / pk.LebarEfektif
+
+		if q > 0 {
+			pk.RasioKendaraanHenti = math.Min(0.9*pk.JumlahAntrian/(q*c)*3600, 1)
+			pk.TundaanLaluLintas = c * 0.5 * (1 - rh) * (1 - rh) / (1 - rh*djTerbatas)
+			if pk.Kapasitas > 0 {
+				pk.TundaanLaluLintas += pk.AntrianSisa * 3600 / pk.Kapasitas
+			}
+			pk.TundaanGeometrik = (1-pk.RasioKendaraanHenti)*(rbki+rbka)*6 + pk.RasioKendaraanHenti*4
+			pk.Tundaan = pk.TundaanLaluLintas + pk.TundaanGeometrik
+		}
+
+		kinerja.Pendekat = append(kinerja.Pendekat, pk)
+	}
+
+	// Pendekat yang hijau bersamaan berbagi waktu hijau, sehingga hanya rasio arus tertinggi setiap fase yang dijumlahkan
+	for _, pendekatFase := range cfg.faseList() {
+		fase := SimpangFaseKinerja{Pendekat: pendekatFase}
+		for _, pk := range kinerja.Pendekat {
+			if containsString(pendekatFase, pk.Kode) && (fase.PendekatKritis == "" || pk.RasioArus > fase.RasioArusKritis) {
+				fase.PendekatKritis, fase.RasioArusKritis = pk.Kode, pk.RasioArus
+			}
+		}
+		kinerja.RasioArusSimp += fase.RasioArusKritis
+		kinerja.Fase = append(kinerja.Fase, fase)
+	}
+
+	if cfg.WaktuHilang > 0 && kinerja.RasioArusSimp < 1 {
+		kinerja.SiklusOptimum = (1.5*cfg.WaktuHilang + 5) / (1 - kinerja.RasioArusSimp)
+	}
+	return kinerja
+}
+
+// BuildSimpangAnalysis menghitung analisis simpang dari dataset yang sudah dimuat (tanpa ID dan tanpa menyimpan)
+func BuildSimpangAnalysis(location *Location, startTime, endTime time.Time, dataset *AnalysisDataset) (*SimpangAnalysis, error) {
+	if !location.IsSimpang() || location.Simpang == nil {
+		return nil, fmt.Errorf("lokasi %s bukan simpang atau belum memiliki konfigurasi simpang", location.ID)
+	}
+	trafficDataList := dataset.Data
+
+	standard, err := ResolveCapacityStandard(PKJI2023Standard{}, *location, trafficDataList)
+	if err != nil {
+		return nil, err
+	}
+
+	jumlahHari := int(math.Ceil(endTime.Sub(startTime).Hours() / 24))
+	if jumlahHari < 1 {
+		jumlahHari = 1
+	}
+
+	analysis := &SimpangAnalysis{
+		LokasiID:             location.ID,
+		NamaLokasi:           location.Nama_lokasi,
+		JenisLokasi:          location.Jenis_lokasi,
+		TipeSimpang:          location.Simpang.TipeSimpang,
+		Tanggal:              startTime,
+		PeriodeHari:          jumlahHari,
+		Timestamp:            time.Now().Add(7 * time.Hour),
+		Gerakan:              []SimpangArusGerakan{},
+		IntervalDikecualikan: dataset.IntervalDikecualikan,
+		Kelengkapan:          dataset.Kelengkapan,
+	}
+	if len(trafficDataList) == 0 {
+		analysis.Keterangan = "Tidak ada data traffic untuk periode yang diminta"
+		return analysis, nil
+	}
+
+	// Jam puncak ditentukan dari arus ekivalen PKJI seluruh zona yang dipetakan
+	var dataDipetakan []TrafficData
+	for _, td := range trafficDataList {
+		var zonas []TrafficZonaArahData
+		for _, za := range td.ZonaArahData {
+			if _, ok := location.Simpang.gerakanZona(za.IDZonaArah); ok {
+				zonas = append(zonas, za)
+			}
+		}
+		if len(zonas) > 0 {
+			mapped := td
+			mapped.ZonaArahData = zonas
+			dataDipetakan = append(dataDipetakan, mapped)
+		}
+	}
+	peak := HitungPeakHour(standard, dataDipetakan, location.Tipe_lokasi)
+	analysis.PeakHour = &peak
+	analysis.JamPuncak = peak.Puncak.JamPuncak
+
+	gerakan, tidakDipetakan := hitungArusGerakan(standard, *location, trafficDataList, peak)
+	analysis.Gerakan = gerakan
+	analysis.ZonaTidakDipetakan = tidakDipetakan
+	analysis.ArusTotal, analysis.RBKi, analysis.RBKa, analysis.RKTB = rasioGerakan(gerakan)
+	analysis.TabelEkivalen = standard.TabelDipakai()
+
+	if len(tidakDipetakan) > 0 {
+		analysis.Catatan = append(analysis.Catatan, fmt.Sprintf("%d zona arah belum dipetakan ke gerakan dan tidak dihitung", len(tidakDipetakan)))
+	}
+	if len(dataDipetakan) == 0 {
+		analysis.Keterangan = "Tidak ada zona arah yang dipetakan ke gerakan simpang"
+		return analysis, nil
+	}
+
+	switch location.Jenis_lokasi {
+	case JenisLokasiSimpangTakBersinyal:
+		kinerja, dj, tundaan := HitungSimpangTakBersinyal(*location, gerakan)
+		analysis.TakBersinyal = kinerja
+		analysis.Kapasitas = kinerja.Kapasitas
+		analysis.DerajatKejenuhan = dj
+		analysis.Tundaan = tundaan
+
+	case JenisLokasiSimpangAPILL:
+		kinerja := HitungSimpangAPILL(*location, gerakan)
+		analysis.APILL = kinerja
+
+		totalArus, totalTundaan := 0.0, 0.0
+		for _, p := range kinerja.Pendekat {
+			analysis.Kapasitas += p.Kapasitas
+			analysis.DerajatKejenuhan = math.Max(analysis.DerajatKejenuhan, p.DerajatKejenuhan)
+			analysis.PanjangAntrianMaks = math.Max(analysis.PanjangAntrianMaks, p.PanjangAntrian)
+			totalArus += p.Arus
+			totalTundaan += p.Arus * p.Tundaan
+		}
+		if totalArus > 0 {
+			analysis.Tundaan = totalTundaan / totalArus
+		}
+		if kinerja.RasioArusSimp >= 1 {
+			analysis.Catatan = append(analysis.Catatan, fmt.Sprintf("Σ rasio arus kritis %d fase ≥ 1, simpang lewat jenuh untuk pengaturan fase saat ini", len(kinerja.Fase)))
+		}
+	}
+
+	analysis.TingkatPelayanan, analysis.Keterangan = GetTingkatPelayananSimpang(location.Jenis_lokasi, analysis.Tundaan, analysis.DerajatKejenuhan)
+	if analysis.DerajatKejenuhan > 1 {
+		analysis.Catatan = append(analysis.Catatan, fmt.Sprintf("DJ %.2f melebihi kapasitas, tingkat pelayanan F", analysis.DerajatKejenuhan))
+	}
+	return analysis, nil
+}
+
+func NextSimpangAnalysisID() (string, error) {
+	collection := database.DB.Collection("simpang_analysis")
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	var last SimpangAnalysis
+	err := collection.FindOne(context.Background(), bson.M{}, findOptions).Decode(&last)
+
+	if err != nil {
+		return "SIMP-00001", nil
+	}
+
+	var lastNum int
+	fmt.Sscanf(last.ID, "SIMP-%d", &lastNum)
+	return fmt.Sprintf("SIMP-%05d", lastNum+1), nil
+}
+
+func CalculateSimpangRealtime(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*SimpangAnalysis, error) {
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, err := LoadTrafficDataForAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildSimpangAnalysis(location, startTime, endTime, dataset)
+}
+
+func CreateSimpangAnalysis(lokasiID string, startTime, endTime time.Time, opts AnalysisOptions) (*SimpangAnalysis, error) {
+	location, err := GetLocationByID(lokasiID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, err := LoadTrafficDataForAnalysis(location, startTime, endTime, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dataset.Data) == 0 {
+		return nil, fmt.Errorf("tidak ada data traffic untuk periode yang diminta")
+	}
+
+	analysis, err := BuildSimpangAnalysis(location, startTime, endTime, dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := NextSimpangAnalysisID()
+	if err != nil {
+		return nil, err
+	}
+	analysis.ID = id
+
+	_, err = database.DB.Collection("simpang_analysis").InsertOne(context.Background(), analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+func GetSimpangAnalysisByLokasiID(lokasiID string, limit int64) ([]SimpangAnalysis, error) {
+	collection := database.DB.Collection("simpang_analysis")
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(context.Background(), bson.M{"lokasi_id": lokasiID}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var analyses []SimpangAnalysis
+	if err = cursor.All(context.Background(), &analyses); err != nil {
+		return nil, err
+	}
+
+	return analyses, nil
+}
+
+func GetLatestSimpangAnalysisByLokasiID(lokasiID string) (*SimpangAnalysis, error) {
+	collection := database.DB.Collection("simpang_analysis")
+
+	var analysis SimpangAnalysis
+	err := collection.FindOne(
+		context.Background(),
+		bson.M{"lokasi_id": lokasiID},
+		options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}}),
+	).Decode(&analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &analysis, nil
+}
+
+func GetSimpangAnalysisByID(id string) (*SimpangAnalysis, error) {
+	collection := database.DB.Collection("simpang_analysis")
+
+	var analysis SimpangAnalysis
+	err := collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &analysis, nil
+}
@@ -0,0 +1,298 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func lokasiSimpangTakBersinyalUji() Location {
+	return Location{
+		Jenis_lokasi:   JenisLokasiSimpangTakBersinyal,
+		Kelas_hambatan: "R",
+		Ukuran_kota:    1.5,
+		Simpang: &SimpangConfig{
+			TipeSimpang: "422",
+			Lingkungan:  "KOM",
+			MedianMayor: "tidak_ada",
+			Pendekat: []SimpangPendekat{
+				{Kode: "U", LebarPendekat: 3.5},
+				{Kode: "S", LebarPendekat: 3.5},
+				{Kode: "T", LebarPendekat: 3.5, JalanMayor: true},
+				{Kode: "B", LebarPendekat: 3.5, JalanMayor: true},
+			},
+		},
+	}
+}
+
+// gerakanSimpangUji membagi arus total skr/jam ke pendekat mayor (70%) dan minor dengan rasio belok tetap
+func gerakanSimpangUji(total float64) []SimpangArusGerakan {
+	var gerakan []SimpangArusGerakan
+	for _, p := range []struct {
+		kode  string
+		porsi float64
+	}{{"T", 0.35}, {"B", 0.35}, {"U", 0.15}, {"S", 0.15}} {
+		for _, g := range []struct {
+			gerakan string
+			porsi   float64
+		}{{GerakanBelokKiri, 0.2}, {GerakanLurus, 0.6}, {GerakanBelokKanan, 0.2}} {
+			arus := total * p.porsi * g.porsi
+			gerakan = append(gerakan, SimpangArusGerakan{Pendekat: p.kode, Gerakan: g.gerakan, KR: int(arus), Arus: arus, ArusSkr: arus})
+		}
+	}
+	return gerakan
+}
+
+func TestHitungSimpangTakBersinyalLewatJenuh(t *testing.T) {
+	location := lokasiSimpangTakBersinyalUji()
+	dasar, dj0, _ := HitungSimpangTakBersinyal(location, gerakanSimpangUji(1000))
+	if dasar.Kapasitas <= 0 || dj0 <= 0 {
+		t.Fatalf("kapasitas %.1f, DJ %.3f", dasar.Kapasitas, dj0)
+	}
+
+	sebelumnya := 0.0
+	for _, target := range []float64{0.3, 0.6, 0.9, 1.0, 1.2, 1.343, 1.5, 2.5} {
+		kinerja, dj, tundaan := HitungSimpangTakBersinyal(location, gerakanSimpangUji(1000*target/dj0))
+		if math.Abs(dj-target) > 1e-9 {
+			t.Fatalf("DJ = %.4f, ingin %.4f", dj, target)
+		}
+		if math.IsInf(tundaan, 0) || math.IsNaN(tundaan) || kinerja.TundaanLaluLintas <= 0 || kinerja.TundaanMayor <= 0 {
+			t.Fatalf("DJ %.3f: tundaan %.2f, TLL %.2f, TMA %.2f harus positif dan hingga", dj, tundaan,
+				kinerja.TundaanLaluLintas, kinerja.TundaanMayor)
+		}
+		if tundaan < sebelumnya {
+			t.Fatalf("DJ %.3f: tundaan %.2f turun dari %.2f", dj, tundaan, sebelumnya)
+		}
+		sebelumnya = tundaan
+
+		los, _ := GetTingkatPelayananSimpang(JenisLokasiSimpangTakBersinyal, tundaan, dj)
+		if dj > 1 && los != "F" {
+			t.Fatalf("DJ %.3f: LoS %s, ingin F", dj, los)
+		}
+	}
+}
+
+func TestGetTingkatPelayananSimpang(t *testing.T) {
+	tests := []struct {
+		jenis   string
+		tundaan float64
+		dj      float64
+		want    string
+	}{
+		{JenisLokasiSimpangTakBersinyal, 4, 0.3, "A"},
+		{JenisLokasiSimpangTakBersinyal, 10, 0.6, "B"},
+		{JenisLokasiSimpangTakBersinyal, 25, 0.9, "D"},
+		{JenisLokasiSimpangTakBersinyal, 50, 0.95, "F"},
+		{JenisLokasiSimpangTakBersinyal, 3, 1.2, "F"},
+		{JenisLokasiSimpangAPILL, 15, 0.7, "B"},
+		{JenisLokasiSimpangAPILL, 60, 0.9, "E"},
+		{JenisLokasiSimpangAPILL, 20, 1.05, "F"},
+	}
+	for _, tt := range tests {
+		if got, _ := GetTingkatPelayananSimpang(tt.jenis, tt.tundaan, tt.dj); got != tt.want {
+			t.Errorf("%s tundaan %.0f DJ %.2f: LoS %s, ingin %s", tt.jenis, tt.tundaan, tt.dj, got, tt.want)
+		}
+	}
+}
+
+// Kapasitas simpang 422 dengan arus 1000 skr/jam: C0 2900, FLP 0.70 + 0.0866 × 3.5, FM 1, FUK 1, FHS 0.94 (KOM sedang),
+// FBKi 0.84 + 1.61 × 0.2, FBKa 1 (4 lengan), FRmi 1.19p² − 1.19p + 1.19 dengan pMI 0.3
+func TestHitungSimpangTakBersinyalKapasitas(t *testing.T) {
+	kinerja, dj, _ := HitungSimpangTakBersinyal(lokasiSimpangTakBersinyalUji(), gerakanSimpangUji(1000))
+
+	faktor := []struct {
+		nama string
+		got  float64
+		want float64
+	}{
+		{"C0", kinerja.KapasitasDasar, 2900},
+		{"FLP", kinerja.FLP, 1.0031},
+		{"FM", kinerja.FM, 1.0},
+		{"FUK", kinerja.FUK, 1.0},
+		{"FHS", kinerja.FHS, 0.94},
+		{"FBKi", kinerja.FBKi, 1.162},
+		{"FBKa", kinerja.FBKa, 1.0},
+		{"FRmi", kinerja.FRmi, 0.9401},
+		{"RasioMinor", kinerja.RasioMinor, 0.3},
+		{"Kapasitas", kinerja.Kapasitas, 2987.1034},
+		{"DJ", dj, 0.33477},
+	}
+	for _, f := range faktor {
+		if math.Abs(f.got-f.want) > 1e-4 {
+			t.Errorf("%s = %.5f, ingin %.5f", f.nama, f.got, f.want)
+		}
+	}
+}
+
+func TestHitungSimpangAPILL(t *testing.T) {
+	location := lokasiSimpangTakBersinyalUji()
+	location.Jenis_lokasi = JenisLokasiSimpangAPILL
+	location.Simpang.WaktuSiklus = 100
+	location.Simpang.WaktuHilang = 12
+	for i := range location.Simpang.Pendekat {
+		location.Simpang.Pendekat[i].WaktuHijau = 30
+	}
+
+	kinerja := HitungSimpangAPILL(location, gerakanSimpangUji(1000))
+	if len(kinerja.Pendekat) != 4 {
+		t.Fatalf("%d pendekat, ingin 4", len(kinerja.Pendekat))
+	}
+	// S = 600 × 3.5 × FUK 1 × FHS 0.94 × FBKa (1 + 0.26 × 0.2) × FBKi (1 − 0.16 × 0.2), C = S × 30/100
+	const arusJenuh = 2010.1953
+	for _, pk := range kinerja.Pendekat {
+		arus := 150.0
+		if pk.Kode == "T" || pk.Kode == "B" {
+			arus = 350
+		}
+		if math.Abs(pk.ArusJenuh-arusJenuh) > 1e-3 || math.Abs(pk.Kapasitas-arusJenuh*0.3) > 1e-3 {
+			t.Errorf("pendekat %s: S %.4f C %.4f, ingin %.4f dan %.4f", pk.Kode, pk.ArusJenuh, pk.Kapasitas, arusJenuh, arusJenuh*0.3)
+		}
+		if math.Abs(pk.DerajatKejenuhan-arus/(arusJenuh*0.3)) > 1e-6 {
+			t.Errorf("pendekat %s: DJ %.4f, ingin %.4f", pk.Kode, pk.DerajatKejenuhan, arus/(arusJenuh*0.3))
+		}
+		if pk.Tundaan <= 0 || pk.JumlahAntrian <= 0 {
+			t.Errorf("pendekat %s: tundaan %.2f antrian %.2f harus positif", pk.Kode, pk.Tundaan, pk.JumlahAntrian)
+		}
+		// Panjang antrian dari NQmax pada POL 5%, bukan dari NQ rata-rata
+		if pk.JumlahAntrianMaks != jumlahAntrianMaks(pk.JumlahAntrian, 5) || pk.JumlahAntrianMaks <= pk.JumlahAntrian ||
+			math.Abs(pk.PanjangAntrian-pk.JumlahAntrianMaks*20/3.5) > 1e-9 {
+			t.Errorf("pendekat %s: NQ %.2f NQmax %.0f PA %.2f", pk.Kode, pk.JumlahAntrian, pk.JumlahAntrianMaks, pk.PanjangAntrian)
+		}
+	}
+	// Tanpa konfigurasi fase setiap pendekat adalah satu fase: IFR = Σ FR semua pendekat
+	if len(kinerja.Fase) != 4 || math.Abs(kinerja.RasioArusSimp-2*(350+150)/arusJenuh) > 1e-6 {
+		t.Errorf("%d fase, IFR %.4f, ingin 4 dan %.4f", len(kinerja.Fase), kinerja.RasioArusSimp, 2*(350+150)/arusJenuh)
+	}
+	// Siklus optimum = (1.5 × 12 + 5) / (1 − IFR)
+	if want := (1.5*12 + 5) / (1 - kinerja.RasioArusSimp); math.Abs(kinerja.SiklusOptimum-want) > 1e-9 {
+		t.Errorf("siklus optimum %.2f, ingin %.2f", kinerja.SiklusOptimum, want)
+	}
+}
+
+// Pendekat berhadapan yang hijau bersamaan hanya menyumbang rasio arus tertinggi fasenya
+func TestHitungSimpangAPILLFase(t *testing.T) {
+	location := lokasiSimpangTakBersinyalUji()
+	location.Jenis_lokasi = JenisLokasiSimpangAPILL
+	location.Simpang.WaktuSiklus = 100
+	location.Simpang.WaktuHilang = 12
+	location.Simpang.Fase = [][]string{{"T", "B"}, {"U", "S"}}
+	for i := range location.Simpang.Pendekat {
+		location.Simpang.Pendekat[i].WaktuHijau = 40
+	}
+	if err := location.Simpang.Validate(location.Jenis_lokasi); err != nil {
+		t.Fatal(err)
+	}
+
+	// Arus T lebih besar dari B agar pendekat kritis fase mayor jelas
+	gerakan := gerakanSimpangUji(1000)
+	for i := range gerakan {
+		if gerakan[i].Pendekat == "T" {
+			gerakan[i].ArusSkr *= 1.2
+		}
+	}
+	kinerja := HitungSimpangAPILL(location, gerakan)
+
+	const arusJenuh = 2010.1953
+	frMayor, frMinor := 420/arusJenuh, 150/arusJenuh
+	if len(kinerja.Fase) != 2 || kinerja.Fase[0].PendekatKritis != "T" || math.Abs(kinerja.Fase[0].RasioArusKritis-frMayor) > 1e-6 ||
+		math.Abs(kinerja.Fase[1].RasioArusKritis-frMinor) > 1e-6 {
+		t.Fatalf("fase %+v, ingin FR kritis %.4f (T) dan %.4f", kinerja.Fase, frMayor, frMinor)
+	}
+	if math.Abs(kinerja.RasioArusSimp-(frMayor+frMinor)) > 1e-6 {
+		t.Fatalf("IFR %.4f, ingin %.4f", kinerja.RasioArusSimp, frMayor+frMinor)
+	}
+	if want := (1.5*12 + 5) / (1 - frMayor - frMinor); math.Abs(kinerja.SiklusOptimum-want) > 1e-4 {
+		t.Fatalf("siklus optimum %.2f, ingin %.2f", kinerja.SiklusOptimum, want)
+	}
+
+	// Arus yang lewat jenuh jika dijumlahkan per pendekat tetap di bawah jenuh dengan dua fase
+	for i := range gerakan {
+		gerakan[i].ArusSkr *= 2
+	}
+	kinerja = HitungSimpangAPILL(location, gerakan)
+	jumlahSemua := 0.0
+	for _, pk := range kinerja.Pendekat {
+		jumlahSemua += pk.RasioArus
+	}
+	if jumlahSemua < 1 || kinerja.RasioArusSimp >= 1 || kinerja.SiklusOptimum <= 0 {
+		t.Fatalf("Σ FR semua pendekat %.3f, IFR %.3f, siklus optimum %.1f", jumlahSemua, kinerja.RasioArusSimp, kinerja.SiklusOptimum)
+	}
+}
+
+func TestValidateSimpangFase(t *testing.T) {
+	tests := []struct {
+		nama  string
+		fase  [][]string
+		hijau map[string]float64
+		gagal bool
+	}{
+		{"tanpa fase", nil, nil, false},
+		{"dua fase", [][]string{{"T", "B"}, {"U", "S"}}, nil, false},
+		{"pendekat tidak ada", [][]string{{"T", "B"}, {"U", "X"}}, nil, true},
+		{"pendekat di dua fase", [][]string{{"T", "B"}, {"U", "S", "T"}}, nil, true},
+		{"pendekat belum masuk fase", [][]string{{"T", "B"}, {"U"}}, nil, true},
+		{"fase kosong", [][]string{{"T", "B"}, {"U", "S"}, {}}, nil, true},
+		{"waktu hijau berbeda dalam satu fase", [][]string{{"T", "B"}, {"U", "S"}}, map[string]float64{"B": 35}, true},
+	}
+	for _, tt := range tests {
+		location := lokasiSimpangTakBersinyalUji()
+		cfg := location.Simpang
+		cfg.WaktuSiklus, cfg.WaktuHilang, cfg.Fase = 100, 12, tt.fase
+		for i := range cfg.Pendekat {
+			cfg.Pendekat[i].WaktuHijau = 40
+			if g, ok := tt.hijau[cfg.Pendekat[i].Kode]; ok {
+				cfg.Pendekat[i].WaktuHijau = g
+			}
+		}
+		if err := cfg.Validate(JenisLokasiSimpangAPILL); (err != nil) != tt.gagal {
+			t.Errorf("%s: error %v, ingin gagal %v", tt.nama, err, tt.gagal)
+		}
+	}
+}
+
+// NQmax pada grafik PKJI didekati kuantil Poisson dengan rata-rata NQ
+func TestJumlahAntrianMaks(t *testing.T) {
+	tests := []struct {
+		nq, pol float64
+		ingin   float64
+	}{
+		{0, 5, 0},
+		{1, 5, 3},
+		{2.4, 5, 5},
+		{5, 5, 9},
+		{10, 10, 14},
+		{10, 5, 15},
+		{10, 1, 18},
+		{40, 5, 51},
+		{900, 5, 950},
+	}
+	for _, tt := range tests {
+		if got := jumlahAntrianMaks(tt.nq, tt.pol); got != tt.ingin {
+			t.Errorf("NQ %.1f POL %.0f%%: NQmax %.0f, ingin %.0f", tt.nq, tt.pol, got, tt.ingin)
+		}
+	}
+}
+
+func TestSimpangFaktorKoreksi(t *testing.T) {
+	tests := []struct {
+		nama string
+		got  float64
+		want float64
+	}{
+		{"C0 322", GetC0Simpang("322"), 2700},
+		{"C0 344", GetC0Simpang("344"), 3200},
+		{"C0 444", GetC0Simpang("444"), 3400},
+		{"FM lebar", GetFM("lebar"), 1.20},
+		{"FUK 0.05", GetFUKSimpang(0.05), 0.82},
+		{"FUK 4", GetFUKSimpang(4), 1.05},
+		{"FHS KIM tinggi RKTB 0.1", GetFHSSimpang("KIM", "H", 0.1), 0.96 * 0.9},
+		{"FHS RKTB dibatasi 0.25", GetFHSSimpang("AT", "L", 0.6), 0.75},
+		{"FBKa 3 lengan", GetFBKaSimpang(3, 0.2), 1.09 - 0.922*0.2},
+		{"FRmi 322 pMI 0.7", GetFRmi("322", 0.7), -0.595*0.49 + 0.595*0.7 + 0.74},
+		{"FRmi 422 pMI dibatasi 0.9", GetFRmi("422", 0.95), 1.19*0.81 - 1.19*0.9 + 1.19},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %.5f, ingin %.5f", tt.nama, tt.got, tt.want)
+		}
+	}
+}
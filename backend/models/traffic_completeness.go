@@ -0,0 +1,345 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Metode pengisian interval yang hilang
+const (
+	GapFillNone     = "none"      // gap hanya dilaporkan
+	GapFillLinear   = "linear"    // interpolasi linier antara interval sebelum dan sesudah gap
+	GapFillLastWeek = "last_week" // profil jam yang sama satu minggu sebelumnya
+)
+
+var GapFillOptions = []string{GapFillNone, GapFillLinear, GapFillLastWeek}
+
+var defaultGapFillMethod = GapFillNone
+
+func IsValidGapFillMethod(value string) bool {
+	for _, v := range GapFillOptions {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDefaultGapFillMethod mengatur metode pengisian gap untuk analisis terjadwal dan request tanpa parameter gap_fill
+func SetDefaultGapFillMethod(method string) {
+	if IsValidGapFillMethod(method) {
+		defaultGapFillMethod = method
+	}
+}
+
+// Opsi pengambilan traffic data untuk analisis MKJI/PKJI/LHR
+type AnalysisOptions struct {
+	ExcludeFlagged  bool   // kecualikan interval dengan kualitas data flagged
+	GapFill         string // metode pengisian interval hilang (GapFill*)
+	HambatanSamping string // sumber kelas hambatan samping (HambatanSampingKonfigurasi/Observasi)
+}
+
+// DefaultAnalysisOptions mengikuti konfigurasi QUALITY_EXCLUDE_FLAGGED, GAP_FILL_METHOD, dan SIDE_FRICTION_SOURCE
+func DefaultAnalysisOptions() AnalysisOptions {
+	return AnalysisOptions{
+		ExcludeFlagged:  dataQualityConfig.ExcludeFlaggedAnalysis,
+		GapFill:         defaultGapFillMethod,
+		HambatanSamping: defaultHambatanSampingSource,
+	}
+}
+
+// Rentang interval berurutan yang tidak memiliki data
+type IntervalGap struct {
+	Start            time.Time `bson:"start" json:"start"`
+	End              time.Time `bson:"end" json:"end"`
+	IntervalHilang   int       `bson:"interval_hilang" json:"interval_hilang"`
+	IntervalEstimasi int       `bson:"interval_estimasi" json:"interval_estimasi"`
+}
+
+// Kelengkapan data traffic terhadap interval lokasi
+type KelengkapanData struct {
+	IntervalDetik      int           `bson:"interval_detik" json:"interval_detik"`
+	IntervalDiharapkan int           `bson:"interval_diharapkan" json:"interval_diharapkan"`
+	IntervalTersedia   int           `bson:"interval_tersedia" json:"interval_tersedia"`
+	IntervalHilang     int           `bson:"interval_hilang" json:"interval_hilang"`
+	IntervalEstimasi   int           `bson:"interval_estimasi" json:"interval_estimasi"`
+	Persentase         float64       `bson:"persentase" json:"persentase"` // interval tersedia / diharapkan (%)
+	MetodeIsiGap       string        `bson:"metode_isi_gap" json:"metode_isi_gap"`
+	Gaps               []IntervalGap `bson:"gaps" json:"gaps"`
+}
+
+// Traffic data yang siap dianalisis beserta informasi kelengkapannya
+type AnalysisDataset struct {
+	Data                 []TrafficData
+	IntervalDikecualikan int
+	Kelengkapan          *KelengkapanData
+}
+
+// LoadTrafficDataForAnalysis mengambil traffic data lokasi, mengecualikan interval flagged (opsional),
+// mendeteksi interval yang hilang, lalu mengisinya sesuai metode. Interval hasil estimasi ditandai Estimated.
+func LoadTrafficDataForAnalysis(location *Location, startTime, endTime time.Time, opts AnalysisOptions) (*AnalysisDataset, error) {
+	if opts.GapFill == "" {
+		opts.GapFill = GapFillNone
+	}
+	if !IsValidGapFillMethod(opts.GapFill) {
+		return nil, fmt.Errorf("metode gap_fill tidak valid: %s", opts.GapFill)
+	}
+
+	trafficDataList, excluded, err := GetTrafficDataForAnalysis(location.ID, startTime, endTime, opts.ExcludeFlagged)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := expectedIntervalDuration(location, trafficDataList)
+	buckets := bucketTrafficData(trafficDataList, interval)
+	kelengkapan := detectTrafficGaps(location, buckets, startTime, endTime, interval)
+	kelengkapan.MetodeIsiGap = opts.GapFill
+
+	var estimated []TrafficData
+	switch opts.GapFill {
+	case GapFillLinear:
+		estimated = fillGapsLinear(location, buckets, kelengkapan, interval)
+	case GapFillLastWeek:
+		lastWeek, _, err := GetTrafficDataForAnalysis(location.ID, startTime.AddDate(0, 0, -7), endTime.AddDate(0, 0, -7), opts.ExcludeFlagged)
+		if err != nil {
+			return nil, err
+		}
+		estimated = fillGapsLastWeek(location, bucketTrafficData(lastWeek, interval), kelengkapan, interval)
+	}
+
+	for _, gap := range kelengkapan.Gaps {
+		kelengkapan.IntervalEstimasi += gap.IntervalEstimasi
+	}
+
+	return &AnalysisDataset{
+		Data:                 append(trafficDataList, estimated...),
+		IntervalDikecualikan: excluded,
+		Kelengkapan:          kelengkapan,
+	}, nil
+}
+
+// expectedIntervalDuration memakai interval lokasi; jika kamera mengirim dengan interval lebih panjang,
+// interval kamera yang paling sering dipakai agar tidak muncul gap palsu
+func expectedIntervalDuration(location *Location, trafficDataList []TrafficData) time.Duration {
+	intervalDetik := location.Interval
+
+	frekuensi := make(map[int]int)
+	modus, modusCount := 0, 0
+	for _, td := range trafficDataList {
+		if td.IntervalMenit <= 0 {
+			continue
+		}
+		frekuensi[td.IntervalMenit]++
+		if frekuensi[td.IntervalMenit] > modusCount {
+			modus, modusCount = td.IntervalMenit, frekuensi[td.IntervalMenit]
+		}
+	}
+	if modus*60 > intervalDetik {
+		intervalDetik = modus * 60
+	}
+	if intervalDetik <= 0 {
+		intervalDetik = 300
+	}
+	return time.Duration(intervalDetik) * time.Second
+}
+
+// bucketTrafficData mengelompokkan traffic data ke bucket interval (key = awal bucket dalam UTC).
+// Key map time.Time ikut membandingkan lokasi zona waktu, sehingga semua key dan lookup dinormalisasi ke UTC.
+func bucketTrafficData(trafficDataList []TrafficData, interval time.Duration) map[time.Time][]TrafficData {
+	buckets := make(map[time.Time][]TrafficData)
+	for _, td := range trafficDataList {
+		key := td.Timestamp.UTC().Truncate(interval)
+		buckets[key] = append(buckets[key], td)
+	}
+	return buckets
+}
+
+// detectTrafficGaps membandingkan bucket yang ada dengan bucket yang diharapkan pada rentang waktu.
+// Bucket yang belum selesai (setelah waktu lokal lokasi saat ini) tidak dihitung.
+func detectTrafficGaps(location *Location, buckets map[time.Time][]TrafficData, startTime, endTime time.Time, interval time.Duration) *KelengkapanData {
+	kelengkapan := &KelengkapanData{
+		IntervalDetik: int(interval.Seconds()),
+		Gaps:          []IntervalGap{},
+	}
+
+	startTime, endTime = startTime.UTC(), endTime.UTC()
+	nowLocal := time.Now().UTC().Add(time.Duration(location.Zona_waktu * float64(time.Hour)))
+	if endTime.After(nowLocal) {
+		endTime = nowLocal
+	}
+
+	var current *IntervalGap
+	for bucket := startTime.Truncate(interval); !bucket.Add(interval).After(endTime); bucket = bucket.Add(interval) {
+		if bucket.Before(startTime) {
+			continue
+		}
+		kelengkapan.IntervalDiharapkan++
+		if len(buckets[bucket]) > 0 {
+			kelengkapan.IntervalTersedia++
+			if current != nil {
+				kelengkapan.Gaps = append(kelengkapan.Gaps, *current)
+				current = nil
+			}
+			continue
+		}
+
+		kelengkapan.IntervalHilang++
+		if current == nil {
+			current = &IntervalGap{Start: bucket}
+		}
+		current.End = bucket.Add(interval)
+		current.IntervalHilang++
+	}
+	if current != nil {
+		kelengkapan.Gaps = append(kelengkapan.Gaps, *current)
+	}
+
+	if kelengkapan.IntervalDiharapkan > 0 {
+		kelengkapan.Persentase = math.Round(float64(kelengkapan.IntervalTersedia)/float64(kelengkapan.IntervalDiharapkan)*10000) / 100
+	}
+	return kelengkapan
+}
+
+// Jumlah kendaraan per zona arah dan kelas dalam satu bucket
+type bucketCount struct {
+	namaArah map[string]string
+	kelas    map[string]map[int]TrafficKelasDetail
+}
+
+func sumBucket(trafficDataList []TrafficData) bucketCount {
+	count := bucketCount{namaArah: make(map[string]string), kelas: make(map[string]map[int]TrafficKelasDetail)}
+	for _, td := range trafficDataList {
+		for _, za := range td.ZonaArahData {
+			count.namaArah[za.IDZonaArah] = za.NamaArah
+			if count.kelas[za.IDZonaArah] == nil {
+				count.kelas[za.IDZonaArah] = make(map[int]TrafficKelasDetail)
+			}
+			for _, kd := range za.KelasData {
+				existing := count.kelas[za.IDZonaArah][kd.Kelas]
+				kd.JumlahKendaraan += existing.JumlahKendaraan
+				count.kelas[za.IDZonaArah][kd.Kelas] = kd
+			}
+		}
+	}
+	return count
+}
+
+// buildEstimatedTrafficData menyusun traffic data estimasi dari jumlah kendaraan per zona dan kelas
+func buildEstimatedTrafficData(location *Location, bucket time.Time, interval time.Duration, count bucketCount, metode string) TrafficData {
+	zonaIDs := make([]string, 0, len(count.kelas))
+	for id := range count.kelas {
+		zonaIDs = append(zonaIDs, id)
+	}
+	sort.Strings(zonaIDs)
+
+	var zonaArahData []TrafficZonaArahData
+	totalKendaraan := 0
+	for _, id := range zonaIDs {
+		kelasList := make([]int, 0, len(count.kelas[id]))
+		for kelas := range count.kelas[id] {
+			kelasList = append(kelasList, kelas)
+		}
+		sort.Ints(kelasList)
+
+		zona := TrafficZonaArahData{IDZonaArah: id, NamaArah: count.namaArah[id], KelasData: []TrafficKelasDetail{}}
+		for _, kelas := range kelasList {
+			kd := count.kelas[id][kelas]
+			zona.KelasData = append(zona.KelasData, kd)
+			zona.TotalKendaraan += kd.JumlahKendaraan
+		}
+		zonaArahData = append(zonaArahData, zona)
+		totalKendaraan += zona.TotalKendaraan
+	}
+
+	return TrafficData{
+		LokasiID:       location.ID,
+		NamaLokasi:     location.Nama_lokasi,
+		TipeLokasi:     location.Tipe_lokasi,
+		Timestamp:      bucket,
+		IntervalEnd:    bucket.Add(interval),
+		ZonaArahData:   zonaArahData,
+		TotalKendaraan: totalKendaraan,
+		IntervalMenit:  int(interval.Minutes()),
+		Estimated:      true,
+		MetodeEstimasi: metode,
+	}
+}
+
+// fillGapsLinear menginterpolasi jumlah kendaraan per zona dan kelas antara bucket sebelum dan sesudah gap.
+// Gap di awal atau akhir rentang tidak diisi karena tidak memiliki dua titik acuan.
+func fillGapsLinear(location *Location, buckets map[time.Time][]TrafficData, kelengkapan *KelengkapanData, interval time.Duration) []TrafficData {
+	var estimated []TrafficData
+	for i := range kelengkapan.Gaps {
+		gap := &kelengkapan.Gaps[i]
+		before, okBefore := buckets[gap.Start.Add(-interval)]
+		after, okAfter := buckets[gap.End]
+		if !okBefore || !okAfter {
+			continue
+		}
+		prev, next := sumBucket(before), sumBucket(after)
+
+		// Gabungan zona dan kelas dari kedua sisi; yang tidak ada di satu sisi dianggap 0
+		zonaKelas := make(map[string]map[int]TrafficKelasDetail)
+		namaArah := make(map[string]string)
+		for _, side := range []bucketCount{prev, next} {
+			for id, kelasMap := range side.kelas {
+				namaArah[id] = side.namaArah[id]
+				if zonaKelas[id] == nil {
+					zonaKelas[id] = make(map[int]TrafficKelasDetail)
+				}
+				for kelas, kd := range kelasMap {
+					zonaKelas[id][kelas] = kd
+				}
+			}
+		}
+
+		n := gap.IntervalHilang
+		for k := 1; k <= n; k++ {
+			ratio := float64(k) / float64(n+1)
+			count := bucketCount{namaArah: namaArah, kelas: make(map[string]map[int]TrafficKelasDetail)}
+			for id, kelasMap := range zonaKelas {
+				count.kelas[id] = make(map[int]TrafficKelasDetail)
+				for kelas, kd := range kelasMap {
+					a := float64(prev.kelas[id][kelas].JumlahKendaraan)
+					b := float64(next.kelas[id][kelas].JumlahKendaraan)
+					kd.JumlahKendaraan = int(math.Round(a + (b-a)*ratio))
+					kd.KecepatanRataRata = interpolateSpeed(prev.kelas[id][kelas], next.kelas[id][kelas], ratio)
+					count.kelas[id][kelas] = kd
+				}
+			}
+			bucket := gap.Start.Add(time.Duration(k-1) * interval)
+			estimated = append(estimated, buildEstimatedTrafficData(location, bucket, interval, count, GapFillLinear))
+		}
+		gap.IntervalEstimasi = n
+	}
+	return estimated
+}
+
+func interpolateSpeed(a, b TrafficKelasDetail, ratio float64) float64 {
+	switch {
+	case a.KecepatanRataRata == 0:
+		return b.KecepatanRataRata
+	case b.KecepatanRataRata == 0:
+		return a.KecepatanRataRata
+	}
+	return a.KecepatanRataRata + (b.KecepatanRataRata-a.KecepatanRataRata)*ratio
+}
+
+// fillGapsLastWeek menyalin jumlah kendaraan pada bucket yang sama tujuh hari sebelumnya
+func fillGapsLastWeek(location *Location, lastWeekBuckets map[time.Time][]TrafficData, kelengkapan *KelengkapanData, interval time.Duration) []TrafficData {
+	var estimated []TrafficData
+	for i := range kelengkapan.Gaps {
+		gap := &kelengkapan.Gaps[i]
+		for bucket := gap.Start; bucket.Before(gap.End); bucket = bucket.Add(interval) {
+			profile, ok := lastWeekBuckets[bucket.AddDate(0, 0, -7)]
+			if !ok {
+				continue
+			}
+			estimated = append(estimated, buildEstimatedTrafficData(location, bucket, interval, sumBucket(profile), GapFillLastWeek))
+			gap.IntervalEstimasi++
+		}
+	}
+	return estimated
+}
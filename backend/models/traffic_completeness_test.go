@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func trafficDataUji(ts time.Time, jumlah int) TrafficData {
+	return TrafficData{
+		Timestamp:      ts,
+		IntervalMenit:  5,
+		TotalKendaraan: jumlah,
+		ZonaArahData: []TrafficZonaArahData{{
+			IDZonaArah: "ZA-1",
+			KelasData:  []TrafficKelasDetail{{Kelas: 1, JumlahKendaraan: jumlah}},
+		}},
+	}
+}
+
+// Data dari Mongo ber-lokasi UTC, periode dari time.Now() ber-lokasi Local: bucket harus tetap cocok
+func TestDetectTrafficGapsLokasiCampuran(t *testing.T) {
+	interval := 5 * time.Minute
+	location := &Location{ID: "LOK-1", Interval: 300}
+	start := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+
+	var data []TrafficData
+	for i := 0; i < 12; i++ {
+		if i == 5 || i == 6 {
+			continue
+		}
+		data = append(data, trafficDataUji(start.Add(time.Duration(i)*interval), 10*(i+1)))
+	}
+	buckets := bucketTrafficData(data, interval)
+
+	zona := []*time.Location{time.Local, time.FixedZone("WIB", 7*3600)}
+	for _, loc := range zona {
+		kelengkapan := detectTrafficGaps(location, buckets, start.In(loc), start.Add(time.Hour).In(loc), interval)
+		if kelengkapan.IntervalDiharapkan != 12 || kelengkapan.IntervalTersedia != 10 || kelengkapan.IntervalHilang != 2 {
+			t.Fatalf("%s: kelengkapan = %d/%d hilang %d, ingin 10/12 hilang 2", loc, kelengkapan.IntervalTersedia,
+				kelengkapan.IntervalDiharapkan, kelengkapan.IntervalHilang)
+		}
+		if len(kelengkapan.Gaps) != 1 || !kelengkapan.Gaps[0].Start.Equal(start.Add(5*interval)) {
+			t.Fatalf("%s: gaps = %+v", loc, kelengkapan.Gaps)
+		}
+
+		estimated := fillGapsLinear(location, buckets, kelengkapan, interval)
+		if len(estimated) != 2 {
+			t.Fatalf("%s: interval estimasi = %d, ingin 2", loc, len(estimated))
+		}
+		// Interpolasi antara 50 (bucket ke-4) dan 80 (bucket ke-7)
+		if estimated[0].TotalKendaraan != 60 || estimated[1].TotalKendaraan != 70 {
+			t.Fatalf("%s: estimasi = %d, %d, ingin 60, 70", loc, estimated[0].TotalKendaraan, estimated[1].TotalKendaraan)
+		}
+		for _, td := range estimated {
+			if td.Timestamp.Location() != time.UTC {
+				t.Fatalf("%s: timestamp estimasi ber-lokasi %s, ingin UTC", loc, td.Timestamp.Location())
+			}
+		}
+	}
+}
+
+func TestFillGapsLastWeekLokasiCampuran(t *testing.T) {
+	interval := 5 * time.Minute
+	location := &Location{ID: "LOK-1", Interval: 300}
+	start := time.Date(2025, 1, 13, 8, 0, 0, 0, time.UTC)
+
+	data := []TrafficData{trafficDataUji(start, 10), trafficDataUji(start.Add(2*interval), 30)}
+	lastWeek := []TrafficData{trafficDataUji(start.AddDate(0, 0, -7).Add(interval), 25)}
+
+	kelengkapan := detectTrafficGaps(location, bucketTrafficData(data, interval), start.In(time.Local),
+		start.Add(3*interval).In(time.Local), interval)
+	estimated := fillGapsLastWeek(location, bucketTrafficData(lastWeek, interval), kelengkapan, interval)
+	if len(estimated) != 1 || estimated[0].TotalKendaraan != 25 {
+		t.Fatalf("estimasi = %+v, ingin satu interval 25 kendaraan", estimated)
+	}
+}
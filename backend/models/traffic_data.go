@@ -43,6 +43,9 @@ type TrafficMKJIAnalysis struct {
 	DerajatKejenuhan float64 `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"` // DS = Q/C
 	TingkatPelayanan string  `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"` // Level of Service (A-F)
 	Keterangan       string  `bson:"keterangan" json:"keterangan"`               // Deskripsi LoS
+
+	PerArah       *DirectionalAnalysis `bson:"per_arah,omitempty" json:"per_arah,omitempty"`             // Arus, pemisahan arah, dan DS/DJ per arah (jalan terbagi)
+	TabelEkivalen []EkivalenTableRef   `bson:"tabel_ekivalen,omitempty" json:"tabel_ekivalen,omitempty"` // Versi tabel ekivalen yang dipakai
 }
 
 type TrafficPKJIAnalysis struct {
@@ -62,20 +65,33 @@ type TrafficPKJIAnalysis struct {
 	DerajatKejenuhan float64 `bson:"derajat_kejenuhan" json:"derajat_kejenuhan"` // DJ = V/C
 	TingkatPelayanan string  `bson:"tingkat_pelayanan" json:"tingkat_pelayanan"` // Level of Service (A-F)
 	Keterangan       string  `bson:"keterangan" json:"keterangan"`               // Deskripsi LoS
+
+	PerArah       *DirectionalAnalysis `bson:"per_arah,omitempty" json:"per_arah,omitempty"`             // Arus, pemisahan arah, dan DS/DJ per arah (jalan terbagi)
+	TabelEkivalen []EkivalenTableRef   `bson:"tabel_ekivalen,omitempty" json:"tabel_ekivalen,omitempty"` // Versi tabel ekivalen yang dipakai
 }
 
 type TrafficData struct {
-	ID             string                `bson:"_id" json:"id"`
-	LokasiID       string                `bson:"lokasi_id" json:"lokasi_id"`
-	NamaLokasi     string                `bson:"nama_lokasi" json:"nama_lokasi"`
-	TipeLokasi     string                `bson:"tipe_lokasi" json:"tipe_lokasi"`
-	Timestamp      time.Time             `bson:"timestamp" json:"timestamp"`
-	ZonaArahData   []TrafficZonaArahData `bson:"zona_arah_data" json:"zona_arah_data"`
-	TotalKendaraan int                   `bson:"total_kendaraan" json:"total_kendaraan"`
-	IntervalMenit  int                   `bson:"interval_menit" json:"interval_menit"`
-	RawDataID      string                `bson:"raw_data_id,omitempty" json:"raw_data_id,omitempty"`
-	MKJIAnalysis   *TrafficMKJIAnalysis  `bson:"mkji_analysis" json:"mkji_analysis"`
-	PKJIAnalysis   *TrafficPKJIAnalysis  `bson:"pkji_analysis" json:"pkji_analysis"`
+	ID                string                `bson:"_id" json:"id"`
+	LokasiID          string                `bson:"lokasi_id" json:"lokasi_id"`
+	CameraID          string                `bson:"camera_id,omitempty" json:"camera_id,omitempty"` // Kamera pengirim (kosong untuk input manual)
+	NamaLokasi        string                `bson:"nama_lokasi" json:"nama_lokasi"`
+	TipeLokasi        string                `bson:"tipe_lokasi" json:"tipe_lokasi"`
+	Timestamp         time.Time             `bson:"timestamp" json:"timestamp"`                                   // Awal interval (waktu lokal)
+	IntervalEnd       time.Time             `bson:"interval_end,omitempty" json:"interval_end,omitempty"`         // Akhir interval (waktu lokal)
+	TimestampSource   string                `bson:"timestamp_source,omitempty" json:"timestamp_source,omitempty"` // camera atau server
+	ZonaArahData      []TrafficZonaArahData `bson:"zona_arah_data" json:"zona_arah_data"`
+	TotalKendaraan    int                   `bson:"total_kendaraan" json:"total_kendaraan"`
+	IntervalMenit     int                   `bson:"interval_menit" json:"interval_menit"`
+	RawDataID         string                `bson:"raw_data_id,omitempty" json:"raw_data_id,omitempty"`
+	IngestKey         string                `bson:"ingest_key,omitempty" json:"ingest_key,omitempty"`
+	Version           int                   `bson:"version,omitempty" json:"version,omitempty"`                         // Versi hasil proses ulang (kosong = versi awal)
+	PreviousVersionID string                `bson:"previous_version_id,omitempty" json:"previous_version_id,omitempty"` // ID versi sebelumnya di traffic_data_versions
+	ReprocessedAt     *time.Time            `bson:"reprocessed_at,omitempty" json:"reprocessed_at,omitempty"`
+	Estimated         bool                  `bson:"estimated,omitempty" json:"estimated,omitempty"`             // Interval hasil pengisian gap, tidak disimpan
+	MetodeEstimasi    string                `bson:"metode_estimasi,omitempty" json:"metode_estimasi,omitempty"` // Metode pengisian gap (linear/last_week)
+	DataQuality       *TrafficDataQuality   `bson:"data_quality,omitempty" json:"data_quality,omitempty"`       // Skor dan flag kualitas data interval
+	MKJIAnalysis      *TrafficMKJIAnalysis  `bson:"mkji_analysis" json:"mkji_analysis"`
+	PKJIAnalysis      *TrafficPKJIAnalysis  `bson:"pkji_analysis" json:"pkji_analysis"`
 }
 
 func NextTrafficDataID() (string, error) {
@@ -170,6 +186,18 @@ func GetLatestTrafficDataByLokasiID(lokasiID string) (*TrafficData, error) {
 	return &trafficData, nil
 }
 
+func GetTrafficDataByIngestKey(ingestKey string) (*TrafficData, error) {
+	collection := database.DB.Collection("traffic_data")
+
+	var trafficData TrafficData
+	err := collection.FindOne(context.Background(), bson.M{"ingest_key": ingestKey}).Decode(&trafficData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trafficData, nil
+}
+
 func GetTrafficDataByID(id string) (*TrafficData, error) {
 	collection := database.DB.Collection("traffic_data")
 
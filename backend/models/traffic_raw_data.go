@@ -50,6 +50,7 @@ type TrafficRawData struct {
 	IsProcessed    bool          `bson:"is_processed" json:"is_processed"`
 	ProcessedAt    *time.Time    `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
 	ProcessedID    string        `bson:"processed_id,omitempty" json:"processed_id,omitempty"` // Reference to TrafficData ID
+	IngestKey      string        `bson:"ingest_key,omitempty" json:"ingest_key,omitempty"`     // Kunci idempotensi payload kamera
 	CreatedAt      time.Time     `bson:"created_at" json:"created_at"`
 }
 
@@ -69,7 +70,7 @@ func NextRawDataID() (string, error) {
 	return fmt.Sprintf("RAW-%05d", lastNum+1), nil
 }
 
-func SaveRawData(lokasiID string, cameraID string, timestamp time.Time, zonaData []RawZonaData, intervalMenit int, totalKendaraan int) (*TrafficRawData, error) {
+func SaveRawData(lokasiID string, cameraID string, timestamp time.Time, zonaData []RawZonaData, intervalMenit int, totalKendaraan int, ingestKey string) (*TrafficRawData, error) {
 	collection := database.DB.Collection("traffic_raw_data")
 
 	id, err := NextRawDataID()
@@ -86,6 +87,7 @@ func SaveRawData(lokasiID string, cameraID string, timestamp time.Time, zonaData
 		ZonaData:       zonaData,
 		TotalKendaraan: totalKendaraan,
 		IsProcessed:    false,
+		IngestKey:      ingestKey,
 		CreatedAt:      time.Now().Add(7 * time.Hour),
 	}
 
@@ -133,6 +135,13 @@ func MarkRawDataAsProcessed(rawDataID string, processedID string) error {
 	return err
 }
 
+func DeleteRawDataByID(id string) error {
+	collection := database.DB.Collection("traffic_raw_data")
+
+	_, err := collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
 func GetRawDataByLokasiID(lokasiID string, startTime, endTime time.Time) ([]TrafficRawData, error) {
 	collection := database.DB.Collection("traffic_raw_data")
 
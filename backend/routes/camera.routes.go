@@ -13,10 +13,14 @@ func SetupCameraRoutes(router fiber.Router) {
 	camera.Use(middleware.RestrictTo("superadmin"))
 
 	camera.Get("/options", controllers.GetCameraOptions)
+	camera.Get("/health", controllers.GetCamerasHealth)
 	camera.Post("/", controllers.CreateCamera)
 	camera.Get("/", controllers.GetAllCameras)
 	camera.Get("/:id", controllers.GetCameraByID)
+	camera.Get("/:id/health", controllers.GetCameraHealthByID)
 	camera.Get("/lokasi/:lokasi_id", controllers.GetCamerasByLokasiID)
 	camera.Put("/:id", controllers.UpdateCamera)
+	camera.Post("/:id/rotate-key", controllers.RotateCameraAPIKey)
+	camera.Put("/:id/signature", controllers.UpdateCameraSignature)
 	camera.Delete("/:id", controllers.DeleteCamera)
 }
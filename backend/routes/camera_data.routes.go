@@ -2,6 +2,7 @@ package routes
 
 import (
 	"backend/controllers"
+	"backend/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,6 +14,9 @@ func SetupCameraDataRoutes(router fiber.Router) {
 	// XML data wrapped in JSON
 	camera.Post("/data/json", controllers.ReceiveCameraDataJSON)
 	camera.Post("/data/stream", controllers.ReceiveCameraDataStream)
+	camera.Post("/data/batch", controllers.ReceiveCameraDataBatch)
+	camera.Get("/data/status/:tracking_id", controllers.GetCameraIngestStatus)
+	camera.Get("/queue", middleware.Protected(), middleware.RestrictTo("admin", "superadmin"), controllers.GetCameraIngestQueue)
 	camera.Post("/validate", controllers.ValidateCameraAPIKey)
 	camera.Get("/status/:api_key", controllers.GetCameraStatus)
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupDeadLetterRoutes(router fiber.Router) {
+	deadLetter := router.Group("/dead-letters")
+	deadLetter.Use(middleware.Protected())
+	deadLetter.Use(middleware.RestrictTo("superadmin"))
+
+	deadLetter.Get("/", controllers.GetDeadLetters)
+	deadLetter.Post("/replay", controllers.ReplayDeadLetters)
+	deadLetter.Get("/:id", controllers.GetDeadLetterByID)
+	deadLetter.Put("/:id", controllers.UpdateDeadLetter)
+	deadLetter.Post("/:id/replay", controllers.ReplayDeadLetter)
+	deadLetter.Delete("/:id", controllers.DiscardDeadLetter)
+}
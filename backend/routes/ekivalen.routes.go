@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupEkivalenRoutes(app *fiber.App) {
+	ekivalen := app.Group("/ekivalen")
+
+	ekivalen.Use(middleware.Protected())
+
+	ekivalen.Get("/", controllers.GetEkivalenTables)
+	ekivalen.Get("/aktif", controllers.GetActiveEkivalenTable)
+	ekivalen.Get("/:id", controllers.GetEkivalenTableByID)
+
+	// Perubahan tabel ekivalen hanya oleh superadmin; tabel yang sudah dipakai analisis tidak dapat diubah
+	ekivalen.Post("/", middleware.RestrictTo("superadmin"), controllers.CreateEkivalenTable)
+	ekivalen.Post("/init", middleware.RestrictTo("superadmin"), controllers.InitEkivalenTablesHandler)
+	ekivalen.Put("/:id", middleware.RestrictTo("superadmin"), controllers.UpdateEkivalenTable)
+	ekivalen.Delete("/:id", middleware.RestrictTo("superadmin"), controllers.DeleteEkivalenTable)
+}
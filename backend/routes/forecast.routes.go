@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupForecastRoutes(app *fiber.App) {
+	forecast := app.Group("/forecast")
+
+	forecast.Use(middleware.Protected())
+
+	forecast.Get("/:lokasi_id", controllers.GetForecast)
+	forecast.Get("/:lokasi_id/riwayat", controllers.GetForecastHistory)
+	forecast.Get("/:lokasi_id/akurasi", controllers.GetForecastAkurasi)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupLHRTRoutes(app *fiber.App) {
+	lhrt := app.Group("/lhrt")
+
+	lhrt.Use(middleware.Protected())
+
+	// Faktor hari dan bulan dari pos permanen
+	lhrt.Get("/faktor", controllers.GetFaktorLHRT)
+	lhrt.Post("/faktor/hitung", middleware.RestrictTo("superadmin"), controllers.HitungFaktorLHRT)
+	lhrt.Get("/faktor/:id", controllers.GetFaktorLHRTByID)
+
+	lhrt.Get("/tahunan", controllers.GetLHRTTahunanSemua)
+	lhrt.Post("/tahunan", controllers.CreateLHRTTahunan)
+
+	lhrt.Get("/:lokasi_id", controllers.GetLHRT)
+	lhrt.Get("/:lokasi_id/tahunan", controllers.GetLHRTTahunanLokasi)
+	lhrt.Get("/:lokasi_id/tahunan/:tahun", controllers.GetLHRTTahunanDetail)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupPKJIRoutes(app *fiber.App) {
+	pkji := app.Group("/pkji")
+
+	pkji.Use(middleware.Protected())
+
+	pkji.Get("/analysis/:lokasi_id", controllers.GetPKJIAnalysis)
+	pkji.Post("/analysis", controllers.CreatePKJIAnalysis)
+	pkji.Get("/analysis/:lokasi_id/history", controllers.GetPKJIAnalysisHistory)
+	pkji.Get("/analysis/:lokasi_id/latest", controllers.GetLatestPKJIAnalysis)
+	pkji.Get("/analysis/detail/:id", controllers.GetPKJIAnalysisByID)
+
+	pkji.Get("/kapasitas/:lokasi_id", controllers.GetKapasitasJalanPKJI)
+	pkji.Get("/kecepatan/:lokasi_id", controllers.GetKecepatanPKJI)
+
+	// Perbandingan MKJI 1997 vs PKJI 2023 untuk lokasi dan periode yang sama
+	pkji.Get("/compare/:lokasi_id", controllers.CompareAnalysis)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupProjectionRoutes(app *fiber.App) {
+	proyeksi := app.Group("/proyeksi")
+
+	proyeksi.Use(middleware.Protected())
+
+	proyeksi.Get("/:lokasi_id", controllers.GetProyeksi)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupReprocessRoutes(router fiber.Router) {
+	reprocess := router.Group("/reprocess")
+	reprocess.Use(middleware.Protected())
+	reprocess.Use(middleware.RestrictTo("superadmin"))
+
+	reprocess.Post("/", controllers.CreateReprocessJob)
+	reprocess.Get("/", controllers.GetReprocessJobs)
+	reprocess.Get("/versions/:id", controllers.GetTrafficDataVersion)
+	reprocess.Get("/:id", controllers.GetReprocessJobByID)
+	reprocess.Post("/:id/pause", controllers.PauseReprocessJob)
+	reprocess.Post("/:id/resume", controllers.ResumeReprocessJob)
+	reprocess.Post("/:id/cancel", controllers.CancelReprocessJob)
+}
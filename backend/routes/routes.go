@@ -16,4 +16,13 @@ func Setup(app *fiber.App) {
 	SetupTrafficDataRoutes(app)
 	SetupTrafficRawDataRoutes(app)
 	SetupMKJIRoutes(app)
+	SetupPKJIRoutes(app)
+	SetupEkivalenRoutes(app)
+	SetupSideFrictionRoutes(app)
+	SetupSimpangRoutes(app)
+	SetupLHRTRoutes(app)
+	SetupProjectionRoutes(app)
+	SetupForecastRoutes(app)
+	SetupDeadLetterRoutes(app)
+	SetupReprocessRoutes(app)
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSideFrictionRoutes(app *fiber.App) {
+	hambatan := app.Group("/hambatan-samping")
+
+	hambatan.Use(middleware.Protected())
+
+	hambatan.Get("/tidak-sesuai", controllers.GetHambatanSampingTidakSesuai)
+	hambatan.Get("/:lokasi_id", controllers.GetHambatanSamping)
+	hambatan.Get("/:lokasi_id/latest", controllers.GetLatestHambatanSamping)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSimpangRoutes(app *fiber.App) {
+	simpang := app.Group("/simpang")
+
+	simpang.Use(middleware.Protected())
+
+	simpang.Get("/analysis/:lokasi_id", controllers.GetSimpangAnalysis)
+	simpang.Post("/analysis", controllers.CreateSimpangAnalysis)
+	simpang.Get("/analysis/:lokasi_id/history", controllers.GetSimpangAnalysisHistory)
+	simpang.Get("/analysis/:lokasi_id/latest", controllers.GetLatestSimpangAnalysis)
+	simpang.Get("/analysis/detail/:id", controllers.GetSimpangAnalysisByID)
+
+	simpang.Put("/konfigurasi/:lokasi_id", middleware.RestrictTo("superadmin"), controllers.UpdateSimpangKonfigurasi)
+}
@@ -15,6 +15,8 @@ func SetupTrafficDataRoutes(router fiber.Router) {
 	traffic.Get("/", controllers.GetAllTrafficData)
 	traffic.Get("/:id", controllers.GetTrafficDataByID)
 	traffic.Get("/lokasi/:lokasi_id", controllers.GetTrafficDataByLokasiID)
+	traffic.Get("/lokasi/:lokasi_id/quality", controllers.GetTrafficDataQuality)
+	traffic.Get("/lokasi/:lokasi_id/completeness", controllers.GetTrafficDataCompleteness)
 	traffic.Get("/lokasi/:lokasi_id/latest", controllers.GetLatestTrafficDataByLokasiID)
 	traffic.Delete("/:id", middleware.RestrictTo("superadmin"), controllers.DeleteTrafficData)
 	traffic.Delete("/cleanup", middleware.RestrictTo("superadmin"), controllers.CleanupOldTrafficData)
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Service untuk menyimpan prakiraan lalu lintas jangka pendek setiap lokasi secara berkala
+// dan membandingkan prakiraan yang horizonnya sudah lewat dengan data aktual
+type ForecastService struct {
+	interval time.Duration
+	stopChan chan bool
+}
+
+// Membuat instance baru ForecastService; horizon mengikuti FORECAST_HORIZON_HOURS
+func NewForecastService(interval time.Duration) *ForecastService {
+	return &ForecastService{
+		interval: interval,
+		stopChan: make(chan bool),
+	}
+}
+
+// Start menjalankan prakiraan pertama lalu mengulanginya setiap interval
+func (s *ForecastService) Start() {
+	if s.interval <= 0 {
+		log.Println("Prakiraan lalu lintas terjadwal nonaktif")
+		return
+	}
+	go s.run()
+}
+
+func (s *ForecastService) Stop() {
+	if s.interval <= 0 {
+		return
+	}
+	s.stopChan <- true
+}
+
+func (s *ForecastService) run() {
+	s.RunAll()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.RunAll()
+		case <-s.stopChan:
+			log.Println("Menghentikan prakiraan lalu lintas")
+			return
+		}
+	}
+}
+
+// RunAll mengevaluasi prakiraan tertunda lalu menyimpan prakiraan baru semua lokasi ruas
+func (s *ForecastService) RunAll() {
+	if n, err := models.EvaluasiForecastTertunda(); err != nil {
+		log.Printf("Error mengevaluasi prakiraan lalu lintas: %v", err)
+	} else if n > 0 {
+		log.Printf("Mengevaluasi %d prakiraan lalu lintas", n)
+	}
+
+	cursor, err := database.DB.Collection("locations").Find(context.Background(), bson.M{})
+	if err != nil {
+		log.Printf("Error mendapatkan lokasi untuk prakiraan lalu lintas: %v", err)
+		return
+	}
+	var locations []models.Location
+	if err := cursor.All(context.Background(), &locations); err != nil {
+		log.Printf("Error mendapatkan lokasi untuk prakiraan lalu lintas: %v", err)
+		return
+	}
+
+	excludeFlagged := models.DefaultAnalysisOptions().ExcludeFlagged
+	for _, location := range locations {
+		if location.IsSimpang() {
+			continue
+		}
+		forecast, err := models.BuatForecast(location.ID, models.AnalysisMethodPKJI2023, 0, excludeFlagged)
+		if errors.Is(err, models.ErrRiwayatForecastTidakCukup) {
+			continue
+		}
+		if err == nil {
+			err = models.SaveTrafficForecast(forecast)
+		}
+		if err != nil {
+			log.Printf("Error membuat prakiraan lalu lintas lokasi %s: %v", location.ID, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/models"
+
+	"github.com/google/uuid"
+)
+
+// Konstanta untuk antrian ingest data kamera
+const (
+	DefaultIngestWorkers   = 4
+	DefaultIngestQueueSize = 1000
+	IngestJobRetention     = 1 * time.Hour // Lama status job disimpan untuk dilacak
+	IngestCleanupInterval  = 5 * time.Minute
+)
+
+// Status job ingest
+const (
+	IngestJobQueued     = "queued"
+	IngestJobProcessing = "processing"
+	IngestJobDone       = "done"
+)
+
+var (
+	ErrIngestQueueFull    = errors.New("antrian ingest penuh")
+	ErrIngestQueueStopped = errors.New("antrian ingest tidak berjalan")
+)
+
+// Satu job ingest berisi satu atau lebih payload yang diproses berurutan
+type IngestJob struct {
+	ID         string                     `json:"tracking_id"`
+	Status     string                     `json:"status"`
+	ItemCount  int                        `json:"item_count"`
+	Results    []models.CameraBatchResult `json:"results,omitempty"`
+	EnqueuedAt time.Time                  `json:"enqueued_at"`
+	StartedAt  *time.Time                 `json:"started_at,omitempty"`
+	FinishedAt *time.Time                 `json:"finished_at,omitempty"`
+
+	items  []string
+	source *models.IngestSource
+}
+
+// Statistik antrian ingest
+type IngestQueueStats struct {
+	Depth     int   `json:"depth"`
+	Capacity  int   `json:"capacity"`
+	Workers   int   `json:"workers"`
+	Busy      int64 `json:"busy"`
+	Enqueued  int64 `json:"enqueued"`
+	Processed int64 `json:"processed"`
+	Rejected  int64 `json:"rejected"`
+	Running   bool  `json:"running"`
+}
+
+// Service antrian antara controller dan models.ProcessCameraData dengan worker pool terbatas
+type IngestionQueueService struct {
+	workers int
+	jobs    chan *IngestJob
+
+	// Fungsi proses payload, default models.ProcessCameraBatch
+	process func(items []string, source *models.IngestSource) []models.CameraBatchResult
+
+	mu      sync.RWMutex
+	tracked map[string]*IngestJob
+	running bool
+
+	busy      int64
+	enqueued  int64
+	processed int64
+	rejected  int64
+
+	wg       sync.WaitGroup
+	stopChan chan bool
+}
+
+var defaultIngestionQueue *IngestionQueueService
+
+// SetDefaultIngestionQueue mendaftarkan antrian yang dipakai controller
+func SetDefaultIngestionQueue(q *IngestionQueueService) {
+	defaultIngestionQueue = q
+}
+
+// DefaultIngestionQueue mengembalikan antrian aktif, nil jika ingest berjalan sinkron
+func DefaultIngestionQueue() *IngestionQueueService {
+	return defaultIngestionQueue
+}
+
+// Membuat instance baru IngestionQueueService
+func NewIngestionQueueService(workers int, queueSize int) *IngestionQueueService {
+	if workers <= 0 {
+		workers = DefaultIngestWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultIngestQueueSize
+	}
+
+	return &IngestionQueueService{
+		workers:  workers,
+		jobs:     make(chan *IngestJob, queueSize),
+		process:  models.ProcessCameraBatch,
+		tracked:  make(map[string]*IngestJob),
+		stopChan: make(chan bool),
+	}
+}
+
+// Menjalankan worker pool
+func (q *IngestionQueueService) Start() {
+	q.mu.Lock()
+	q.running = true
+	q.mu.Unlock()
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(i + 1)
+	}
+	go q.cleanupTrackedJobs()
+
+	log.Printf("Antrian ingest berjalan: %d worker, kapasitas %d", q.workers, cap(q.jobs))
+}
+
+// Stop menolak job baru dan menunggu job yang sudah antri selesai diproses
+func (q *IngestionQueueService) Stop() {
+	q.mu.Lock()
+	if !q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = false
+	close(q.jobs)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	q.stopChan <- true
+	log.Println("Antrian ingest dihentikan")
+}
+
+// Enqueue memasukkan payload ke antrian tanpa menunggu; gagal segera jika antrian penuh.
+// Yang dikembalikan adalah salinan status saat masuk antrian karena job asli diubah worker.
+func (q *IngestionQueueService) Enqueue(items []string, source *models.IngestSource) (*IngestJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.running {
+		return nil, ErrIngestQueueStopped
+	}
+
+	job := &IngestJob{
+		ID:         uuid.New().String(),
+		Status:     IngestJobQueued,
+		ItemCount:  len(items),
+		EnqueuedAt: time.Now(),
+		items:      items,
+		source:     source,
+	}
+
+	select {
+	case q.jobs <- job:
+		q.tracked[job.ID] = job
+		atomic.AddInt64(&q.enqueued, 1)
+		return job.snapshot(), nil
+	default:
+		atomic.AddInt64(&q.rejected, 1)
+		return nil, ErrIngestQueueFull
+	}
+}
+
+// GetJob mengembalikan salinan status job berdasarkan tracking ID
+func (q *IngestionQueueService) GetJob(id string) (*IngestJob, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	job, ok := q.tracked[id]
+	if !ok {
+		return nil, false
+	}
+	return job.snapshot(), true
+}
+
+// snapshot menyalin status job tanpa payload; pemanggil harus memegang q.mu
+func (job *IngestJob) snapshot() *IngestJob {
+	snapshot := *job
+	snapshot.items = nil
+	snapshot.source = nil
+	return &snapshot
+}
+
+// Stats mengembalikan kedalaman dan statistik antrian
+func (q *IngestionQueueService) Stats() IngestQueueStats {
+	q.mu.RLock()
+	running := q.running
+	q.mu.RUnlock()
+
+	return IngestQueueStats{
+		Depth:     len(q.jobs),
+		Capacity:  cap(q.jobs),
+		Workers:   q.workers,
+		Busy:      atomic.LoadInt64(&q.busy),
+		Enqueued:  atomic.LoadInt64(&q.enqueued),
+		Processed: atomic.LoadInt64(&q.processed),
+		Rejected:  atomic.LoadInt64(&q.rejected),
+		Running:   running,
+	}
+}
+
+func (q *IngestionQueueService) worker(n int) {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		atomic.AddInt64(&q.busy, 1)
+
+		startedAt := time.Now()
+		q.mu.Lock()
+		job.Status = IngestJobProcessing
+		job.StartedAt = &startedAt
+		q.mu.Unlock()
+
+		results := q.process(job.items, job.source)
+
+		finishedAt := time.Now()
+		q.mu.Lock()
+		job.Results = results
+		job.Status = IngestJobDone
+		job.FinishedAt = &finishedAt
+		job.items = nil
+		job.source = nil
+		q.mu.Unlock()
+
+		atomic.AddInt64(&q.busy, -1)
+		atomic.AddInt64(&q.processed, 1)
+
+		for _, r := range results {
+			if r.Status == models.IngestStatusRejected {
+				log.Printf("Worker ingest %d: job %s item %d ditolak: %s", n, job.ID, r.Index, r.Reason)
+			}
+		}
+	}
+}
+
+// cleanupTrackedJobs menghapus status job yang sudah selesai lebih lama dari IngestJobRetention
+func (q *IngestionQueueService) cleanupTrackedJobs() {
+	ticker := time.NewTicker(IngestCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cutoff := time.Now().Add(-IngestJobRetention)
+			q.mu.Lock()
+			for id, job := range q.tracked {
+				if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+					delete(q.tracked, id)
+				}
+			}
+			q.mu.Unlock()
+		case <-q.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+// prosesTertahan memproses batch setelah gerbang dibuka, agar isi antrian dapat diatur dalam test
+type prosesTertahan struct {
+	gerbang chan struct{}
+	mu      sync.Mutex
+	selesai []string
+}
+
+func newProsesTertahan() *prosesTertahan {
+	return &prosesTertahan{gerbang: make(chan struct{})}
+}
+
+func (p *prosesTertahan) process(items []string, source *models.IngestSource) []models.CameraBatchResult {
+	<-p.gerbang
+	results := make([]models.CameraBatchResult, len(items))
+	for i, item := range items {
+		results[i] = models.CameraBatchResult{Index: i, Status: models.IngestStatusAccepted, ID: "TRF_" + item}
+		if item == "" {
+			results[i] = models.CameraBatchResult{Index: i, Status: models.IngestStatusRejected, Reason: "xml_data tidak boleh kosong"}
+		}
+	}
+	p.mu.Lock()
+	p.selesai = append(p.selesai, items...)
+	p.mu.Unlock()
+	return results
+}
+
+func newQueueUji(workers, size int) (*IngestionQueueService, *prosesTertahan) {
+	proses := newProsesTertahan()
+	q := NewIngestionQueueService(workers, size)
+	q.process = proses.process
+	return q, proses
+}
+
+// tungguJob menunggu sampai job berstatus done
+func tungguJob(t *testing.T, q *IngestionQueueService, id string) *IngestJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := q.GetJob(id); ok && job.Status == IngestJobDone {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s tidak selesai", id)
+	return nil
+}
+
+// tungguSibuk menunggu sampai semua worker sedang memproses job
+func tungguSibuk(t *testing.T, q *IngestionQueueService, busy int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Stats().Busy != busy {
+		if time.Now().After(deadline) {
+			t.Fatalf("busy %d, ingin %d", q.Stats().Busy, busy)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIngestionQueuePenuh(t *testing.T) {
+	q, proses := newQueueUji(1, 2)
+	q.Start()
+
+	// Job pertama diambil worker dan tertahan, dua job berikutnya mengisi antrian
+	if _, err := q.Enqueue([]string{"1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	tungguSibuk(t, q, 1)
+	for _, item := range []string{"2", "3"} {
+		if _, err := q.Enqueue([]string{item}, nil); err != nil {
+			t.Fatalf("item %s: %v", item, err)
+		}
+	}
+
+	// Antrian penuh ditolak segera (controller menjawab 429) tanpa menunggu worker
+	job, err := q.Enqueue([]string{"4"}, nil)
+	if !errors.Is(err, ErrIngestQueueFull) || job != nil {
+		t.Fatalf("job %v error %v, ingin ErrIngestQueueFull", job, err)
+	}
+	stats := q.Stats()
+	if stats.Depth != 2 || stats.Capacity != 2 || stats.Enqueued != 3 || stats.Rejected != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	close(proses.gerbang)
+	q.Stop()
+}
+
+func TestIngestionQueueTrackingID(t *testing.T) {
+	q, proses := newQueueUji(1, 4)
+	q.Start()
+	defer q.Stop()
+
+	job, err := q.Enqueue([]string{"A", "", "B"}, &models.IngestSource{SourceIP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.Status != IngestJobQueued || job.ItemCount != 3 || job.items != nil || job.source != nil {
+		t.Fatalf("job saat masuk antrian = %+v", job)
+	}
+	if _, ok := q.GetJob("tidak-ada"); ok {
+		t.Fatal("tracking ID tidak dikenal harus tidak ditemukan")
+	}
+
+	tungguSibuk(t, q, 1)
+	if tracked, ok := q.GetJob(job.ID); !ok || tracked.Status != IngestJobProcessing || tracked.StartedAt == nil {
+		t.Fatalf("job sedang diproses = %+v", tracked)
+	}
+	// Snapshot dari Enqueue tidak ikut berubah oleh worker
+	if job.Status != IngestJobQueued {
+		t.Fatalf("snapshot berubah menjadi %s", job.Status)
+	}
+
+	close(proses.gerbang)
+	done := tungguJob(t, q, job.ID)
+	if len(done.Results) != 3 || done.FinishedAt == nil {
+		t.Fatalf("job selesai = %+v", done)
+	}
+	if done.Results[0].ID != "TRF_A" || done.Results[1].Status != models.IngestStatusRejected || done.Results[2].ID != "TRF_B" {
+		t.Fatalf("hasil = %+v", done.Results)
+	}
+}
+
+// Stop menolak job baru tetapi menyelesaikan semua job yang sudah masuk antrian
+func TestIngestionQueueStopMenguras(t *testing.T) {
+	q, proses := newQueueUji(2, 10)
+	q.Start()
+
+	var ids []string
+	for _, item := range []string{"1", "2", "3", "4", "5"} {
+		job, err := q.Enqueue([]string{item}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	tungguSibuk(t, q, 2)
+
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+
+	// Tunggu sampai Stop menutup antrian, lalu job baru harus ditolak
+	deadline := time.Now().Add(2 * time.Second)
+	for q.Stats().Running {
+		if time.Now().After(deadline) {
+			t.Fatal("antrian tidak berhenti menerima job")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := q.Enqueue([]string{"6"}, nil); !errors.Is(err, ErrIngestQueueStopped) {
+		t.Fatalf("error %v, ingin ErrIngestQueueStopped", err)
+	}
+	select {
+	case <-stopped:
+		t.Fatal("Stop kembali sebelum job dalam antrian selesai")
+	default:
+	}
+
+	close(proses.gerbang)
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop tidak selesai")
+	}
+
+	if len(proses.selesai) != 5 {
+		t.Fatalf("%d item diproses, ingin 5", len(proses.selesai))
+	}
+	for _, id := range ids {
+		if job, ok := q.GetJob(id); !ok || job.Status != IngestJobDone {
+			t.Fatalf("job %s setelah Stop = %+v", id, job)
+		}
+	}
+	if stats := q.Stats(); stats.Processed != 5 || stats.Depth != 0 || stats.Busy != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	// Stop kedua tidak memblokir
+	q.Stop()
+}
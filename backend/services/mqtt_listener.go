@@ -0,0 +1,334 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/models"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	DefaultMQTTTopicPrefix = "plato/camera"
+	DefaultMQTTClientID    = "plato-backend"
+	mqttQoSAtLeastOnce     = 1
+
+	// Percobaan proses pesan yang gagal sementara sebelum masuk dead-letter, jeda berlipat dua
+	defaultMQTTRetryAttempts = 3
+	defaultMQTTRetryDelay    = time.Second
+)
+
+// Pesan MQTT yang diterima listener. Ack mengirim PUBACK ke broker (QoS 1).
+type MQTTMessage interface {
+	Topic() string
+	Payload() []byte
+	Ack()
+}
+
+// Klien MQTT minimal yang dipakai listener. Produksi memakai paho; broker stand-in lokal
+// (misalnya mosquitto di docker-compose atau klien tiruan) cukup mengimplementasikan interface ini.
+type MQTTClient interface {
+	Connect() error
+	Subscribe(topic string, qos byte, handler func(MQTTMessage)) error
+	Disconnect()
+}
+
+// Konfigurasi koneksi ke broker MQTT
+type MQTTClientOptions struct {
+	BrokerURL string
+	ClientID  string
+	Username  string
+	Password  string
+}
+
+// Implementasi MQTTClient dengan paho. Sesi persisten (clean session = false) dan auto-ack dimatikan
+// sehingga pesan QoS 1 yang belum di-ack dikirim ulang broker setelah reconnect.
+type pahoMQTTClient struct {
+	client mqtt.Client
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler
+	qos           map[string]byte
+}
+
+func NewPahoMQTTClient(opts MQTTClientOptions) MQTTClient {
+	if opts.ClientID == "" {
+		opts.ClientID = DefaultMQTTClientID
+	}
+
+	p := &pahoMQTTClient{
+		subscriptions: make(map[string]mqtt.MessageHandler),
+		qos:           make(map[string]byte),
+	}
+
+	clientOpts := mqtt.NewClientOptions().
+		AddBroker(opts.BrokerURL).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetCleanSession(false).
+		SetAutoAckDisabled(true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(p.resubscribe).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("Koneksi MQTT terputus: %v", err)
+		})
+
+	p.client = mqtt.NewClient(clientOpts)
+	return p
+}
+
+func (p *pahoMQTTClient) Connect() error {
+	token := p.client.Connect()
+	if !token.WaitTimeout(30 * time.Second) {
+		// ConnectRetry aktif: koneksi terus dicoba di background
+		log.Println("Broker MQTT belum dapat dihubungi, mencoba ulang di background")
+		return nil
+	}
+	return token.Error()
+}
+
+func (p *pahoMQTTClient) Subscribe(topic string, qos byte, handler func(MQTTMessage)) error {
+	pahoHandler := func(_ mqtt.Client, msg mqtt.Message) {
+		handler(msg)
+	}
+
+	p.mu.Lock()
+	p.subscriptions[topic] = pahoHandler
+	p.qos[topic] = qos
+	p.mu.Unlock()
+
+	if !p.client.IsConnectionOpen() {
+		// Subscribe dilakukan oleh resubscribe saat koneksi berhasil
+		return nil
+	}
+	token := p.client.Subscribe(topic, qos, pahoHandler)
+	token.Wait()
+	return token.Error()
+}
+
+// resubscribe memasang ulang semua subscription setiap kali koneksi (ulang) berhasil
+func (p *pahoMQTTClient) resubscribe(client mqtt.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for topic, handler := range p.subscriptions {
+		token := client.Subscribe(topic, p.qos[topic], handler)
+		token.Wait()
+		if err := token.Error(); err != nil {
+			log.Printf("Gagal subscribe topic MQTT %s: %v", topic, err)
+			continue
+		}
+		log.Printf("Subscribe topic MQTT %s (QoS %d)", topic, p.qos[topic])
+	}
+}
+
+func (p *pahoMQTTClient) Disconnect() {
+	p.client.Disconnect(1000)
+}
+
+// Statistik listener MQTT
+type MQTTListenerStats struct {
+	Received     int64 `json:"received"`
+	Accepted     int64 `json:"accepted"`
+	Rejected     int64 `json:"rejected"`
+	Retried      int64 `json:"retried"`       // percobaan ulang setelah kegagalan sementara
+	DeadLettered int64 `json:"dead_lettered"` // gagal sementara di semua percobaan, disimpan ke dead-letter lalu di-ack
+	Failed       int64 `json:"failed"`        // dead-letter juga gagal disimpan, tidak di-ack sehingga dikirim ulang broker
+}
+
+// Service yang berlangganan topic data kamera per API key (<prefix>/<api_key>/data) dan
+// memproses payload lewat jalur yang sama dengan ReceiveCameraData.
+type MQTTListenerService struct {
+	client      MQTTClient
+	topicPrefix string
+
+	// Fungsi proses payload, default models.IngestCameraPayload
+	ingest func(payload string, source *models.IngestSource) (*models.TrafficData, models.IngestStatus, error)
+	// Akses database yang dipakai sebelum ingest, dapat diganti untuk pengujian
+	lookupCamera   func(apiKey string) (*models.Camera, error)
+	saveDeadLetter func(body string, source *models.IngestSource, ingestErr *models.CameraIngestError) (*models.DeadLetter, error)
+
+	retryAttempts int
+	retryDelay    time.Duration
+
+	received     int64
+	accepted     int64
+	rejected     int64
+	retried      int64
+	deadLettered int64
+	failed       int64
+}
+
+// Membuat instance baru MQTTListenerService
+func NewMQTTListenerService(client MQTTClient, topicPrefix string) *MQTTListenerService {
+	topicPrefix = strings.Trim(topicPrefix, "/")
+	if topicPrefix == "" {
+		topicPrefix = DefaultMQTTTopicPrefix
+	}
+
+	return &MQTTListenerService{
+		client:         client,
+		topicPrefix:    topicPrefix,
+		ingest:         models.IngestCameraPayload,
+		lookupCamera:   models.GetCameraByAPIKey,
+		saveDeadLetter: models.SaveDeadLetter,
+		retryAttempts:  defaultMQTTRetryAttempts,
+		retryDelay:     defaultMQTTRetryDelay,
+	}
+}
+
+// Topic tempat kamera dengan API key tertentu mempublikasikan data
+func (s *MQTTListenerService) CameraTopic(apiKey string) string {
+	return fmt.Sprintf("%s/%s/data", s.topicPrefix, apiKey)
+}
+
+// Menjalankan listener: koneksi ke broker dan subscribe ke semua topic kamera
+func (s *MQTTListenerService) Start() error {
+	if err := s.client.Connect(); err != nil {
+		return fmt.Errorf("gagal terhubung ke broker MQTT: %v", err)
+	}
+
+	topic := s.CameraTopic("+")
+	if err := s.client.Subscribe(topic, mqttQoSAtLeastOnce, s.handleMessage); err != nil {
+		s.client.Disconnect()
+		return fmt.Errorf("gagal subscribe topic %s: %v", topic, err)
+	}
+
+	log.Printf("MQTT listener berjalan pada topic %s", topic)
+	return nil
+}
+
+// Stop memutus koneksi ke broker
+func (s *MQTTListenerService) Stop() {
+	s.client.Disconnect()
+	log.Println("MQTT listener dihentikan")
+}
+
+// Stats mengembalikan jumlah pesan yang diterima dan hasil prosesnya
+func (s *MQTTListenerService) Stats() MQTTListenerStats {
+	return MQTTListenerStats{
+		Received:     atomic.LoadInt64(&s.received),
+		Accepted:     atomic.LoadInt64(&s.accepted),
+		Rejected:     atomic.LoadInt64(&s.rejected),
+		Retried:      atomic.LoadInt64(&s.retried),
+		DeadLettered: atomic.LoadInt64(&s.deadLettered),
+		Failed:       atomic.LoadInt64(&s.failed),
+	}
+}
+
+// apiKeyFromTopic mengambil API key dari topic <prefix>/<api_key>/data
+func (s *MQTTListenerService) apiKeyFromTopic(topic string) (string, bool) {
+	rest, ok := strings.CutPrefix(topic, s.topicPrefix+"/")
+	if !ok {
+		return "", false
+	}
+	apiKey, ok := strings.CutSuffix(rest, "/data")
+	if !ok || apiKey == "" || strings.Contains(apiKey, "/") {
+		return "", false
+	}
+	return apiKey, true
+}
+
+// handleMessage memproses satu pesan. Pesan di-ack setelah data tersimpan (accepted/duplicate)
+// atau ditolak permanen dan masuk dead-letter. Kegagalan sementara (misalnya database) dicoba ulang
+// dengan jeda berlipat; jika tetap gagal, payload disimpan ke dead-letter (tahap process) lalu di-ack
+// agar dapat di-replay. Pesan hanya dibiarkan tanpa ack jika dead-letter juga gagal disimpan.
+func (s *MQTTListenerService) handleMessage(msg MQTTMessage) {
+	atomic.AddInt64(&s.received, 1)
+
+	source := models.NewIngestSource("mqtt:"+msg.Topic(), "", nil)
+	payload := string(msg.Payload())
+
+	apiKey, ok := s.apiKeyFromTopic(msg.Topic())
+	if !ok {
+		log.Printf("Pesan MQTT pada topic tidak dikenal %s diabaikan", msg.Topic())
+		atomic.AddInt64(&s.rejected, 1)
+		msg.Ack()
+		return
+	}
+
+	// Kamera hanya boleh mempublikasikan ke topic API key miliknya sendiri.
+	// Pesan MQTT tidak membawa header signature, sehingga kamera yang mewajibkan signature harus memakai HTTP.
+	var ingestErr *models.CameraIngestError
+	if payloadKey, err := models.ExtractCameraAPIKey(payload); err == nil && payloadKey != apiKey {
+		ingestErr = &models.CameraIngestError{
+			Stage:  models.IngestStageAPIKey,
+			APIKey: payloadKey,
+			Err:    fmt.Errorf("API key pada payload tidak sesuai dengan topic %s", msg.Topic()),
+		}
+	} else if camera, err := s.lookupCamera(apiKey); err == nil && camera.RequireSignature {
+		ingestErr = &models.CameraIngestError{
+			Stage:    models.IngestStageSignature,
+			APIKey:   apiKey,
+			CameraID: camera.ID,
+			Err:      fmt.Errorf("kamera %s mewajibkan signature, payload MQTT tidak dapat diverifikasi", camera.ID),
+		}
+	}
+	if ingestErr != nil {
+		if !s.deadLetter(msg, payload, source, ingestErr) {
+			return
+		}
+		log.Printf("Pesan MQTT ditolak: %v", ingestErr)
+		atomic.AddInt64(&s.rejected, 1)
+		msg.Ack()
+		return
+	}
+
+	var (
+		trafficData *models.TrafficData
+		status      models.IngestStatus
+		err         error
+	)
+	delay := s.retryDelay
+	for attempt := 1; ; attempt++ {
+		trafficData, status, err = s.ingest(payload, source)
+		if err == nil || errors.As(err, &ingestErr) || attempt >= s.retryAttempts {
+			break
+		}
+		log.Printf("Gagal memproses pesan MQTT dari %s (percobaan %d/%d), dicoba ulang dalam %s: %v",
+			msg.Topic(), attempt, s.retryAttempts, delay, err)
+		atomic.AddInt64(&s.retried, 1)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	if err != nil {
+		if ingestErr != nil {
+			log.Printf("Pesan MQTT dari %s ditolak (tahap %s): %v", msg.Topic(), ingestErr.Stage, err)
+			atomic.AddInt64(&s.rejected, 1)
+			msg.Ack()
+			return
+		}
+		processErr := &models.CameraIngestError{Stage: models.IngestStageProcess, APIKey: apiKey, Err: err}
+		if !s.deadLetter(msg, payload, source, processErr) {
+			return
+		}
+		log.Printf("Pesan MQTT dari %s gagal setelah %d percobaan, disimpan ke dead-letter: %v", msg.Topic(), s.retryAttempts, err)
+		atomic.AddInt64(&s.deadLettered, 1)
+		msg.Ack()
+		return
+	}
+
+	atomic.AddInt64(&s.accepted, 1)
+	msg.Ack()
+	log.Printf("Pesan MQTT dari %s diproses: %s (%s)", msg.Topic(), trafficData.ID, status)
+}
+
+// deadLetter menyimpan payload ke dead-letter. Jika gagal, pesan dibiarkan tanpa ack agar dikirim ulang broker.
+func (s *MQTTListenerService) deadLetter(msg MQTTMessage, payload string, source *models.IngestSource, ingestErr *models.CameraIngestError) bool {
+	if _, err := s.saveDeadLetter(payload, source, ingestErr); err != nil {
+		log.Printf("Gagal menyimpan dead-letter MQTT dari %s, menunggu pengiriman ulang: %v", msg.Topic(), err)
+		atomic.AddInt64(&s.failed, 1)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/models"
+)
+
+// fakeBroker adalah broker lokal dalam proses yang mengimplementasikan MQTTClient
+type fakeBroker struct {
+	mu        sync.Mutex
+	connected bool
+	handlers  map[string]func(MQTTMessage)
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{handlers: make(map[string]func(MQTTMessage))}
+}
+
+func (b *fakeBroker) Connect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connected = true
+	return nil
+}
+
+func (b *fakeBroker) Subscribe(topic string, qos byte, handler func(MQTTMessage)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.connected {
+		return errors.New("belum terhubung")
+	}
+	b.handlers[topic] = handler
+	return nil
+}
+
+func (b *fakeBroker) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connected = false
+}
+
+// publish mengirim pesan QoS 1 ke semua subscription yang cocok dan mengembalikan pesan untuk diperiksa ack-nya
+func (b *fakeBroker) publish(topic, payload string) *fakeMessage {
+	msg := &fakeMessage{topic: topic, payload: []byte(payload)}
+	b.mu.Lock()
+	var handlers []func(MQTTMessage)
+	for filter, handler := range b.handlers {
+		if topicMatches(filter, topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return msg
+}
+
+// topicMatches mencocokkan topic dengan filter MQTT (wildcard + dan #)
+func topicMatches(filter, topic string) bool {
+	filterParts, topicParts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, part := range filterParts {
+		if part == "#" {
+			return true
+		}
+		if i >= len(topicParts) || (part != "+" && part != topicParts[i]) {
+			return false
+		}
+	}
+	return len(filterParts) == len(topicParts)
+}
+
+type fakeMessage struct {
+	topic   string
+	payload []byte
+	acks    int
+}
+
+func (m *fakeMessage) Topic() string   { return m.topic }
+func (m *fakeMessage) Payload() []byte { return m.payload }
+func (m *fakeMessage) Ack()            { m.acks++ }
+
+// listenerUji menyiapkan listener dengan ingest dan database tiruan; ingest mengembalikan ingestErrs berurutan
+// sebelum berhasil
+type listenerUji struct {
+	*MQTTListenerService
+	broker      *fakeBroker
+	ingestCalls int
+	deadLetters []*models.CameraIngestError
+}
+
+func newListenerUji(t *testing.T, ingestErrs ...error) *listenerUji {
+	t.Helper()
+	l := &listenerUji{broker: newFakeBroker()}
+	l.MQTTListenerService = NewMQTTListenerService(l.broker, "")
+	l.retryDelay = 0
+	l.ingest = func(payload string, source *models.IngestSource) (*models.TrafficData, models.IngestStatus, error) {
+		l.ingestCalls++
+		if l.ingestCalls <= len(ingestErrs) {
+			return nil, "", ingestErrs[l.ingestCalls-1]
+		}
+		return &models.TrafficData{ID: fmt.Sprintf("TRF_%d", l.ingestCalls)}, models.IngestStatusAccepted, nil
+	}
+	l.lookupCamera = func(apiKey string) (*models.Camera, error) {
+		return &models.Camera{ID: "CAM-00001", RequireSignature: apiKey == "kunci-bertanda"}, nil
+	}
+	l.saveDeadLetter = func(body string, source *models.IngestSource, ingestErr *models.CameraIngestError) (*models.DeadLetter, error) {
+		l.deadLetters = append(l.deadLetters, ingestErr)
+		return &models.DeadLetter{ID: "DLQ-00001", Stage: ingestErr.Stage}, nil
+	}
+	if err := l.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return l
+}
+
+func TestMQTTListenerAckSetelahTersimpan(t *testing.T) {
+	l := newListenerUji(t)
+	msg := l.broker.publish(l.CameraTopic("kunci-a"), "<Root/>")
+
+	if msg.acks != 1 || l.ingestCalls != 1 {
+		t.Fatalf("ack %d, ingest %d kali, ingin 1 dan 1", msg.acks, l.ingestCalls)
+	}
+	if stats := l.Stats(); stats.Received != 1 || stats.Accepted != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestMQTTListenerCobaUlangGagalSementara(t *testing.T) {
+	l := newListenerUji(t, errors.New("database timeout"), errors.New("database timeout"))
+	msg := l.broker.publish(l.CameraTopic("kunci-a"), "<Root/>")
+
+	if msg.acks != 1 || l.ingestCalls != 3 || len(l.deadLetters) != 0 {
+		t.Fatalf("ack %d, ingest %d kali, dead-letter %d, ingin 1, 3, 0", msg.acks, l.ingestCalls, len(l.deadLetters))
+	}
+	if stats := l.Stats(); stats.Retried != 2 || stats.Accepted != 1 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestMQTTListenerDeadLetterSetelahSemuaPercobaan(t *testing.T) {
+	transient := errors.New("database timeout")
+	l := newListenerUji(t, transient, transient, transient)
+	msg := l.broker.publish(l.CameraTopic("kunci-a"), "<Root/>")
+
+	if msg.acks != 1 || l.ingestCalls != defaultMQTTRetryAttempts {
+		t.Fatalf("ack %d, ingest %d kali, ingin 1 dan %d", msg.acks, l.ingestCalls, defaultMQTTRetryAttempts)
+	}
+	if len(l.deadLetters) != 1 || l.deadLetters[0].Stage != models.IngestStageProcess || l.deadLetters[0].APIKey != "kunci-a" {
+		t.Fatalf("dead-letter = %+v, ingin satu entri tahap process", l.deadLetters)
+	}
+	if stats := l.Stats(); stats.DeadLettered != 1 || stats.Retried != 2 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestMQTTListenerTanpaAckJikaDeadLetterGagal(t *testing.T) {
+	transient := errors.New("database timeout")
+	l := newListenerUji(t, transient, transient, transient)
+	l.saveDeadLetter = func(string, *models.IngestSource, *models.CameraIngestError) (*models.DeadLetter, error) {
+		return nil, transient
+	}
+	msg := l.broker.publish(l.CameraTopic("kunci-a"), "<Root/>")
+
+	if msg.acks != 0 {
+		t.Fatalf("ack %d, ingin 0 agar broker mengirim ulang", msg.acks)
+	}
+	if stats := l.Stats(); stats.Failed != 1 || stats.DeadLettered != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestMQTTListenerDitolakPermanen(t *testing.T) {
+	payloadJSON := `{"api_key": "kunci-lain", "interval_seconds": 300, "timestamp": "2025-01-15T08:05:00+07:00", "zones": []}`
+	tests := []struct {
+		nama        string
+		topic       string
+		payload     string
+		ingestErr   error
+		ingestCalls int
+		stage       string
+	}{
+		{"API key payload berbeda", "plato/camera/kunci-a/data", payloadJSON, nil, 0, models.IngestStageAPIKey},
+		{"kamera wajib signature", "plato/camera/kunci-bertanda/data", "<Root/>", nil, 0, models.IngestStageSignature},
+		{"error ingest", "plato/camera/kunci-a/data", "<Root/>",
+			&models.CameraIngestError{Stage: models.IngestStageParse, Err: errors.New("XML rusak")}, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			var errs []error
+			if tt.ingestErr != nil {
+				errs = append(errs, tt.ingestErr)
+			}
+			l := newListenerUji(t, errs...)
+			msg := l.broker.publish(tt.topic, tt.payload)
+
+			if msg.acks != 1 || l.ingestCalls != tt.ingestCalls {
+				t.Fatalf("ack %d, ingest %d kali, ingin 1 dan %d", msg.acks, l.ingestCalls, tt.ingestCalls)
+			}
+			if tt.stage != "" && (len(l.deadLetters) != 1 || l.deadLetters[0].Stage != tt.stage) {
+				t.Fatalf("dead-letter = %+v, ingin tahap %s", l.deadLetters, tt.stage)
+			}
+			if stats := l.Stats(); stats.Rejected != 1 || stats.Retried != 0 {
+				t.Fatalf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestMQTTListenerAPIKeyFromTopic(t *testing.T) {
+	s := NewMQTTListenerService(newFakeBroker(), "/plato/camera/")
+	tests := []struct {
+		topic string
+		want  string
+		ok    bool
+	}{
+		{"plato/camera/kunci-a/data", "kunci-a", true},
+		{"plato/camera/kunci-a/status", "", false},
+		{"plato/camera//data", "", false},
+		{"plato/camera/a/b/data", "", false},
+		{"lain/camera/kunci-a/data", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := s.apiKeyFromTopic(tt.topic); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: %q %v, ingin %q %v", tt.topic, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Service untuk membangun ulang traffic_data dari traffic_raw_data secara bertahap (per batch)
+type ReprocessService struct {
+	mu       sync.Mutex
+	running  map[string]bool
+	stopping bool
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+}
+
+var defaultReprocessService *ReprocessService
+
+func SetDefaultReprocessService(s *ReprocessService) {
+	defaultReprocessService = s
+}
+
+func DefaultReprocessService() *ReprocessService {
+	return defaultReprocessService
+}
+
+// Membuat instance baru ReprocessService
+func NewReprocessService() *ReprocessService {
+	return &ReprocessService{
+		running:  make(map[string]bool),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start melanjutkan job yang masih berstatus running saat server terakhir berhenti
+func (s *ReprocessService) Start() {
+	jobs, err := models.GetReprocessJobs(bson.M{"status": models.ReprocessStatusRunning}, 100)
+	if err != nil {
+		log.Printf("Error mengambil job proses ulang yang terhenti: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		log.Printf("Melanjutkan job proses ulang %s dari cursor terakhir", job.ID)
+		s.Run(job.ID)
+	}
+}
+
+// Run menjalankan job di background; job yang sudah berjalan tidak dijalankan dua kali
+func (s *ReprocessService) Run(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return fmt.Errorf("service proses ulang sedang berhenti")
+	}
+	if s.running[jobID] {
+		return fmt.Errorf("job %s sedang berjalan", jobID)
+	}
+
+	if err := models.SetReprocessJobStatus(jobID, models.ReprocessStatusRunning); err != nil {
+		return err
+	}
+
+	s.running[jobID] = true
+	s.wg.Add(1)
+	go s.process(jobID)
+	return nil
+}
+
+// Stop menghentikan semua job di antara dua raw data dan menunggu sampai progress tersimpan.
+// Status job tetap running sehingga Start melanjutkannya dari cursor terakhir saat server hidup lagi.
+func (s *ReprocessService) Stop() {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return
+	}
+	s.stopping = true
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Service proses ulang dihentikan")
+}
+
+func (s *ReprocessService) stopped() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *ReprocessService) IsRunning(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[jobID]
+}
+
+func (s *ReprocessService) process(jobID string) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, jobID)
+		s.mu.Unlock()
+	}()
+
+	job, err := models.GetReprocessJobByID(jobID)
+	if err != nil {
+		log.Printf("Job proses ulang %s tidak ditemukan: %v", jobID, err)
+		return
+	}
+
+	if job.StartedAt == nil {
+		startedAt := time.Now().Add(7 * time.Hour)
+		job.StartedAt = &startedAt
+	}
+
+	location, err := models.GetLocationByID(job.LokasiID)
+	if err != nil {
+		s.fail(job, fmt.Sprintf("lokasi tidak ditemukan: %v", err))
+		return
+	}
+	klasifikasiMap := models.GetKlasifikasiMap(location.Tipe_lokasi)
+
+	log.Printf("Job proses ulang %s berjalan: lokasi %s, %s s/d %s, mode %s",
+		job.ID, job.LokasiID, job.StartTime.Format(time.RFC3339), job.EndTime.Format(time.RFC3339), job.Mode)
+
+	for {
+		// Status dicek setiap batch agar pause/cancel dari API dihormati
+		current, err := models.GetReprocessJobByID(job.ID)
+		if err != nil {
+			s.fail(job, err.Error())
+			return
+		}
+		if current.Status != models.ReprocessStatusRunning {
+			log.Printf("Job proses ulang %s berhenti dengan status %s", job.ID, current.Status)
+			return
+		}
+
+		batch, err := models.GetNextReprocessBatch(job)
+		if err != nil {
+			s.fail(job, err.Error())
+			return
+		}
+
+		if len(batch) == 0 {
+			finishedAt := time.Now().Add(7 * time.Hour)
+			job.Status = models.ReprocessStatusCompleted
+			job.FinishedAt = &finishedAt
+			if err := models.SaveReprocessJobProgress(job); err != nil {
+				log.Printf("Error menyimpan progress job %s: %v", job.ID, err)
+			}
+			log.Printf("Job proses ulang %s selesai: %d diproses, %d baru, %d diganti, %d error",
+				job.ID, job.ProcessedCount, job.CreatedCount, job.ReplacedCount, job.ErrorCount)
+			return
+		}
+
+		for i := range batch {
+			if s.stopped() {
+				if err := models.SaveReprocessJobProgress(job); err != nil {
+					log.Printf("Error menyimpan progress job %s: %v", job.ID, err)
+				}
+				log.Printf("Job proses ulang %s dihentikan setelah %d raw data, dilanjutkan saat server berjalan lagi",
+					job.ID, job.ProcessedCount)
+				return
+			}
+
+			raw := &batch[i]
+			replaced, err := models.ReprocessRawData(raw, location, klasifikasiMap, job.ID)
+			if err != nil {
+				job.ErrorCount++
+				job.LastError = fmt.Sprintf("%s: %v", raw.ID, err)
+				log.Printf("Job proses ulang %s gagal memproses %s: %v", job.ID, raw.ID, err)
+			} else if replaced {
+				job.ReplacedCount++
+			} else {
+				job.CreatedCount++
+			}
+
+			job.ProcessedCount++
+			cursorTime := raw.Timestamp
+			job.CursorTime = &cursorTime
+			job.CursorID = raw.ID
+		}
+
+		if err := models.SaveReprocessJobProgress(job); err != nil {
+			log.Printf("Error menyimpan progress job %s: %v", job.ID, err)
+		}
+	}
+}
+
+func (s *ReprocessService) fail(job *models.ReprocessJob, message string) {
+	finishedAt := time.Now().Add(7 * time.Hour)
+	job.Status = models.ReprocessStatusFailed
+	job.LastError = message
+	job.FinishedAt = &finishedAt
+	if err := models.SaveReprocessJobProgress(job); err != nil {
+		log.Printf("Error menyimpan status job %s: %v", job.ID, err)
+	}
+	log.Printf("Job proses ulang %s gagal: %s", job.ID, message)
+}
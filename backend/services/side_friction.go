@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Service untuk mengestimasi kelas hambatan samping setiap lokasi secara berkala dari data terukur
+// dan menandai lokasi yang kelas hambatan sampingnya tidak sesuai konfigurasi
+type SideFrictionService struct {
+	interval   time.Duration
+	windowDays int
+	stopChan   chan bool
+}
+
+// Membuat instance baru SideFrictionService
+func NewSideFrictionService(interval time.Duration, windowDays int) *SideFrictionService {
+	if windowDays <= 0 {
+		windowDays = 7
+	}
+	return &SideFrictionService{
+		interval:   interval,
+		windowDays: windowDays,
+		stopChan:   make(chan bool),
+	}
+}
+
+// Start menjalankan estimasi pertama lalu mengulanginya setiap interval
+func (s *SideFrictionService) Start() {
+	if s.interval <= 0 {
+		log.Println("Estimasi hambatan samping terjadwal nonaktif")
+		return
+	}
+	go s.run()
+}
+
+func (s *SideFrictionService) Stop() {
+	if s.interval <= 0 {
+		return
+	}
+	s.stopChan <- true
+}
+
+func (s *SideFrictionService) run() {
+	s.RunAll()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.RunAll()
+		case <-s.stopChan:
+			log.Println("Menghentikan estimasi hambatan samping")
+			return
+		}
+	}
+}
+
+// RunAll mengestimasi kelas hambatan samping semua lokasi untuk windowDays hari terakhir
+func (s *SideFrictionService) RunAll() {
+	cursor, err := database.DB.Collection("locations").Find(context.Background(), bson.M{})
+	if err != nil {
+		log.Printf("Error mendapatkan lokasi untuk estimasi hambatan samping: %v", err)
+		return
+	}
+	var locations []models.Location
+	if err := cursor.All(context.Background(), &locations); err != nil {
+		log.Printf("Error mendapatkan lokasi untuk estimasi hambatan samping: %v", err)
+		return
+	}
+
+	// Traffic data disimpan dalam waktu lokal (UTC+7)
+	endTime := time.Now().Add(7 * time.Hour)
+	startTime := endTime.AddDate(0, 0, -s.windowDays)
+
+	for _, location := range locations {
+		if err := s.estimateLocation(location, startTime, endTime); err != nil {
+			log.Printf("Error mengestimasi hambatan samping lokasi %s: %v", location.ID, err)
+		}
+	}
+}
+
+func (s *SideFrictionService) estimateLocation(location models.Location, startTime, endTime time.Time) error {
+	estimate, err := models.HitungHambatanSamping(&location, startTime, endTime, models.DefaultAnalysisOptions())
+	if err != nil {
+		return err
+	}
+	if estimate.KelasObservasi == "" {
+		return nil
+	}
+
+	if err := models.SaveSideFrictionEstimate(estimate); err != nil {
+		return err
+	}
+
+	if estimate.TidakSesuai {
+		log.Printf("Hambatan samping %s (%s): terukur %s pada jam puncak %s, konfigurasi %s (selisih %+d kelas)",
+			location.Nama_lokasi, location.ID, estimate.KelasObservasi, estimate.JamPuncak,
+			estimate.KelasKonfigurasi, estimate.Selisih)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
@@ -37,6 +38,9 @@ func (s *TrafficCollectorService) Start() {
 	if err != nil {
 		log.Printf("Error mengecek lokasi tidak aktif: %v", err)
 	}
+	if err := models.CheckInactiveCameras(); err != nil {
+		log.Printf("Error mengecek kamera tidak aktif: %v", err)
+	}
 	s.logLocationStatus()
 
 	go s.monitorInactiveLocations()
@@ -97,6 +101,52 @@ func (s *TrafficCollectorService) runDailyLHRAnalysis() {
 			log.Printf("Error menghitung LHR harian untuk lokasi %s: %v", location.ID, err)
 		}
 	}
+
+	s.updateLHRT(locations)
+}
+
+// Memperbarui faktor LHRT dari pos permanen (365 hari terakhir) lalu LHRT tahunan berjalan setiap lokasi
+func (s *TrafficCollectorService) updateLHRT(locations []models.Location) {
+	// Traffic data disimpan dalam waktu lokal (UTC+7)
+	now := time.Now().Add(7 * time.Hour)
+	endTime := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startTime := endTime.AddDate(-1, 0, 0)
+	excludeFlagged := models.DefaultAnalysisOptions().ExcludeFlagged
+
+	faktor, err := models.HitungFaktorLHRT(startTime, endTime, excludeFlagged)
+	switch {
+	case errors.Is(err, models.ErrTidakAdaPosPermanen):
+		log.Printf("Faktor LHRT tidak diperbarui: %v", err)
+	case err != nil:
+		log.Printf("Error menghitung faktor LHRT: %v", err)
+	default:
+		for i := range faktor {
+			if err := models.SaveFaktorLHRT(&faktor[i]); err != nil {
+				log.Printf("Error menyimpan faktor LHRT kelompok %s: %v", faktor[i].Kelompok, err)
+				continue
+			}
+			log.Printf("Faktor LHRT kelompok %s diperbarui dari %d pos permanen", faktor[i].Kelompok, len(faktor[i].PosPermanen))
+		}
+	}
+
+	tahun := endTime.AddDate(0, 0, -1).Year()
+	for _, location := range locations {
+		estimate, err := models.HitungLHRTTahunan(&location, tahun, excludeFlagged)
+		if err != nil {
+			log.Printf("Error menghitung LHRT %d untuk lokasi %s: %v", tahun, location.ID, err)
+			continue
+		}
+		if estimate.HariLengkap == 0 {
+			continue
+		}
+		if err := models.SaveLHRTTahunan(estimate); err != nil {
+			log.Printf("Error menyimpan LHRT %d untuk lokasi %s: %v", tahun, location.ID, err)
+			continue
+		}
+		log.Printf("LHRT %d %s: %.0f kend/hari (metode %s, %d hari lengkap, galat ±%.1f%%, kepercayaan %s)",
+			tahun, location.Nama_lokasi, estimate.LHRT, estimate.Metode, estimate.HariLengkap,
+			estimate.GalatRelatif, estimate.Kepercayaan)
+	}
 }
 
 // Menghitung LHR harian untuk satu lokasi
@@ -106,10 +156,11 @@ func (s *TrafficCollectorService) calculateDailyLHRForLocation(location models.L
 	startOfYesterday := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
 	endOfYesterday := startOfYesterday.Add(24 * time.Hour)
 
-	trafficDataList, err := models.GetTrafficDataByLokasiID(location.ID, startOfYesterday, endOfYesterday)
+	dataset, err := models.LoadTrafficDataForAnalysis(&location, startOfYesterday, endOfYesterday, models.DefaultAnalysisOptions())
 	if err != nil {
 		return err
 	}
+	trafficDataList := dataset.Data
 
 	if len(trafficDataList) == 0 {
 		log.Printf("Tidak ada data lalu lintas untuk lokasi %s kemarin", location.ID)
@@ -123,27 +174,33 @@ func (s *TrafficCollectorService) calculateDailyLHRForLocation(location models.L
 
 	// Perhitungan PKJI 2023
 	pkjiCount := models.HitungPKJICount(trafficDataList, location.Tipe_lokasi)
-	arusLaluLintasPKJI, jamPuncakPKJI := models.HitungVolumePKJI(trafficDataList, location.Tipe_lokasi)
+	peakPKJI := models.HitungPeakHour(models.PKJI2023Standard{}, trafficDataList, location.Tipe_lokasi)
 
 	lhrPKJI := float64(totalKendaraanHari)
 	lhrSKR := pkjiCount.TotalSkr
 
-	log.Printf("LHR Harian (PKJI2023) %s (%s): Total=%d, SM=%d, KR=%d, KB=%d, KTB=%d, LHR=%.0f, LHRSKR=%.2f, JamPuncak=%s, ArusPuncak=%.2f skr/jam",
+	log.Printf("LHR Harian (PKJI2023) %s (%s): Total=%d, SM=%d, KR=%d, KB=%d, KTB=%d, LHR=%.0f, LHRSKR=%.2f, JamPuncak=%s, ArusPuncak=%.2f skr/jam, PHF=%.2f, K=%.3f",
 		location.Nama_lokasi, startOfYesterday.Format("2006-01-02"),
 		totalKendaraanHari, pkjiCount.SM, pkjiCount.KR, pkjiCount.KB, pkjiCount.KTB,
-		lhrPKJI, lhrSKR, jamPuncakPKJI, arusLaluLintasPKJI)
+		lhrPKJI, lhrSKR, peakPKJI.Puncak.JamPuncak, peakPKJI.Puncak.Arus, peakPKJI.Puncak.PHF, peakPKJI.KFaktor)
 
 	// Perhitungan MKJI 1997
 	mkjiCount := models.HitungMKJICount(trafficDataList, location.Tipe_lokasi)
-	arusLaluLintasMKJI, jamPuncakMKJI := models.HitungArusLaluLintas(trafficDataList, location.Tipe_lokasi)
+	peakMKJI := models.HitungPeakHour(models.MKJI1997Standard{}, trafficDataList, location.Tipe_lokasi)
 
 	lhrMKJI := models.HitungLHR(totalKendaraanHari, 1)
 	lhrSMP := models.HitungLHRSMP(mkjiCount, 1)
 
-	log.Printf("LHR Harian (MKJI1997) %s (%s): Total=%d, MC=%d, LV=%d, HV=%d, UM=%d, LHR=%.0f, LHRSMP=%.2f, JamPuncak=%s, ArusPuncak=%.2f smp/jam",
+	log.Printf("LHR Harian (MKJI1997) %s (%s): Total=%d, MC=%d, LV=%d, HV=%d, UM=%d, LHR=%.0f, LHRSMP=%.2f, JamPuncak=%s, ArusPuncak=%.2f smp/jam, PHF=%.2f, K=%.3f",
 		location.Nama_lokasi, startOfYesterday.Format("2006-01-02"),
 		totalKendaraanHari, mkjiCount.MC, mkjiCount.LV, mkjiCount.HV, mkjiCount.UM,
-		lhrMKJI, lhrSMP, jamPuncakMKJI, arusLaluLintasMKJI)
+		lhrMKJI, lhrSMP, peakMKJI.Puncak.JamPuncak, peakMKJI.Puncak.Arus, peakMKJI.Puncak.PHF, peakMKJI.KFaktor)
+
+	kelengkapan := dataset.Kelengkapan
+	log.Printf("Kelengkapan data %s (%s): %d/%d interval (%.2f%%), %d gap, %d interval diestimasi (metode %s)",
+		location.Nama_lokasi, startOfYesterday.Format("2006-01-02"),
+		kelengkapan.IntervalTersedia, kelengkapan.IntervalDiharapkan, kelengkapan.Persentase,
+		len(kelengkapan.Gaps), kelengkapan.IntervalEstimasi, kelengkapan.MetodeIsiGap)
 
 	return nil
 }
@@ -170,16 +227,21 @@ func (s *TrafficCollectorService) calculateAnalysisForLocation(location models.L
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	trafficDataList, err := models.GetTrafficDataByLokasiID(location.ID, startOfDay, endOfDay)
+	dataset, err := models.LoadTrafficDataForAnalysis(&location, startOfDay, endOfDay, models.DefaultAnalysisOptions())
 	if err != nil {
 		return err
 	}
+	trafficDataList := dataset.Data
 
 	if len(trafficDataList) == 0 {
 		log.Printf("Tidak ada data lalu lintas untuk lokasi %s hari ini", location.ID)
 		return nil
 	}
 
+	if location.IsSimpang() {
+		return s.calculateSimpangForLocation(location, startOfDay, endOfDay, dataset)
+	}
+
 	totalKendaraanHari := 0
 	for _, td := range trafficDataList {
 		totalKendaraanHari += td.TotalKendaraan
@@ -204,38 +266,8 @@ func (s *TrafficCollectorService) calculateMKJI1997ForLocation(location models.L
 
 	arusLaluLintas, jamPuncak := models.HitungArusLaluLintas(trafficDataList, location.Tipe_lokasi)
 
-	kapasitasDasar := models.GetKapasitasDasar(location.Tipe_arah, location.Tipe_lokasi)
-	fcw := models.GetFCW(location.Lebar_jalur, location.Tipe_arah)
-	fcsp := models.GetFCSP(location.Persentase, location.Tipe_arah)
-
-	var fcsf, fccs float64
-	var kapasitas float64
-
-	switch location.Tipe_lokasi {
-	case "perkotaan":
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = models.GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
-	case "luar_kota", "12_kelas":
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf
-	case "bebas_hambatan":
-		fcsf = 1.0
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp
-	default:
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = models.GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
-	}
-
-	derajatKejenuhan := 0.0
-	if kapasitas > 0 {
-		derajatKejenuhan = arusLaluLintas / kapasitas
-	}
-
-	tingkatPelayanan, _ := models.GetTingkatPelayanan(derajatKejenuhan)
+	kinerja := models.EvaluateCapacity(models.MKJI1997Standard{}, location, arusLaluLintas)
+	derajatKejenuhan, tingkatPelayanan := kinerja.DerajatKejenuhan, kinerja.TingkatPelayanan
 
 	lhr := models.HitungLHR(totalKendaraanHari, 1)
 	lhrSMP := models.HitungLHRSMP(mkjiCount, 1)
@@ -251,14 +283,8 @@ func (s *TrafficCollectorService) calculateMKJI1997ForLocation(location models.L
 func (s *TrafficCollectorService) calculatePKJI2023ForLocation(location models.Location, trafficDataList []models.TrafficData, totalKendaraanHari int) error {
 	pkjiCount := models.HitungPKJICount(trafficDataList, location.Tipe_lokasi)
 	volumeLaluLintas, jamPuncak := models.HitungVolumePKJI(trafficDataList, location.Tipe_lokasi)
-	kapasitas, _, _, _, _, _ := models.HitungKapasitasPKJI(location)
-
-	derajatKejenuhan := 0.0
-	if kapasitas > 0 {
-		derajatKejenuhan = volumeLaluLintas / kapasitas
-	}
-
-	tingkatPelayanan, _ := models.GetTingkatPelayananPKJI(derajatKejenuhan)
+	kinerja := models.EvaluateCapacity(models.PKJI2023Standard{}, location, volumeLaluLintas)
+	derajatKejenuhan, tingkatPelayanan := kinerja.DerajatKejenuhan, kinerja.TingkatPelayanan
 
 	lhr := float64(totalKendaraanHari)
 	lhrSKR := pkjiCount.TotalSkr
@@ -270,12 +296,29 @@ func (s *TrafficCollectorService) calculatePKJI2023ForLocation(location models.L
 	return nil
 }
 
+// Menghitung kinerja simpang PKJI 2023 untuk lokasi simpang
+func (s *TrafficCollectorService) calculateSimpangForLocation(location models.Location, startTime, endTime time.Time, dataset *models.AnalysisDataset) error {
+	analysis, err := models.BuildSimpangAnalysis(&location, startTime, endTime, dataset)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Analisis simpang %s (%s): JamPuncak=%s, Arus=%.2f skr/jam, DJ=%.3f, Tundaan=%.1f detik/skr, LoS=%s",
+		location.Nama_lokasi, location.Jenis_lokasi, analysis.JamPuncak, analysis.ArusTotal,
+		analysis.DerajatKejenuhan, analysis.Tundaan, analysis.TingkatPelayanan)
+
+	return nil
+}
+
 // ProcessIncomingCameraData memproses data XML dari kamera dan menyimpan ke database
 func (s *TrafficCollectorService) ProcessIncomingCameraData(xmlData string) (*models.TrafficData, error) {
-	trafficData, err := models.ProcessCameraData(xmlData)
+	trafficData, status, err := models.ProcessCameraData(xmlData)
 	if err != nil {
 		return nil, err
 	}
+	if status == models.IngestStatusDuplicate {
+		return trafficData, nil
+	}
 
 	// MongoDB menyimpan waktu dalam UTC, jadi kita simpan waktu lokal (UTC+7)
 	localTime := time.Now().Add(7 * time.Hour)
@@ -370,6 +413,9 @@ func (s *TrafficCollectorService) monitorInactiveLocations() {
 			if err != nil {
 				log.Printf("Error mengecek lokasi tidak aktif: %v", err)
 			}
+			if err := models.CheckInactiveCameras(); err != nil {
+				log.Printf("Error mengecek kamera tidak aktif: %v", err)
+			}
 			s.logLocationStatus()
 		case <-s.stopChan:
 			log.Println("Menghentikan monitor lokasi tidak aktif")
@@ -418,59 +464,29 @@ func (s *TrafficCollectorService) GetLocationTrafficSummary(lokasiID string, sta
 		return nil, err
 	}
 
-	trafficDataList, err := models.GetTrafficDataByLokasiID(lokasiID, startTime, endTime)
+	dataset, err := models.LoadTrafficDataForAnalysis(location, startTime, endTime, models.DefaultAnalysisOptions())
 	if err != nil {
 		return nil, err
 	}
+	trafficDataList := dataset.Data
 
 	if len(trafficDataList) == 0 {
 		return &TrafficSummary{
-			LokasiID:   lokasiID,
-			NamaLokasi: location.Nama_lokasi,
-			StartTime:  startTime,
-			EndTime:    endTime,
-			DataCount:  0,
+			LokasiID:    lokasiID,
+			NamaLokasi:  location.Nama_lokasi,
+			StartTime:   startTime,
+			EndTime:     endTime,
+			DataCount:   0,
+			Kelengkapan: dataset.Kelengkapan,
 		}, nil
 	}
 
 	mkjiCount := models.HitungMKJICount(trafficDataList, location.Tipe_lokasi)
-	arusLaluLintas, jamPuncak := models.HitungArusLaluLintas(trafficDataList, location.Tipe_lokasi)
-
-	kapasitasDasar := models.GetKapasitasDasar(location.Tipe_arah, location.Tipe_lokasi)
-	fcw := models.GetFCW(location.Lebar_jalur, location.Tipe_arah)
-	fcsp := models.GetFCSP(location.Persentase, location.Tipe_arah)
-
-	var fcsf, fccs float64
-	var kapasitas float64
-
-	switch location.Tipe_lokasi {
-	case "perkotaan":
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = models.GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
-
-	case "luar_kota", "12_kelas":
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf
-
-	case "bebas_hambatan":
-		fcsf = 1.0
-		fccs = 1.0
-		kapasitas = kapasitasDasar * fcw * fcsp
-
-	default:
-		fcsf = models.GetFCSF(location.Tipe_hambatan, location.Kelas_hambatan, 1.5)
-		fccs = models.GetFCCS(location.Ukuran_kota)
-		kapasitas = kapasitasDasar * fcw * fcsp * fcsf * fccs
-	}
-
-	derajatKejenuhan := 0.0
-	if kapasitas > 0 {
-		derajatKejenuhan = arusLaluLintas / kapasitas
-	}
+	peakHour := models.HitungPeakHour(models.MKJI1997Standard{}, trafficDataList, location.Tipe_lokasi)
+	arusLaluLintas, jamPuncak := peakHour.Puncak.Arus, peakHour.Puncak.JamPuncak
 
-	tingkatPelayanan, _ := models.GetTingkatPelayanan(derajatKejenuhan)
+	kinerja := models.EvaluateCapacity(models.MKJI1997Standard{}, *location, arusLaluLintas)
+	kapasitas, derajatKejenuhan, tingkatPelayanan := kinerja.Kapasitas, kinerja.DerajatKejenuhan, kinerja.TingkatPelayanan
 
 	totalKendaraan := 0
 	for _, td := range trafficDataList {
@@ -487,24 +503,30 @@ func (s *TrafficCollectorService) GetLocationTrafficSummary(lokasiID string, sta
 		MKJICount:        mkjiCount,
 		ArusLaluLintas:   arusLaluLintas,
 		JamPuncak:        jamPuncak,
+		PHF:              peakHour.Puncak.PHF,
+		KFaktor:          peakHour.KFaktor,
 		Kapasitas:        kapasitas,
 		DerajatKejenuhan: derajatKejenuhan,
 		TingkatPelayanan: tingkatPelayanan,
+		Kelengkapan:      dataset.Kelengkapan,
 	}, nil
 }
 
 // TrafficSummary adalah struktur untuk ringkasan data lalu lintas
 type TrafficSummary struct {
-	LokasiID         string           `json:"lokasi_id"`
-	NamaLokasi       string           `json:"nama_lokasi"`
-	StartTime        time.Time        `json:"start_time"`
-	EndTime          time.Time        `json:"end_time"`
-	DataCount        int              `json:"data_count"`
-	TotalKendaraan   int              `json:"total_kendaraan"`
-	MKJICount        models.MKJICount `json:"mkji_count"`
-	ArusLaluLintas   float64          `json:"arus_lalu_lintas"`
-	JamPuncak        string           `json:"jam_puncak"`
-	Kapasitas        float64          `json:"kapasitas"`
-	DerajatKejenuhan float64          `json:"derajat_kejenuhan"`
-	TingkatPelayanan string           `json:"tingkat_pelayanan"`
+	LokasiID         string                  `json:"lokasi_id"`
+	NamaLokasi       string                  `json:"nama_lokasi"`
+	StartTime        time.Time               `json:"start_time"`
+	EndTime          time.Time               `json:"end_time"`
+	DataCount        int                     `json:"data_count"`
+	TotalKendaraan   int                     `json:"total_kendaraan"`
+	MKJICount        models.MKJICount        `json:"mkji_count"`
+	ArusLaluLintas   float64                 `json:"arus_lalu_lintas"`
+	JamPuncak        string                  `json:"jam_puncak"`
+	PHF              float64                 `json:"phf"`
+	KFaktor          float64                 `json:"k_faktor"`
+	Kapasitas        float64                 `json:"kapasitas"`
+	DerajatKejenuhan float64                 `json:"derajat_kejenuhan"`
+	TingkatPelayanan string                  `json:"tingkat_pelayanan"`
+	Kelengkapan      *models.KelengkapanData `json:"kelengkapan"`
 }